@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// runLeagues implements the leagues subcommand, printing the ids of the
+// leagues whose name matches a search, for finding the -leagueid to watch
+func runLeagues(args []string) error {
+	flags := flag.NewFlagSet("leagues", flag.ExitOnError)
+	var steamKey, search string
+	flags.StringVar(&steamKey, "steamkey", "", "Steam API Key")
+	flags.StringVar(&search, "search", "", "Part of the league name to search for, e.g. International")
+	flags.Parse(args)
+	if steamKey == "" {
+		return fmt.Errorf("steamkey is required")
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	client, err := dota.NewClient(logger, steamKey)
+	if err != nil {
+		return errors.Wrap(err, "Error creating dota client")
+	}
+	leagues, err := client.SearchLeagues(context.Background(), search)
+	if err != nil {
+		return errors.Wrap(err, "Error searching leagues")
+	}
+	if len(leagues) == 0 {
+		return fmt.Errorf("no leagues matching %q", search)
+	}
+	return printLeagues(os.Stdout, leagues)
+}
+
+// printLeagues prints the ids and names of leagues as a table, newest
+// league first as they are the ones usually looked for
+func printLeagues(w io.Writer, leagues []dota.League) error {
+	sort.Slice(leagues, func(i, j int) bool { return leagues[i].LeagueID > leagues[j].LeagueID })
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, league := range leagues {
+		fmt.Fprintf(tw, "%d\t%s\n", league.LeagueID, league.Name)
+	}
+	return tw.Flush()
+}
+
+// resolveLeagueName returns the id of the league named name, ignoring
+// case. If no league has the exact name, the only league whose name
+// contains it is used. Several such leagues are printed to stderr for the
+// user to pick the league id of.
+func resolveLeagueName(logger *logrus.Logger, steamKey, name string) (int, error) {
+	client, err := dota.NewClient(logger, steamKey)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error creating dota client")
+	}
+	leagues, err := client.SearchLeagues(context.Background(), name)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error searching leagues")
+	}
+	for _, league := range leagues {
+		if strings.EqualFold(strings.TrimSpace(league.Name), strings.TrimSpace(name)) {
+			return league.LeagueID, nil
+		}
+	}
+	switch len(leagues) {
+	case 0:
+		return 0, errors.Errorf("Error resolving league: no leagues matching %q", name)
+	case 1:
+		return leagues[0].LeagueID, nil
+	}
+	fmt.Fprintf(os.Stderr, "Several leagues match %q, use -leagueid with one of:\n", name)
+	printLeagues(os.Stderr, leagues)
+	return 0, errors.Errorf("Error resolving league: %d leagues matching %q", len(leagues), name)
+}
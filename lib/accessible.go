@@ -0,0 +1,136 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// accessibleScoreStyle is the score style of the accessible format, read
+// out as e.g. "2 to 1" by screen readers
+var accessibleScoreStyle = scoreStyle{name: "accessible", separator: " to "}
+
+// accessibleTemplates replace the announcement templates, by template
+// name, for guilds and users in accessibility mode. They label the
+// winner explicitly and are written as sentences, so that they read well
+// using a screen reader.
+var accessibleTemplates = templateCatalog(
+	tmplMatchesDraftingAccessible,
+	tmplMatchesStartedAccessible,
+	tmplScoreUpdatesAccessible,
+	tmplMatchesFinishedAccessible,
+	tmplFloodDigestAccessible,
+)
+
+var tmplMatchesDraftingAccessible = template.Must(newTemplate("MatchesDrafting").Parse(strings.TrimSpace(`
+{{ range . }}
+Drafting: {{ .RadiantTeam.TeamName }} versus {{ .DireTeam.TeamName }}, {{ with .Stage }}{{ . }}, {{ end }}game {{ .GameNumber }}.
+{{- end -}}
+`)))
+
+var tmplMatchesStartedAccessible = template.Must(newTemplate("MatchesStarted").Parse(strings.TrimSpace(`
+{{ range . }}
+Match started: {{ .RadiantTeam.TeamName }} versus {{ .DireTeam.TeamName }}, {{ with .Stage }}{{ . }}, {{ end }}game {{ .GameNumber }}{{ with clock }}, at {{ . }}{{ end }}.
+{{- end -}}
+`)))
+
+var tmplScoreUpdatesAccessible = template.Must(newTemplate("ScoreUpdates").Parse(strings.TrimSpace(`
+{{ range . }}
+Score update, game {{ .Game.GameNumber }}: {{ .Game.RadiantTeam.TeamName }} {{ .RadiantScore }} kills, {{ .Game.DireTeam.TeamName }} {{ .DireScore }} kills, after {{ .Duration }}.{{ if .Favored }} {{ .Favored }} is favored to win, at {{ .WinChance }} percent.{{ end }}
+{{- if .RoshanAlive }} Roshan is alive.{{ else if .RoshanRespawn }} Roshan respawns in {{ .RoshanRespawn }}.{{ end }}
+{{- end -}}
+`)))
+
+var tmplMatchesFinishedAccessible = template.Must(newTemplate("MatchesFinished").Parse(strings.TrimSpace(`
+{{ range . }}
+{{- if .Remake }}
+Game {{ .GameNumber }}, {{ .FirstTeam }} versus {{ .SecondTeam }}, ended early and is replayed.
+{{- else }}
+Match ended{{ if .Delayed }}, result delayed{{ end }}, {{ with .Stage }}{{ . }}, {{ end }}game {{ .GameNumber }}. {{ if winners }}Winner: {{ .WinnerName }}. Loser: {{ .LoserName }}.{{ else }}{{ .FirstTeam }} versus {{ .SecondTeam }}.{{ end }}{{ if kills }} Kills: {{ score .WinnerScore .LoserScore }}.{{ end }}{{ if and winners .UpsetWinnerSeed }} An upset: seed {{ .UpsetWinnerSeed }} defeated seed {{ .UpsetLoserSeed }}.{{ end }}{{ with clock }} Ended at {{ . }}.{{ end }}
+{{- end }}
+{{- end -}}
+`)))
+
+var tmplFloodDigestAccessible = template.Must(newTemplate("FloodDigest").Parse(strings.TrimSpace(`
+{{ if .Started }}Other games started in the last hour:
+{{- range .Started }}
+{{ .RadiantTeam.TeamName }} versus {{ .DireTeam.TeamName }}, game {{ .GameNumber }}.
+{{- end }}
+{{ end }}
+{{- if .Finished }}Other games ended in the last hour:
+{{- range .Finished }}
+Game {{ .GameNumber }}. {{ if winners }}Winner: {{ .WinnerName }}. Loser: {{ .LoserName }}.{{ else }}{{ .FirstTeam }} versus {{ .SecondTeam }}.{{ end }}{{ if kills }} Kills: {{ score .WinnerScore .LoserScore }}.{{ end }}
+{{- end }}
+{{- end -}}
+`)))
+
+// rendered returns the template to render for tmpl in the format: the
+// accessible variant in accessibility mode, the compact variant in the
+// compact layout, or else the translation of tmpl into the language of
+// the format
+func (format textFormat) rendered(tmpl *template.Template) *template.Template {
+	if format.accessible {
+		if accessible, ok := accessibleTemplates[tmpl.Name()]; ok {
+			return accessible
+		}
+		return tmpl
+	}
+	if format.compact {
+		if compact, ok := compactTemplates[tmpl.Name()]; ok {
+			return compact
+		}
+	}
+	return format.language.localize(tmpl)
+}
+
+// onOffString describes whether a mode, e.g. accessibility mode, is on
+func onOffString(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// handleAccessibleCommand turns accessibility mode on or off for the
+// guild, or for the direct messages of the user if used in direct
+// messages. Changing the mode of a guild requires the Manage Server
+// permission.
+func (bot *bot) handleAccessibleCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	state := strings.ToLower(in.stringOption("state"))
+	if state != "" && state != "on" && state != "off" {
+		return textResponse("Give \"on\" or \"off\"."), nil
+	}
+	if in.GuildID == "" {
+		if state == "" {
+			sub, err := bot.interactionSubscription(ctx, in)
+			if err != nil {
+				return nil, err
+			}
+			return textResponse("Accessibility mode is " + onOffString(sub != nil && sub.Accessible) + "."), nil
+		}
+		return bot.changeDMSubscription(ctx, in, func(settings *guildSettings) string {
+			sub := settings.subscription(in.ChannelID)
+			if sub == nil {
+				return "You are not subscribed to announcements."
+			}
+			sub.Accessible = state == "on"
+			return "Accessibility mode is now " + state + "."
+		})
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	if state == "" {
+		return textResponse("Accessibility mode is " + onOffString(settings.Accessible) + "."), nil
+	}
+	if !in.hasPermission(permissionManageGuild) {
+		return textResponse("Changing accessibility mode requires the Manage Server permission."), nil
+	}
+	settings.Accessible = state == "on"
+	if err := bot.saveGuildSettings(ctx, guildID(in.GuildID), settings); err != nil {
+		return nil, err
+	}
+	return textResponse(fmt.Sprintf("Accessibility mode is now %s for this server.", state)), nil
+}
@@ -0,0 +1,36 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAccessibleRendering(t *testing.T) {
+	format := (&guildSettings{Accessible: true, Language: "ru", ScoreStyle: "colon"}).textFormat()
+	items := []matchesFinishedDataItem{{GameNumber: 2, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17}}
+	got, err := renderTemplate(tmplMatchesFinished, format, items)
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	if want := "Match ended, game 2. Winner: OG. Loser: Liquid. Kills: 32 to 17."; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+	// Every template has an accessible variant
+	for _, preview := range previewTemplates {
+		if _, ok := accessibleTemplates[preview.tmpl.Name()]; !ok {
+			t.Errorf("No accessible variant of %s", preview.name)
+			continue
+		}
+		if _, err := renderTemplate(preview.tmpl, format, preview.fixture); err != nil {
+			t.Errorf("Error rendering accessible %s: %v", preview.name, err)
+		}
+	}
+
+	result := matchResult{matchesFinishedDataItem: items[0]}
+	if got := renderResult(result, true, format); strings.Contains(got, "||") || strings.Contains(got, "32") {
+		t.Errorf("renderResult() with spoilers = %q, want the result left out", got)
+	}
+	if got, want := renderResult(result, false, format), "Game 2. Winner: OG. Loser: Liquid. Kills: 32 to 17."; got != want {
+		t.Errorf("renderResult() = %q, want %q", got, want)
+	}
+}
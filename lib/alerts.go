@@ -0,0 +1,82 @@
+package timatch
+
+import (
+	"sync"
+	"time"
+)
+
+// alertCooldown is the minimum time between two admin alerts of the
+// same kind
+const alertCooldown = 30 * time.Minute
+
+// steamFailureAlertThreshold is the number of consecutive failed Steam
+// API polls after which the admin is alerted
+const steamFailureAlertThreshold = 5
+
+// Kinds of admin alerts, used for rate limiting alerts
+const (
+	alertSteamFailures = "steam_failures"
+	alertNoChannels    = "no_channels"
+	alertGaveUp        = "gave_up"
+)
+
+// adminAlerts keeps track of sent admin alerts, for rate limiting
+type adminAlerts struct {
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+	// steamFailures is the number of consecutive failed Steam API polls.
+	// Guarded by the bot's stateMu
+	steamFailures int
+}
+
+// alertAdmin sends an operational alert to the admin channel, if one is
+// configured. Alerts of the same kind are sent at most once per
+// alertCooldown.
+func (bot *bot) alertAdmin(kind string, content string) {
+	if bot.adminChannelID == "" {
+		return
+	}
+	bot.alerts.mu.Lock()
+	if time.Since(bot.alerts.lastSent[kind]) < alertCooldown {
+		bot.alerts.mu.Unlock()
+		bot.logger.Debugf("Not sending %s alert, sent recently", kind)
+		return
+	}
+	bot.alerts.lastSent[kind] = time.Now()
+	bot.alerts.mu.Unlock()
+	bot.sendAdmin(content)
+}
+
+// sendAdmin sends a message to the admin channel, if one is configured,
+// without rate limiting
+func (bot *bot) sendAdmin(content string) {
+	if bot.adminChannelID == "" {
+		return
+	}
+	_, err := bot.discordSession.ChannelMessageSend(string(bot.adminChannelID), "**[timatch]** "+content)
+	if err != nil {
+		bot.logger.WithField(logFieldChannelID, bot.adminChannelID).WithError(err).Error("Failed sending admin alert")
+	}
+}
+
+// steamPollFailed records a failed Steam API poll, alerting the admin
+// once there have been steamFailureAlertThreshold failures in a row.
+// Failures during maintenance are expected, so are not counted.
+func (bot *bot) steamPollFailed(err error) {
+	if bot.maintenance.active(time.Now()) {
+		return
+	}
+	bot.alerts.steamFailures++
+	if bot.alerts.steamFailures == steamFailureAlertThreshold {
+		bot.alertAdmin(alertSteamFailures, "Steam API requests have failed "+
+			"several times in a row, last error: "+err.Error())
+	}
+}
+
+// steamPollSucceeded resets the consecutive Steam API failure count
+func (bot *bot) steamPollSucceeded() {
+	if bot.alerts.steamFailures >= steamFailureAlertThreshold {
+		bot.alertAdmin(alertSteamFailures+"_recovered", "Steam API requests are succeeding again")
+	}
+	bot.alerts.steamFailures = 0
+}
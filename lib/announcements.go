@@ -0,0 +1,58 @@
+package timatch
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// announcementSeries is the event of a playoff series finishing, used in
+// the idempotency keys of announcements next to the match states
+const announcementSeries = "series"
+
+// announcementKey returns the idempotency key of the announcement of an
+// event of a match (or series) to a channel
+func announcementKey(event string, id int64, channelID channelID) string {
+	return "announced/" + event + "/" + strconv.FormatInt(id, 10) + "/" + string(channelID)
+}
+
+// claimAnnouncement records in the store that an event of a match (or
+// series) is announced to a channel, before the announcement is sent. ok
+// is false if the event was already announced to the channel, in which
+// case it must not be sent again. The keys are per channel, so that an
+// announcement interrupted part way, e.g. by a restart, is never repeated
+// to the channels that already got it.
+func (bot *bot) claimAnnouncement(ctx context.Context, event string, id int64, channelID channelID) (ok bool) {
+	ok, err := bot.store.SetNX(ctx, announcementKey(event, id, channelID), time.Now(), finishedMatchTTL)
+	if err != nil {
+		// Announcing twice is better than not announcing at all
+		bot.logger.WithField(logFieldChannelID, channelID).WithError(err).Errorf("Error recording %s announcement of %d", event, id)
+		return true
+	}
+	return ok
+}
+
+// claimGames claims the announcement of an event of the games to a
+// channel, returning the games not already announced to it
+func (bot *bot) claimGames(ctx context.Context, event string, channelID channelID, games []dota.LiveLeagueGame) []dota.LiveLeagueGame {
+	claimed := make([]dota.LiveLeagueGame, 0, len(games))
+	for _, game := range games {
+		if bot.claimAnnouncement(ctx, event, game.MatchID, channelID) {
+			claimed = append(claimed, game)
+		}
+	}
+	return claimed
+}
+
+// claimFinished is the claimGames equivalent for finished games
+func (bot *bot) claimFinished(ctx context.Context, channelID channelID, items []matchesFinishedDataItem) []matchesFinishedDataItem {
+	claimed := make([]matchesFinishedDataItem, 0, len(items))
+	for _, item := range items {
+		if bot.claimAnnouncement(ctx, matchStateFinished, item.MatchID, channelID) {
+			claimed = append(claimed, item)
+		}
+	}
+	return claimed
+}
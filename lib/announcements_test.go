@@ -0,0 +1,40 @@
+package timatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestClaimGames(t *testing.T) {
+	ctx := context.Background()
+	bot := &bot{store: storage.NewMemoryStore()}
+	games := []dota.LiveLeagueGame{{MatchID: 1}, {MatchID: 2}}
+	if got := bot.claimGames(ctx, matchStateStarted, "10", games[:1]); len(got) != 1 {
+		t.Fatalf("claimGames() = %v, want match 1", got)
+	}
+	got := bot.claimGames(ctx, matchStateStarted, "10", games)
+	if len(got) != 1 || got[0].MatchID != 2 {
+		t.Errorf("claimGames() after announcing match 1 = %v, want match 2", got)
+	}
+	if got := bot.claimGames(ctx, matchStateStarted, "20", games); len(got) != 2 {
+		t.Errorf("claimGames() of another channel = %v, want both matches", got)
+	}
+	if got := bot.claimGames(ctx, matchStateDrafting, "10", games); len(got) != 2 {
+		t.Errorf("claimGames() of another event = %v, want both matches", got)
+	}
+}
+
+func TestClaimFinished(t *testing.T) {
+	ctx := context.Background()
+	bot := &bot{store: storage.NewMemoryStore()}
+	items := []matchesFinishedDataItem{{MatchID: 1}}
+	if got := bot.claimFinished(ctx, "10", items); len(got) != 1 {
+		t.Errorf("claimFinished() = %v, want match 1", got)
+	}
+	if got := bot.claimFinished(ctx, "10", items); len(got) != 0 {
+		t.Errorf("claimFinished() again = %v, want none", got)
+	}
+}
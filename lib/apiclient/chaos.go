@@ -0,0 +1,125 @@
+package apiclient
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Chaos describes faults injected into the requests of all clients, for
+// verifying that the bot copes with misbehaving APIs. Rates are the
+// probability, from 0 to 1, of a request being affected.
+type Chaos struct {
+	// FailureRate is the rate of requests failing with a 503 status
+	FailureRate float64
+	// DelayRate is the rate of requests being delayed by up to MaxDelay
+	DelayRate float64
+	MaxDelay  time.Duration
+	// MalformedRate is the rate of responses being cut short, so that
+	// they can not be decoded
+	MalformedRate float64
+}
+
+// defaultChaosMaxDelay is the MaxDelay of chaos not giving one
+const defaultChaosMaxDelay = 10 * time.Second
+
+var (
+	chaosMu sync.Mutex
+	chaos   *Chaos
+	// chaosRand is guarded by chaosMu, as rand.Rand is not safe for
+	// concurrent use
+	chaosRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetChaos enables injecting the faults described by c into the requests
+// of all clients, or disables it if c is nil
+func SetChaos(c *Chaos) {
+	chaosMu.Lock()
+	defer chaosMu.Unlock()
+	chaos = c
+}
+
+// ParseChaos parses a comma separated list of key=value pairs, e.g.
+// "fail=0.1,delay=0.2,maxdelay=5s,malformed=0.05"
+func ParseChaos(s string) (*Chaos, error) {
+	c := &Chaos{MaxDelay: defaultChaosMaxDelay}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("Invalid chaos option %q, expected key=value", part)
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if key == "maxdelay" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, errors.Errorf("Invalid chaos maxdelay %q", value)
+			}
+			c.MaxDelay = d
+			continue
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, errors.Errorf("Invalid chaos rate %q of %s, expected 0 to 1", value, key)
+		}
+		switch key {
+		case "fail":
+			c.FailureRate = rate
+		case "delay":
+			c.DelayRate = rate
+		case "malformed":
+			c.MalformedRate = rate
+		default:
+			return nil, errors.Errorf("Unknown chaos option %q", key)
+		}
+	}
+	return c, nil
+}
+
+// chaosFault is the fault injected into a request
+type chaosFault struct {
+	delay     time.Duration
+	fail      bool
+	malformed bool
+}
+
+// nextChaosFault decides the faults to inject into the next request
+func nextChaosFault() chaosFault {
+	chaosMu.Lock()
+	defer chaosMu.Unlock()
+	var fault chaosFault
+	if chaos == nil {
+		return fault
+	}
+	if chaosRand.Float64() < chaos.DelayRate {
+		fault.delay = time.Duration(chaosRand.Float64() * float64(chaos.MaxDelay))
+	}
+	fault.fail = chaosRand.Float64() < chaos.FailureRate
+	fault.malformed = chaosRand.Float64() < chaos.MalformedRate
+	return fault
+}
+
+// inject applies the fault to a request about to be sent. Returns an
+// error if the request should fail instead of being sent.
+func (fault chaosFault) inject(ctx context.Context) error {
+	if fault.delay > 0 {
+		select {
+		case <-time.After(fault.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if fault.fail {
+		return errors.WithStack(&StatusError{StatusCode: http.StatusServiceUnavailable})
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package apiclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChaos(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    *Chaos
+		wantErr bool
+	}{
+		{"", &Chaos{MaxDelay: defaultChaosMaxDelay}, false},
+		{"fail=0.1, delay=0.5,maxdelay=2s,malformed=1", &Chaos{FailureRate: 0.1, DelayRate: 0.5, MaxDelay: 2 * time.Second, MalformedRate: 1}, false},
+		{"fail=1.5", nil, true},
+		{"fail", nil, true},
+		{"maxdelay=-1s", nil, true},
+		{"explode=0.1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChaos(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseChaos(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseChaos(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestNextChaosFault(t *testing.T) {
+	defer SetChaos(nil)
+	if fault := nextChaosFault(); fault != (chaosFault{}) {
+		t.Errorf("nextChaosFault() without chaos = %+v, want none", fault)
+	}
+	SetChaos(&Chaos{FailureRate: 1, DelayRate: 1, MaxDelay: time.Second, MalformedRate: 1})
+	fault := nextChaosFault()
+	if !fault.fail || !fault.malformed || fault.delay >= time.Second {
+		t.Errorf("nextChaosFault() with all faults = %+v, want all faults", fault)
+	}
+	SetChaos(&Chaos{MaxDelay: time.Second})
+	if fault := nextChaosFault(); fault != (chaosFault{}) {
+		t.Errorf("nextChaosFault() with zero rates = %+v, want none", fault)
+	}
+}
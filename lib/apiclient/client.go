@@ -0,0 +1,122 @@
+// Package apiclient implements the HTTP plumbing shared by the clients of
+// the web APIs used by the bot: rate limiting and decoding of JSON
+// responses.
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Client sends requests, at most one per interval, keeping stats of
+// the requests per endpoint
+type Client struct {
+	logger   *logrus.Logger
+	interval time.Duration
+
+	rateLimitCh chan struct{}
+
+	statsMu sync.Mutex
+	stats   map[string]*endpointStats
+}
+
+func NewClient(logger *logrus.Logger, interval time.Duration) *Client {
+	rateLimitCh := make(chan struct{}, 1)
+	rateLimitCh <- struct{}{}
+	return &Client{
+		logger:      logger,
+		interval:    interval,
+		rateLimitCh: rateLimitCh,
+		stats:       make(map[string]*endpointStats),
+	}
+}
+
+// StatusError is returned for responses with a status code other than 200
+type StatusError struct {
+	StatusCode int
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("Bad HTTP response status code: %d", err.StatusCode)
+}
+
+// NewRequest creates a GET request for apiPath, relative to baseURL
+func NewRequest(ctx context.Context, baseURL *url.URL, apiPath string) (*http.Request, error) {
+	u, err := url.Parse(apiPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing apiPath")
+	}
+	req, err := http.NewRequest("GET", baseURL.ResolveReference(u).String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating Request")
+	}
+	return req.WithContext(ctx), nil
+}
+
+func (client *Client) getRateLimitToken(ctx context.Context) (returnToken func(), err error) {
+	select {
+	case <-client.rateLimitCh:
+	case <-ctx.Done():
+		return func() {}, ctx.Err()
+	}
+	// The returned func, when called, spawns a go-routine that returns
+	// the rate limit token to the channel when a new request is allowed
+	// to be made.
+	return func() {
+		go func() {
+			time.Sleep(client.interval)
+			client.rateLimitCh <- struct{}{}
+		}()
+	}, nil
+}
+
+// GetJSON sends req and decodes the JSON response into jsonRes, unless
+// jsonRes is nil. fields are added to the log entry of the request.
+func (client *Client) GetJSON(ctx context.Context, req *http.Request, jsonRes interface{}, fields logrus.Fields) error {
+	returnToken, err := client.getRateLimitToken(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error while waiting for rate limit token")
+	}
+	defer returnToken()
+
+	start := time.Now()
+	err = client.getJSON(ctx, req, jsonRes, fields)
+	client.record(req.URL.Path, time.Since(start), err)
+	return err
+}
+
+func (client *Client) getJSON(ctx context.Context, req *http.Request, jsonRes interface{}, fields logrus.Fields) error {
+	fault := nextChaosFault()
+	if err := fault.inject(ctx); err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "Error sending request")
+	}
+	defer res.Body.Close()
+	client.logger.WithFields(fields).WithField("path", req.URL.EscapedPath()).WithField("status", res.StatusCode).
+		Debugf("%s: %s - [%s]", req.Method, req.URL.EscapedPath(), res.Status)
+	if res.StatusCode != 200 {
+		return errors.WithStack(&StatusError{StatusCode: res.StatusCode})
+	}
+	if jsonRes != nil {
+		var body io.Reader = res.Body
+		if fault.malformed {
+			body = io.LimitReader(body, 16)
+		}
+		if err := json.NewDecoder(body).Decode(jsonRes); err != nil {
+			return errors.Wrap(err, "Error decoding result as JSON")
+		}
+	}
+	return nil
+}
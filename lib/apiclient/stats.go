@@ -0,0 +1,106 @@
+package apiclient
+
+import (
+	"sort"
+	"time"
+)
+
+// latencySamples is the number of latencies kept per endpoint, for
+// computing latency percentiles
+const latencySamples = 100
+
+// EndpointStats are the stats of the requests sent to an endpoint
+type EndpointStats struct {
+	// Endpoint is the path of the endpoint
+	Endpoint string
+	Requests int
+	Failures int
+	// ConsecutiveFailures is the number of requests that have failed
+	// since the last successful request
+	ConsecutiveFailures int
+	// P95Latency is the 95th percentile latency of the last requests
+	P95Latency  time.Duration
+	LastError   string
+	LastErrorAt time.Time
+}
+
+// SuccessRate returns the share of requests that succeeded, from 0 to 1
+func (stats EndpointStats) SuccessRate() float64 {
+	if stats.Requests == 0 {
+		return 1
+	}
+	return float64(stats.Requests-stats.Failures) / float64(stats.Requests)
+}
+
+// endpointStats tracks the requests sent to an endpoint
+type endpointStats struct {
+	EndpointStats
+	// latencies is a ring buffer of the latest latencies
+	latencies []time.Duration
+	next      int
+}
+
+// record records a request to endpoint, that took latency and failed
+// with err unless nil
+func (client *Client) record(endpoint string, latency time.Duration, err error) {
+	client.statsMu.Lock()
+	defer client.statsMu.Unlock()
+	stats, ok := client.stats[endpoint]
+	if !ok {
+		stats = &endpointStats{EndpointStats: EndpointStats{Endpoint: endpoint}}
+		client.stats[endpoint] = stats
+	}
+	stats.Requests++
+	if err != nil {
+		stats.Failures++
+		stats.ConsecutiveFailures++
+		stats.LastError = err.Error()
+		stats.LastErrorAt = time.Now()
+	} else {
+		stats.ConsecutiveFailures = 0
+	}
+	if len(stats.latencies) < latencySamples {
+		stats.latencies = append(stats.latencies, latency)
+	} else {
+		stats.latencies[stats.next] = latency
+		stats.next = (stats.next + 1) % latencySamples
+	}
+}
+
+// Stats returns the stats of the endpoints requested by the client,
+// ordered by endpoint
+func (client *Client) Stats() []EndpointStats {
+	client.statsMu.Lock()
+	defer client.statsMu.Unlock()
+	all := make([]EndpointStats, 0, len(client.stats))
+	for _, stats := range client.stats {
+		s := stats.EndpointStats
+		s.P95Latency = percentile(stats.latencies, 0.95)
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Endpoint < all[j].Endpoint
+	})
+	return all
+}
+
+// percentile returns the p percentile (0 to 1) of latencies, using the
+// nearest-rank method
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
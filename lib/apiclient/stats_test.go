@@ -0,0 +1,45 @@
+package apiclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClientStats(t *testing.T) {
+	client := NewClient(nil, time.Second)
+	client.record("/a", 10*time.Millisecond, nil)
+	client.record("/a", 30*time.Millisecond, errors.New("boom"))
+	client.record("/a", 20*time.Millisecond, errors.New("bang"))
+	client.record("/b", 5*time.Millisecond, nil)
+	stats := client.Stats()
+	if len(stats) != 2 || stats[0].Endpoint != "/a" || stats[1].Endpoint != "/b" {
+		t.Fatalf("Stats() = %+v, want /a and /b", stats)
+	}
+	a := stats[0]
+	if a.Requests != 3 || a.Failures != 2 || a.ConsecutiveFailures != 2 {
+		t.Errorf("Stats() of /a = %+v, want 3 requests, 2 failures in a row", a)
+	}
+	if a.LastError != "bang" {
+		t.Errorf("LastError = %q, want bang", a.LastError)
+	}
+	if a.P95Latency != 30*time.Millisecond {
+		t.Errorf("P95Latency = %v, want 30ms", a.P95Latency)
+	}
+	if rate := stats[1].SuccessRate(); rate != 1 {
+		t.Errorf("SuccessRate() of /b = %v, want 1", rate)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i))
+	}
+	if got := percentile(latencies, 0.95); got != 95 {
+		t.Errorf("percentile(1..100, 0.95) = %v, want 95", got)
+	}
+	if got := percentile(nil, 0.95); got != 0 {
+		t.Errorf("percentile(nil) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,102 @@
+package timatch
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/verath/timatch/lib/apiclient"
+)
+
+// apiHealthOpenFailures is the number of failed requests in a row after
+// which an endpoint is shown as failing
+const apiHealthOpenFailures = 3
+
+// apiEndpointHealth is the health of a Steam API endpoint, as shown on
+// the /apihealth panel
+type apiEndpointHealth struct {
+	Endpoint     string  `json:"endpoint"`
+	Requests     int     `json:"requests"`
+	SuccessRate  float64 `json:"success_rate"`
+	P95LatencyMS float64 `json:"p95_latency_ms"`
+	LastError    string  `json:"last_error,omitempty"`
+	LastErrorAt  string  `json:"last_error_at,omitempty"`
+	// State is "ok", or "failing" after apiHealthOpenFailures failed
+	// requests in a row. The client has no circuit breaker, so this is
+	// what a breaker would report.
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+func newAPIEndpointHealth(stats apiclient.EndpointStats) apiEndpointHealth {
+	health := apiEndpointHealth{
+		Endpoint:            stats.Endpoint,
+		Requests:            stats.Requests,
+		SuccessRate:         stats.SuccessRate(),
+		P95LatencyMS:        float64(stats.P95Latency) / float64(time.Millisecond),
+		LastError:           stats.LastError,
+		State:               "ok",
+		ConsecutiveFailures: stats.ConsecutiveFailures,
+	}
+	if !stats.LastErrorAt.IsZero() {
+		health.LastErrorAt = stats.LastErrorAt.Format(time.RFC3339)
+	}
+	if stats.ConsecutiveFailures >= apiHealthOpenFailures {
+		health.State = "failing"
+	}
+	return health
+}
+
+const apiHealthHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Steam API health</title></head>
+<body>
+<h1>Steam API health</h1>
+<table>
+<tr><th>Endpoint</th><th>Requests</th><th>Success rate</th><th>p95 latency</th><th>State</th><th>Last error</th></tr>
+{{- range .}}
+<tr><td>{{.Endpoint}}</td><td>{{.Requests}}</td><td>{{printf "%.1f" (percent .SuccessRate)}}%</td><td>{{printf "%.0f" .P95LatencyMS}} ms</td><td>{{.State}} ({{.ConsecutiveFailures}} failed in a row)</td><td>{{if .LastError}}{{.LastError}} at {{.LastErrorAt}}{{end}}</td></tr>
+{{- else}}
+<tr><td colspan="6">No requests sent yet</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`
+
+var tmplAPIHealthHTML = htmltemplate.Must(htmltemplate.New("APIHealthHTML").Funcs(htmltemplate.FuncMap{
+	"percent": func(rate float64) float64 { return 100 * rate },
+}).Parse(apiHealthHTML))
+
+// handleAPIHealth serves the success rate, latency and last error of
+// each Steam API endpoint, as JSON or, for /apihealth.html, as HTML
+func (bot *bot) handleAPIHealth(w http.ResponseWriter, r *http.Request) {
+	stats := bot.dotaClient.Stats()
+	if bot.stratzClient != nil {
+		stats = append(stats, bot.stratzClient.Stats()...)
+	}
+	endpoints := make([]apiEndpointHealth, len(stats))
+	for i, s := range stats {
+		endpoints[i] = newAPIEndpointHealth(s)
+	}
+	var buf bytes.Buffer
+	var err error
+	if strings.HasSuffix(r.URL.Path, ".html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = tmplAPIHealthHTML.Execute(&buf, endpoints)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(&buf).Encode(endpoints)
+	}
+	if err != nil {
+		bot.logger.WithError(err).Error("Error rendering API health")
+		http.Error(w, "Error rendering API health", http.StatusInternalServerError)
+		return
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		bot.logger.WithError(err).Error("Error writing API health response")
+	}
+}
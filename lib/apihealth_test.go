@@ -0,0 +1,35 @@
+package timatch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/apiclient"
+)
+
+func TestAPIEndpointHealth(t *testing.T) {
+	stats := apiclient.EndpointStats{
+		Endpoint:            "/IDOTA2Match_570/GetMatchDetails/v1",
+		Requests:            4,
+		Failures:            3,
+		ConsecutiveFailures: 3,
+		P95Latency:          250 * time.Millisecond,
+		LastError:           "Unexpected status code 503",
+		LastErrorAt:         time.Date(2019, 8, 20, 12, 0, 0, 0, time.UTC),
+	}
+	health := newAPIEndpointHealth(stats)
+	if health.State != "failing" || health.SuccessRate != 0.25 || health.P95LatencyMS != 250 {
+		t.Errorf("newAPIEndpointHealth() = %+v, want failing, 0.25, 250ms", health)
+	}
+	var buf bytes.Buffer
+	if err := tmplAPIHealthHTML.Execute(&buf, []apiEndpointHealth{health}); err != nil {
+		t.Fatalf("Error rendering API health: %v", err)
+	}
+	for _, want := range []string{"25.0%", "250 ms", "failing (3 failed in a row)", "2019-08-20T12:00:00Z"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("API health HTML does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
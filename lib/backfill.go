@@ -0,0 +1,74 @@
+package timatch
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// backfillSeriesGap is the longest time between the starts of two games
+// of the same teams for them to be games of the same series
+const backfillSeriesGap = 3 * time.Hour
+
+// backfillDue tests if the finished games of the watched league are yet
+// to be backfilled. Not done when following games, as without a league
+func (watcher *Watcher) backfillDue() bool {
+	return watcher.backfill > 0 && !watcher.following && watcher.backfilledLeague != watcher.leagueID
+}
+
+// backfillFinished queues the matches of the match history that started
+// within the backfill period and have not been seen finished, so that the
+// games finished while the watcher was not running are sent as finished
+func (watcher *Watcher) backfillFinished(ctx context.Context, matches []dota.MatchHistoryMatch) {
+	watcher.backfilledLeague = watcher.leagueID
+	since := time.Now().Add(-watcher.backfill).Unix()
+	gameNumbers := backfillGameNumbers(matches)
+	queued := 0
+	for _, match := range matches {
+		if match.StartTime < since {
+			continue
+		}
+		if _, ok := watcher.finished[match.MatchID]; ok {
+			continue
+		}
+		watcher.markFinished(match.MatchID, time.Now())
+		if !watcher.claimState(ctx, matchStateFinished, match.MatchID) {
+			continue
+		}
+		watcher.backfilled[match.MatchID] = gameNumbers[match.MatchID]
+		watcher.finishedQueue = append(watcher.finishedQueue, finishedQueueEntry{MatchID: match.MatchID, AddedAt: time.Now()})
+		queued++
+	}
+	watcher.logger.WithField(logFieldLeagueID, watcher.leagueID).Infof("Backfilling %d finished matches", queued)
+}
+
+// backfillGameNumbers infers the game numbers of matches of the match
+// history, as the history does not give them: games of the same teams
+// started within backfillSeriesGap of each other are games of a series
+func backfillGameNumbers(matches []dota.MatchHistoryMatch) map[int64]int {
+	sorted := make([]dota.MatchHistoryMatch, len(matches))
+	copy(sorted, matches)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].StartTime < sorted[j].StartTime })
+	type teams [2]int
+	type lastGame struct {
+		startTime  int64
+		gameNumber int
+	}
+	last := make(map[teams]lastGame)
+	gameNumbers := make(map[int64]int, len(matches))
+	for _, match := range sorted {
+		pair := teams{match.RadiantTeamID, match.DireTeamID}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		gameNumber := 1
+		if prev, ok := last[pair]; ok && pair[0] != 0 && match.StartTime-prev.startTime <= int64(backfillSeriesGap/time.Second) {
+			gameNumber = prev.gameNumber + 1
+		}
+		last[pair] = lastGame{startTime: match.StartTime, gameNumber: gameNumber}
+		gameNumbers[match.MatchID] = gameNumber
+	}
+	return gameNumbers
+}
@@ -0,0 +1,56 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestBackfillGameNumbers(t *testing.T) {
+	hour := int64(60 * 60)
+	matches := []dota.MatchHistoryMatch{
+		{MatchID: 3, StartTime: 2 * hour, RadiantTeamID: 20, DireTeamID: 10},
+		{MatchID: 1, StartTime: 0, RadiantTeamID: 10, DireTeamID: 20},
+		{MatchID: 2, StartTime: 0, RadiantTeamID: 30, DireTeamID: 40},
+		{MatchID: 4, StartTime: 24 * hour, RadiantTeamID: 10, DireTeamID: 20},
+	}
+	got := backfillGameNumbers(matches)
+	want := map[int64]int{1: 1, 2: 1, 3: 2, 4: 1}
+	for matchID, gameNumber := range want {
+		if got[matchID] != gameNumber {
+			t.Errorf("backfillGameNumbers()[%d] = %d, want %d", matchID, got[matchID], gameNumber)
+		}
+	}
+}
+
+func TestBackfillFinished(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	watcher := newWatcher(logger, &stubDataSource{})
+	watcher.leagueID = 1
+	watcher.backfill = 24 * time.Hour
+	watcher.finished[2] = struct{}{}
+	now := time.Now().Unix()
+	matches := []dota.MatchHistoryMatch{
+		{MatchID: 1, StartTime: now - 60*60},
+		{MatchID: 2, StartTime: now - 2*60*60},
+		{MatchID: 3, StartTime: now - 48*60*60},
+	}
+	if !watcher.backfillDue() {
+		t.Fatal("backfillDue() = false, want true")
+	}
+	watcher.backfillFinished(context.Background(), matches)
+	if len(watcher.finishedQueue) != 1 || watcher.finishedQueue[0].MatchID != 1 {
+		t.Errorf("finishedQueue = %v, want only match 1", watcher.finishedQueue)
+	}
+	if watcher.backfilled[1] != 1 {
+		t.Errorf("backfilled[1] = %d, want game 1", watcher.backfilled[1])
+	}
+	if watcher.backfillDue() {
+		t.Error("backfillDue() after backfilling = true, want false")
+	}
+}
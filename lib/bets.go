@@ -0,0 +1,309 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// startingBalance is the number of points of users that have not bet yet
+const startingBalance = 1000
+
+// maxBetHistory is the number of bets kept in the history of a wallet
+const maxBetHistory = 10
+
+// bet is the points a user bet on a team winning a game
+type bet struct {
+	TeamID int `json:"team_id"`
+	Amount int `json:"amount"`
+}
+
+// betRecord is a bet in the history of a wallet. Payout is the points
+// paid back for the bet, once Settled.
+type betRecord struct {
+	MatchID  int64     `json:"match_id"`
+	Team     string    `json:"team"`
+	Opponent string    `json:"opponent"`
+	Amount   int       `json:"amount"`
+	Payout   int       `json:"payout"`
+	Settled  bool      `json:"settled"`
+	PlacedAt time.Time `json:"placed_at"`
+}
+
+// wallet is the points of a user in a guild, and their most recent bets
+type wallet struct {
+	Name    string      `json:"name"`
+	Balance int         `json:"balance"`
+	History []betRecord `json:"history"`
+}
+
+func betPrefix(matchID int64) string {
+	return "bet/" + strconv.FormatInt(matchID, 10) + "/"
+}
+
+func betKey(matchID int64, guildID guildID, userID string) string {
+	return betPrefix(matchID) + string(guildID) + "/" + userID
+}
+
+func walletKey(guildID guildID, userID string) string {
+	return "wallet/" + string(guildID) + "/" + userID
+}
+
+// loadWallet loads the wallet of a user in a guild, with the starting
+// balance for users that have not bet yet
+func (bot *bot) loadWallet(ctx context.Context, guildID guildID, userID string) (*wallet, error) {
+	w := &wallet{Balance: startingBalance}
+	if _, err := bot.store.Get(ctx, walletKey(guildID, userID), w); err != nil {
+		return nil, errors.Wrap(err, "Error getting wallet")
+	}
+	return w, nil
+}
+
+// addHistory adds a bet to the history of the wallet, dropping the
+// oldest bets beyond maxBetHistory
+func (w *wallet) addHistory(record betRecord) {
+	w.History = append(w.History, record)
+	if len(w.History) > maxBetHistory {
+		w.History = w.History[len(w.History)-maxBetHistory:]
+	}
+}
+
+// bettableGame returns the live game of a team that has not started
+// yet, and the team and its opponent in the game
+func (bot *bot) bettableGame(teamID int) (game dota.LiveLeagueGame, team, opponent dota.LiveLeagueGamesTeam, ok bool) {
+	games, _ := bot.liveGames.get()
+	for _, game := range games {
+		if isGameStarted(game) {
+			continue
+		}
+		if game.RadiantTeam.TeamID == teamID {
+			return game, game.RadiantTeam, game.DireTeam, true
+		}
+		if game.DireTeam.TeamID == teamID {
+			return game, game.DireTeam, game.RadiantTeam, true
+		}
+	}
+	return dota.LiveLeagueGame{}, dota.LiveLeagueGamesTeam{}, dota.LiveLeagueGamesTeam{}, false
+}
+
+// handleBetCommand places a bet of points on a team winning its drafting
+// game
+func (bot *bot) handleBetCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	user := in.user()
+	if in.GuildID == "" || user == nil {
+		return textResponse("Betting is only available in servers."), nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Betting {
+		return textResponse("Betting is not enabled in this server, see `/settings betting`."), nil
+	}
+	amount := in.intOption("amount", 0)
+	if amount <= 0 {
+		return textResponse("The amount must be at least 1 point."), nil
+	}
+	query := in.stringOption("team")
+	teamID, err := bot.findTeamID(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error finding team")
+	}
+	if teamID == 0 {
+		return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	game, team, opponent, ok := bot.bettableGame(teamID)
+	if !ok {
+		return textResponse(fmt.Sprintf("%s has no game open for bets, bets close when the game starts.", bot.teamName(teamID))), nil
+	}
+	return textResponse(bot.placeBet(ctx, guildID(in.GuildID), user.ID, user.Username, game.MatchID, team, opponent, amount)), nil
+}
+
+// placeBet places a bet, responding with the outcome. Users can bet once
+// per game, and not more than their balance.
+func (bot *bot) placeBet(ctx context.Context, guildID guildID, userID, name string, matchID int64, team, opponent dota.LiveLeagueGamesTeam, amount int) string {
+	logger := bot.logger.WithField(logFieldGuildID, guildID).WithField(logFieldMatchID, matchID)
+	bot.walletsMu.Lock()
+	defer bot.walletsMu.Unlock()
+	// The started state is stored as the game starts, before it shows
+	// up as started in the live games
+	var started interface{}
+	if found, err := bot.store.Get(ctx, matchStateKey(matchStateStarted, matchID), &started); err != nil || found {
+		return "The game has started, bets are closed."
+	}
+	var existing bet
+	if found, err := bot.store.Get(ctx, betKey(matchID, guildID, userID), &existing); err != nil {
+		logger.WithError(err).Error("Error getting bet")
+		return "Could not place the bet, try again later."
+	} else if found {
+		return fmt.Sprintf("You already bet %d points on %s in this game.", existing.Amount, bot.teamName(existing.TeamID))
+	}
+	w, err := bot.loadWallet(ctx, guildID, userID)
+	if err != nil {
+		logger.WithError(err).Error("Error loading wallet")
+		return "Could not place the bet, try again later."
+	}
+	if amount > w.Balance {
+		return fmt.Sprintf("You only have %d points.", w.Balance)
+	}
+	// The bet is stored first, so that a failure storing the wallet
+	// at worst gives a free bet rather than losing the points
+	if err := bot.store.Set(ctx, betKey(matchID, guildID, userID), bet{TeamID: team.TeamID, Amount: amount}, finishedMatchTTL); err != nil {
+		logger.WithError(err).Error("Error storing bet")
+		return "Could not place the bet, try again later."
+	}
+	w.Name = name
+	w.Balance -= amount
+	w.addHistory(betRecord{MatchID: matchID, Team: team.TeamName, Opponent: opponent.TeamName, Amount: amount, PlacedAt: time.Now()})
+	if err := bot.store.Set(ctx, walletKey(guildID, userID), w, 0); err != nil {
+		logger.WithError(err).Error("Error storing wallet")
+	}
+	return fmt.Sprintf("You bet %d points on %s to beat %s, leaving you %d points.", amount, team.TeamName, opponent.TeamName, w.Balance)
+}
+
+// betPayouts returns the payouts of the bets on a game, by user id. The
+// pool of all bets is shared by the bets on the winner, in proportion to
+// their amounts. If no one bet on the winner, the bets are refunded.
+func betPayouts(bets map[string]bet, winnerTeamID int) map[string]int {
+	pool, winnerPool := 0, 0
+	for _, b := range bets {
+		pool += b.Amount
+		if b.TeamID == winnerTeamID {
+			winnerPool += b.Amount
+		}
+	}
+	payouts := make(map[string]int, len(bets))
+	for userID, b := range bets {
+		switch {
+		case winnerPool == 0:
+			payouts[userID] = b.Amount
+		case b.TeamID == winnerTeamID:
+			payouts[userID] = b.Amount * pool / winnerPool
+		default:
+			payouts[userID] = 0
+		}
+	}
+	return payouts
+}
+
+// settleBets pays out the bets on a finished game, in each guild they
+// were placed in
+func (bot *bot) settleBets(ctx context.Context, item matchesFinishedDataItem) {
+	logger := bot.logger.WithField(logFieldMatchID, item.MatchID)
+	bot.walletsMu.Lock()
+	defer bot.walletsMu.Unlock()
+	keys, err := bot.store.Keys(ctx, betPrefix(item.MatchID))
+	if err != nil {
+		logger.WithError(err).Error("Error getting bets")
+		return
+	}
+	betsByGuild := make(map[guildID]map[string]bet)
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimPrefix(key, betPrefix(item.MatchID)), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		var b bet
+		if found, err := bot.store.Get(ctx, key, &b); err != nil || !found {
+			continue
+		}
+		// Deleted before paying out, so that a bet is never paid twice
+		if err := bot.store.Delete(ctx, key); err != nil {
+			logger.WithError(err).Errorf("Error deleting bet %s", key)
+			continue
+		}
+		guildID := guildID(parts[0])
+		if betsByGuild[guildID] == nil {
+			betsByGuild[guildID] = make(map[string]bet)
+		}
+		betsByGuild[guildID][parts[1]] = b
+	}
+	for guildID, bets := range betsByGuild {
+		picks := make([]pickRecord, 0, len(bets))
+		for userID, payout := range betPayouts(bets, item.WinnerTeamID) {
+			b := bets[userID]
+			w, err := bot.loadWallet(ctx, guildID, userID)
+			if err != nil {
+				logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error loading wallet")
+				continue
+			}
+			w.Balance += payout
+			for i := range w.History {
+				if w.History[i].MatchID == item.MatchID && !w.History[i].Settled {
+					w.History[i].Payout = payout
+					w.History[i].Settled = true
+				}
+			}
+			if err := bot.store.Set(ctx, walletKey(guildID, userID), w, 0); err != nil {
+				logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error storing wallet")
+			}
+			picks = append(picks, pickRecord{Kind: pickKindBet, MatchID: item.MatchID, UserID: userID, Name: w.Name,
+				Team: bot.teamName(b.TeamID), Correct: b.TeamID == item.WinnerTeamID, Amount: b.Amount,
+				Payout: payout, ScoredAt: time.Now()})
+		}
+		bot.recordPicks(ctx, guildID, picks)
+	}
+}
+
+// handleBalanceCommand responds with the points of the user
+func (bot *bot) handleBalanceCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Betting is only available in servers."), nil
+	}
+	w, err := bot.loadWallet(ctx, guildID(in.GuildID), in.userID())
+	if err != nil {
+		return nil, err
+	}
+	open := 0
+	for _, record := range w.History {
+		if !record.Settled {
+			open += record.Amount
+		}
+	}
+	if open > 0 {
+		return textResponse(fmt.Sprintf("You have %d points, and %d points in open bets.", w.Balance, open)), nil
+	}
+	return textResponse(fmt.Sprintf("You have %d points.", w.Balance)), nil
+}
+
+// renderBetHistory renders the bets of a wallet, most recent first
+func renderBetHistory(w *wallet) string {
+	var b strings.Builder
+	b.WriteString("**Your bets**\n")
+	for i := len(w.History) - 1; i >= 0; i-- {
+		record := w.History[i]
+		fmt.Fprintf(&b, "%d on %s vs. %s: ", record.Amount, record.Team, record.Opponent)
+		switch {
+		case !record.Settled:
+			b.WriteString("open")
+		case record.Payout > record.Amount:
+			fmt.Fprintf(&b, "won %d", record.Payout-record.Amount)
+		case record.Payout == record.Amount:
+			b.WriteString("refunded")
+		default:
+			fmt.Fprintf(&b, "lost %d", record.Amount-record.Payout)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// handleBetsCommand responds with the most recent bets of the user
+func (bot *bot) handleBetsCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Betting is only available in servers."), nil
+	}
+	w, err := bot.loadWallet(ctx, guildID(in.GuildID), in.userID())
+	if err != nil {
+		return nil, err
+	}
+	if len(w.History) == 0 {
+		return textResponse("You have not bet on any games yet."), nil
+	}
+	return textResponse(renderBetHistory(w)), nil
+}
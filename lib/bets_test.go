@@ -0,0 +1,66 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestBetPayouts(t *testing.T) {
+	bets := map[string]bet{
+		"1": {TeamID: 10, Amount: 100},
+		"2": {TeamID: 10, Amount: 300},
+		"3": {TeamID: 20, Amount: 400},
+	}
+	payouts := betPayouts(bets, 10)
+	if payouts["1"] != 200 || payouts["2"] != 600 || payouts["3"] != 0 {
+		t.Errorf("betPayouts() = %v, want the pool shared by the winners", payouts)
+	}
+	payouts = betPayouts(bets, 30)
+	if payouts["1"] != 100 || payouts["2"] != 300 || payouts["3"] != 400 {
+		t.Errorf("betPayouts() without winning bets = %v, want refunds", payouts)
+	}
+}
+
+func TestPlaceAndSettleBets(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, store: storage.NewMemoryStore()}
+	ctx := context.Background()
+	og := dota.LiveLeagueGamesTeam{TeamID: 10, TeamName: "OG"}
+	liquid := dota.LiveLeagueGamesTeam{TeamID: 20, TeamName: "Team Liquid"}
+
+	bot.placeBet(ctx, "1", "100", "alice", 1, og, liquid, 100)
+	if got, want := bot.placeBet(ctx, "1", "100", "alice", 1, liquid, og, 100), "You already bet 100 points on Team 10 in this game."; got != want {
+		t.Errorf("placeBet() twice = %q, want %q", got, want)
+	}
+	if got, want := bot.placeBet(ctx, "1", "200", "bob", 1, liquid, og, 5000), "You only have 1000 points."; got != want {
+		t.Errorf("placeBet() beyond the balance = %q, want %q", got, want)
+	}
+	bot.placeBet(ctx, "1", "200", "bob", 1, liquid, og, 300)
+
+	bot.settleBets(ctx, matchesFinishedDataItem{MatchID: 1, WinnerTeamID: 10, LoserTeamID: 20})
+	// Settling again must not pay out twice
+	bot.settleBets(ctx, matchesFinishedDataItem{MatchID: 1, WinnerTeamID: 10, LoserTeamID: 20})
+	alice, err := bot.loadWallet(ctx, "1", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Balance != 1300 {
+		t.Errorf("Winner balance = %d, want 1300", alice.Balance)
+	}
+	if got, want := renderBetHistory(alice), "**Your bets**\n100 on OG vs. Team Liquid: won 300"; got != want {
+		t.Errorf("renderBetHistory() = %q, want %q", got, want)
+	}
+	bob, err := bot.loadWallet(ctx, "1", "200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.Balance != 700 {
+		t.Errorf("Loser balance = %d, want 700", bob.Balance)
+	}
+}
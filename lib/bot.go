@@ -3,24 +3,40 @@ package timatch
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"github.com/bwmarrin/discordgo"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/opendota"
+	"github.com/verath/timatch/lib/storage"
+	"github.com/verath/timatch/lib/stratz"
+	"github.com/verath/timatch/lib/twitch"
 )
 
 // updateInterval is the number of seconds between fetches
 // of live matches
 const updateInterval = 60 * time.Second
 
+// notableUpdateInterval is the update interval used while a notable
+// team is playing
+const notableUpdateInterval = 20 * time.Second
+
 type finishedQueueEntry struct {
 	MatchID int64
 	AddedAt time.Time
+	// Attempts is the number of failed attempts at fetching the details
+	// of the game, retried from NextAttemptAt, see detailsBackoff
+	Attempts      int
+	NextAttemptAt time.Time
+	// Late is true once the details are no longer retried with backoff
+	// but swept for a late result, see Config.LateResults
+	Late bool
 }
 
 type guildID string
@@ -30,33 +46,358 @@ type bot struct {
 	logger         *logrus.Logger
 	discordSession *discordgo.Session
 	dotaClient     *dota.Client
+	openDotaClient *opendota.Client
+	// stratzClient is nil unless a STRATZ token is configured
+	stratzClient *stratz.Client
+	// matchData is the source of the live and finished league games,
+	// see Config.DataSource
+	matchData matchDataSource
+	// store persists the match state, so that it survives restarts
+	// and can be shared between bot instances
+	store storage.Store
 
+	// leagueMu guards leagueID and leagueName. As they are only changed
+	// by the run loop, the run loop may read them without locking
+	leagueMu sync.RWMutex
 	// leagueID is the dota 2 league ID of the tournament we
 	// are watching
 	leagueID int
+	// leagueName is the name of the league we are watching, if known
+	leagueName string
+	// autoDetectLeague is true if the league to watch should be
+	// detected from the league listing each year (see detectLeague)
+	autoDetectLeague bool
+	// lastLeagueDetect is the time detectLeague last queried the
+	// league listing
+	lastLeagueDetect time.Time
 
 	channelsMu sync.RWMutex
 	// Ids of discord channels where we post updates, each
 	// channel id mapping to the guild it is associated with
 	channels map[channelID]guildID
 
-	// Map of match ids that we have seen in the drafting phase
-	matchesDrafting map[int64]struct{}
-	// Map of match ids that we have seen started
-	matchesStarted map[int64]struct{}
-	// Map of match ids that were started an are no longer live (i.e. finished)
-	matchesFinished map[int64]struct{}
+	// stateMu guards the state of the bot announced from, such as the
+	// maps of matches below, as it is shared by the run loop and the
+	// handling of the events of the watcher, see run. Not held while
+	// making requests
+	stateMu sync.Mutex
+	// watcher detects the games drafting, started and finished, see
+	// consumeEvents
+	watcher *Watcher
+	// pruneAfter is how long after the state of a match is kept once
+	// no longer tracked by the watcher, see pruneMatches
+	pruneAfter time.Duration
+	prunedAt   time.Time
 
 	// Map of match ids to the match's game number. We must store this as
 	// the game number is not provided in the GetMatchDetails result
 	gameNumbers map[int64]int
+	// seriesIDs are the series of the live games seen, by match id
+	seriesIDs map[int64]int64
+	// stages are the stages of the league of the live games seen, by
+	// match id, see gameStage
+	stages map[int64]string
+	// deficits are the largest net worth deficits of the teams of the
+	// live games seen, by match id, see trackDeficits
+	deficits map[int64]goldDeficits
+
+	// bus passes the games drafting, started and finished to the
+	// features consuming them, see subscribeEvents
+	bus eventBus
+	// eventLog is the log the events of bus are written to, or nil
+	eventLog *eventLog
+
+	// httpAddr is the address to serve the admin HTTP endpoints on, or
+	// empty if the admin HTTP endpoints are disabled
+	httpAddr string
+	health   health
+	// pprof is true if the pprof handlers should be served on the
+	// admin HTTP listener
+	pprof bool
+
+	// floodControl is true if only games involving notable teams should
+	// be announced individually, with all other games announced in a
+	// digest every floodDigestInterval
+	floodControl bool
+	floodDigest  floodDigest
+	// notableTeams is the set of team ids of notable teams
+	notableTeams map[int]struct{}
+	// teams is the set of team ids of the teams whose games are
+	// announced, all teams if empty. ignoreTeams is the set of team ids
+	// of teams whose games are never announced
+	teams       map[int]struct{}
+	ignoreTeams map[int]struct{}
+	// notableLive is true if a notable team was playing as of the
+	// last update
+	notableLive bool
+	// hypeAlerts are the kinds of hype alerts sent, and hype what has
+	// been alerted of each live game, see checkHype
+	hypeAlerts map[string]bool
+	hype       map[int64]*hypeState
+	// scoreUpdates maps match ids of live games of notable teams to the
+	// number of scoreUpdateIntervals of game time announced
+	scoreUpdates map[int64]int
+
+	// Map of match ids to the match's importance score (see gameImportance)
+	matchImportance map[int64]int
+	// minImportance is the minimum importance score of games to announce
+	// to guilds that have not changed the minimportance setting
+	minImportance int
+
+	teamNamesMu sync.RWMutex
+	// Map of team ids to team names, learned from the API responses
+	teamNames map[int]string
+
+	// bracketUpdates is true if the bracket should be posted when a
+	// playoff series finishes
+	bracketUpdates bool
+	bracket        bracketState
+	// scheduleUpdates is true if series about to start and the schedule
+	// of the day should be announced
+	scheduleUpdates bool
+	schedule        scheduleState
+	// topLive is true if the top live games of all of Dota played by
+	// teams, or of an average MMR of at least topLiveMinMMR if not 0,
+	// should be announced, see updateTopLiveGames
+	topLive          bool
+	topLiveMinMMR    int
+	topLiveCheckedAt time.Time
+	// topLiveSeen are the times the top live games were first seen, by
+	// match id, pruned with the state of finished matches
+	topLiveSeen map[int64]time.Time
+	// followTeams is true if the games of teams in any league are
+	// announced rather than the games of a league, and watchMatches the
+	// set of match ids of the games announced if not empty, see
+	// isFollowedGame
+	followTeams  bool
+	watchMatches map[int64]struct{}
+	// draftReads is true if a read of the drafts should be posted when
+	// games start
+	draftReads bool
+	// records is true if tournament records should be announced as
+	// they are broken
+	records bool
+	// matchStats is true if the stats of finished games should be
+	// posted once OpenDota has parsed their replays
+	matchStats     bool
+	statsQueue     []matchStatsEntry
+	statsCheckedAt time.Time
+	// historyScan is the number of recent messages of each channel
+	// scanned for announcements already made, see scanChannelHistory.
+	// Announcements are only marked for the scan if not 0
+	historyScan int
+	// summaryAfter is the time without live games after which the
+	// tournament report is posted, or 0 to only post it once the
+	// bracket is completed
+	summaryAfter time.Duration
+	// lastLiveAt is the time live games were last seen, or the time the
+	// bot started if none have been seen
+	lastLiveAt time.Time
+
+	// adminChannelID is the channel operational alerts are sent to, or
+	// empty if alerts are disabled
+	adminChannelID channelID
+	// adminUserID is the user whose DM channel is used as admin channel
+	adminUserID string
+	alerts      adminAlerts
+	maintenance maintenance
+
+	// coalesce collects the messages to each channel for a window, to
+	// send them as one, or is nil if messages are sent right away
+	coalesce *coalesceQueue
+	// sends is the queue of messages to send
+	sends *sendQueue
+
+	// prizeDistribution is the distribution of the prize pool over
+	// the tournament placements
+	prizeDistribution PrizeDistribution
+	prizePool         prizePoolCache
+	// prizeStep and prizeRecord are the prize pool amounts announced
+	// when passed, see checkPrizeMilestones
+	prizeStep      int64
+	prizeRecord    int64
+	prizeCheckedAt time.Time
+
+	proPlayers proPlayersCache
+	teamLogos  teamLogosCache
+	positions  teamPositionsCache
+	heroes     heroesCache
+	// liveGames are the live games as of the last poll
+	liveGames liveGamesCache
 
-	// Queue of finished matches that we have yet to fetch the finished
-	// match details for.
-	finishedQueue []finishedQueueEntry
+	// twitchClient is nil unless Twitch credentials are configured
+	twitchClient      *twitch.Client
+	broadcastChannels []BroadcastChannel
+	streams           streamsState
+	// communityPredictions is nil unless a community predictions URL
+	// is configured
+	communityPredictions *communityPredictionsSource
+
+	// commands are the slash commands handled by the bot, by name
+	commands map[string]*command
+	// edits are the scheduled edits of sent messages. All edits of
+	// messages should go through the queue, see editMessage
+	edits *editQueue
+	// tickers are the messages of live games edited with their score,
+	// in guilds using the ticker
+	tickers liveTickers
+	// walletsMu serializes the updates of the betting wallets, see
+	// placeBet and settleBets
+	walletsMu sync.Mutex
+	// customTemplates replace the bot's templates, by template name
+	customTemplates map[string]*template.Template
+}
+
+// Config holds the configuration of a bot.
+type Config struct {
+	DiscordToken string
+	SteamKey     string
+	// LeagueID is the league to watch. May be 0 if AutoDetectLeague is set
+	LeagueID int
+	// AutoDetectLeague enables automatic detection of the current year's
+	// The International league
+	AutoDetectLeague bool
+	// Store is the store used for persisting state. If nil, state
+	// is only kept in memory
+	Store storage.Store
+	// HTTPAddr is the address to serve the admin HTTP endpoints
+	// (e.g. /healthz) on. Empty to disable
+	HTTPAddr string
+	// PProf enables serving net/http/pprof on the admin HTTP listener
+	PProf bool
+	// FloodControl enables flood control, meant for high-volume leagues
+	// such as open qualifiers
+	FloodControl bool
+	// NotableTeams is a list of team ids of teams whose games are always
+	// announced individually
+	NotableTeams []int
+	// Teams is a list of team ids of the teams whose games are
+	// announced. If empty, games of all teams are announced
+	Teams []int
+	// IgnoreTeams is a list of team ids of teams whose games are never
+	// announced
+	IgnoreTeams []int
+	// MinImportance is the minimum importance score (0-100) of games
+	// to announce
+	MinImportance int
+	// BracketUpdates enables posting the playoff bracket whenever a
+	// playoff series finishes
+	BracketUpdates bool
+	// DraftReads enables posting a read of the drafts, from the heroes
+	// picked, when games start
+	DraftReads bool
+	// Records enables announcing tournament records, such as the
+	// longest game, as they are broken
+	Records bool
+	// HypeAlerts are the kinds of in-game hype alerts to send: "kills"
+	// for kill milestones, "tied" for games still even after 50
+	// minutes, "comeback" for teams taking the lead after being far
+	// behind in kills, "rapier" for players getting a Divine Rapier,
+	// "megacreeps" for teams losing their last barracks and "roshan"
+	// for Roshan being taken late in the game
+	HypeAlerts []string
+	// Schedule enables announcing the series of the league about to
+	// start, and posting the schedule of each day
+	Schedule bool
+	// TopLive enables announcing the top live games of all of Dota played
+	// by teams, e.g. professional games outside the watched league
+	TopLive bool
+	// TopLiveMinMMR also announces top live games not played by teams
+	// with at least this average MMR, if TopLive is set. 0 to only
+	// announce games of teams
+	TopLiveMinMMR int
+	// FollowTeams announces the games of the teams of Teams in any league
+	// instead of the games of a league, LeagueID must not be set
+	FollowTeams bool
+	// MatchIDs is a list of match ids of games to announce, in any
+	// league, instead of the games of a league, e.g. showmatches.
+	// LeagueID must not be set
+	MatchIDs []int64
+	// MatchStats enables posting the stats of finished games, such as
+	// the KDA of the players, once parsed by OpenDota
+	MatchStats bool
+	// Backfill announces the results of games of the league that finished
+	// within this period but were not announced, e.g. as the bot was not
+	// running. 0 to only announce games seen live
+	Backfill time.Duration
+	// AnnounceLive announces games already drafting or started when the
+	// bot starts. By default these are only tracked, as they were most
+	// likely announced before a restart
+	AnnounceLive bool
+	// Since ignores the results of games that started more than this long
+	// before the bot started. 0 for the default of 3 hours, which covers
+	// games in progress when the bot starts. Backfilled games are only
+	// limited by the Backfill period
+	Since time.Duration
+	// DetailsMaxAge is how long fetching the details of a finished game is
+	// retried for, with backoff, before giving up on announcing its
+	// result. 0 for the default of 10 minutes
+	DetailsMaxAge time.Duration
+	// LateResults keeps checking for the details of a finished game for
+	// this long after DetailsMaxAge, less often, so that results
+	// published late are still announced, flagged as delayed. 0 to give
+	// up after DetailsMaxAge
+	LateResults time.Duration
+	// EventLog is the path of a file to append the games drafting,
+	// started and finished to, as JSON lines, or empty for none
+	EventLog string
+	// PruneAfter is how long the state of a finished match is kept in
+	// memory. 0 for the default of 24 hours
+	PruneAfter time.Duration
+	// HistoryScan is the number of recent messages of each channel to scan
+	// for games already announced when the bot connects, so that a restart
+	// without the state of the bot, e.g. after a crash, does not announce
+	// them again. Announcements are marked with the games invisibly for the
+	// scan. 0 disables the scan and the marks
+	HistoryScan int
+	// SummaryAfter is the time the league must have had no live games
+	// for before the tournament report is posted. 0 to only post the
+	// report when the playoff bracket is completed
+	SummaryAfter time.Duration
+	// AdminChannelID is a channel to send operational alerts to
+	AdminChannelID string
+	// AdminUserID is a user to send operational alerts to, as direct
+	// messages. Ignored if AdminChannelID is set
+	AdminUserID string
+	// PrizeDistribution is the prize pool distribution of the league.
+	// Defaults to DefaultPrizeDistribution
+	PrizeDistribution PrizeDistribution
+	// PrizeMilestoneStep announces the prize pool passing each multiple
+	// of the amount, in dollars. 0 to not announce milestones
+	PrizeMilestoneStep int64
+	// PrizeRecord announces the prize pool passing the amount, in
+	// dollars, as a new record. 0 to not announce a record
+	PrizeRecord int64
+	// TwitchClientID and TwitchClientSecret are the credentials of a
+	// Twitch application, used to check which BroadcastChannels are live
+	TwitchClientID     string
+	TwitchClientSecret string
+	// StratzToken is a STRATZ API token. With a token, STRATZ is used
+	// for the league games the Steam Web API fails to give
+	StratzToken string
+	// DataSource is the primary source of the live and finished league
+	// games, "steam" (the default) or "stratz", which requires
+	// StratzToken. The other source is used as the fallback
+	DataSource string
+	// CommunityPredictionsURL is the URL of aggregate community
+	// prediction data, e.g. the compendium picks for the tournament
+	// winner, compared against the predictions of each server in
+	// /leaderboard. Empty for none
+	CommunityPredictionsURL string
+	// BroadcastChannels are linked to in started announcements while live
+	BroadcastChannels []BroadcastChannel
+	// Templates replace the bot's announcement templates, as template
+	// text by the names listed by PreviewTemplateNames
+	Templates map[string]string
+	// Languages are languages added to the bot's own
+	Languages []LanguageBundle
+	// CoalesceWindow is the time messages to a channel are collected for
+	// before being sent as one message, or 0 to send them right away
+	CoalesceWindow time.Duration
 }
 
-func NewBot(logger *logrus.Logger, discordToken string, steamKey string, leagueID int) (*bot, error) {
+func NewBot(logger *logrus.Logger, config Config) (*bot, error) {
+	discordToken := config.DiscordToken
 	if !strings.HasPrefix(discordToken, "Bot ") {
 		discordToken = "Bot " + discordToken
 	}
@@ -64,222 +405,890 @@ func NewBot(logger *logrus.Logger, discordToken string, steamKey string, leagueI
 	if err != nil {
 		return nil, errors.Wrap(err, "Error creating discordgo session")
 	}
-	dotaClient, err := dota.NewClient(logger, steamKey)
+	dotaClient, err := dota.NewClient(logger, config.SteamKey)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error creating dotaClient")
 	}
-	return &bot{
-		logger:          logger,
-		discordSession:  discordSession,
-		dotaClient:      dotaClient,
-		leagueID:        leagueID,
-		channels:        make(map[channelID]guildID),
-		matchesDrafting: make(map[int64]struct{}),
-		matchesStarted:  make(map[int64]struct{}),
-		matchesFinished: make(map[int64]struct{}),
-		gameNumbers:     make(map[int64]int),
-		finishedQueue:   make([]finishedQueueEntry, 0),
-	}, nil
+	openDotaClient, err := opendota.NewClient(logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating openDotaClient")
+	}
+	store := config.Store
+	if store == nil {
+		store = storage.NewMemoryStore()
+	}
+	prizeDistribution := config.PrizeDistribution
+	if prizeDistribution == nil {
+		prizeDistribution, err = ParsePrizeDistribution(DefaultPrizeDistribution)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error parsing default prize distribution")
+		}
+	}
+	customTemplates, err := parseCustomTemplates(config.Templates)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing templates")
+	}
+	if err := addLanguageBundles(config.Languages); err != nil {
+		return nil, errors.Wrap(err, "Error adding languages")
+	}
+	var twitchClient *twitch.Client
+	if config.TwitchClientID != "" {
+		twitchClient = twitch.NewClient(config.TwitchClientID, config.TwitchClientSecret)
+	}
+	hypeAlerts, err := parseHypeAlerts(config.HypeAlerts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing hype alerts")
+	}
+	var stratzClient *stratz.Client
+	var matchData matchDataSource = dotaClient
+	if config.StratzToken != "" {
+		stratzClient, err = stratz.NewClient(logger, config.StratzToken)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error creating stratzClient")
+		}
+		matchData = &fallbackDataSource{logger: logger, primary: dotaClient, fallback: stratzClient}
+	}
+	switch config.DataSource {
+	case "", dataSourceSteam:
+	case dataSourceStratz:
+		if stratzClient == nil {
+			return nil, errors.New("Error using STRATZ as data source: no STRATZ token")
+		}
+		matchData = &fallbackDataSource{logger: logger, primary: stratzClient, fallback: dotaClient}
+	default:
+		return nil, errors.Errorf("Error using data source %q: unknown data source", config.DataSource)
+	}
+	since := config.Since
+	if since == 0 {
+		since = defaultSince
+	}
+	pruneAfter := config.PruneAfter
+	if pruneAfter == 0 {
+		pruneAfter = defaultPruneAfter
+	}
+	watcher := newWatcher(logger, matchData)
+	if config.DataSource != dataSourceStratz {
+		watcher.sequence = dotaClient
+	}
+	if config.DetailsMaxAge != 0 {
+		watcher.detailsMaxAge = config.DetailsMaxAge
+	}
+	watcher.lateResults = config.LateResults
+	watcher.pruneAfter = pruneAfter
+	watcher.announceLive = config.AnnounceLive
+	watcher.since = since
+	watcher.backfill = config.Backfill
+	bot := &bot{
+		logger:           logger,
+		discordSession:   discordSession,
+		dotaClient:       dotaClient,
+		openDotaClient:   openDotaClient,
+		stratzClient:     stratzClient,
+		matchData:        matchData,
+		store:            store,
+		leagueID:         config.LeagueID,
+		autoDetectLeague: config.AutoDetectLeague,
+		channels:         make(map[channelID]guildID),
+		watcher:          watcher,
+		pruneAfter:       pruneAfter,
+		gameNumbers:      make(map[int64]int),
+		seriesIDs:        make(map[int64]int64),
+		stages:           make(map[int64]string),
+		deficits:         make(map[int64]goldDeficits),
+
+		httpAddr:          config.HTTPAddr,
+		pprof:             config.PProf,
+		floodControl:      config.FloodControl,
+		notableTeams:      teamIDSet(config.NotableTeams),
+		teams:             teamIDSet(config.Teams),
+		ignoreTeams:       teamIDSet(config.IgnoreTeams),
+		matchImportance:   make(map[int64]int),
+		scoreUpdates:      make(map[int64]int),
+		hypeAlerts:        hypeAlerts,
+		hype:              make(map[int64]*hypeState),
+		minImportance:     config.MinImportance,
+		teamNames:         make(map[int]string),
+		bracketUpdates:    config.BracketUpdates,
+		draftReads:        config.DraftReads,
+		records:           config.Records,
+		matchStats:        config.MatchStats,
+		scheduleUpdates:   config.Schedule,
+		topLive:           config.TopLive,
+		topLiveMinMMR:     config.TopLiveMinMMR,
+		topLiveSeen:       make(map[int64]time.Time),
+		followTeams:       config.FollowTeams,
+		watchMatches:      matchIDSet(config.MatchIDs),
+		summaryAfter:      config.SummaryAfter,
+		historyScan:       config.HistoryScan,
+		adminChannelID:    channelID(config.AdminChannelID),
+		adminUserID:       config.AdminUserID,
+		alerts:            adminAlerts{lastSent: make(map[string]time.Time)},
+		prizeDistribution: prizeDistribution,
+		prizeStep:         config.PrizeMilestoneStep,
+		prizeRecord:       config.PrizeRecord,
+		twitchClient:      twitchClient,
+		broadcastChannels: config.BroadcastChannels,
+		edits:             newEditQueue(),
+		customTemplates:   customTemplates,
+	}
+	watcher.follow = bot.isFollowedGame
+	watcher.claim = bot.claimWatcherState
+	watcher.setLeague(bot.leagueID, bot.isFollowing())
+	bot.sends = newSendQueue(logger, bot.deliverMessage)
+	if config.CoalesceWindow > 0 {
+		bot.coalesce = newCoalesceQueue(config.CoalesceWindow)
+	}
+	if config.CommunityPredictionsURL != "" {
+		bot.communityPredictions = newCommunityPredictionsSource(logger, config.CommunityPredictionsURL)
+	}
+	bot.commands = bot.newCommands()
+	if config.EventLog != "" {
+		if bot.eventLog, err = openEventLog(config.EventLog); err != nil {
+			return nil, err
+		}
+	}
+	bot.subscribeEvents()
+	return bot, nil
 }
 
 func (bot *bot) Run(ctx context.Context) error {
+	bot.health.startedAt = time.Now()
+	bot.lastLiveAt = bot.health.startedAt
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if bot.eventLog != nil {
+		defer func() {
+			if err := bot.eventLog.close(); err != nil {
+				bot.logger.WithError(err).Error("Error closing the event log")
+			}
+		}()
+	}
+	if bot.httpAddr != "" {
+		go func() {
+			err := bot.serveHTTP(ctx, bot.httpAddr)
+			if errors.Cause(err) != context.Canceled {
+				bot.logger.WithError(err).Error("Admin HTTP server stopped")
+			}
+		}()
+	}
+	if err := bot.loadState(ctx); err != nil {
+		return errors.Wrap(err, "Error loading state")
+	}
+	if err := bot.loadMaintenance(ctx); err != nil {
+		return err
+	}
+	go bot.edits.run(ctx, bot.applyEdit)
+	if bot.coalesce != nil {
+		go bot.coalesce.run(ctx, bot.sendCoalesced)
+	}
+	// Resolved before connecting, as event handlers may send alerts
+	if bot.adminChannelID == "" && bot.adminUserID != "" {
+		dmChannel, err := bot.discordSession.UserChannelCreate(bot.adminUserID)
+		if err != nil {
+			return errors.Wrap(err, "Error creating admin DM channel")
+		}
+		bot.adminChannelID = channelID(dmChannel.ID)
+	}
 	defer bot.discordSession.AddHandler(bot.onReadyHandler)()
 	defer bot.discordSession.AddHandler(bot.onGuildCreate)()
 	defer bot.discordSession.AddHandler(bot.onGuildDelete)()
+	defer bot.discordSession.AddHandler(bot.onEvent)()
 	if err := bot.discordSession.Open(); err != nil {
 		return errors.Wrap(err, "Error connecting to Discord")
 	}
 	defer func() {
+		if !bot.sends.wait(sendShutdownTimeout) {
+			bot.logger.Warnf("Stopping with %d messages not sent", bot.sends.getStats().Queued)
+		}
 		if closeErr := bot.discordSession.Close(); closeErr != nil {
-			bot.logger.Errorf("Error closing Discord connection: %+v", closeErr)
+			bot.logger.WithError(closeErr).Error("Error closing Discord connection")
 		}
 	}()
 	return errors.Wrap(bot.run(ctx), "Error during run")
 }
 
+// run runs the bot until ctx is done. The games are detected by the
+// watcher, whose events are handled as they are sent, see consumeEvents,
+// so that a slow API request of the watcher does not delay the run loop.
+// The run loop does the rest of the updates.
 func (bot *bot) run(ctx context.Context) error {
+	var tasks sync.WaitGroup
+	defer tasks.Wait()
+	tasks.Add(2)
+	go func() {
+		defer tasks.Done()
+		bot.watcher.Run(ctx)
+	}()
+	go func() {
+		defer tasks.Done()
+		bot.consumeEvents(ctx)
+	}()
 	for {
-		bot.updateLiveGames(ctx)
-		bot.updateFinishedGames(ctx)
-		bot.fetchFinishedMatchDetails(ctx)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(updateInterval):
+		bot.update(ctx)
+		bot.stateMu.Lock()
+		interval := bot.nextUpdateInterval()
+		bot.stateMu.Unlock()
+		if err := bot.wait(ctx, interval); err != nil {
+			return err
 		}
 	}
 }
 
-func (bot *bot) updateLiveGames(ctx context.Context) {
-	liveGamesRes, err := bot.dotaClient.GetLiveLeagueGames(ctx, bot.leagueID)
-	if err != nil {
-		bot.logger.Errorf("Error getting live games: %+v", err)
-		return
+// update does the updates of the run loop, other than polling the live
+// games and the match history. Only holds stateMu around the state shared
+// with the handling of the events, not while making requests
+func (bot *bot) update(ctx context.Context) {
+	if bot.autoDetectLeague {
+		bot.detectLeague(ctx)
 	}
-	newDrafting := make([]dota.LiveLeagueGame, 0)
-	newStarted := make([]dota.LiveLeagueGame, 0)
-	for _, game := range liveGamesRes.Result.Games {
-		if game.GameNumber == 0 {
-			game.GameNumber = game.RadiantSeriesWins + game.DireSeriesWins + 1
+	if !bot.isWatching() {
+		bot.logger.Debug("No league to watch yet")
+		// Nothing to poll while waiting for a league, which
+		// should not be reported as being stuck
+		bot.health.setSteamPolled()
+	} else {
+		if bot.leagueID != 0 {
+			// The bracket is used for the importance of live games
+			bot.updateBracket(ctx)
+			bot.updateSchedule(ctx)
+		}
+		if bot.floodControl {
+			bot.sendFloodDigest(ctx)
+		}
+		if bot.leagueID != 0 {
+			bot.postIdleReport(ctx)
+			bot.checkPrizeMilestones(ctx)
 		}
-		bot.gameNumbers[game.MatchID] = game.GameNumber
+		bot.checkMatchStats(ctx)
+	}
+	bot.updateTopLiveGames(ctx)
+	bot.sendQuietDigests(ctx)
+	bot.sendRecaps(ctx)
+	bot.updateMaintenance(ctx)
+	bot.stateMu.Lock()
+	bot.pruneMatches(time.Now())
+	bot.stateMu.Unlock()
+	bot.health.setFinishedQueueLen(bot.watcher.queueLen())
+}
 
-		if !isGameStarted(game) {
-			if _, ok := bot.matchesDrafting[game.MatchID]; !ok {
-				newDrafting = append(newDrafting, game)
-				bot.matchesDrafting[game.MatchID] = struct{}{}
-			}
-		} else {
-			if _, ok := bot.matchesStarted[game.MatchID]; !ok {
-				newStarted = append(newStarted, game)
-				bot.matchesStarted[game.MatchID] = struct{}{}
+// isWatching tests if there are games to poll: of the watched league, or
+// followed in any league
+func (bot *bot) isWatching() bool {
+	return bot.leagueID != 0 || bot.isFollowing()
+}
+
+// wait waits d until the next update
+func (bot *bot) wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		if bot.coalesce != nil {
+			// Sent while still connected, as the announcements are
+			// already recorded as sent
+			for _, msg := range bot.coalesce.flush() {
+				bot.sendCoalesced(msg)
 			}
 		}
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
 	}
-	if len(newDrafting) > 0 {
-		bot.sendTemplateMessage(tmplMatchesDrafting, newDrafting, false)
+}
+
+// consumeEvents handles the events of the watcher until it stops, the
+// events of a poll of the live games at a time, see Watcher.Events
+func (bot *bot) consumeEvents(ctx context.Context) {
+	var events []WatcherEvent
+	for event := range bot.watcher.Events() {
+		events = append(events, event)
+		switch event.(type) {
+		case *LiveGames, *PollFailed:
+		default:
+			continue
+		}
+		bot.stateMu.Lock()
+		bot.handleEvents(ctx, events)
+		bot.watcher.setInterval(bot.nextUpdateInterval())
+		bot.stateMu.Unlock()
+		events = nil
 	}
-	if len(newStarted) > 0 {
-		bot.sendTemplateMessage(tmplMatchesStarted, newStarted, true)
+}
+
+// handleEvents updates the state of the bot from the events of a poll of
+// the live games, then publishes them on the event bus
+func (bot *bot) handleEvents(ctx context.Context, events []WatcherEvent) {
+	for _, event := range events {
+		switch event := event.(type) {
+		case *PollFailed:
+			bot.steamPollFailed(event.Err)
+		case *GameDropped:
+			bot.alertAdmin(alertGaveUp, fmt.Sprintf("Gave up on fetching match details for "+
+				"match %d, its result will not be announced", event.MatchID))
+		case *GameFinished:
+			bot.trackFinishedGame(ctx, event)
+		case *LiveGames:
+			bot.health.setSteamPolled()
+			bot.steamPollSucceeded()
+			bot.trackLiveGames(ctx, event.Games)
+		}
+	}
+	for _, event := range events {
+		// The stages are tracked with the live games
+		switch event := event.(type) {
+		case *DraftStarted:
+			event.Game.Stage = bot.stages[event.Game.MatchID]
+		case *GameStarted:
+			event.Game.Stage = bot.stages[event.Game.MatchID]
+		}
 	}
+	bot.bus.publish(ctx, events)
 }
 
-func (bot *bot) updateFinishedGames(ctx context.Context) {
-	if len(bot.matchesStarted) == len(bot.matchesFinished) {
-		bot.logger.Debug("Not fetching match history, all known games already finished")
-		return
+// trackLiveGames updates the state of the bot kept of the live games,
+// setting the stages of the games
+func (bot *bot) trackLiveGames(ctx context.Context, games []dota.LiveLeagueGame) {
+	bot.notableLive = false
+	for i := range games {
+		game := &games[i]
+		if bot.isNotableTeam(game.RadiantTeam.TeamID, game.DireTeam.TeamID) {
+			bot.notableLive = true
+		}
+		bot.setGameNumber(ctx, game.MatchID, game.GameNumber)
+		if game.SeriesID != 0 {
+			bot.seriesIDs[game.MatchID] = game.SeriesID
+		}
+		if game.Stage = bot.gameStage(*game); game.Stage != "" {
+			bot.stages[game.MatchID] = game.Stage
+		}
+		if bot.records {
+			bot.trackDeficits(*game)
+		}
+		bot.learnTeamName(game.RadiantTeam.TeamID, game.RadiantTeam.TeamName)
+		bot.learnTeamName(game.DireTeam.TeamID, game.DireTeam.TeamName)
+		bot.updateImportance(*game)
 	}
-	historyRes, err := bot.dotaClient.GetMatchHistory(ctx, bot.leagueID)
-	if err != nil {
-		bot.logger.Errorf("Error getting match history: %+v", err)
-		return
+	bot.liveGames.set(games)
+	if len(games) > 0 {
+		bot.lastLiveAt = time.Now()
 	}
-	for _, match := range historyRes.Result.Matches {
-		_, isStarted := bot.matchesStarted[match.MatchID]
-		_, isFinished := bot.matchesFinished[match.MatchID]
-		if isStarted && !isFinished {
-			bot.logger.Debugf("Match finished %d", match.MatchID)
-			bot.matchesFinished[match.MatchID] = struct{}{}
-			entry := finishedQueueEntry{MatchID: match.MatchID, AddedAt: time.Now()}
-			bot.finishedQueue = append(bot.finishedQueue, entry)
+}
+
+// trackFinishedGame updates the state of the bot kept of a finished game
+func (bot *bot) trackFinishedGame(ctx context.Context, event *GameFinished) {
+	matchID := event.Game.MatchID
+	if _, ok := bot.gameNumbers[matchID]; !ok && event.Game.GameNumber != 0 {
+		// Inferred for games finished before the bot started
+		bot.setGameNumber(ctx, matchID, event.Game.GameNumber)
+	}
+	bot.expireGameNumber(ctx, matchID)
+	delete(bot.scoreUpdates, matchID)
+	delete(bot.hype, matchID)
+}
+
+// announceEvents is the subscriber of the event bus announcing the games
+// to the channels: the results of the finished games first, then the
+// games drafting and started and the updates of the live games
+func (bot *bot) announceEvents(ctx context.Context, events []WatcherEvent) {
+	var finished []matchesFinishedDataItem
+	var drafting, started []dota.LiveLeagueGame
+	var live *LiveGames
+	for _, event := range events {
+		switch event := event.(type) {
+		case *GameFinished:
+			finished = append(finished, bot.finishedResult(ctx, event))
+		case *DraftStarted:
+			drafting = append(drafting, event.Game)
+		case *GameStarted:
+			started = append(started, event.Game)
+		case *LiveGames:
+			live = event
 		}
 	}
+	if len(finished) > 0 {
+		bot.announceFinished(ctx, finished)
+	}
+	if live != nil {
+		bot.announceLiveGames(ctx, live.Games, drafting, started)
+	}
 }
 
-func (bot *bot) fetchFinishedMatchDetails(ctx context.Context) {
-	remainingQueue := make([]finishedQueueEntry, 0)
-	finishedDetails := make([]matchesFinishedDataItem, 0)
-	for _, entry := range bot.finishedQueue {
-		details, err := bot.dotaClient.GetMatchDetails(ctx, entry.MatchID)
-		if err != nil {
-			bot.logger.Debugf("Error getting match details for %d: %+v", entry.MatchID, err)
-			// Retry entries until they have been in the queue for > 10 min
-			if time.Since(entry.AddedAt) <= 10*time.Minute {
-				bot.logger.Debugf("<= 10 minutes ago, trying %d again next time", entry.MatchID)
-				remainingQueue = append(remainingQueue, entry)
-			} else {
-				bot.logger.Errorf("Giving up on fetching match details for %d")
+// announceLiveGames announces the games first seen drafting and started,
+// and the score updates and hype alerts of the live games
+func (bot *bot) announceLiveGames(ctx context.Context, games []dota.LiveLeagueGame, newDrafting []dota.LiveLeagueGame, newStarted []dota.LiveLeagueGame) {
+	scoreUpdates := make([]scoreUpdate, 0)
+	var hypeAlerts []hypeAlert
+	for _, game := range games {
+		if update, ok := bot.checkScoreUpdate(game); ok {
+			scoreUpdates = append(scoreUpdates, update)
+		}
+		hypeAlerts = append(hypeAlerts, bot.checkHype(game)...)
+	}
+	bot.updateTickers(games)
+	// Games held back by flood control are only included in the digest
+	// once started, drafting is not worth a mention there
+	newDrafting, _ = bot.filterNotableGames(newDrafting)
+	newStarted, heldBack := bot.filterNotableGames(newStarted)
+	bot.floodDigest.Started = append(bot.floodDigest.Started, heldBack...)
+	if len(newDrafting) > 0 {
+		// Prediction prompts are posted after the announcements
+		prompts := make(map[channelID][]dota.LiveLeagueGame)
+		bot.sendTemplateGuildMessage(ctx, tmplMatchesDrafting, eventDrafting, func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{} {
+			games := bot.announcedGames(newDrafting, settings, sub)
+			if games := bot.claimGames(ctx, matchStateDrafting, channelID, games); len(games) > 0 {
+				if settings.usesPredictions() {
+					prompts[channelID] = games
+				}
+				return games
 			}
-			continue
+			return nil
+		})
+		for channelID, games := range prompts {
+			bot.postPredictionPrompts(channelID, games)
 		}
-		if details.Result.RadiantWin {
-			finishedDetails = append(finishedDetails, matchesFinishedDataItem{
-				GameNumber:  bot.gameNumbers[entry.MatchID],
-				WinnerName:  details.Result.RadiantName,
-				LoserName:   details.Result.DireName,
-				WinnerScore: details.Result.RadiantScore,
-				LoserScore:  details.Result.DireScore,
-			})
-		} else {
-			finishedDetails = append(finishedDetails, matchesFinishedDataItem{
-				GameNumber:  bot.gameNumbers[entry.MatchID],
-				WinnerName:  details.Result.DireName,
-				LoserName:   details.Result.RadiantName,
-				WinnerScore: details.Result.DireScore,
-				LoserScore:  details.Result.RadiantScore,
+	}
+	if len(scoreUpdates) > 0 {
+		bot.sendTemplateGuildMessage(ctx, tmplScoreUpdates, eventScoreUpdates, func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{} {
+			if settings.usesTicker() {
+				// The tickers show the score instead
+				return nil
+			}
+			if updates := bot.announcedScoreUpdates(scoreUpdates, sub); len(updates) > 0 {
+				return updates
+			}
+			return nil
+		})
+	}
+	if len(hypeAlerts) > 0 {
+		bot.sendGuildMessage(ctx, eventHype, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+			format := settings.channelTextFormat(string(channelID))
+			if bot.hypeAlerts[hypeRapier] {
+				// For the heroes carrying rapiers
+				names, err := bot.heroNames(ctx, format.language.code)
+				if err != nil {
+					bot.logger.WithError(err).Warn("Error getting hero names")
+				}
+				format.heroNames = names
+			}
+			var lines []string
+			for _, alert := range hypeAlerts {
+				teamIDs := []int{alert.Game.RadiantTeam.TeamID, alert.Game.DireTeam.TeamID}
+				if bot.isAnnouncedTeam(teamIDs...) && sub.includesTeams(teamIDs...) {
+					lines = append(lines, renderHypeAlert(alert, format))
+				}
+			}
+			return strings.Join(lines, "\n")
+		})
+	}
+	if len(newStarted) > 0 {
+		newStarted = bot.resolvePlayerNames(ctx, newStarted)
+		// The games announced to each channel, for the follow-up message
+		startedByChannel := make(map[channelID][]dota.LiveLeagueGame)
+		bot.sendTemplateGuildMessage(ctx, tmplMatchesStarted, eventStarted, func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{} {
+			games := bot.announcedGames(newStarted, settings, sub)
+			if games := bot.claimGames(ctx, matchStateStarted, channelID, games); len(games) > 0 {
+				startedByChannel[channelID] = games
+				if settings.usesTicker() {
+					bot.startTickers(channelID, settings, games)
+					return nil
+				}
+				return games
+			}
+			return nil
+		})
+		// The team role pings and stream links are sent separately so
+		// that they are not read out by TTS
+		liveChannels := bot.liveBroadcastChannels(ctx)
+		bot.sendGuildMessage(ctx, eventFollowUp, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+			games := startedByChannel[channelID]
+			if len(games) == 0 {
+				return ""
+			}
+			teamIDs := make([]int, 0, 2*len(games))
+			for _, game := range games {
+				teamIDs = append(teamIDs, game.RadiantTeam.TeamID, game.DireTeam.TeamID)
+			}
+			lines := make([]string, 0, 2)
+			if mentions := settings.roleMentions(teamIDs...); mentions != "" {
+				lines = append(lines, mentions)
+			}
+			if links := renderStreamLinks(settings.filterBroadcastChannels(liveChannels)); links != "" {
+				lines = append(lines, links)
+			}
+			return strings.Join(lines, "\n")
+		})
+		if bot.draftReads {
+			bot.sendGuildMessage(ctx, eventDraftRead, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+				return renderDraftReads(startedByChannel[channelID])
 			})
 		}
 	}
-	bot.finishedQueue = remainingQueue
+}
+
+// announceFinished announces the results of the finished games
+func (bot *bot) announceFinished(ctx context.Context, finishedDetails []matchesFinishedDataItem) {
+	defer bot.removeTickers(finishedDetails)
+	if len(finishedDetails) > 0 {
+		bot.unpinFinished(ctx, finishedDetails)
+	}
+	finishedDetails, heldBack := bot.filterNotableFinished(finishedDetails)
+	for _, item := range heldBack {
+		// Remakes are not worth a mention in the digest
+		if !item.Remake {
+			bot.floodDigest.Finished = append(bot.floodDigest.Finished, item)
+		}
+	}
 	if len(finishedDetails) > 0 {
-		bot.sendTemplateMessage(tmplMatchesFinished, finishedDetails, true)
+		bot.sendTemplateGuildMessage(ctx, tmplMatchesFinished, eventFinished, func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{} {
+			items := bot.announcedFinished(finishedDetails, settings, sub)
+			items = bot.claimFinished(ctx, channelID, items)
+			// Games with a ticker get their result in the ticker instead
+			if items := bot.finishTickers(channelID, items); len(items) > 0 {
+				return items
+			}
+			return nil
+		})
 	}
 }
 
-// isGameStarted tests if a game is past the drafting phase.
-func isGameStarted(game dota.LiveLeagueGame) bool {
-	if game.Scoreboard.Duration > 0 {
-		return true
+// finishedResult returns the result of a finished game to announce, with
+// the upset seeds rated when the result was saved, see saveResults
+func (bot *bot) finishedResult(ctx context.Context, event *GameFinished) matchesFinishedDataItem {
+	item := bot.finishedItem(event)
+	if item.Remake {
+		return item
+	}
+	var result matchResult
+	if found, err := bot.store.Get(ctx, resultKey(item.MatchID), &result); err != nil {
+		bot.logger.WithField(logFieldMatchID, item.MatchID).WithError(err).Errorf("Error getting result of %d", item.MatchID)
+	} else if found {
+		item.UpsetWinnerSeed, item.UpsetLoserSeed = result.UpsetWinnerSeed, result.UpsetLoserSeed
+	}
+	return item
+}
+
+// finishedItem returns the result of a finished game
+func (bot *bot) finishedItem(event *GameFinished) matchesFinishedDataItem {
+	matchID := event.Game.MatchID
+	details := event.Details
+	gameNumber, ok := bot.gameNumbers[matchID]
+	if !ok {
+		gameNumber = event.Game.GameNumber
+	}
+	item := matchesFinishedDataItem{
+		MatchID:      matchID,
+		GameNumber:   gameNumber,
+		SeriesID:     bot.seriesIDs[matchID],
+		Stage:        bot.stages[matchID],
+		WinnerName:   details.RadiantName,
+		LoserName:    details.DireName,
+		WinnerScore:  details.RadiantScore,
+		LoserScore:   details.DireScore,
+		WinnerTeamID: details.RadiantTeamID,
+		LoserTeamID:  details.DireTeamID,
+		Importance:   bot.finishedImportance(matchID, details.RadiantTeamID, details.DireTeamID),
+		Delayed:      event.Late,
+		Remake:       event.Remake,
 	}
-	// We check for 9 picks rather than 10 to err a bit
-	// on the safe side (we don't want to miss a game starting!)
-	direPicks := game.Scoreboard.Dire.Picks
-	radiantPicks := game.Scoreboard.Radiant.Picks
-	if len(direPicks)+len(radiantPicks) >= 9 {
-		return true
+	if !details.RadiantWin {
+		item.WinnerName, item.LoserName = item.LoserName, item.WinnerName
+		item.WinnerScore, item.LoserScore = item.LoserScore, item.WinnerScore
+		item.WinnerTeamID, item.LoserTeamID = item.LoserTeamID, item.WinnerTeamID
 	}
-	return false
+	return item
 }
 
-// addGuildChannel adds a channel id to the channels to be notified of new matches. The
-// channel is associated with the provided guild id, so that all channels for a given
-// guild id can be removed when the guild is removed.
-func (bot *bot) addGuildChannel(guildID guildID, channelID channelID) {
+// setGuildChannels replaces the channels of a guild to be notified of new
+// matches. The channels are associated with the provided guild id, so that
+// all channels for a given guild id can be removed when the guild is removed.
+func (bot *bot) setGuildChannels(guildID guildID, channelIDs []channelID) {
 	bot.channelsMu.Lock()
 	defer bot.channelsMu.Unlock()
-	bot.channels[channelID] = guildID
+	for chID, gID := range bot.channels {
+		if gID == guildID {
+			delete(bot.channels, chID)
+		}
+	}
+	for _, chID := range channelIDs {
+		bot.channels[chID] = guildID
+	}
 }
 
 // removeGuildChannels removes all discord channel id associated with the guildID from
 // the list of channels that should be notified of new matches
 func (bot *bot) removeGuildChannels(guildID guildID) {
 	bot.channelsMu.Lock()
-	defer bot.channelsMu.Unlock()
 	for channelID, gID := range bot.channels {
 		if gID == guildID {
 			delete(bot.channels, channelID)
 		}
 	}
+	noChannels := len(bot.channels) == 0
+	bot.channelsMu.Unlock()
+	if noChannels {
+		bot.alertAdmin(alertNoChannels, "No channels left to send announcements to")
+	}
 }
 
-// sendMessage sends a message to all registered channels. If tts is true, the
-// message is sent as a TTS message
-func (bot *bot) sendMessage(content string, tts bool) {
+// forEachChannel calls fn with the settings of the guild and the
+// subscription of each registered channel, followed by the staging channels
+// of the guilds and the direct message subscriptions of users. The
+// subscription is nil for channels announced to by default, and for
+// staging channels. Muted channels are skipped.
+func (bot *bot) forEachChannel(ctx context.Context, fn func(channelID channelID, settings *guildSettings, sub *channelSubscription)) {
+	// The channels are copied so that the lock is not held while loading
+	// settings and sending messages
 	bot.channelsMu.RLock()
-	defer bot.channelsMu.RUnlock()
-	for channelID := range bot.channels {
-		var err error
-		if tts {
-			_, err = bot.discordSession.ChannelMessageSendTTS(string(channelID), content)
-		} else {
-			_, err = bot.discordSession.ChannelMessageSend(string(channelID), content)
+	channels := make(map[channelID]guildID, len(bot.channels))
+	for channelID, guildID := range bot.channels {
+		channels[channelID] = guildID
+	}
+	bot.channelsMu.RUnlock()
+	muted, err := bot.mutedChannels(ctx)
+	if err != nil {
+		bot.logger.WithError(err).Error("Error getting muted channels, sending to all")
+	}
+	guildSettingsByID := make(map[guildID]*guildSettings)
+	now := time.Now()
+	for channelID, guildID := range channels {
+		if ctx.Err() != nil {
+			return
 		}
-		if err != nil {
-			bot.logger.Errorf("Failed sending message to channel %s: %+v", channelID, err)
+		settings, ok := guildSettingsByID[guildID]
+		if !ok {
+			var err error
+			settings, err = bot.getGuildSettings(ctx, guildID)
+			if err != nil {
+				bot.logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error getting settings, using defaults")
+				settings = &guildSettings{}
+			}
+			guildSettingsByID[guildID] = settings
+		}
+		if settings.stagingChannel(now) == string(channelID) {
+			// Sent to below, as the staging channel
+			continue
+		}
+		if _, ok := muted[channelID]; ok {
+			continue
+		}
+		fn(channelID, settings, settings.subscription(string(channelID)))
+	}
+	for _, settings := range guildSettingsByID {
+		if ctx.Err() != nil {
+			return
+		}
+		id := channelID(settings.stagingChannel(now))
+		if _, ok := muted[id]; id != "" && !ok {
+			fn(id, settings.stagingSettings(), nil)
 		}
 	}
+	subs, err := bot.loadDMSubscriptions(ctx)
+	if err != nil {
+		bot.logger.WithError(err).Error("Error loading direct message subscriptions")
+		return
+	}
+	// Direct messages are sent with the default settings, but for the
+	// accessibility mode of the user
+	for i := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, ok := muted[channelID(subs[i].ChannelID)]; ok {
+			continue
+		}
+		fn(channelID(subs[i].ChannelID), &guildSettings{Accessible: subs[i].Accessible}, &subs[i])
+	}
 }
 
-// sendTemplateMessage executes a template with the provided data, then calls
-// sendMessage with the template string. If tts is true, the message is sent
-// as a TTS message
-func (bot *bot) sendTemplateMessage(tmpl *template.Template, data interface{}, tts bool) {
-	var msg bytes.Buffer
-	err := tmpl.Execute(&msg, data)
-	if err != nil {
-		bot.logger.Errorf("Failed executing template '%s': %+v", tmpl.Name, err)
+// sendGuildMessage sends a message rendered for each channel, given the
+// settings of its guild and its subscription. Channels for which
+// render returns "" are skipped, and messages to guilds in quiet hours are
+// held back until the quiet hours end. Channels opted out of the event
+// are skipped before rendering, and messages are sent as TTS if the guild
+// has TTS on for the event. With a coalescing window, the messages are
+// collected and sent per channel once the window ends.
+func (bot *bot) sendGuildMessage(ctx context.Context, event string, render func(channelID channelID, settings *guildSettings, sub *channelSubscription) string) {
+	bot.forEachChannel(ctx, func(channelID channelID, settings *guildSettings, sub *channelSubscription) {
+		if !sub.includesEvent(event) {
+			return
+		}
+		content := render(channelID, settings, sub)
+		if content == "" {
+			return
+		}
+		bot.deliverGuildMessage(ctx, channelID, settings, event, content, nil)
+	})
+}
+
+// deliverGuildMessage sends an announcement of event to a channel of a
+// guild, holding it back during the quiet hours of the guild. data is the
+// data the announcement was rendered from, if rendered from a template,
+// for pinning started games and adding buttons revealing hidden results.
+func (bot *bot) deliverGuildMessage(ctx context.Context, channelID channelID, settings *guildSettings, event string, content string, data interface{}) {
+	if until := settings.quietHoursEnd(time.Now()); !until.IsZero() {
+		if err := bot.queueQuietMessage(ctx, channelID, until, content); err != nil {
+			bot.logger.WithField(logFieldChannelID, channelID).WithError(err).Error("Error holding back message during quiet hours")
+		}
 		return
 	}
-	bot.sendMessage(msg.String(), tts)
+	tts := event != eventFollowUp && settings.tts(event)
+	var pinned []int64
+	if games, ok := data.([]dota.LiveLeagueGame); ok && event == eventStarted && settings.PinLive {
+		for _, game := range games {
+			pinned = append(pinned, game.MatchID)
+		}
+	}
+	var components []messageComponent
+	if items, ok := data.([]matchesFinishedDataItem); ok && settings.resultDetail() != resultDetailFull {
+		components = revealResultButtons(items)
+	}
+	if len(pinned) > 0 || len(components) > 0 {
+		// Sent right away, as coalescing would lose track of the
+		// message to pin and the games of the buttons
+		bot.sendAnnouncement(channelID, content, tts, components, pinned)
+		return
+	}
+	if bot.coalesce != nil {
+		bot.coalesce.add(channelID, content, tts, time.Now())
+		return
+	}
+	bot.sendChannelMessage(channelID, content, tts)
+}
+
+// sendAnnouncement queues an announcement to a channel as
+// sendChannelMessage does, adding the components to its last message and
+// pinning its first message while the games of pinned are live
+func (bot *bot) sendAnnouncement(channelID channelID, content string, tts bool, components []messageComponent, pinned []int64) {
+	parts := splitContent(content)
+	for i, content := range parts {
+		msg := outboundMessage{channelID: channelID, content: content, tts: tts}
+		if i == 0 && len(pinned) > 0 {
+			msg.sent = func(messageID string) { bot.pinMessage(channelID, messageID, pinned) }
+		}
+		if i == len(parts)-1 {
+			msg.components = components
+		}
+		bot.sends.enqueue(msg)
+	}
+}
+
+// sendChannelMessage queues a message to a channel, split into several if
+// too long
+func (bot *bot) sendChannelMessage(channelID channelID, content string, tts bool) {
+	// Many games starting or finishing at once may not fit in a single
+	// message
+	for _, content := range splitContent(content) {
+		bot.sends.enqueue(outboundMessage{channelID: channelID, content: content, tts: tts})
+	}
+}
+
+// sendEmbeds sends a message of embeds, rendered for the settings of each
+// guild, to all registered channels
+func (bot *bot) sendEmbeds(ctx context.Context, render func(settings *guildSettings) []*discordgo.MessageEmbed) {
+	bot.forEachChannel(ctx, func(channelID channelID, settings *guildSettings, sub *channelSubscription) {
+		bot.sendChannelEmbeds(channelID, render(settings))
+	})
+}
+
+// sendChannelEmbeds sends a message of embeds to a channel
+func (bot *bot) sendChannelEmbeds(channelID channelID, embeds []*discordgo.MessageEmbed) {
+	// discordgo only supports sending a single embed per message
+	data := struct {
+		Embeds []*discordgo.MessageEmbed `json:"embeds"`
+	}{embeds}
+	_, err := bot.discordSession.Request("POST", discordgo.EndpointChannelMessages(string(channelID)), data)
+	if err != nil {
+		bot.logger.WithField(logFieldChannelID, channelID).WithError(err).Errorf("Failed sending embeds to channel %s", channelID)
+	}
+}
+
+// sendTemplateGuildMessage executes a template with the data returned by
+// data for each channel, then sends the result to the channel. Channels
+// for which data returns nil are skipped. In guilds using series threads,
+// the games of series are announced in the threads of the series instead.
+func (bot *bot) sendTemplateGuildMessage(ctx context.Context, tmpl *template.Template, event string, data func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{}) {
+	defaultTmpl := bot.template(tmpl)
+	// The hero names of the lineups of started games, by language
+	heroNames := make(map[string]map[int]string)
+	bot.forEachChannel(ctx, func(channelID channelID, settings *guildSettings, sub *channelSubscription) {
+		if !sub.includesEvent(event) {
+			return
+		}
+		guildData := data(channelID, settings, sub)
+		if guildData == nil {
+			return
+		}
+		format := settings.channelTextFormat(string(channelID))
+		if event == eventStarted {
+			names, ok := heroNames[format.language.code]
+			if !ok {
+				var err error
+				if names, err = bot.heroNames(ctx, format.language.code); err != nil {
+					// The lineups are still useful without the heroes
+					bot.logger.WithError(err).Warn("Error getting hero names")
+				}
+				heroNames[format.language.code] = names
+			}
+			format.heroNames = names
+		}
+		render := func(data interface{}) string {
+			if content, ok := bot.renderGuildTemplate(tmpl, channelID, settings, format, data); ok {
+				return content
+			}
+			content, err := renderTemplate(defaultTmpl, format, data)
+			if err != nil {
+				bot.logger.WithError(err).Errorf("Failed executing template '%s'", defaultTmpl.Name())
+				return ""
+			}
+			return content
+		}
+		if settings.SeriesThreads {
+			if guildData = bot.sendSeriesThreads(ctx, channelID, settings, event, guildData, render); guildData == nil {
+				return
+			}
+		}
+		if content := render(guildData); content != "" {
+			if bot.historyScan > 0 {
+				content += historyMarker(event, guildData)
+			}
+			bot.deliverGuildMessage(ctx, channelID, settings, event, content, guildData)
+		}
+	})
+}
+
+// renderTemplate executes tmpl, translated into the language of the format
+// or in its accessible variant,
+// with data, formatting in the given format, returning the result
+func renderTemplate(tmpl *template.Template, format textFormat, data interface{}) (string, error) {
+	tmpl, err := format.rendered(tmpl).Clone()
+	if err != nil {
+		return "", errors.Wrap(err, "Error cloning template")
+	}
+	tmpl.Funcs(format.templateFuncs())
+	var msg bytes.Buffer
+	if err := tmpl.Execute(&msg, data); err != nil {
+		return "", err
+	}
+	return msg.String(), nil
 }
 
 // onReadyHandler is called by discordgo when the discord session is ready,
 // i.e. after we have connected to Discord.
 func (bot *bot) onReadyHandler(s *discordgo.Session, msg *discordgo.Ready) {
 	bot.logger.Debug("Got Ready event")
-	err := s.UpdateStatus(-1, "Watching Dota!")
-	if err != nil {
-		bot.logger.Errorf("Could not update status: %+v", err)
+	bot.updateStatus()
+	bot.registerCommands(s, msg.User.ID)
+}
+
+// updateStatus sets the "playing" status of the bot to reflect the
+// league currently being watched
+func (bot *bot) updateStatus() {
+	bot.leagueMu.RLock()
+	status := "Watching Dota!"
+	if bot.leagueName != "" {
+		status = "Watching " + bot.leagueName
+	}
+	bot.leagueMu.RUnlock()
+	if err := bot.discordSession.UpdateStatus(-1, status); err != nil {
+		bot.logger.WithError(err).Error("Could not update status")
 	}
 }
 
@@ -287,29 +1296,29 @@ func (bot *bot) onReadyHandler(s *discordgo.Session, msg *discordgo.Ready) {
 // added to a new guild. onGuildCreate is also called for each guild during
 // the initial logon sequence
 func (bot *bot) onGuildCreate(s *discordgo.Session, msg *discordgo.GuildCreate) {
-	bot.logger.Debugf("Got GuildCreate event: %s (%s)", msg.ID, msg.Name)
-	// Select the channel with the first (lowest) position as the channel to send
-	// messages to
-	var firstCh *discordgo.Channel
-	for _, ch := range msg.Channels {
-		if ch.Type != discordgo.ChannelTypeGuildText {
-			// not a text channel
-			continue
-		}
-		if firstCh == nil || firstCh.Position > ch.Position {
-			firstCh = ch
-		}
+	logger := bot.logger.WithField(logFieldGuildID, msg.ID)
+	logger.Debugf("Got GuildCreate event: %s (%s)", msg.ID, msg.Name)
+	settings, err := bot.getGuildSettings(context.Background(), guildID(msg.ID))
+	if err != nil {
+		logger.WithError(err).Error("Error getting guild settings, using defaults")
+		settings = &guildSettings{}
 	}
-	if firstCh != nil {
-		bot.logger.Debugf("Using channel %s (%s)", firstCh.ID, firstCh.Name)
-		bot.addGuildChannel(guildID(msg.ID), channelID(firstCh.ID))
+	channelIDs := settings.announceChannels(msg.Channels)
+	if len(channelIDs) > 0 {
+		logger.Debugf("Using channels %v", channelIDs)
 	} else {
-		bot.logger.Warnf("No channel for guild %s (%s)", msg.ID, msg.Name)
+		logger.Warnf("No channel for guild %s (%s)", msg.ID, msg.Name)
+	}
+	bot.setGuildChannels(guildID(msg.ID), channelIDs)
+	if bot.historyScan > 0 {
+		for _, channelID := range channelIDs {
+			bot.scanChannelHistory(context.Background(), s, channelID)
+		}
 	}
 }
 
 // onGuildDelete is called whenever a guild is no longer accessible to us
 func (bot *bot) onGuildDelete(s *discordgo.Session, msg *discordgo.GuildDelete) {
-	bot.logger.Debugf("Got GuildDelete event: %s", msg.ID)
+	bot.logger.WithField(logFieldGuildID, msg.ID).Debugf("Got GuildDelete event: %s", msg.ID)
 	bot.removeGuildChannels(guildID(msg.ID))
 }
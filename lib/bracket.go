@@ -0,0 +1,281 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// bracketUpdateInterval is the time between fetches of the league data
+// used for game importance and detecting finished playoff series
+const bracketUpdateInterval = 5 * time.Minute
+
+// bracketState tracks the playoff bracket of the watched league, so that
+// the bracket can be posted when a playoff series finishes.
+type bracketState struct {
+	lastUpdate time.Time
+	// mu guards groups, which is read by the /bracket command
+	mu sync.Mutex
+	// groups are the bracket node groups as of the last update
+	groups []dota.LeagueNodeGroup
+	// stages are the stages of the series of the league as of the last
+	// update, see leagueStages
+	stages []seriesStage
+	// completedNodes is the set of node ids of completed series. nil
+	// until the first update, so that series completed before the bot
+	// started are not announced
+	completedNodes map[int]struct{}
+}
+
+// updateBracket fetches the league data and, if bracket updates are
+// enabled, posts the bracket, with the series highlighted, for each
+// playoff series completed since the last update.
+func (bot *bot) updateBracket(ctx context.Context) {
+	if time.Since(bot.bracket.lastUpdate) < bracketUpdateInterval {
+		return
+	}
+	bot.bracket.lastUpdate = time.Now()
+	leagueData, err := bot.dotaClient.GetLeagueData(ctx, bot.leagueID)
+	if err != nil {
+		bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error getting league data")
+		return
+	}
+	// Guarded by stateMu, as read by the handling of the events
+	bot.stateMu.Lock()
+	bot.bracket.stages = leagueStages(leagueData.NodeGroups)
+	bot.stateMu.Unlock()
+	groups := bracketGroups(leagueData.NodeGroups)
+	for _, group := range groups {
+		for _, standing := range group.TeamStandings {
+			bot.learnTeamName(standing.TeamID, standing.Name)
+		}
+	}
+	firstUpdate := bot.bracket.completedNodes == nil
+	if firstUpdate {
+		bot.bracket.completedNodes = make(map[int]struct{})
+	}
+	for _, group := range groups {
+		for _, node := range group.Nodes {
+			if !node.IsCompleted {
+				continue
+			}
+			if _, ok := bot.bracket.completedNodes[node.NodeID]; ok {
+				continue
+			}
+			bot.bracket.completedNodes[node.NodeID] = struct{}{}
+			if !firstUpdate && bot.bracketUpdates {
+				bot.sendGuildMessage(ctx, eventSeries, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+					if !bot.claimAnnouncement(ctx, announcementSeries, int64(node.NodeID), channelID) {
+						return ""
+					}
+					return bot.renderBracket(group, node.NodeID, settings.textFormat())
+				})
+			}
+		}
+	}
+	bot.bracket.mu.Lock()
+	bot.bracket.groups = groups
+	bot.bracket.mu.Unlock()
+	if bracketCompleted(groups) {
+		bot.postReport(ctx)
+	}
+}
+
+// bracketGroups returns all playoff bracket node groups, including
+// nested ones.
+func bracketGroups(groups []dota.LeagueNodeGroup) []dota.LeagueNodeGroup {
+	brackets := make([]dota.LeagueNodeGroup, 0)
+	for _, group := range groups {
+		if group.IsBracket() {
+			brackets = append(brackets, group)
+		}
+		brackets = append(brackets, bracketGroups(group.NodeGroups)...)
+	}
+	return brackets
+}
+
+// bracketRounds groups the nodes of a bracket into rounds. A node's round
+// is one more than the latest round of the nodes feeding into it.
+func bracketRounds(group dota.LeagueNodeGroup) [][]dota.LeagueNode {
+	nodes := make(map[int]dota.LeagueNode)
+	for _, node := range group.Nodes {
+		nodes[node.NodeID] = node
+	}
+	rounds := make(map[int]int)
+	var roundOf func(nodeID int, depth int) int
+	roundOf = func(nodeID int, depth int) int {
+		if r, ok := rounds[nodeID]; ok {
+			return r
+		}
+		node, ok := nodes[nodeID]
+		// depth guards against cycles in malformed data
+		if !ok || depth > len(nodes) {
+			return 0
+		}
+		r := 1
+		for _, incoming := range []int{node.IncomingNodeID1, node.IncomingNodeID2} {
+			if incoming == 0 {
+				continue
+			}
+			if incomingRound := roundOf(incoming, depth+1) + 1; incomingRound > r {
+				r = incomingRound
+			}
+		}
+		rounds[nodeID] = r
+		return r
+	}
+	maxRound := 0
+	for nodeID := range nodes {
+		if r := roundOf(nodeID, 0); r > maxRound {
+			maxRound = r
+		}
+	}
+	result := make([][]dota.LeagueNode, maxRound)
+	for nodeID, node := range nodes {
+		r := rounds[nodeID] - 1
+		result[r] = append(result[r], node)
+	}
+	for _, round := range result {
+		sort.Slice(round, func(i, j int) bool { return round[i].NodeID < round[j].NodeID })
+	}
+	return result
+}
+
+// renderBracket renders a bracket as a compact monospaced diagram, one
+// line per series grouped by round. The series with node id highlightNode
+// (if any) is marked with an arrow.
+func (bot *bot) renderBracket(group dota.LeagueNodeGroup, highlightNode int, format textFormat) string {
+	rounds := bracketRounds(group)
+	if format.accessible {
+		return renderBracketAccessible(group.Name, rounds, highlightNode, bot.teamName, format)
+	}
+	width := len("TBD")
+	for _, node := range group.Nodes {
+		if n := len(bot.teamName(node.TeamID1)); n > width {
+			width = n
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n```\n", group.Name)
+	for i, round := range rounds {
+		fmt.Fprintf(&b, "Round %d\n", i+1)
+		for _, node := range round {
+			marker := "  "
+			if node.NodeID == highlightNode {
+				marker = "> "
+			}
+			fmt.Fprintf(&b, "%s%-*s %s  %s\n", marker, width,
+				bot.teamName(node.TeamID1), format.Score(node.Team1Wins, node.Team2Wins), bot.teamName(node.TeamID2))
+		}
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// renderBracketAccessible is the renderBracket equivalent in accessibility
+// mode, listing the series as sentences rather than drawing the bracket
+func renderBracketAccessible(name string, rounds [][]dota.LeagueNode, highlightNode int, teamName func(int) string, format textFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", name)
+	for i, round := range rounds {
+		for _, node := range round {
+			if node.NodeID == highlightNode {
+				b.WriteString("Just ended: ")
+			}
+			fmt.Fprintf(&b, "Round %d: %s versus %s, %s.\n", i+1,
+				teamName(node.TeamID1), teamName(node.TeamID2), format.Score(node.Team1Wins, node.Team2Wins))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// learnTeamName records the name of a team, for use when only the
+// team id is known
+func (bot *bot) learnTeamName(teamID int, name string) {
+	if teamID == 0 || name == "" {
+		return
+	}
+	bot.teamNamesMu.Lock()
+	defer bot.teamNamesMu.Unlock()
+	bot.teamNames[teamID] = name
+}
+
+// teamName returns the name of a team, "TBD" for a team id of 0
+func (bot *bot) teamName(teamID int) string {
+	if teamID == 0 {
+		return "TBD"
+	}
+	bot.teamNamesMu.RLock()
+	defer bot.teamNamesMu.RUnlock()
+	if name, ok := bot.teamNames[teamID]; ok {
+		return name
+	}
+	return fmt.Sprintf("Team %d", teamID)
+}
+
+// handleBracketCommand responds with the playoff brackets of the watched
+// league
+func (bot *bot) handleBracketCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	bot.bracket.mu.Lock()
+	groups := bot.bracket.groups
+	bot.bracket.mu.Unlock()
+	if groups == nil {
+		leagueData, err := bot.dotaClient.GetLeagueData(ctx, leagueID)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error getting league data")
+		}
+		groups = bracketGroups(leagueData.NodeGroups)
+	}
+	if len(groups) == 0 {
+		return textResponse("There is no playoff bracket yet."), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	rendered := make([]string, 0, len(groups)+1)
+	for _, group := range groups {
+		rendered = append(rendered, bot.renderBracket(group, 0, format))
+	}
+	if upcoming := bot.renderUpcomingMatchups(groups, format); upcoming != "" {
+		rendered = append(rendered, upcoming)
+	}
+	return textResponse(strings.Join(rendered, "\n")), nil
+}
+
+// renderUpcomingMatchups lists the series of the brackets yet to finish
+// whose teams are known, by scheduled time, e.g. "OG vs. Team Liquid,
+// 2019-08-20 18:00 UTC", or returns "" if there are none
+func (bot *bot) renderUpcomingMatchups(groups []dota.LeagueNodeGroup, format textFormat) string {
+	var nodes []dota.LeagueNode
+	for _, group := range groups {
+		for _, node := range group.Nodes {
+			if !node.IsCompleted && node.TeamID1 != 0 && node.TeamID2 != 0 {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return ""
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].ScheduledTime < nodes[j].ScheduledTime })
+	var b strings.Builder
+	b.WriteString("**Upcoming matchups**")
+	for _, node := range nodes {
+		fmt.Fprintf(&b, "\n- %s vs. %s", bot.teamName(node.TeamID1), bot.teamName(node.TeamID2))
+		switch {
+		case node.HasStarted:
+			fmt.Fprintf(&b, ", in progress (%s)", format.Score(node.Team1Wins, node.Team2Wins))
+		case node.ScheduledTime != 0:
+			fmt.Fprintf(&b, ", %s", format.DateTime(time.Unix(node.ScheduledTime, 0)))
+		}
+	}
+	return b.String()
+}
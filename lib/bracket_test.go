@@ -0,0 +1,74 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// testBracket is a single elimination bracket of four teams, where the
+// semifinals feed into the final
+var testBracket = dota.LeagueNodeGroup{
+	NodeGroupType: dota.NodeGroupTypeBracketSingle,
+	Nodes: []dota.LeagueNode{
+		{NodeID: 3, TeamID1: 1, TeamID2: 3, IncomingNodeID1: 1, IncomingNodeID2: 2, SeriesID: 30},
+		{NodeID: 1, TeamID1: 1, TeamID2: 2, IsCompleted: true, WinningNodeID: 3, SeriesID: 10},
+		{NodeID: 2, TeamID1: 3, TeamID2: 4, IsCompleted: true, WinningNodeID: 3, SeriesID: 20},
+	},
+}
+
+func nodeIDs(rounds [][]dota.LeagueNode) [][]int {
+	ids := make([][]int, 0, len(rounds))
+	for _, round := range rounds {
+		roundIDs := make([]int, 0, len(round))
+		for _, node := range round {
+			roundIDs = append(roundIDs, node.NodeID)
+		}
+		ids = append(ids, roundIDs)
+	}
+	return ids
+}
+
+func TestBracketRounds(t *testing.T) {
+	want := [][]int{{1, 2}, {3}}
+	if got := nodeIDs(bracketRounds(testBracket)); !reflect.DeepEqual(got, want) {
+		t.Errorf("bracketRounds() = %v, want %v", got, want)
+	}
+}
+
+func TestBracketRoundsCycle(t *testing.T) {
+	group := dota.LeagueNodeGroup{Nodes: []dota.LeagueNode{
+		{NodeID: 1, IncomingNodeID1: 2},
+		{NodeID: 2, IncomingNodeID1: 1},
+	}}
+	rounds := bracketRounds(group)
+	total := 0
+	for _, round := range rounds {
+		total += len(round)
+	}
+	if total != 2 {
+		t.Errorf("bracketRounds() of a cycle = %v, want both nodes", nodeIDs(rounds))
+	}
+}
+
+func TestRenderUpcomingMatchups(t *testing.T) {
+	bot := &bot{teamNames: map[int]string{1: "OG", 2: "Team Liquid", 3: "PSG.LGD"}}
+	groups := []dota.LeagueNodeGroup{{
+		NodeGroupType: dota.NodeGroupTypeBracketSingle,
+		Nodes: []dota.LeagueNode{
+			{NodeID: 1, TeamID1: 1, TeamID2: 2, IsCompleted: true},
+			{NodeID: 2, TeamID1: 1, TeamID2: 3, ScheduledTime: 1566324000},
+			{NodeID: 3, TeamID1: 2, TeamID2: 3, HasStarted: true, Team1Wins: 1},
+			{NodeID: 4, TeamID1: 1},
+		},
+	}}
+	got := bot.renderUpcomingMatchups(groups, textFormat{score: scoreStyles[0]})
+	want := "**Upcoming matchups**\n- Team Liquid vs. PSG.LGD, in progress (1 - 0)\n- OG vs. PSG.LGD, 2019-08-20 18:00 UTC"
+	if got != want {
+		t.Errorf("renderUpcomingMatchups() = %q, want %q", got, want)
+	}
+	if got := bot.renderUpcomingMatchups(nil, textFormat{}); got != "" {
+		t.Errorf("renderUpcomingMatchups() without series = %q, want none", got)
+	}
+}
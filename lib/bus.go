@@ -0,0 +1,70 @@
+package timatch
+
+import (
+	"context"
+)
+
+// eventBus passes the events of the watcher to the features consuming
+// them, such as the announcements, predictions and bets, so that they are
+// not entangled with the detection of the games or with each other. The
+// events of a poll of the live games are published together, and handled
+// in the order of subscription by the goroutine publishing them, holding
+// stateMu. Subscriptions are made when the bot is created, see
+// subscribeEvents.
+type eventBus struct {
+	subscribers []func(ctx context.Context, events []WatcherEvent)
+}
+
+func (bus *eventBus) subscribe(handle func(ctx context.Context, events []WatcherEvent)) {
+	bus.subscribers = append(bus.subscribers, handle)
+}
+
+func (bus *eventBus) publish(ctx context.Context, events []WatcherEvent) {
+	for _, handle := range bus.subscribers {
+		handle(ctx, events)
+	}
+}
+
+// finishedGames returns the GameFinished events of events, only those of
+// games played out rather than remade if played
+func finishedGames(events []WatcherEvent, played bool) []*GameFinished {
+	var finished []*GameFinished
+	for _, event := range events {
+		if event, ok := event.(*GameFinished); ok && !(played && event.Remake) {
+			finished = append(finished, event)
+		}
+	}
+	return finished
+}
+
+// subscribeEvents subscribes the features consuming the games of the bot
+// to its event bus
+func (bot *bot) subscribeEvents() {
+	// Before the announcements, which announce the upsets rated
+	bot.bus.subscribe(bot.saveResults)
+	bot.bus.subscribe(bot.announceEvents)
+	bot.bus.subscribe(func(ctx context.Context, events []WatcherEvent) {
+		for _, event := range finishedGames(events, true) {
+			bot.scorePredictions(ctx, bot.finishedItem(event))
+		}
+	})
+	bot.bus.subscribe(func(ctx context.Context, events []WatcherEvent) {
+		for _, event := range finishedGames(events, false) {
+			item := bot.finishedItem(event)
+			if item.Remake {
+				// The bets on remade games are refunded, as no one
+				// bet on a winner
+				item.WinnerTeamID = 0
+			}
+			bot.settleBets(ctx, item)
+		}
+	})
+	bot.bus.subscribe(func(ctx context.Context, events []WatcherEvent) {
+		for _, event := range finishedGames(events, true) {
+			bot.queueMatchStats([]matchesFinishedDataItem{bot.finishedItem(event)})
+		}
+	})
+	if bot.eventLog != nil {
+		bot.bus.subscribe(bot.logEvents)
+	}
+}
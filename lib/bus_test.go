@@ -0,0 +1,34 @@
+package timatch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+	var handled []string
+	bus.subscribe(func(ctx context.Context, events []WatcherEvent) {
+		for _, event := range finishedGames(events, true) {
+			handled = append(handled, "played "+event.Details.RadiantName)
+		}
+	})
+	bus.subscribe(func(ctx context.Context, events []WatcherEvent) {
+		for _, event := range finishedGames(events, false) {
+			handled = append(handled, "all "+event.Details.RadiantName)
+		}
+	})
+	bus.publish(context.Background(), []WatcherEvent{
+		&GameFinished{Details: dota.MatchDetails{RadiantName: "OG"}},
+		&GameStarted{Game: dota.LiveLeagueGame{MatchID: 1}},
+		&GameFinished{Details: dota.MatchDetails{RadiantName: "Liquid"}, Remake: true},
+		&LiveGames{},
+	})
+	want := []string{"played OG", "all OG", "all Liquid"}
+	if !reflect.DeepEqual(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+}
@@ -0,0 +1,126 @@
+package timatch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// coalescedMessage is the messages to a channel collected during a
+// coalescing window
+type coalescedMessage struct {
+	channelID channelID
+	// first is the time the first message was collected
+	first    time.Time
+	contents []string
+	// tts is true if any of the messages was to be sent as TTS
+	tts bool
+}
+
+// content returns the collected messages as a single message
+func (msg coalescedMessage) content() string {
+	return strings.Join(msg.contents, "\n")
+}
+
+// coalesceQueue collects the messages to each channel for a window, then
+// sends them as one, so that several series running at once do not ping
+// a channel for every event
+type coalesceQueue struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending []*coalescedMessage
+	// wake is signalled when a channel's first message is collected
+	wake chan struct{}
+}
+
+func newCoalesceQueue(window time.Duration) *coalesceQueue {
+	return &coalesceQueue{
+		window: window,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// add collects a message to a channel, starting the window of the
+// channel unless already started
+func (queue *coalesceQueue) add(channelID channelID, content string, tts bool, now time.Time) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for _, msg := range queue.pending {
+		if msg.channelID == channelID {
+			msg.contents = append(msg.contents, content)
+			msg.tts = msg.tts || tts
+			return
+		}
+	}
+	queue.pending = append(queue.pending, &coalescedMessage{
+		channelID: channelID,
+		first:     now,
+		contents:  []string{content},
+		tts:       tts,
+	})
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the messages of a channel whose window has
+// ended at now. If none has, wait is the time until one does, or 0 if no
+// messages are pending.
+func (queue *coalesceQueue) next(now time.Time) (msg coalescedMessage, wait time.Duration, ok bool) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for i, pending := range queue.pending {
+		w := queue.window - now.Sub(pending.first)
+		if w <= 0 {
+			queue.pending = append(queue.pending[:i], queue.pending[i+1:]...)
+			return *pending, 0, true
+		}
+		if wait == 0 || w < wait {
+			wait = w
+		}
+	}
+	return coalescedMessage{}, wait, false
+}
+
+// flush removes and returns all pending messages, regardless of their
+// windows
+func (queue *coalesceQueue) flush() []coalescedMessage {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	msgs := make([]coalescedMessage, len(queue.pending))
+	for i, pending := range queue.pending {
+		msgs[i] = *pending
+	}
+	queue.pending = nil
+	return msgs
+}
+
+// run sends the collected messages using send as their windows end, until
+// ctx is done
+func (queue *coalesceQueue) run(ctx context.Context, send func(msg coalescedMessage)) {
+	for {
+		msg, wait, ok := queue.next(time.Now())
+		if ok {
+			send(msg)
+			continue
+		}
+		var ready <-chan time.Time
+		if wait > 0 {
+			ready = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-queue.wake:
+		case <-ready:
+		}
+	}
+}
+
+// sendCoalesced sends the messages collected for a channel
+func (bot *bot) sendCoalesced(msg coalescedMessage) {
+	bot.sendChannelMessage(msg.channelID, msg.content(), msg.tts)
+}
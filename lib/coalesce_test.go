@@ -0,0 +1,28 @@
+package timatch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoalesceQueue(t *testing.T) {
+	queue := newCoalesceQueue(30 * time.Second)
+	now := time.Now()
+	queue.add("1", "Drafting", false, now)
+	queue.add("2", "Started", true, now.Add(10*time.Second))
+	queue.add("1", "Started", true, now.Add(20*time.Second))
+
+	if _, wait, ok := queue.next(now.Add(25 * time.Second)); ok || wait != 5*time.Second {
+		t.Errorf("next() before the window ends = %v, %v, want wait 5s", ok, wait)
+	}
+	msg, _, ok := queue.next(now.Add(30 * time.Second))
+	if !ok || msg.channelID != "1" || msg.content() != "Drafting\nStarted" || !msg.tts {
+		t.Errorf("next() = %+v, %v, want both messages to 1 as TTS", msg, ok)
+	}
+	if msgs := queue.flush(); len(msgs) != 1 || msgs[0].channelID != "2" {
+		t.Errorf("flush() = %+v, want the messages to 2", msgs)
+	}
+	if _, wait, ok := queue.next(now.Add(time.Hour)); ok || wait != 0 {
+		t.Errorf("next() of empty queue = %v, %v, want nothing", ok, wait)
+	}
+}
@@ -0,0 +1,437 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// commandTimeout is the time a command handler may run for
+const commandTimeout = 10 * time.Second
+
+// commandHandler handles an invocation of a command, returning the
+// response to send.
+type commandHandler func(ctx context.Context, in *interaction) (*interactionResponseData, error)
+
+// command is a slash command handled by the bot
+type command struct {
+	definition applicationCommand
+	handler    commandHandler
+	// ephemeral is true if the response should only be visible to the
+	// user invoking the command
+	ephemeral bool
+}
+
+// errorResponse is sent when a command handler fails
+var errorResponse = &interactionResponseData{
+	Content: "Something went wrong, please try again later.",
+	Flags:   messageFlagEphemeral,
+}
+
+// newCommands returns the commands handled by the bot, by name
+func (bot *bot) newCommands() map[string]*command {
+	commands := []*command{
+		{
+			definition: applicationCommand{
+				Name:        "settings",
+				Description: "Show or change the bot settings of this server",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "name",
+					Description: "Name of the setting to change",
+					Choices:     guildSettingChoices(),
+				}, {
+					Type:        commandOptionString,
+					Name:        "value",
+					Description: "New value of the setting",
+				}},
+			},
+			handler:   bot.handleSettingsCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "subscribe",
+				Description: "Send announcements to this channel",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team",
+					Description: "Only announce games of this team (name, tag or id)",
+				}},
+			},
+			handler:   bot.handleSubscribeCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "unsubscribe",
+				Description: "Stop sending announcements to this channel",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team",
+					Description: "Only stop announcing games of this team (name, tag or id)",
+				}},
+			},
+			handler:   bot.handleUnsubscribeCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "events",
+				Description: "Show or change the kinds of announcements sent to this channel",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "event",
+					Description: "Kind of announcement: " + announcementEventNames(),
+				}, {
+					Type:        commandOptionString,
+					Name:        "state",
+					Description: "\"on\" or \"off\"",
+				}},
+			},
+			handler:   bot.handleEventsCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "accessible",
+				Description: "Show or change accessibility mode, sending screen reader friendly messages",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "state",
+					Description: "\"on\" or \"off\"",
+				}},
+			},
+			handler:   bot.handleAccessibleCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "mute",
+				Description: "Stop sending announcements to this channel for a while",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "duration",
+					Description: "How long to mute for, e.g. 8h or 2d, or \"off\" to unmute",
+					Required:    true,
+				}},
+			},
+			handler:   bot.handleMuteCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "remind",
+				Description: "Show or change how long before scheduled series start this channel is reminded of them",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionInteger,
+					Name:        "minutes",
+					Description: "Minutes before the start, or 0 for the default",
+				}},
+			},
+			handler:   bot.handleRemindCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "prizes",
+				Description: "Show the prize pool and its distribution",
+			},
+			handler: bot.handlePrizesCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "bracket",
+				Description: "Show the playoff bracket",
+			},
+			handler: bot.handleBracketCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "summary",
+				Description: "Show the tournament in numbers so far",
+			},
+			handler: bot.handleSummaryCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "live",
+				Description: "Show the scoreboards of the live games",
+			},
+			handler:   bot.handleLiveCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "results",
+				Description: "Show the results of today's games",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionInteger,
+					Name:        "count",
+					Description: "Show the last count games instead of today's",
+				}, {
+					Type:        commandOptionBoolean,
+					Name:        "spoilers",
+					Description: "Hide the winners behind spoiler tags",
+				}},
+			},
+			handler: bot.handleResultsCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "roster",
+				Description: "Show the players of a team",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team",
+					Description: "Name, tag or id of the team",
+					Required:    true,
+				}},
+			},
+			handler: bot.handleRosterCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "team",
+				Description: "Show the record, game durations and kills of a team",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "name",
+					Description: "Name, tag or id of the team",
+					Required:    true,
+				}},
+			},
+			handler: bot.handleTeamCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "h2h",
+				Description: "Show the record of two teams against each other",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team1",
+					Description: "Name, tag or id of the first team",
+					Required:    true,
+				}, {
+					Type:        commandOptionString,
+					Name:        "team2",
+					Description: "Name, tag or id of the second team",
+					Required:    true,
+				}, {
+					Type:        commandOptionBoolean,
+					Name:        "all",
+					Description: "Include the games of all leagues the bot has watched",
+				}},
+			},
+			handler: bot.handleH2HCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "hero",
+				Description: "Show the tournament stats of a hero",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "name",
+					Description: "Name of the hero",
+					Required:    true,
+				}},
+			},
+			handler: bot.handleHeroCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "meta",
+				Description: "Show the most picked, banned and winning heroes of the league",
+			},
+			handler: bot.handleMetaCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "leaderboard",
+				Description: "Show the best predictors of the server",
+			},
+			handler: bot.handleLeaderboardCommand,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "bet",
+				Description: "Bet points on a team winning its next game",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team",
+					Description: "Name, tag or id of the team",
+					Required:    true,
+				}, {
+					Type:        commandOptionInteger,
+					Name:        "amount",
+					Description: "Number of points to bet",
+					Required:    true,
+				}},
+			},
+			handler:   bot.handleBetCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "balance",
+				Description: "Show your points",
+			},
+			handler:   bot.handleBalanceCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "bets",
+				Description: "Show your most recent bets",
+			},
+			handler:   bot.handleBetsCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "teamrole",
+				Description: "Show or change the roles pinged when games of teams start",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "team",
+					Description: "Name, tag or id of the team",
+				}, {
+					Type:        commandOptionRole,
+					Name:        "role",
+					Description: "Role to ping, leave out to remove the role of the team",
+				}},
+			},
+			handler:   bot.handleTeamRoleCommand,
+			ephemeral: true,
+		},
+		{
+			definition: applicationCommand{
+				Name:        "template",
+				Description: "Show or change the templates of the announcements",
+				Options: []applicationCommandOption{{
+					Type:        commandOptionString,
+					Name:        "name",
+					Description: "Name of the template, e.g. started or finished",
+				}, {
+					Type:        commandOptionString,
+					Name:        "text",
+					Description: "Template text, leave out to restore the default",
+				}},
+			},
+			handler:   bot.handleTemplateCommand,
+			ephemeral: true,
+		},
+	}
+	byName := make(map[string]*command)
+	for _, cmd := range commands {
+		byName[cmd.definition.Name] = cmd
+	}
+	return byName
+}
+
+// registerCommands registers the slash commands of the bot with Discord
+func (bot *bot) registerCommands(s *discordgo.Session, applicationID string) {
+	definitions := make([]applicationCommand, 0, len(bot.commands))
+	for _, cmd := range bot.commands {
+		definitions = append(definitions, cmd.definition)
+	}
+	if err := registerApplicationCommands(s, applicationID, definitions); err != nil {
+		bot.logger.WithError(err).Error("Error registering commands")
+	}
+}
+
+// onEvent is called by discordgo for every event received. Interactions
+// are not known to our discordgo version, so they are picked out here.
+func (bot *bot) onEvent(s *discordgo.Session, event *discordgo.Event) {
+	if event.Type != eventInteractionCreate {
+		return
+	}
+	in := &interaction{}
+	if err := json.Unmarshal(event.RawData, in); err != nil {
+		bot.logger.WithError(err).Error("Error decoding interaction")
+		return
+	}
+	switch in.Type {
+	case interactionTypeApplicationCommand:
+		bot.handleCommand(s, in)
+	case interactionTypeMessageComponent:
+		bot.handleComponent(s, in)
+	}
+}
+
+// handleCommand runs the handler of the invoked command
+func (bot *bot) handleCommand(s *discordgo.Session, in *interaction) {
+	logger := bot.logger.WithField(logFieldGuildID, in.GuildID).WithField(logFieldChannelID, in.ChannelID)
+	cmd, ok := bot.commands[in.Data.Name]
+	if !ok {
+		logger.Warnf("Got unknown command %s", in.Data.Name)
+		return
+	}
+	logger.Debugf("Got command %s from %s", in.Data.Name, in.userID())
+	bot.runInteractionHandler(s, in, logger, in.Data.Name, cmd.handler, cmd.ephemeral)
+}
+
+// handleComponent runs the handler of a clicked component, found by the
+// part of its custom id before any ":". Responses are only visible to
+// the user clicking.
+func (bot *bot) handleComponent(s *discordgo.Session, in *interaction) {
+	logger := bot.logger.WithField(logFieldGuildID, in.GuildID).WithField(logFieldChannelID, in.ChannelID)
+	name := strings.SplitN(in.Data.CustomID, ":", 2)[0]
+	handler := bot.componentHandler(name)
+	if handler == nil {
+		logger.Warnf("Got unknown component %s", in.Data.CustomID)
+		return
+	}
+	logger.Debugf("Got component %s from %s", in.Data.CustomID, in.userID())
+	bot.runInteractionHandler(s, in, logger, name, handler, true)
+}
+
+// componentHandler returns the handler of the components named name, or
+// nil if there is no such component
+func (bot *bot) componentHandler(name string) commandHandler {
+	switch name {
+	case componentRevealResults:
+		return bot.handleRevealResults
+	case componentRevealResult:
+		return bot.handleRevealResult
+	case componentPredict:
+		return bot.handlePredict
+	}
+	return nil
+}
+
+// runInteractionHandler runs handler for the interaction. The interaction
+// is responded to with a deferred response straight away, so that the
+// handler is not limited by Discord's 3 second response deadline.
+func (bot *bot) runInteractionHandler(s *discordgo.Session, in *interaction, logger *logrus.Entry, name string, handler commandHandler, ephemeral bool) {
+	deferred := &interactionResponse{
+		Type: interactionResponseDeferredChannelMessage,
+		Data: &interactionResponseData{},
+	}
+	if ephemeral {
+		deferred.Data.Flags = messageFlagEphemeral
+	}
+	if err := respondInteraction(s, in, deferred); err != nil {
+		logger.WithError(err).Errorf("Error deferring response to %s", name)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	res, err := handler(ctx, in)
+	if err != nil {
+		logger.WithError(err).Errorf("Error handling %s", name)
+		res = errorResponse
+	}
+	if err := editInteractionResponse(s, in, res); err != nil {
+		logger.WithError(err).Errorf("Error responding to %s", name)
+	}
+}
+
+// textResponse is a helper for creating a plain text response
+func textResponse(content string) *interactionResponseData {
+	return &interactionResponseData{Content: content}
+}
@@ -0,0 +1,144 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/apiclient"
+)
+
+// communityPredictionsInterval is the time the community predictions are
+// cached for before being fetched again
+const communityPredictionsInterval = 1 * time.Hour
+
+// communityComparisonSize is the number of teams of the community
+// predictions compared in /leaderboard
+const communityComparisonSize = 5
+
+// communityPredictions is the aggregate community prediction data, e.g.
+// the share of compendium owners that picked each team as the tournament
+// winner. There is no official API for this data, so it is read as JSON
+// from an operator provided URL, see Config.CommunityPredictionsURL.
+type communityPredictions struct {
+	// Source is a human readable description of where the data is from,
+	// e.g. "Compendium"
+	Source      string                    `json:"source"`
+	Predictions []communityPredictionItem `json:"predictions"`
+}
+
+type communityPredictionItem struct {
+	TeamName string `json:"team_name"`
+	// Share is the fraction (0-1) of the community that picked the team
+	Share float64 `json:"share"`
+}
+
+// communityPredictionsSource fetches the community predictions from a
+// URL, caching them for communityPredictionsInterval. The last fetched
+// predictions are kept if fetching fails.
+type communityPredictionsSource struct {
+	url string
+	api *apiclient.Client
+
+	mu          sync.Mutex
+	lastFetch   time.Time
+	predictions *communityPredictions
+}
+
+func newCommunityPredictionsSource(logger *logrus.Logger, url string) *communityPredictionsSource {
+	return &communityPredictionsSource{
+		url: url,
+		api: apiclient.NewClient(logger, time.Second),
+	}
+}
+
+// get returns the community predictions, sorted by share, highest first,
+// fetching them if not fetched within communityPredictionsInterval. The
+// predictions are nil if they have never been fetched.
+func (src *communityPredictionsSource) get(ctx context.Context) (*communityPredictions, error) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if time.Since(src.lastFetch) < communityPredictionsInterval {
+		return src.predictions, nil
+	}
+	src.lastFetch = time.Now()
+	req, err := http.NewRequest("GET", src.url, nil)
+	if err != nil {
+		return src.predictions, errors.Wrap(err, "Error creating request")
+	}
+	predictions := &communityPredictions{}
+	if err := src.api.GetJSON(ctx, req.WithContext(ctx), predictions, nil); err != nil {
+		return src.predictions, errors.Wrap(err, "Error getting community predictions")
+	}
+	sort.SliceStable(predictions.Predictions, func(i, j int) bool {
+		return predictions.Predictions[i].Share > predictions.Predictions[j].Share
+	})
+	src.predictions = predictions
+	return src.predictions, nil
+}
+
+// renderCommunityComparison renders the teams most picked by the community
+// next to the share of the guild's scored predictions backing each team,
+// from the pick history of the guild in the league
+func renderCommunityComparison(community *communityPredictions, history []pickRecord) string {
+	if community == nil || len(community.Predictions) == 0 {
+		return ""
+	}
+	guildPicks := make(map[string]int)
+	total := 0
+	for _, record := range history {
+		if record.Kind != pickKindPrediction {
+			continue
+		}
+		guildPicks[strings.ToLower(record.Team)]++
+		total++
+	}
+	source := community.Source
+	if source == "" {
+		source = "Community"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s picks vs. this server's predictions**\n", source)
+	items := community.Predictions
+	if len(items) > communityComparisonSize {
+		items = items[:communityComparisonSize]
+	}
+	for _, item := range items {
+		fmt.Fprintf(&b, "%s: %.0f%%", item.TeamName, item.Share*100)
+		if total > 0 {
+			share := float64(guildPicks[strings.ToLower(item.TeamName)]) / float64(total)
+			fmt.Fprintf(&b, " (server: %.0f%%)", share*100)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// communityComparison renders the comparison of the community predictions
+// and the predictions of a guild for /leaderboard, or an empty string if
+// there are no community predictions
+func (bot *bot) communityComparison(ctx context.Context, guildID guildID) string {
+	if bot.communityPredictions == nil {
+		return ""
+	}
+	community, err := bot.communityPredictions.get(ctx)
+	if err != nil {
+		// The last fetched predictions, if any, are still shown
+		bot.logger.WithError(err).Warn("Error getting community predictions")
+	}
+	if community == nil {
+		return ""
+	}
+	history, err := bot.loadPickHistory(ctx, guildID, bot.currentLeagueID())
+	if err != nil {
+		bot.logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error loading pick history")
+		history = nil
+	}
+	return renderCommunityComparison(community, history)
+}
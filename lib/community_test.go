@@ -0,0 +1,66 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRenderCommunityComparison(t *testing.T) {
+	community := &communityPredictions{
+		Source: "Compendium",
+		Predictions: []communityPredictionItem{
+			{TeamName: "OG", Share: 0.4},
+			{TeamName: "Team Liquid", Share: 0.25},
+		},
+	}
+	history := []pickRecord{
+		{Kind: pickKindPrediction, Team: "og"},
+		{Kind: pickKindPrediction, Team: "Team Secret"},
+		{Kind: pickKindPrediction, Team: "OG"},
+		{Kind: pickKindPrediction, Team: "Team Liquid"},
+		{Kind: pickKindBet, Team: "Team Liquid"},
+	}
+	want := "**Compendium picks vs. this server's predictions**\n" +
+		"OG: 40% (server: 50%)\n" +
+		"Team Liquid: 25% (server: 25%)"
+	if got := renderCommunityComparison(community, history); got != want {
+		t.Errorf("renderCommunityComparison() = %q, want %q", got, want)
+	}
+	if got := renderCommunityComparison(nil, history); got != "" {
+		t.Errorf("expected no comparison without community predictions, got %q", got)
+	}
+}
+
+func TestCommunityPredictionsSource(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"source": "Compendium", "predictions": [
+			{"team_name": "Team Liquid", "share": 0.25},
+			{"team_name": "OG", "share": 0.4}
+		]}`)
+	}))
+	defer server.Close()
+
+	src := newCommunityPredictionsSource(logger, server.URL)
+	for i := 0; i < 2; i++ {
+		predictions, err := src.get(context.Background())
+		if err != nil {
+			t.Fatalf("Error getting community predictions: %+v", err)
+		}
+		if len(predictions.Predictions) != 2 || predictions.Predictions[0].TeamName != "OG" {
+			t.Errorf("expected predictions sorted by share, got %+v", predictions.Predictions)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the predictions to be cached, got %d requests", requests)
+	}
+}
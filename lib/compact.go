@@ -0,0 +1,93 @@
+package timatch
+
+import (
+	"strings"
+	"text/template"
+)
+
+const (
+	layoutFull    = "full"
+	layoutCompact = "compact"
+)
+
+// layouts are the layouts a guild or channel can choose from. The first
+// one is the default.
+var layouts = []string{layoutFull, layoutCompact}
+
+// compactTemplates replace the announcement templates, by template name,
+// in channels using the compact layout. Each game gets a single short
+// line with the outcome or state first, so that it fits in the push
+// notification previews of phones.
+var compactTemplates = templateCatalog(
+	tmplMatchesDraftingCompact,
+	tmplMatchesStartedCompact,
+	tmplScoreUpdatesCompact,
+	tmplMatchesFinishedCompact,
+	tmplFloodDigestCompact,
+)
+
+var tmplMatchesDraftingCompact = template.Must(newTemplate("MatchesDrafting").Parse(strings.TrimSpace(`
+{{ range . }}
+📝 {{ .RadiantTeam.TeamName }} {{ score .RadiantSeriesWins .DireSeriesWins }} {{ .DireTeam.TeamName }} ({{ bestOf .SeriesType }}, G{{ .GameNumber }})
+{{- end -}}
+`)))
+
+var tmplMatchesStartedCompact = template.Must(newTemplate("MatchesStarted").Parse(strings.TrimSpace(`
+{{ range . }}
+▶️ {{ .RadiantTeam.TeamName }} {{ score .RadiantSeriesWins .DireSeriesWins }} {{ .DireTeam.TeamName }} ({{ bestOf .SeriesType }}, G{{ .GameNumber }})
+{{- end -}}
+`)))
+
+var tmplScoreUpdatesCompact = template.Must(newTemplate("ScoreUpdates").Parse(strings.TrimSpace(`
+{{ range . }}
+📊 {{ .Game.RadiantTeam.TeamName }} {{ score .RadiantScore .DireScore }} {{ .Game.DireTeam.TeamName }} ({{ .Duration }}, G{{ .Game.GameNumber }}{{ if .Favored }}, {{ .Favored }} {{ .WinChance }}%{{ end }})
+{{- end -}}
+`)))
+
+var tmplMatchesFinishedCompact = template.Must(newTemplate("MatchesFinished").Parse(strings.TrimSpace(`
+{{ range . }}
+{{ if .Remake }}🔁 {{ .FirstTeam }} vs {{ .SecondTeam }} remade (G{{ .GameNumber }})
+{{- else }}{{ if winners }}✅ {{ .WinnerName }} {{ if kills }}{{ score .WinnerScore .LoserScore }}{{ else }}beat{{ end }} {{ .LoserName }}{{ else }}🏁 {{ .FirstTeam }} vs {{ .SecondTeam }}{{ end }} (G{{ .GameNumber }}{{ if and winners .UpsetWinnerSeed }}, upset #{{ .UpsetWinnerSeed }} over #{{ .UpsetLoserSeed }}{{ end }}{{ if .Delayed }}, late{{ end }}){{ end }}
+{{- end -}}
+`)))
+
+var tmplFloodDigestCompact = template.Must(newTemplate("FloodDigest").Parse(strings.TrimSpace(`
+{{ range .Started }}
+▶️ {{ .RadiantTeam.TeamName }} vs {{ .DireTeam.TeamName }} (G{{ .GameNumber }})
+{{- end }}
+{{- range .Finished }}
+{{ if winners }}✅ {{ .WinnerName }} {{ if kills }}{{ score .WinnerScore .LoserScore }}{{ else }}beat{{ end }} {{ .LoserName }}{{ else }}🏁 {{ .FirstTeam }} vs {{ .SecondTeam }}{{ end }} (G{{ .GameNumber }})
+{{- end -}}
+`)))
+
+// bestOf names a series type, e.g. "Bo3"
+func bestOf(seriesType int) string {
+	switch seriesType {
+	case seriesTypeBestOf3:
+		return "Bo3"
+	case seriesTypeBestOf5:
+		return "Bo5"
+	}
+	return "Bo1"
+}
+
+func findLayout(name string) (string, bool) {
+	for _, layout := range layouts {
+		if strings.EqualFold(layout, name) {
+			return layout, true
+		}
+	}
+	return "", false
+}
+
+// layout returns the layout of announcements to the channel, or to the
+// guild if channelID is empty
+func (settings *guildSettings) layout(channelID string) string {
+	if layout, ok := settings.ChannelLayouts[channelID]; ok {
+		return layout
+	}
+	if layout, ok := findLayout(settings.Layout); ok {
+		return layout
+	}
+	return layouts[0]
+}
@@ -0,0 +1,59 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestCompactRendering(t *testing.T) {
+	settings := &guildSettings{ScoreStyle: "endash", ChannelLayouts: map[string]string{"1": layoutCompact}}
+	if settings.textFormat().compact {
+		t.Error("Guild format is compact, want only the channel compact")
+	}
+	format := settings.channelTextFormat("1")
+	finished := []matchesFinishedDataItem{{GameNumber: 2, WinnerName: "OG", LoserName: "Secret", WinnerScore: 32, LoserScore: 17}}
+	got, err := renderTemplate(tmplMatchesFinished, format, finished)
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	if want := "✅ OG 32–17 Secret (G2)"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+	started := []dota.LiveLeagueGame{{
+		RadiantTeam:       dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:          dota.LiveLeagueGamesTeam{TeamName: "Secret"},
+		RadiantSeriesWins: 1,
+		GameNumber:        2,
+		SeriesType:        seriesTypeBestOf3,
+	}}
+	got, err = renderTemplate(tmplMatchesStarted, format, started)
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	if want := "▶️ OG 1–0 Secret (Bo3, G2)"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+	// Every template has a compact variant of one line per game
+	for _, preview := range previewTemplates {
+		if _, ok := compactTemplates[preview.tmpl.Name()]; !ok {
+			t.Errorf("No compact variant of %s", preview.name)
+			continue
+		}
+		got, err := renderTemplate(preview.tmpl, format, preview.fixture)
+		if err != nil {
+			t.Errorf("Error rendering compact %s: %v", preview.name, err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+			if len(line) > 80 {
+				t.Errorf("Compact %s line %q longer than 80 bytes", preview.name, line)
+			}
+		}
+	}
+	// Accessibility mode takes precedence over the layout
+	settings.Accessible = true
+	if got, _ := renderTemplate(tmplMatchesFinished, settings.channelTextFormat("1"), finished); strings.Contains(got, "✅") {
+		t.Errorf("renderTemplate() in accessibility mode = %q, want the accessible variant", got)
+	}
+}
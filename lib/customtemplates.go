@@ -0,0 +1,151 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// alertGuildTemplate is the kind of admin alerts about guild templates
+// failing to render, suffixed by the channel id
+const alertGuildTemplate = "guild_template"
+
+// parseCustomTemplate parses a template replacing the bot's own, given as
+// template text by the name used by RenderPreview. The template is
+// validated by rendering it with the bundled fixture, so that mistakes
+// are found when setting it rather than when announcing. Returns the
+// bot's template being replaced along with the parsed template.
+func parseCustomTemplate(name string, text string) (replaced *template.Template, tmpl *template.Template, err error) {
+	preview, err := findPreviewTemplate(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err = newTemplate(name).Parse(strings.TrimSpace(text))
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Error parsing %s template", name)
+	}
+	if _, err := renderTemplate(tmpl, defaultTextFormat, preview.fixture); err != nil {
+		return nil, nil, errors.Wrapf(err, "Error rendering %s template", name)
+	}
+	return preview.tmpl, tmpl, nil
+}
+
+// parseCustomTemplates parses the templates replacing the bot's own, see
+// parseCustomTemplate. The parsed templates are returned by the name of
+// the template they replace.
+func parseCustomTemplates(texts map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(texts))
+	for name, text := range texts {
+		replaced, tmpl, err := parseCustomTemplate(name, text)
+		if err != nil {
+			return nil, err
+		}
+		templates[replaced.Name()] = tmpl
+	}
+	return templates, nil
+}
+
+// template returns the template replacing tmpl, or tmpl if it has not
+// been replaced. Replaced templates are used regardless of the language
+// of the guild.
+func (bot *bot) template(tmpl *template.Template) *template.Template {
+	if custom, ok := bot.customTemplates[tmpl.Name()]; ok {
+		return custom
+	}
+	return tmpl
+}
+
+// guildTemplate returns the text of the guild's template replacing tmpl,
+// set using the /template command, or "" if the guild has not replaced it
+func (settings *guildSettings) guildTemplate(tmpl *template.Template) (name string, text string) {
+	for _, preview := range previewTemplates {
+		if preview.tmpl.Name() == tmpl.Name() {
+			return preview.name, settings.Templates[preview.name]
+		}
+	}
+	return "", ""
+}
+
+// renderGuildTemplate renders the guild's template replacing tmpl, if any.
+// Templates are validated when set, but may still fail on data not
+// covered by the fixtures, in which case the admin is alerted and false
+// is returned so that the caller falls back to tmpl.
+func (bot *bot) renderGuildTemplate(tmpl *template.Template, channelID channelID, settings *guildSettings, format textFormat, data interface{}) (string, bool) {
+	name, text := settings.guildTemplate(tmpl)
+	if text == "" {
+		return "", false
+	}
+	_, custom, err := parseCustomTemplate(name, text)
+	var content string
+	if err == nil {
+		content, err = renderTemplate(custom, format, data)
+	}
+	if err != nil {
+		bot.logger.WithError(err).WithField(logFieldChannelID, channelID).
+			Errorf("Failed executing guild template '%s', using the default", name)
+		bot.alertAdmin(alertGuildTemplate+"_"+string(channelID), fmt.Sprintf(
+			"The %s template of channel <#%s> failed, using the default: %v", name, channelID, err))
+		return "", false
+	}
+	return content, true
+}
+
+// handleTemplateCommand lists the templates replaced by the guild, or
+// replaces a template if given a name and text. Giving a name without
+// text restores the default template. Changing templates requires the
+// Manage Server permission.
+func (bot *bot) handleTemplateCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Templates can only be changed in a server."), nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	name := in.stringOption("name")
+	if name == "" {
+		if len(settings.Templates) == 0 {
+			return textResponse("No templates replaced. Templates: " + strings.Join(PreviewTemplateNames(), ", ")), nil
+		}
+		names := make([]string, 0, len(settings.Templates))
+		for name := range settings.Templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var b strings.Builder
+		b.WriteString("**Templates**\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s: `%s`\n", name, settings.Templates[name])
+		}
+		return textResponse(b.String()), nil
+	}
+	if !in.hasPermission(permissionManageGuild) {
+		return textResponse("Changing templates requires the Manage Server permission."), nil
+	}
+	text := in.stringOption("text")
+	if text == "" {
+		if _, ok := settings.Templates[name]; !ok {
+			return textResponse(fmt.Sprintf("The %s template is not replaced.", name)), nil
+		}
+		delete(settings.Templates, name)
+	} else {
+		if _, _, err := parseCustomTemplate(name, text); err != nil {
+			return textResponse(fmt.Sprintf("Invalid template: %v", err)), nil
+		}
+		if settings.Templates == nil {
+			settings.Templates = make(map[string]string)
+		}
+		settings.Templates[name] = text
+	}
+	if err := bot.saveGuildSettings(ctx, guildID(in.GuildID), settings); err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return textResponse(fmt.Sprintf("The %s template is restored to the default.", name)), nil
+	}
+	return textResponse(fmt.Sprintf("The %s template is replaced.", name)), nil
+}
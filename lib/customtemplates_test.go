@@ -0,0 +1,68 @@
+package timatch
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseCustomTemplates(t *testing.T) {
+	templates, err := parseCustomTemplates(map[string]string{
+		"finished": "{{ range . }}GG {{ .WinnerName }}{{ end }}",
+	})
+	if err != nil {
+		t.Fatalf("parseCustomTemplates() error: %v", err)
+	}
+	bot := &bot{customTemplates: templates}
+	if got := bot.template(tmplMatchesStarted); got != tmplMatchesStarted {
+		t.Error("template() of a template not replaced, want the bot's own")
+	}
+	items := []matchesFinishedDataItem{{WinnerName: "OG"}}
+	// The replaced template is used even for guilds in other languages
+	format := (&guildSettings{Language: "ru"}).textFormat()
+	got, err := renderTemplate(bot.template(tmplMatchesFinished), format, items)
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	if want := "GG OG"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestParseCustomTemplatesErrors(t *testing.T) {
+	tests := []map[string]string{
+		{"unknown": "hi"},
+		{"finished": "{{ range . }"},
+		// Valid syntax, but the data has no such field
+		{"finished": "{{ range . }}{{ .Winner }}{{ end }}"},
+	}
+	for _, texts := range tests {
+		if _, err := parseCustomTemplates(texts); err == nil {
+			t.Errorf("parseCustomTemplates(%v) = no error, want error", texts)
+		}
+	}
+}
+
+func TestRenderGuildTemplate(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger}
+	items := []matchesFinishedDataItem{{WinnerName: "OG"}}
+	settings := &guildSettings{Templates: map[string]string{
+		"finished": "{{ range . }}GG {{ .WinnerName }}{{ end }}",
+	}}
+	got, ok := bot.renderGuildTemplate(tmplMatchesFinished, "1", settings, defaultTextFormat, items)
+	if want := "GG OG"; !ok || got != want {
+		t.Errorf("renderGuildTemplate() = %q, %v, want %q", got, ok, want)
+	}
+	if _, ok := bot.renderGuildTemplate(tmplMatchesStarted, "1", settings, defaultTextFormat, items); ok {
+		t.Error("renderGuildTemplate() of a template not replaced = true, want false")
+	}
+	// Fails on the data, as the template expects another type
+	settings.Templates["finished"] = "{{ range . }}{{ .WinnerName }}{{ end }}"
+	if _, ok := bot.renderGuildTemplate(tmplMatchesFinished, "1", settings, defaultTextFormat, []int{1}); ok {
+		t.Error("renderGuildTemplate() of a failing template = true, want false")
+	}
+}
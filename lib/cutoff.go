@@ -0,0 +1,43 @@
+package timatch
+
+import (
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// defaultSince is the default of Config.Since, the longest games usually
+// last, so that the results of games in progress when the bot starts are
+// still announced
+const defaultSince = 3 * time.Hour
+
+// trackStartTime records the start of a live game the first time it is
+// seen started, from the game time on its scoreboard
+func (watcher *Watcher) trackStartTime(game dota.LiveLeagueGame, now time.Time) {
+	if !isGameStarted(game) {
+		return
+	}
+	if _, ok := watcher.startTimes[game.MatchID]; ok {
+		return
+	}
+	watcher.startTimes[game.MatchID] = now.Unix() - int64(game.Scoreboard.Duration)
+}
+
+// isBeforeCutoff tests if a match of the match history started more than
+// the since period before the watcher started, e.g. old games the live
+// games wrongly still list, whose results must not be announced as new.
+// The start of the match history is used, or the tracked start of the live
+// game if the history does not give one.
+func (watcher *Watcher) isBeforeCutoff(match dota.MatchHistoryMatch) bool {
+	if watcher.since == 0 {
+		return false
+	}
+	startTime := match.StartTime
+	if startTime == 0 {
+		startTime = watcher.startTimes[match.MatchID]
+	}
+	if startTime == 0 {
+		return false
+	}
+	return startTime < watcher.startedAt.Add(-watcher.since).Unix()
+}
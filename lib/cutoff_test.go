@@ -0,0 +1,48 @@
+package timatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestIsBeforeCutoff(t *testing.T) {
+	watcher := &Watcher{startTimes: make(map[int64]int64), since: time.Hour, startedAt: time.Now()}
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	recent := time.Now().Add(-30 * time.Minute).Unix()
+	if !watcher.isBeforeCutoff(dota.MatchHistoryMatch{MatchID: 1, StartTime: old}) {
+		t.Error("expected a match started before the cutoff to be before the cutoff")
+	}
+	if watcher.isBeforeCutoff(dota.MatchHistoryMatch{MatchID: 2, StartTime: recent}) {
+		t.Error("expected a match started after the cutoff not to be before the cutoff")
+	}
+	if watcher.isBeforeCutoff(dota.MatchHistoryMatch{MatchID: 3}) {
+		t.Error("expected a match without a known start not to be before the cutoff")
+	}
+	watcher.startTimes[4] = old
+	if !watcher.isBeforeCutoff(dota.MatchHistoryMatch{MatchID: 4}) {
+		t.Error("expected the tracked start to be used when the history has none")
+	}
+}
+
+func TestTrackStartTime(t *testing.T) {
+	watcher := &Watcher{startTimes: make(map[int64]int64)}
+	now := time.Now()
+	game := dota.LiveLeagueGame{MatchID: 1}
+	watcher.trackStartTime(game, now)
+	if _, ok := watcher.startTimes[1]; ok {
+		t.Fatal("expected no start time for a drafting game")
+	}
+	game.Scoreboard.Duration = 600
+	watcher.trackStartTime(game, now)
+	want := now.Unix() - 600
+	if got := watcher.startTimes[1]; got != want {
+		t.Errorf("expected start time %d, got %d", want, got)
+	}
+	game.Scoreboard.Duration = 1200
+	watcher.trackStartTime(game, now)
+	if got := watcher.startTimes[1]; got != want {
+		t.Errorf("expected the first start time to be kept, got %d", got)
+	}
+}
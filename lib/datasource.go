@@ -0,0 +1,72 @@
+package timatch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// Sources of the live and finished league games, see Config.DataSource
+const (
+	dataSourceSteam  = "steam"
+	dataSourceStratz = "stratz"
+)
+
+// matchDataSource is a source of the live and finished games of a league,
+// implemented by the Steam and STRATZ clients
+type matchDataSource interface {
+	GetLiveLeagueGames(ctx context.Context, leagueID int) (*dota.LiveLeagueGamesResponse, error)
+	GetMatchHistory(ctx context.Context, leagueID int) (*dota.MatchHistoryResponse, error)
+	GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error)
+}
+
+// fallbackDataSource uses the fallback source for the requests the
+// primary source fails
+type fallbackDataSource struct {
+	logger   *logrus.Logger
+	primary  matchDataSource
+	fallback matchDataSource
+}
+
+func (source *fallbackDataSource) GetLiveLeagueGames(ctx context.Context, leagueID int) (*dota.LiveLeagueGamesResponse, error) {
+	res, err := source.primary.GetLiveLeagueGames(ctx, leagueID)
+	if err == nil || ctx.Err() != nil {
+		return res, err
+	}
+	source.logger.WithError(err).Warn("Error getting live league games, using the fallback data source")
+	res, fallbackErr := source.fallback.GetLiveLeagueGames(ctx, leagueID)
+	if fallbackErr != nil {
+		return nil, errors.Wrapf(err, "Error getting live league games (fallback: %v)", fallbackErr)
+	}
+	return res, nil
+}
+
+func (source *fallbackDataSource) GetMatchHistory(ctx context.Context, leagueID int) (*dota.MatchHistoryResponse, error) {
+	res, err := source.primary.GetMatchHistory(ctx, leagueID)
+	if err == nil || ctx.Err() != nil {
+		return res, err
+	}
+	source.logger.WithError(err).Warn("Error getting match history, using the fallback data source")
+	res, fallbackErr := source.fallback.GetMatchHistory(ctx, leagueID)
+	if fallbackErr != nil {
+		return nil, errors.Wrapf(err, "Error getting match history (fallback: %v)", fallbackErr)
+	}
+	return res, nil
+}
+
+func (source *fallbackDataSource) GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error) {
+	res, err := source.primary.GetMatchDetails(ctx, matchID)
+	if err == nil || ctx.Err() != nil {
+		return res, err
+	}
+	// Match details are often not available right after a game, which
+	// is retried rather than logged as a failure
+	source.logger.WithError(err).Debug("Error getting match details, using the fallback data source")
+	res, fallbackErr := source.fallback.GetMatchDetails(ctx, matchID)
+	if fallbackErr != nil {
+		return nil, errors.Wrapf(err, "Error getting match details (fallback: %v)", fallbackErr)
+	}
+	return res, nil
+}
@@ -0,0 +1,57 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// stubDataSource gives the same games for every league, or fails if err
+// is set
+type stubDataSource struct {
+	games []dota.LiveLeagueGame
+	err   error
+}
+
+func (source *stubDataSource) GetLiveLeagueGames(ctx context.Context, leagueID int) (*dota.LiveLeagueGamesResponse, error) {
+	if source.err != nil {
+		return nil, source.err
+	}
+	res := &dota.LiveLeagueGamesResponse{}
+	res.Result.Games = source.games
+	return res, nil
+}
+
+func (source *stubDataSource) GetMatchHistory(ctx context.Context, leagueID int) (*dota.MatchHistoryResponse, error) {
+	return nil, source.err
+}
+
+func (source *stubDataSource) GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error) {
+	return nil, source.err
+}
+
+func TestFallbackDataSource(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	steam := &stubDataSource{games: []dota.LiveLeagueGame{{MatchID: 1}}}
+	stratz := &stubDataSource{games: []dota.LiveLeagueGame{{MatchID: 2}}}
+	source := &fallbackDataSource{logger: logger, primary: steam, fallback: stratz}
+	ctx := context.Background()
+	res, err := source.GetLiveLeagueGames(ctx, 1)
+	if err != nil || res.Result.Games[0].MatchID != 1 {
+		t.Errorf("GetLiveLeagueGames() = %v, %v, want the primary games", res, err)
+	}
+	steam.err = errors.New("Bad steam result")
+	res, err = source.GetLiveLeagueGames(ctx, 1)
+	if err != nil || res.Result.Games[0].MatchID != 2 {
+		t.Errorf("GetLiveLeagueGames() with primary failing = %v, %v, want the fallback games", res, err)
+	}
+	stratz.err = errors.New("Bad HTTP response status code: 503")
+	if _, err := source.GetLiveLeagueGames(ctx, 1); err == nil {
+		t.Error("GetLiveLeagueGames() with both failing, want error")
+	}
+}
@@ -0,0 +1,132 @@
+package timatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// detailWorkers is the number of workers fetching the details of finished
+// games. The requests are still bounded by the rate limiter of the API
+// client, but are not waited for by the poll loop
+const detailWorkers = 4
+
+// defaultDetailsMaxAge is the default of Config.DetailsMaxAge
+const defaultDetailsMaxAge = 10 * time.Minute
+
+// lateResultsInterval is the time between checks for the late results of
+// games given up on, see Config.LateResults
+const lateResultsInterval = 15 * time.Minute
+
+// detailsRetryBackoff is the time waited before retrying the details of a
+// game after the first failed attempt, doubled for each further attempt
+const detailsRetryBackoff = 30 * time.Second
+
+// detailsMaxBackoff caps the time waited between attempts
+const detailsMaxBackoff = 10 * time.Minute
+
+// detailsBackoff returns the time to wait before fetching the details of a
+// game after attempts failed attempts: 30s, 1m, 2m and so on
+func detailsBackoff(attempts int) time.Duration {
+	backoff := detailsRetryBackoff
+	for i := 1; i < attempts && backoff < detailsMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > detailsMaxBackoff {
+		backoff = detailsMaxBackoff
+	}
+	return backoff
+}
+
+// detailFetch is the fetched details of a game of the finished queue, or
+// the error fetching them
+type detailFetch struct {
+	details *dota.MatchDetailsResponse
+	err     error
+}
+
+// detailFetcher fetches the details of the games of the finished queue in
+// the background, so that a backlog of finished games does not delay the
+// polling of live games. The fetched details are collected by the poll
+// loop, which is woken when details are fetched.
+type detailFetcher struct {
+	jobs chan int64
+	// pending is the set of match ids queued or being fetched, guarded
+	// by the mu of the watcher
+	pending map[int64]struct{}
+
+	mu      sync.Mutex
+	fetched map[int64]detailFetch
+	// wake is signalled when the details of a game are fetched
+	wake chan struct{}
+}
+
+func newDetailFetcher() *detailFetcher {
+	return &detailFetcher{
+		jobs:    make(chan int64, detailWorkers),
+		pending: make(map[int64]struct{}),
+		fetched: make(map[int64]detailFetch),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// start starts the workers fetching the details of the queued games
+// using fetch, until ctx is done
+func (fetcher *detailFetcher) start(ctx context.Context, fetch func(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error)) {
+	for i := 0; i < detailWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case matchID := <-fetcher.jobs:
+					details, err := fetch(ctx, matchID)
+					fetcher.done(matchID, detailFetch{details: details, err: err})
+				}
+			}
+		}()
+	}
+}
+
+func (fetcher *detailFetcher) done(matchID int64, fetch detailFetch) {
+	fetcher.mu.Lock()
+	fetcher.fetched[matchID] = fetch
+	fetcher.mu.Unlock()
+	select {
+	case fetcher.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queue queues the entries not already being fetched, and not backing
+// off at now, for their details to be fetched. Entries are left for the
+// next call while all workers are busy, as fetching is bounded by the rate
+// limiter anyway.
+func (fetcher *detailFetcher) queue(entries []finishedQueueEntry, now time.Time) {
+	for _, entry := range entries {
+		if _, ok := fetcher.pending[entry.MatchID]; ok || now.Before(entry.NextAttemptAt) {
+			continue
+		}
+		select {
+		case fetcher.jobs <- entry.MatchID:
+			fetcher.pending[entry.MatchID] = struct{}{}
+		default:
+			return
+		}
+	}
+}
+
+// collect returns the details fetched since the last call, by match id.
+// The games are no longer pending, so are fetched again if queued again.
+func (fetcher *detailFetcher) collect() map[int64]detailFetch {
+	fetcher.mu.Lock()
+	fetched := fetcher.fetched
+	fetcher.fetched = make(map[int64]detailFetch)
+	fetcher.mu.Unlock()
+	for matchID := range fetched {
+		delete(fetcher.pending, matchID)
+	}
+	return fetched
+}
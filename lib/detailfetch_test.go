@@ -0,0 +1,83 @@
+package timatch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestDetailFetcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetcher := newDetailFetcher()
+	fetcher.start(ctx, func(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error) {
+		if matchID == 2 {
+			return nil, errors.New("not yet available")
+		}
+		return &dota.MatchDetailsResponse{}, nil
+	})
+	now := time.Now()
+	fetcher.queue([]finishedQueueEntry{{MatchID: 1}, {MatchID: 2}}, now)
+	// Already pending, so not queued again
+	fetcher.queue([]finishedQueueEntry{{MatchID: 1}}, now)
+	// Backing off, so not queued yet
+	fetcher.queue([]finishedQueueEntry{{MatchID: 3, NextAttemptAt: now.Add(time.Minute)}}, now)
+	fetched := make(map[int64]detailFetch)
+	deadline := time.After(time.Second)
+	for len(fetched) < 2 {
+		select {
+		case <-fetcher.wake:
+			for matchID, fetch := range fetcher.collect() {
+				fetched[matchID] = fetch
+			}
+		case <-deadline:
+			t.Fatalf("expected the details of both games to be fetched, got %v", fetched)
+		}
+	}
+	if fetched[1].err != nil || fetched[1].details == nil {
+		t.Errorf("expected the details of match 1, got %v", fetched[1])
+	}
+	if fetched[2].err == nil {
+		t.Error("expected the error fetching match 2")
+	}
+	if _, ok := fetched[3]; ok {
+		t.Error("expected match 3 not to be fetched while backing off")
+	}
+	if len(fetcher.pending) != 0 {
+		t.Errorf("expected no games pending once collected, got %v", fetcher.pending)
+	}
+}
+
+func TestDetailsBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := detailsBackoff(test.attempts); got != test.want {
+			t.Errorf("detailsBackoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestRenderDelayed(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 2, WinnerName: "OG", LoserName: "Liquid", Delayed: true}}
+	got, err := renderTemplate(tmplMatchesFinished, defaultTextFormat, items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if !strings.Contains(got, "Match Ended (delayed): OG defeated Liquid") {
+		t.Errorf("renderTemplate() of delayed result = %q, want it flagged as delayed", got)
+	}
+}
@@ -0,0 +1,72 @@
+package timatch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// dmSubscriptionKeyPrefix is the prefix of the store keys of all
+// subscriptions of users to announcements as direct messages
+const dmSubscriptionKeyPrefix = "dm/"
+
+// dmSubscriptionKey returns the store key of the direct message
+// subscription of a user
+func dmSubscriptionKey(userID string) string {
+	return dmSubscriptionKeyPrefix + userID
+}
+
+// loadDMSubscriptions returns the subscriptions of users to announcements
+// as direct messages. The channel of each subscription is the direct
+// message channel of the user.
+func (bot *bot) loadDMSubscriptions(ctx context.Context) ([]channelSubscription, error) {
+	keys, err := bot.store.Keys(ctx, dmSubscriptionKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error listing direct message subscriptions")
+	}
+	subs := make([]channelSubscription, 0, len(keys))
+	for _, key := range keys {
+		var sub channelSubscription
+		found, err := bot.store.Get(ctx, key, &sub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting %s", key)
+		}
+		if found {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+// changeDMSubscription is the changeSubscription equivalent for commands
+// used in direct messages, changing the subscription of the user. The
+// subscription is changed as the only subscription of a guild, so that
+// the same changes apply to direct messages as to guild channels.
+func (bot *bot) changeDMSubscription(ctx context.Context, in *interaction, change func(settings *guildSettings) string) (*interactionResponseData, error) {
+	userID := in.userID()
+	if userID == "" {
+		return nil, errors.New("Error changing direct message subscription: no user")
+	}
+	settings := &guildSettings{SubscriptionsSet: true}
+	var sub channelSubscription
+	found, err := bot.store.Get(ctx, dmSubscriptionKey(userID), &sub)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting direct message subscription")
+	}
+	if found {
+		// The direct message channel of a user does not change, but the
+		// stored channel is replaced should it do so
+		sub.ChannelID = in.ChannelID
+		settings.Subscriptions = append(settings.Subscriptions, sub)
+	}
+	content := change(settings)
+	if sub := settings.subscription(in.ChannelID); sub != nil {
+		err = bot.store.Set(ctx, dmSubscriptionKey(userID), sub, 0)
+	} else {
+		err = bot.store.Delete(ctx, dmSubscriptionKey(userID))
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error saving direct message subscription")
+	}
+	return textResponse(content), nil
+}
@@ -0,0 +1,54 @@
+package timatch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestChangeDMSubscription(t *testing.T) {
+	ctx := context.Background()
+	bot := &bot{store: storage.NewMemoryStore()}
+	in := &interaction{ChannelID: "10", User: &discordgo.User{ID: "1"}}
+	subscribe := func(teamID int) func(settings *guildSettings) string {
+		return func(settings *guildSettings) string {
+			if sub := settings.subscription(in.ChannelID); sub != nil {
+				sub.Teams = append(sub.Teams, teamID)
+			} else {
+				settings.Subscriptions = append(settings.Subscriptions, channelSubscription{ChannelID: in.ChannelID, Teams: []int{teamID}})
+			}
+			return "ok"
+		}
+	}
+	if _, err := bot.changeDMSubscription(ctx, in, subscribe(2)); err != nil {
+		t.Fatalf("changeDMSubscription() error: %v", err)
+	}
+	if _, err := bot.changeDMSubscription(ctx, in, subscribe(3)); err != nil {
+		t.Fatalf("changeDMSubscription() error: %v", err)
+	}
+	subs, err := bot.loadDMSubscriptions(ctx)
+	if err != nil {
+		t.Fatalf("loadDMSubscriptions() error: %v", err)
+	}
+	want := []channelSubscription{{ChannelID: "10", Teams: []int{2, 3}}}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("loadDMSubscriptions() = %v, want %v", subs, want)
+	}
+	unsubscribe := func(settings *guildSettings) string {
+		settings.Subscriptions = nil
+		return "ok"
+	}
+	if _, err := bot.changeDMSubscription(ctx, in, unsubscribe); err != nil {
+		t.Fatalf("changeDMSubscription() error: %v", err)
+	}
+	subs, err = bot.loadDMSubscriptions(ctx)
+	if err != nil {
+		t.Fatalf("loadDMSubscriptions() error: %v", err)
+	}
+	if len(subs) != 0 {
+		t.Errorf("loadDMSubscriptions() after unsubscribing = %v, want none", subs)
+	}
+}
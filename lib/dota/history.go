@@ -0,0 +1,70 @@
+package dota
+
+import (
+	"context"
+)
+
+// matchHistoryPageSize is the most matches the Steam API gives per page
+// of a match history
+const matchHistoryPageSize = 100
+
+// MatchHistoryIterator iterates the pages of the match history of a
+// league, newest match first:
+//
+//	it := client.MatchHistory(leagueID)
+//	for it.Next(ctx) {
+//		for _, match := range it.Matches() {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type MatchHistoryIterator struct {
+	client   *Client
+	leagueID int
+	// startAt is the match the next page starts at, 0 for the newest
+	startAt int64
+	done    bool
+	matches []MatchHistoryMatch
+	err     error
+}
+
+// MatchHistory returns an iterator of the pages of the match history of
+// a league
+func (client *Client) MatchHistory(leagueID int) *MatchHistoryIterator {
+	return &MatchHistoryIterator{client: client, leagueID: leagueID}
+}
+
+// Next gets the next page of the match history, returning false once all
+// pages are got or getting a page failed, see Err
+func (it *MatchHistoryIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	res, err := it.client.GetMatchHistoryPage(ctx, it.leagueID, it.startAt, matchHistoryPageSize)
+	if err != nil {
+		it.done, it.matches, it.err = true, nil, err
+		return false
+	}
+	it.matches = res.Result.Matches
+	if len(it.matches) == 0 {
+		it.done = true
+		return false
+	}
+	// Pages start at the given match, so the next page starts at the
+	// match before the oldest of this page
+	it.startAt = it.matches[len(it.matches)-1].MatchID - 1
+	it.done = res.Result.ResultsRemaining <= 0
+	return true
+}
+
+// Matches returns the matches of the current page
+func (it *MatchHistoryIterator) Matches() []MatchHistoryMatch {
+	return it.matches
+}
+
+// Err returns the error getting a page, if any
+func (it *MatchHistoryIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,11 @@
+package dota
+
+// Field names used for structured logging of match and league ids. The
+// bot logs the same ids under these names, so they are shared to keep
+// the fields, and their types, consistent.
+const (
+	// LogFieldMatchID is the field of match ids, logged as int64
+	LogFieldMatchID = "match_id"
+	// LogFieldLeagueID is the field of league ids, logged as int
+	LogFieldLeagueID = "league_id"
+)
@@ -4,6 +4,7 @@ type resultChecker interface {
 	checkResult() bool
 }
 
+// LiveLeagueGamesResponse is the response of GetLiveLeagueGames
 type LiveLeagueGamesResponse struct {
 	Result struct {
 		Status int              `json:"status"`
@@ -11,6 +12,8 @@ type LiveLeagueGamesResponse struct {
 	} `json:"result"`
 }
 
+// LiveLeagueGame is a game of a league being played, or drafted, with its
+// teams, series and scoreboard
 type LiveLeagueGame struct {
 	DireSeriesWins    int                      `json:"dire_series_wins"`
 	RadiantSeriesWins int                      `json:"radiant_series_wins"`
@@ -19,19 +22,80 @@ type LiveLeagueGame struct {
 	DireTeam          LiveLeagueGamesTeam      `json:"dire_team"`
 	MatchID           int64                    `json:"match_id"`
 	Scoreboard        LiveLeagueGameScoreboard `json:"scoreboard"`
+	SeriesID          int64                    `json:"series_id"`
+	SeriesType        int                      `json:"series_type"`
+	Spectators        int                      `json:"spectators"`
+	Players           []LiveLeagueGamePlayer   `json:"players"`
+	LeagueID          int                      `json:"league_id"`
+	// Stage is the stage of the league the game is played in, e.g.
+	// "Upper Bracket Final". Not given by the API, but set from the
+	// league data
+	Stage string `json:"stage,omitempty"`
 }
 
+// Teams of the players of live league games. Players of other teams are
+// spectators, such as casters
+const (
+	PlayerTeamRadiant = 0
+	PlayerTeamDire    = 1
+)
+
+// LiveLeagueGamePlayer is a player, or spectator, of a live league game.
+// Team is one of PlayerTeamRadiant and PlayerTeamDire for players
+type LiveLeagueGamePlayer struct {
+	AccountID int64  `json:"account_id"`
+	Name      string `json:"name"`
+	HeroID    int    `json:"hero_id"`
+	Team      int    `json:"team"`
+}
+
+// RadiantPlayers returns the players of the radiant team
+func (game LiveLeagueGame) RadiantPlayers() []LiveLeagueGamePlayer {
+	return game.teamPlayers(PlayerTeamRadiant)
+}
+
+// DirePlayers returns the players of the dire team
+func (game LiveLeagueGame) DirePlayers() []LiveLeagueGamePlayer {
+	return game.teamPlayers(PlayerTeamDire)
+}
+
+func (game LiveLeagueGame) teamPlayers(team int) []LiveLeagueGamePlayer {
+	var players []LiveLeagueGamePlayer
+	for _, player := range game.Players {
+		if player.Team == team {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// LiveLeagueGamesTeam is a team of a live league game. TeamID is 0 for
+// teams without a registered team
 type LiveLeagueGamesTeam struct {
 	TeamName string `json:"team_name"`
+	TeamID   int    `json:"team_id"`
 }
 
+// LiveLeagueGameScoreboard is the state of a live league game. Duration is
+// the game time, in seconds, 0 while drafting
 type LiveLeagueGameScoreboard struct {
-	Duration float32                      `json:"duration"`
-	Radiant  LiveLeagueGameScoreboardTeam `json:"radiant"`
-	Dire     LiveLeagueGameScoreboardTeam `json:"dire"`
+	Duration float32 `json:"duration"`
+	// RoshanRespawnTimer is the time, in seconds, until Roshan respawns,
+	// 0 while Roshan is alive. nil if not given
+	RoshanRespawnTimer *int                         `json:"roshan_respawn_timer"`
+	Radiant            LiveLeagueGameScoreboardTeam `json:"radiant"`
+	Dire               LiveLeagueGameScoreboardTeam `json:"dire"`
 }
 
+// LiveLeagueGameScoreboardTeam is the state of a team of a live league
+// game, with its kills, buildings, draft and players
 type LiveLeagueGameScoreboardTeam struct {
+	Score int `json:"score"`
+	// TowerState and BarracksState are bitmasks of the towers and
+	// barracks of the team still standing, see BarracksState
+	TowerState    int `json:"tower_state"`
+	BarracksState int `json:"barracks_state"`
+
 	Bans []struct {
 		HeroID int `json:"hero_id"`
 	} `json:"bans"`
@@ -40,15 +104,53 @@ type LiveLeagueGameScoreboardTeam struct {
 		HeroID int `json:"hero_id"`
 	} `json:"picks"`
 
-	Players []struct {
-		HeroID int `json:"hero_id"`
-	} `json:"players"`
+	Players []LiveLeagueGameScoreboardPlayer `json:"players"`
+}
+
+// LiveLeagueGameScoreboardPlayer is the state of a player of a live league
+// game
+type LiveLeagueGameScoreboardPlayer struct {
+	HeroID   int `json:"hero_id"`
+	NetWorth int `json:"net_worth"`
+	// Item0-Item5 are the item ids of the inventory slots, 0 if empty
+	Item0 int `json:"item0"`
+	Item1 int `json:"item1"`
+	Item2 int `json:"item2"`
+	Item3 int `json:"item3"`
+	Item4 int `json:"item4"`
+	Item5 int `json:"item5"`
+}
+
+// BarracksState bits, of the melee and ranged barracks of each lane. A
+// team with none of its barracks left gives the other team mega creeps
+const (
+	BarracksTopMelee = 1 << iota
+	BarracksTopRanged
+	BarracksMidMelee
+	BarracksMidRanged
+	BarracksBottomMelee
+	BarracksBottomRanged
+	BarracksAll = 1<<iota - 1
+)
+
+// ItemDivineRapier is the item id of Divine Rapier
+const ItemDivineRapier = 133
+
+// HasItem tests if the player has an item in their inventory
+func (player LiveLeagueGameScoreboardPlayer) HasItem(itemID int) bool {
+	for _, item := range []int{player.Item0, player.Item1, player.Item2, player.Item3, player.Item4, player.Item5} {
+		if item == itemID {
+			return true
+		}
+	}
+	return false
 }
 
 func (res *LiveLeagueGamesResponse) checkResult() bool {
 	return res.Result.Status == 200
 }
 
+// HeroesResponse is the response of GetHeroes
 type HeroesResponse struct {
 	Result struct {
 		Status int `json:"status"`
@@ -65,21 +167,93 @@ func (res *HeroesResponse) checkResult() bool {
 	return res.Result.Status == 200
 }
 
+// TopLiveGameResponse is the response of GetTopLiveGame, which unlike the
+// other endpoints has no result object
+type TopLiveGameResponse struct {
+	GameList []TopLiveGame `json:"game_list"`
+}
+
+// TopLiveGame is a live game of the top live games. The team ids and names
+// are only set for games of teams
+type TopLiveGame struct {
+	MatchID         int64  `json:"match_id"`
+	LeagueID        int    `json:"league_id"`
+	SeriesID        int64  `json:"series_id"`
+	AverageMMR      int    `json:"average_mmr"`
+	Spectators      int    `json:"spectators"`
+	GameTime        int    `json:"game_time"`
+	RadiantTeamID   int    `json:"team_id_radiant"`
+	DireTeamID      int    `json:"team_id_dire"`
+	RadiantTeamName string `json:"team_name_radiant"`
+	DireTeamName    string `json:"team_name_dire"`
+	RadiantScore    int    `json:"radiant_score"`
+	DireScore       int    `json:"dire_score"`
+}
+
+// IsTeamGame tests if the game is played by two teams, e.g. a
+// professional game
+func (game *TopLiveGame) IsTeamGame() bool {
+	return game.RadiantTeamID != 0 && game.DireTeamID != 0
+}
+
+// MatchHistoryResponse is the response of GetMatchHistory and
+// GetMatchHistoryPage
 type MatchHistoryResponse struct {
 	Result struct {
-		Status  int                 `json:"status"`
-		Matches []MatchHistoryMatch `json:"matches"`
+		Status int `json:"status"`
+		// ResultsRemaining is the number of matches older than the
+		// matches of the page, see MatchHistoryIterator
+		ResultsRemaining int                 `json:"results_remaining"`
+		Matches          []MatchHistoryMatch `json:"matches"`
 	} `json:"result"`
 }
 
+// MatchHistoryMatch is a match of a match history
 type MatchHistoryMatch struct {
 	MatchID int64 `json:"match_id"`
+	// StartTime is the start of the match, as a unix timestamp
+	StartTime     int64 `json:"start_time"`
+	RadiantTeamID int   `json:"radiant_team_id"`
+	DireTeamID    int   `json:"dire_team_id"`
 }
 
 func (res *MatchHistoryResponse) checkResult() bool {
 	return res.Result.Status == 1
 }
 
+// MatchSequenceResponse is the response of GetMatchHistoryBySequenceNum
+type MatchSequenceResponse struct {
+	Result struct {
+		Status  int             `json:"status"`
+		Matches []SequenceMatch `json:"matches"`
+	} `json:"result"`
+}
+
+// SequenceMatch is a match of the sequence of all recorded matches, with
+// its details
+type SequenceMatch struct {
+	MatchID  int64 `json:"match_id"`
+	LeagueID int   `json:"leagueid"`
+	// StartTime is the start of the match, as a unix timestamp
+	StartTime int64 `json:"start_time"`
+	MatchDetails
+}
+
+// HistoryMatch returns the match as a match of a match history
+func (match *SequenceMatch) HistoryMatch() MatchHistoryMatch {
+	return MatchHistoryMatch{
+		MatchID:       match.MatchID,
+		StartTime:     match.StartTime,
+		RadiantTeamID: match.RadiantTeamID,
+		DireTeamID:    match.DireTeamID,
+	}
+}
+
+func (res *MatchSequenceResponse) checkResult() bool {
+	return res.Result.Status == 1
+}
+
+// MatchDetailsResponse is the response of GetMatchDetails
 type MatchDetailsResponse struct {
 	Result struct {
 		*MatchDetails
@@ -91,10 +265,207 @@ func (res *MatchDetailsResponse) checkResult() bool {
 	return res.Result.Error == nil && res.Result.MatchDetails != nil
 }
 
+// MatchDetails are the details of a finished match
 type MatchDetails struct {
-	RadiantWin   bool   `json:"radiant_win"`
-	RadiantName  string `json:"radiant_name"`
-	DireName     string `json:"dire_name"`
-	RadiantScore int    `json:"radiant_score"`
-	DireScore    int    `json:"dire_score"`
+	// MatchSeqNum is the position of the match in the sequence of all
+	// recorded matches, see GetMatchHistoryBySequenceNum
+	MatchSeqNum   int64  `json:"match_seq_num"`
+	RadiantWin    bool   `json:"radiant_win"`
+	RadiantName   string `json:"radiant_name"`
+	DireName      string `json:"dire_name"`
+	RadiantTeamID int    `json:"radiant_team_id"`
+	DireTeamID    int    `json:"dire_team_id"`
+	RadiantScore  int    `json:"radiant_score"`
+	DireScore     int    `json:"dire_score"`
+	// Duration is the length of the match, in seconds
+	Duration  int           `json:"duration"`
+	Players   []MatchPlayer `json:"players"`
+	PicksBans []PickBan     `json:"picks_bans"`
+}
+
+// MatchPlayer is a player of a finished match, with their stats
+type MatchPlayer struct {
+	AccountID int64 `json:"account_id"`
+	// PlayerSlot is 0-4 for radiant players, 128-132 for dire players
+	PlayerSlot int `json:"player_slot"`
+	HeroID     int `json:"hero_id"`
+	Kills      int `json:"kills"`
+	Deaths     int `json:"deaths"`
+	Assists    int `json:"assists"`
+	GoldPerMin int `json:"gold_per_min"`
+	XPPerMin   int `json:"xp_per_min"`
+}
+
+// IsRadiant tests if the player played on the radiant side
+func (player *MatchPlayer) IsRadiant() bool {
+	return player.PlayerSlot < 128
+}
+
+// PickBan is a pick or ban of the draft of a finished match
+type PickBan struct {
+	IsPick bool `json:"is_pick"`
+	HeroID int  `json:"hero_id"`
+	// Team is 0 for radiant, 1 for dire
+	Team  int `json:"team"`
+	Order int `json:"order"`
+}
+
+// LeagueListingResponse is the response of GetLeagueListing
+type LeagueListingResponse struct {
+	Result struct {
+		Leagues []League `json:"leagues"`
+	} `json:"result"`
+}
+
+// League is a league of the league listing
+type League struct {
+	LeagueID      int    `json:"leagueid"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	TournamentURL string `json:"tournament_url"`
+	ItemDef       int    `json:"itemdef"`
+}
+
+// LeagueDataResponse is the response of the dota2.com web api
+// GetLeagueData endpoint.
+type LeagueDataResponse struct {
+	Info struct {
+		LeagueID int    `json:"league_id"`
+		Name     string `json:"name"`
+	} `json:"info"`
+	NodeGroups []LeagueNodeGroup `json:"node_groups"`
+}
+
+// Node group types, as reported in LeagueNodeGroup.NodeGroupType
+const (
+	NodeGroupTypeOrganizational      = 1
+	NodeGroupTypeRoundRobin          = 2
+	NodeGroupTypeSwiss               = 3
+	NodeGroupTypeBracketSingle       = 4
+	NodeGroupTypeBracketDoubleSeedLB = 5
+	NodeGroupTypeBracketDoubleAllWB  = 6
+	NodeGroupTypeShowmatch           = 7
+	NodeGroupTypeGSL                 = 8
+)
+
+// LeagueNodeGroup is a stage of a league, e.g. a group stage or a
+// playoff bracket. Node groups may be nested.
+type LeagueNodeGroup struct {
+	NodeGroupID   int                  `json:"node_group_id"`
+	Name          string               `json:"name"`
+	NodeGroupType int                  `json:"node_group_type"`
+	IsCompleted   bool                 `json:"is_completed"`
+	TeamStandings []LeagueTeamStanding `json:"team_standings"`
+	Nodes         []LeagueNode         `json:"nodes"`
+	NodeGroups    []LeagueNodeGroup    `json:"node_groups"`
+}
+
+// IsBracket tests if the node group is a playoff bracket
+func (group *LeagueNodeGroup) IsBracket() bool {
+	switch group.NodeGroupType {
+	case NodeGroupTypeBracketSingle, NodeGroupTypeBracketDoubleSeedLB, NodeGroupTypeBracketDoubleAllWB:
+		return true
+	}
+	return false
+}
+
+// LeagueTeamStanding is the standing of a team within a node group
+type LeagueTeamStanding struct {
+	TeamID   int    `json:"team_id"`
+	Name     string `json:"name"`
+	Tag      string `json:"tag"`
+	Standing int    `json:"standing"`
+	Wins     int    `json:"wins"`
+	Losses   int    `json:"losses"`
+}
+
+// LeagueNode is a single series within a node group
+type LeagueNode struct {
+	NodeID          int    `json:"node_id"`
+	NodeGroupID     int    `json:"node_group_id"`
+	Name            string `json:"name"`
+	NodeType        int    `json:"node_type"`
+	ScheduledTime   int64  `json:"scheduled_time"`
+	SeriesID        int64  `json:"series_id"`
+	TeamID1         int    `json:"team_id_1"`
+	TeamID2         int    `json:"team_id_2"`
+	Team1Wins       int    `json:"team_1_wins"`
+	Team2Wins       int    `json:"team_2_wins"`
+	HasStarted      bool   `json:"has_started"`
+	IsCompleted     bool   `json:"is_completed"`
+	IncomingNodeID1 int    `json:"incoming_node_id_1"`
+	IncomingNodeID2 int    `json:"incoming_node_id_2"`
+	WinningNodeID   int    `json:"winning_node_id"`
+	LosingNodeID    int    `json:"losing_node_id"`
+	Matches         []struct {
+		MatchID       int64 `json:"match_id"`
+		WinningTeamID int   `json:"winning_team_id"`
+	} `json:"matches"`
+}
+
+// TournamentPrizePoolResponse is the response of GetTournamentPrizePool
+type TournamentPrizePoolResponse struct {
+	Result struct {
+		Status    int   `json:"status"`
+		LeagueID  int   `json:"league_id"`
+		PrizePool int64 `json:"prize_pool"`
+	} `json:"result"`
+}
+
+func (res *TournamentPrizePoolResponse) checkResult() bool {
+	return res.Result.Status == 200
+}
+
+// TeamInfoResponse is the response of GetTeamInfoByTeamID
+type TeamInfoResponse struct {
+	Result struct {
+		Status int        `json:"status"`
+		Teams  []TeamInfo `json:"teams"`
+	} `json:"result"`
+}
+
+func (res *TeamInfoResponse) checkResult() bool {
+	return res.Result.Status == 1
+}
+
+// TeamInfo is the info of a team, with the account ids of its players.
+// Logo is the UGC id of the team logo, see GetUGCFileDetails
+type TeamInfo struct {
+	TeamID           int    `json:"team_id"`
+	Name             string `json:"name"`
+	Tag              string `json:"tag"`
+	CountryCode      string `json:"country_code"`
+	Logo             int64  `json:"logo"`
+	LogoSponsor      int64  `json:"logo_sponsor"`
+	URL              string `json:"url"`
+	Player0AccountID int64  `json:"player_0_account_id"`
+	Player1AccountID int64  `json:"player_1_account_id"`
+	Player2AccountID int64  `json:"player_2_account_id"`
+	Player3AccountID int64  `json:"player_3_account_id"`
+	Player4AccountID int64  `json:"player_4_account_id"`
+}
+
+// UGCFileDetailsResponse is the response of GetUGCFileDetails
+type UGCFileDetailsResponse struct {
+	Data struct {
+		Filename string `json:"filename"`
+		URL      string `json:"url"`
+		Size     int    `json:"size"`
+	} `json:"data"`
+}
+
+func (res *UGCFileDetailsResponse) checkResult() bool {
+	return res.Data.URL != ""
+}
+
+// PlayerAccountIDs returns the account ids of the team's players
+func (info *TeamInfo) PlayerAccountIDs() []int64 {
+	ids := make([]int64, 0, 5)
+	for _, id := range []int64{info.Player0AccountID, info.Player1AccountID,
+		info.Player2AccountID, info.Player3AccountID, info.Player4AccountID} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
@@ -1,13 +1,20 @@
+// Package dota is a client for the Dota 2 endpoints of the Steam Web API
+// (https://wiki.teamfortress.com/wiki/WebAPI), and of the dota2.com web
+// API for data not available through it, such as league brackets.
+// Requests are rate limited to one per second, as the Steam Web API
+// allows. For responses with a status code other than 200, the cause of
+// the returned error (see errors.Cause) is an *apiclient.StatusError.
 package dota
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/sirupsen/logrus"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/apiclient"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,94 +22,102 @@ const apiBaseURL = "http://api.steampowered.com"
 const pathGetLiveLeagueGames = "/IDOTA2Match_570/GetLiveLeagueGames/v1/"
 const pathGetHeroes = "/IEconDOTA2_570/GetHeroes/v1/"
 const pathGetMatchHistory = "/IDOTA2Match_570/GetMatchHistory/v1/"
+const pathGetMatchHistoryBySequenceNum = "/IDOTA2Match_570/GetMatchHistoryBySequenceNum/v1/"
+const pathGetTopLiveGame = "/IDOTA2Match_570/GetTopLiveGame/v1/"
 const pathGetMatchDetails = "/IDOTA2Match_570/GetMatchDetails/v1/"
+const pathGetLeagueListing = "/IDOTA2Match_570/GetLeagueListing/v1/"
+const pathGetTournamentPrizePool = "/IEconDOTA2_570/GetTournamentPrizePool/v1/"
+const pathGetTeamInfoByTeamID = "/IDOTA2Match_570/GetTeamInfoByTeamID/v1/"
+const pathGetUGCFileDetails = "/ISteamRemoteStorage/GetUGCFileDetails/v1/"
 
-const limitRequestsPerSecond = 1.0
+// appID is the Steam app id of Dota 2
+const appID = "570"
 
-type Client struct {
-	logger   *logrus.Logger
-	steamKey string
-	baseURL  *url.URL
+// webAPIBaseURL is the base url of the dota2.com web api, which serves
+// data not available through the Steam web api, e.g. league brackets.
+// It does not require a key.
+const webAPIBaseURL = "https://www.dota2.com"
+const pathGetLeagueData = "/webapi/IDOTA2League/GetLeagueData/v001"
+
+// requestInterval is the minimum time between requests, as the Steam API
+// allows about one request per second
+const requestInterval = 1 * time.Second
 
-	rateLimitCh chan struct{}
+// Client is a client for the Dota 2 Web API, safe for concurrent use
+type Client struct {
+	steamKey   string
+	baseURL    *url.URL
+	webBaseURL *url.URL
+	api        *apiclient.Client
 }
 
+// NewClient creates a client using the Steam Web API key steamKey, see
+// https://steamcommunity.com/dev/apikey
 func NewClient(logger *logrus.Logger, steamKey string) (*Client, error) {
 	baseURL, err := url.Parse(apiBaseURL)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error parsing apiBaseUrl")
 	}
-	rateLimitCh := make(chan struct{}, 1)
-	rateLimitCh <- struct{}{}
+	webBaseURL, err := url.Parse(webAPIBaseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing webAPIBaseURL")
+	}
 	return &Client{
-		steamKey:    steamKey,
-		baseURL:     baseURL,
-		logger:      logger,
-		rateLimitCh: rateLimitCh,
-	}, nil
-}
-
-func (client *Client) getRateLimitToken(ctx context.Context) (returnToken func(), err error) {
-	select {
-	case <-client.rateLimitCh:
-	case <-ctx.Done():
-		return func() {}, ctx.Err()
-	}
-	// The returned func, when called, spawns a go-routine that returns
-	// the rate limit token to the channel when a new request is allowed
-	// to be made.
-	return func() {
-		go func() {
-			time.Sleep(time.Second / limitRequestsPerSecond)
-			client.rateLimitCh <- struct{}{}
-		}()
+		steamKey:   steamKey,
+		baseURL:    baseURL,
+		webBaseURL: webBaseURL,
+		api:        apiclient.NewClient(logger, requestInterval),
 	}, nil
 }
 
 func (client *Client) newRequest(ctx context.Context, apiPath string) (*http.Request, error) {
-	u, err := url.Parse(apiPath)
+	req, err := apiclient.NewRequest(ctx, client.baseURL, apiPath)
 	if err != nil {
-		return nil, errors.Wrap(err, "Error parsing apiPath")
+		return nil, err
 	}
-	reqUrl := client.baseURL.ResolveReference(u)
-	query := reqUrl.Query()
+	query := req.URL.Query()
 	query.Set("key", client.steamKey)
-	reqUrl.RawQuery = query.Encode()
-	req, err := http.NewRequest("GET", reqUrl.String(), nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "Error creating Request")
-	}
-	return req.WithContext(ctx), nil
+	req.URL.RawQuery = query.Encode()
+	return req, nil
 }
 
-func (client *Client) getJSON(ctx context.Context, req *http.Request, jsonRes interface{}) error {
-	returnToken, err := client.getRateLimitToken(ctx)
-	if err != nil {
-		return errors.Wrap(err, "Error while waiting for rate limit token")
-	}
-	defer returnToken()
+// newWebAPIRequest creates a request to the dota2.com web api
+func (client *Client) newWebAPIRequest(ctx context.Context, apiPath string) (*http.Request, error) {
+	return apiclient.NewRequest(ctx, client.webBaseURL, apiPath)
+}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return errors.Wrap(err, "Error sending request")
-	}
-	client.logger.Debugf("GET: %s - [%s]", req.URL.EscapedPath(), res.Status)
-	if res.StatusCode != 200 {
-		return errors.Errorf("Bad HTTP response status code: %d", res.StatusCode)
+// requestFields returns structured log fields describing req. The league
+// and match id query parameters are included as fields, as they are the
+// most useful when searching the logs.
+func requestFields(req *http.Request) logrus.Fields {
+	fields := logrus.Fields{}
+	query := req.URL.Query()
+	if matchID, err := strconv.ParseInt(query.Get("match_id"), 10, 64); err == nil {
+		fields[LogFieldMatchID] = matchID
 	}
-	if jsonRes != nil {
-		if err := json.NewDecoder(res.Body).Decode(jsonRes); err != nil {
-			return errors.Wrap(err, "Error decoding result as JSON")
+	// GetTournamentPrizePool names the parameter leagueid
+	for _, param := range []string{"league_id", "leagueid"} {
+		if leagueID, err := strconv.Atoi(query.Get(param)); err == nil {
+			fields[LogFieldLeagueID] = leagueID
 		}
-		if s, ok := jsonRes.(resultChecker); ok {
-			if !s.checkResult() {
-				return errors.Errorf("Bad steam result")
-			}
+	}
+	return fields
+}
+
+func (client *Client) getJSON(ctx context.Context, req *http.Request, jsonRes interface{}) error {
+	if err := client.api.GetJSON(ctx, req, jsonRes, requestFields(req)); err != nil {
+		return err
+	}
+	if s, ok := jsonRes.(resultChecker); ok {
+		if !s.checkResult() {
+			return errors.Errorf("Bad steam result")
 		}
 	}
 	return nil
 }
 
+// GetHeroes gets all heroes, with their names localized to language, e.g.
+// "en"
 func (client *Client) GetHeroes(ctx context.Context, language string) (*HeroesResponse, error) {
 	req, err := client.newRequest(ctx, pathGetHeroes)
 	if err != nil {
@@ -118,13 +133,17 @@ func (client *Client) GetHeroes(ctx context.Context, language string) (*HeroesRe
 	return data, nil
 }
 
+// GetLiveLeagueGames gets the live games of a league. A leagueID of 0
+// gets the live games of all leagues
 func (client *Client) GetLiveLeagueGames(ctx context.Context, leagueID int) (*LiveLeagueGamesResponse, error) {
 	req, err := client.newRequest(ctx, pathGetLiveLeagueGames)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error creating new request")
 	}
 	query := req.URL.Query()
-	query.Set("league_id", strconv.Itoa(leagueID))
+	if leagueID != 0 {
+		query.Set("league_id", strconv.Itoa(leagueID))
+	}
 	req.URL.RawQuery = query.Encode()
 	data := &LiveLeagueGamesResponse{}
 	if err := client.getJSON(ctx, req, data); err != nil {
@@ -133,13 +152,58 @@ func (client *Client) GetLiveLeagueGames(ctx context.Context, leagueID int) (*Li
 	return data, nil
 }
 
+// GetTopLiveGame gets the top live games of all of Dota, by the number of
+// spectators and the skill of the players, as shown in the watch tab of
+// the client. partner selects the page of games, 0 for the top games
+func (client *Client) GetTopLiveGame(ctx context.Context, partner int) (*TopLiveGameResponse, error) {
+	req, err := client.newRequest(ctx, pathGetTopLiveGame)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("partner", strconv.Itoa(partner))
+	req.URL.RawQuery = query.Encode()
+	data := &TopLiveGameResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetMatchHistory gets the full match history of a league, newest match
+// first, requesting all of its pages
 func (client *Client) GetMatchHistory(ctx context.Context, leagueID int) (*MatchHistoryResponse, error) {
+	data := &MatchHistoryResponse{}
+	data.Result.Status = 1
+	it := client.MatchHistory(leagueID)
+	for it.Next(ctx) {
+		data.Result.Matches = append(data.Result.Matches, it.Matches()...)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetMatchHistoryPage gets a page of at most matchesRequested matches of
+// the match history of a league, newest match first, starting at the
+// match startAtMatchID. 0 to start at the newest match. A leagueID of 0
+// gets the history of all public matches
+func (client *Client) GetMatchHistoryPage(ctx context.Context, leagueID int, startAtMatchID int64, matchesRequested int) (*MatchHistoryResponse, error) {
 	req, err := client.newRequest(ctx, pathGetMatchHistory)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error creating new request")
 	}
 	query := req.URL.Query()
-	query.Set("league_id", strconv.Itoa(leagueID))
+	if leagueID != 0 {
+		query.Set("league_id", strconv.Itoa(leagueID))
+	}
+	if startAtMatchID != 0 {
+		query.Set("start_at_match_id", strconv.FormatInt(startAtMatchID, 10))
+	}
+	if matchesRequested != 0 {
+		query.Set("matches_requested", strconv.Itoa(matchesRequested))
+	}
 	req.URL.RawQuery = query.Encode()
 	data := &MatchHistoryResponse{}
 	if err := client.getJSON(ctx, req, data); err != nil {
@@ -148,6 +212,28 @@ func (client *Client) GetMatchHistory(ctx context.Context, leagueID int) (*Match
 	return data, nil
 }
 
+// GetMatchHistoryBySequenceNum gets at most matchesRequested matches of
+// all recorded matches, in the order they were recorded, starting at the
+// match with the sequence number startAtMatchSeqNum
+func (client *Client) GetMatchHistoryBySequenceNum(ctx context.Context, startAtMatchSeqNum int64, matchesRequested int) (*MatchSequenceResponse, error) {
+	req, err := client.newRequest(ctx, pathGetMatchHistoryBySequenceNum)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("start_at_match_seq_num", strconv.FormatInt(startAtMatchSeqNum, 10))
+	query.Set("matches_requested", strconv.Itoa(matchesRequested))
+	req.URL.RawQuery = query.Encode()
+	data := &MatchSequenceResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetMatchDetails gets the details of a finished match, such as its
+// winner, score and players. The details are usually available a few
+// minutes after the match ended, until then an error is returned
 func (client *Client) GetMatchDetails(ctx context.Context, matchID int64) (*MatchDetailsResponse, error) {
 	req, err := client.newRequest(ctx, pathGetMatchDetails)
 	if err != nil {
@@ -162,3 +248,115 @@ func (client *Client) GetMatchDetails(ctx context.Context, matchID int64) (*Matc
 	}
 	return data, nil
 }
+
+// GetLeagueListing gets all leagues, with their names and descriptions
+// localized to language, e.g. "en"
+func (client *Client) GetLeagueListing(ctx context.Context, language string) (*LeagueListingResponse, error) {
+	req, err := client.newRequest(ctx, pathGetLeagueListing)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("language", language)
+	req.URL.RawQuery = query.Encode()
+	data := &LeagueListingResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// SearchLeagues returns the leagues of the league listing whose name
+// contains search, ignoring case, with the English league names. All
+// leagues if search is empty
+func (client *Client) SearchLeagues(ctx context.Context, search string) ([]League, error) {
+	listingRes, err := client.GetLeagueListing(ctx, "en")
+	if err != nil {
+		return nil, err
+	}
+	search = strings.ToLower(strings.TrimSpace(search))
+	leagues := make([]League, 0)
+	for _, league := range listingRes.Result.Leagues {
+		if strings.Contains(strings.ToLower(league.Name), search) {
+			leagues = append(leagues, league)
+		}
+	}
+	return leagues, nil
+}
+
+// GetLeagueData gets the stages, standings and series of a league from
+// the dota2.com web api
+func (client *Client) GetLeagueData(ctx context.Context, leagueID int) (*LeagueDataResponse, error) {
+	req, err := client.newWebAPIRequest(ctx, pathGetLeagueData)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("league_id", strconv.Itoa(leagueID))
+	query.Set("delay_seconds", "0")
+	req.URL.RawQuery = query.Encode()
+	data := &LeagueDataResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetTournamentPrizePool gets the current prize pool of a league, in US
+// dollars
+func (client *Client) GetTournamentPrizePool(ctx context.Context, leagueID int) (*TournamentPrizePoolResponse, error) {
+	req, err := client.newRequest(ctx, pathGetTournamentPrizePool)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("leagueid", strconv.Itoa(leagueID))
+	req.URL.RawQuery = query.Encode()
+	data := &TournamentPrizePoolResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetTeamInfoByTeamID gets the info of a team. If there is no team with
+// the id, the team with the next higher id is returned, so TeamID of the
+// result should be checked
+func (client *Client) GetTeamInfoByTeamID(ctx context.Context, teamID int) (*TeamInfoResponse, error) {
+	req, err := client.newRequest(ctx, pathGetTeamInfoByTeamID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("start_at_team_id", strconv.Itoa(teamID))
+	query.Set("teams_requested", "1")
+	req.URL.RawQuery = query.Encode()
+	data := &TeamInfoResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetUGCFileDetails gets the details of a user generated content file,
+// such as a team logo (see TeamInfo.Logo), including its download url
+func (client *Client) GetUGCFileDetails(ctx context.Context, ugcID int64) (*UGCFileDetailsResponse, error) {
+	req, err := client.newRequest(ctx, pathGetUGCFileDetails)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	query := req.URL.Query()
+	query.Set("appid", appID)
+	query.Set("ugcid", strconv.FormatInt(ugcID, 10))
+	req.URL.RawQuery = query.Encode()
+	data := &UGCFileDetailsResponse{}
+	if err := client.getJSON(ctx, req, data); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// Stats returns the stats of the requests sent to each endpoint
+func (client *Client) Stats() []apiclient.EndpointStats {
+	return client.api.Stats()
+}
@@ -0,0 +1,188 @@
+package timatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// draftStrategy is a way of playing a draft is built for
+type draftStrategy int
+
+const (
+	strategyTeamfight draftStrategy = iota
+	strategySplitPush
+	strategyPickOff
+	strategyPush
+	strategyLateGame
+	numDraftStrategies
+)
+
+// draftStrategyNames describe the strategies, as drafted by a team
+var draftStrategyNames = [numDraftStrategies]string{
+	strategyTeamfight: "heavy teamfight",
+	strategySplitPush: "a split-push lineup",
+	strategyPickOff:   "a pick-off lineup",
+	strategyPush:      "an early push lineup",
+	strategyLateGame:  "a late-game lineup",
+}
+
+// draftReadMinHeroes is the number of heroes of a strategy a team needs
+// for the draft to be read as that strategy
+const draftReadMinHeroes = 2
+
+// heroStrategies are the strategies each hero is good at, by hero id.
+// This is a rough heuristic for flavor, not a tier list: heroes not
+// listed count towards no strategy.
+var heroStrategies = map[int][]draftStrategy{
+	1:   {strategySplitPush, strategyLateGame}, // Anti-Mage
+	3:   {strategyPickOff},                     // Bane
+	4:   {strategyPickOff},                     // Bloodseeker
+	5:   {strategyTeamfight},                   // Crystal Maiden
+	6:   {strategyPush},                        // Drow Ranger
+	7:   {strategyTeamfight},                   // Earthshaker
+	8:   {strategySplitPush, strategyLateGame}, // Juggernaut
+	9:   {strategyPickOff},                     // Mirana
+	10:  {strategySplitPush, strategyLateGame}, // Morphling
+	12:  {strategySplitPush, strategyLateGame}, // Phantom Lancer
+	13:  {strategyTeamfight},                   // Puck
+	14:  {strategyPickOff},                     // Pudge
+	15:  {strategyPush},                        // Razor
+	16:  {strategyTeamfight},                   // Sand King
+	17:  {strategyPickOff},                     // Storm Spirit
+	18:  {strategyLateGame},                    // Sven
+	20:  {strategyPickOff},                     // Vengeful Spirit
+	22:  {strategyPickOff},                     // Zeus
+	23:  {strategyTeamfight},                   // Kunkka
+	25:  {strategyPush},                        // Lina
+	26:  {strategyPickOff},                     // Lion
+	27:  {strategyPush},                        // Shadow Shaman
+	28:  {strategyPickOff},                     // Slardar
+	29:  {strategyTeamfight},                   // Tidehunter
+	31:  {strategyTeamfight},                   // Lich
+	32:  {strategyPickOff},                     // Riki
+	33:  {strategyTeamfight},                   // Enigma
+	34:  {strategySplitPush},                   // Tinker
+	35:  {strategyLateGame},                    // Sniper
+	37:  {strategyTeamfight},                   // Warlock
+	38:  {strategyPush},                        // Beastmaster
+	39:  {strategyPickOff},                     // Queen of Pain
+	40:  {strategyPush},                        // Venomancer
+	41:  {strategyTeamfight, strategyLateGame}, // Faceless Void
+	42:  {strategyLateGame},                    // Wraith King
+	43:  {strategyTeamfight, strategyPush},     // Death Prophet
+	44:  {strategyPickOff},                     // Phantom Assassin
+	45:  {strategyPush},                        // Pugna
+	46:  {strategyPickOff},                     // Templar Assassin
+	48:  {strategyPush, strategyLateGame},      // Luna
+	49:  {strategyPush},                        // Dragon Knight
+	51:  {strategyPickOff},                     // Clockwerk
+	52:  {strategyPush},                        // Leshrac
+	53:  {strategySplitPush},                   // Nature's Prophet
+	54:  {strategyLateGame},                    // Lifestealer
+	55:  {strategyTeamfight},                   // Dark Seer
+	56:  {strategyPickOff},                     // Clinkz
+	58:  {strategyPush},                        // Enchantress
+	60:  {strategyPickOff},                     // Night Stalker
+	61:  {strategySplitPush},                   // Broodmother
+	62:  {strategyPickOff},                     // Bounty Hunter
+	63:  {strategyLateGame},                    // Weaver
+	64:  {strategyTeamfight, strategyPush},     // Jakiro
+	65:  {strategyPickOff},                     // Batrider
+	66:  {strategyPush},                        // Chen
+	67:  {strategyLateGame},                    // Spectre
+	68:  {strategyTeamfight},                   // Ancient Apparition
+	71:  {strategyPickOff},                     // Spirit Breaker
+	72:  {strategyLateGame},                    // Gyrocopter
+	73:  {strategyLateGame},                    // Alchemist
+	74:  {strategyTeamfight},                   // Invoker
+	75:  {strategyTeamfight},                   // Silencer
+	77:  {strategySplitPush, strategyPush},     // Lycan
+	79:  {strategyPickOff},                     // Shadow Demon
+	80:  {strategySplitPush},                   // Lone Druid
+	81:  {strategyLateGame},                    // Chaos Knight
+	82:  {strategySplitPush},                   // Meepo
+	83:  {strategyTeamfight},                   // Treant Protector
+	87:  {strategyTeamfight},                   // Disruptor
+	88:  {strategyPickOff},                     // Nyx Assassin
+	89:  {strategySplitPush, strategyLateGame}, // Naga Siren
+	92:  {strategyPush},                        // Visage
+	93:  {strategyLateGame},                    // Slark
+	94:  {strategyTeamfight, strategyLateGame}, // Medusa
+	95:  {strategyPush},                        // Troll Warlord
+	96:  {strategyTeamfight},                   // Centaur Warrunner
+	97:  {strategyTeamfight},                   // Magnus
+	98:  {strategySplitPush},                   // Timbersaw
+	100: {strategyPickOff},                     // Tusk
+	101: {strategyPickOff},                     // Skywrath Mage
+	103: {strategyTeamfight},                   // Elder Titan
+	104: {strategyPickOff},                     // Legion Commander
+	106: {strategyPickOff},                     // Ember Spirit
+	107: {strategyPickOff},                     // Earth Spirit
+	108: {strategyTeamfight},                   // Underlord
+	109: {strategySplitPush, strategyLateGame}, // Terrorblade
+	110: {strategyTeamfight},                   // Phoenix
+	112: {strategyTeamfight},                   // Winter Wyvern
+	113: {strategySplitPush, strategyLateGame}, // Arc Warden
+	120: {strategyTeamfight},                   // Pangolier
+	121: {strategyPickOff},                     // Grimstroke
+	123: {strategyPickOff},                     // Hoodwink
+	126: {strategyPickOff},                     // Void Spirit
+	128: {strategyTeamfight},                   // Snapfire
+	129: {strategyTeamfight},                   // Mars
+	135: {strategyTeamfight},                   // Dawnbreaker
+	136: {strategyPickOff},                     // Marci
+	137: {strategyTeamfight},                   // Primal Beast
+	138: {strategyLateGame},                    // Muerta
+}
+
+// readDraft returns the description of the strategy a draft is built
+// for: the strategy most of its heroes are good at, if at least
+// draftReadMinHeroes are. Ties go to the strategy listed first.
+func readDraft(heroIDs []int) string {
+	var counts [numDraftStrategies]int
+	for _, heroID := range heroIDs {
+		for _, strategy := range heroStrategies[heroID] {
+			counts[strategy]++
+		}
+	}
+	best := strategyTeamfight
+	for strategy := strategyTeamfight; strategy < numDraftStrategies; strategy++ {
+		if counts[strategy] > counts[best] {
+			best = strategy
+		}
+	}
+	if counts[best] < draftReadMinHeroes {
+		return "a balanced lineup"
+	}
+	return draftStrategyNames[best]
+}
+
+// pickedHeroes returns the heroes picked by a team, or nil unless the
+// team has picked all five
+func pickedHeroes(team dota.LiveLeagueGameScoreboardTeam) []int {
+	if len(team.Picks) != 5 {
+		return nil
+	}
+	heroIDs := make([]int, len(team.Picks))
+	for i, pick := range team.Picks {
+		heroIDs[i] = pick.HeroID
+	}
+	return heroIDs
+}
+
+// renderDraftReads renders a read of the drafts of the started games, a
+// line per game, leaving out games whose picks are not known
+func renderDraftReads(games []dota.LiveLeagueGame) string {
+	lines := make([]string, 0, len(games))
+	for _, game := range games {
+		radiant, dire := pickedHeroes(game.Scoreboard.Radiant), pickedHeroes(game.Scoreboard.Dire)
+		if radiant == nil || dire == nil {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("Draft read (Game %d): %s drafted %s; %s drafted %s.", game.GameNumber,
+			game.RadiantTeam.TeamName, readDraft(radiant), game.DireTeam.TeamName, readDraft(dire)))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+package timatch
+
+import (
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestReadDraft(t *testing.T) {
+	tests := []struct {
+		heroIDs []int
+		want    string
+	}{
+		// Enigma, Magnus, Tidehunter, Lion, Sniper
+		{[]int{33, 97, 29, 26, 35}, "heavy teamfight"},
+		// Nature's Prophet, Tinker, Anti-Mage, Lion, Crystal Maiden
+		{[]int{53, 34, 1, 26, 5}, "a split-push lineup"},
+		// One hero of each of several strategies
+		{[]int{33, 53, 26, 6, 18}, "a balanced lineup"},
+		// Faceless Void and Medusa count towards both of their
+		// strategies, teamfight being listed first
+		{[]int{41, 94, 2, 0, 999}, "heavy teamfight"},
+	}
+	for _, test := range tests {
+		if got := readDraft(test.heroIDs); got != test.want {
+			t.Errorf("readDraft(%v) = %q, want %q", test.heroIDs, got, test.want)
+		}
+	}
+}
+
+func TestRenderDraftReads(t *testing.T) {
+	var game dota.LiveLeagueGame
+	game.GameNumber = 2
+	game.RadiantTeam.TeamName = "OG"
+	game.DireTeam.TeamName = "Team Liquid"
+	incomplete := game
+	for _, heroID := range []int{33, 97, 29, 26, 35} {
+		game.Scoreboard.Radiant.Picks = append(game.Scoreboard.Radiant.Picks, struct {
+			HeroID int `json:"hero_id"`
+		}{heroID})
+	}
+	for _, heroID := range []int{53, 34, 1, 26, 5} {
+		game.Scoreboard.Dire.Picks = append(game.Scoreboard.Dire.Picks, struct {
+			HeroID int `json:"hero_id"`
+		}{heroID})
+	}
+	got := renderDraftReads([]dota.LiveLeagueGame{game, incomplete})
+	want := "Draft read (Game 2): OG drafted heavy teamfight; Team Liquid drafted a split-push lineup."
+	if got != want {
+		t.Errorf("renderDraftReads() = %q, want %q", got, want)
+	}
+}
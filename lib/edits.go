@@ -0,0 +1,117 @@
+package timatch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// editInterval is the minimum time between edits of messages in the same
+// channel. Discord allows 5 edits per 5 seconds in a channel
+const editInterval = 1 * time.Second
+
+// messageEdit is an edit of the content of a sent message
+type messageEdit struct {
+	channelID channelID
+	messageID string
+	content   string
+}
+
+// editQueue schedules edits of sent messages, spacing out the edits in
+// each channel to stay within Discord's rate limits. Successive edits of
+// the same message are coalesced, only the latest content is sent.
+type editQueue struct {
+	mu       sync.Mutex
+	pending  []messageEdit
+	lastEdit map[channelID]time.Time
+	// wake is signalled when an edit is scheduled
+	wake chan struct{}
+}
+
+func newEditQueue() *editQueue {
+	return &editQueue{
+		lastEdit: make(map[channelID]time.Time),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// edit schedules an edit of a message, replacing the content of any
+// pending edit of the message
+func (queue *editQueue) edit(channelID channelID, messageID string, content string) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	coalesced := false
+	for i := range queue.pending {
+		if queue.pending[i].channelID == channelID && queue.pending[i].messageID == messageID {
+			queue.pending[i].content = content
+			coalesced = true
+			break
+		}
+	}
+	if !coalesced {
+		queue.pending = append(queue.pending, messageEdit{channelID: channelID, messageID: messageID, content: content})
+	}
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the first pending edit in a channel that has
+// not been edited in the last editInterval at now. If no edit is ready,
+// wait is the time until one is, or 0 if there are no pending edits.
+func (queue *editQueue) next(now time.Time) (edit messageEdit, wait time.Duration, ok bool) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for channelID, last := range queue.lastEdit {
+		if now.Sub(last) >= editInterval {
+			delete(queue.lastEdit, channelID)
+		}
+	}
+	for i, edit := range queue.pending {
+		last, edited := queue.lastEdit[edit.channelID]
+		if !edited {
+			queue.pending = append(queue.pending[:i], queue.pending[i+1:]...)
+			queue.lastEdit[edit.channelID] = now
+			return edit, 0, true
+		}
+		if w := editInterval - now.Sub(last); wait == 0 || w < wait {
+			wait = w
+		}
+	}
+	return messageEdit{}, wait, false
+}
+
+// run applies the scheduled edits using apply until ctx is done
+func (queue *editQueue) run(ctx context.Context, apply func(edit messageEdit)) {
+	for {
+		edit, wait, ok := queue.next(time.Now())
+		if ok {
+			apply(edit)
+			continue
+		}
+		var ready <-chan time.Time
+		if wait > 0 {
+			ready = time.After(wait)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-queue.wake:
+		case <-ready:
+		}
+	}
+}
+
+// editMessage schedules an edit of a sent message
+func (bot *bot) editMessage(channelID channelID, messageID string, content string) {
+	bot.edits.edit(channelID, messageID, content)
+}
+
+// applyEdit edits a message, as scheduled by the edit queue
+func (bot *bot) applyEdit(edit messageEdit) {
+	_, err := bot.discordSession.ChannelMessageEdit(string(edit.channelID), edit.messageID, edit.content)
+	if err != nil {
+		bot.logger.WithField(logFieldChannelID, edit.channelID).WithError(err).Errorf("Failed editing message %s", edit.messageID)
+	}
+}
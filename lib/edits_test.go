@@ -0,0 +1,52 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEditQueueCoalesce(t *testing.T) {
+	queue := newEditQueue()
+	queue.edit("1", "10", "a")
+	queue.edit("1", "11", "b")
+	queue.edit("1", "10", "c")
+	now := time.Now()
+	edit, _, ok := queue.next(now)
+	want := messageEdit{channelID: "1", messageID: "10", content: "c"}
+	if !ok || !reflect.DeepEqual(edit, want) {
+		t.Fatalf("next() = %+v, %v, want %+v", edit, ok, want)
+	}
+	// The channel was just edited, so the next edit has to wait
+	if _, wait, ok := queue.next(now.Add(editInterval / 4)); ok || wait != editInterval*3/4 {
+		t.Errorf("next() in the same channel = %v, %v, want wait %v", ok, wait, editInterval*3/4)
+	}
+	edit, _, ok = queue.next(now.Add(editInterval))
+	want = messageEdit{channelID: "1", messageID: "11", content: "b"}
+	if !ok || !reflect.DeepEqual(edit, want) {
+		t.Fatalf("next() = %+v, %v, want %+v", edit, ok, want)
+	}
+	if _, wait, ok := queue.next(now.Add(2 * editInterval)); ok || wait != 0 {
+		t.Errorf("next() of empty queue = %v, %v, want nothing", ok, wait)
+	}
+}
+
+func TestEditQueueChannels(t *testing.T) {
+	queue := newEditQueue()
+	queue.edit("1", "10", "a")
+	queue.edit("1", "11", "b")
+	queue.edit("2", "20", "c")
+	now := time.Now()
+	var got []string
+	for i := 0; i < 2; i++ {
+		edit, _, ok := queue.next(now)
+		if !ok {
+			t.Fatalf("next() #%d = no edit, want an edit", i)
+		}
+		got = append(got, edit.messageID)
+	}
+	// The edit of the other channel is not held back by the first channel
+	if want := []string{"10", "20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("next() edited %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package timatch
+
+import (
+	"context"
+	"math"
+	"strconv"
+)
+
+// Parameters of the Elo ratings of the teams of a league
+const (
+	eloInitialRating = 1500
+	eloK             = 32
+	// eloMinGames is the number of games both teams must have played
+	// for a result to count as an upset, as the ratings of teams new to
+	// the league say little
+	eloMinGames = 3
+	// upsetMaxChance is the highest expected chance of the winner of a
+	// game for it to be an upset
+	upsetMaxChance = 0.3
+)
+
+// eloRatings are the Elo ratings of the teams of a league, from the
+// results seen during the league, and the number of games rated, by team
+// id
+type eloRatings struct {
+	Ratings map[int]float64 `json:"ratings"`
+	Games   map[int]int     `json:"games"`
+}
+
+func eloKey(leagueID int) string {
+	return "elo/" + strconv.Itoa(leagueID)
+}
+
+func (ratings *eloRatings) rating(teamID int) float64 {
+	if rating, ok := ratings.Ratings[teamID]; ok {
+		return rating
+	}
+	return eloInitialRating
+}
+
+// seed returns the rank of a team by rating, 1 being the highest rated
+func (ratings *eloRatings) seed(teamID int) int {
+	seed := 1
+	for otherID, rating := range ratings.Ratings {
+		if otherID != teamID && rating > ratings.rating(teamID) {
+			seed++
+		}
+	}
+	return seed
+}
+
+// update rates a game won by winnerID over loserID. If the result is an
+// upset, the seeds of the teams before the game are returned, else 0.
+func (ratings *eloRatings) update(winnerID, loserID int) (winnerSeed, loserSeed int) {
+	if ratings.Ratings == nil {
+		ratings.Ratings = make(map[int]float64)
+		ratings.Games = make(map[int]int)
+	}
+	winner, loser := ratings.rating(winnerID), ratings.rating(loserID)
+	expected := 1 / (1 + math.Pow(10, (loser-winner)/400))
+	if expected < upsetMaxChance && ratings.Games[winnerID] >= eloMinGames && ratings.Games[loserID] >= eloMinGames {
+		winnerSeed, loserSeed = ratings.seed(winnerID), ratings.seed(loserID)
+	}
+	ratings.Ratings[winnerID] = winner + eloK*(1-expected)
+	ratings.Ratings[loserID] = loser - eloK*(1-expected)
+	ratings.Games[winnerID]++
+	ratings.Games[loserID]++
+	return winnerSeed, loserSeed
+}
+
+// rateResult updates the Elo ratings of the league with a finished game,
+// marking the game as an upset if it is one
+func (bot *bot) rateResult(ctx context.Context, item *matchesFinishedDataItem) {
+	if item.WinnerTeamID == 0 || item.LoserTeamID == 0 {
+		return
+	}
+	logger := bot.logger.WithField(logFieldMatchID, item.MatchID)
+	leagueID := bot.currentLeagueID()
+	var ratings eloRatings
+	if _, err := bot.store.Get(ctx, eloKey(leagueID), &ratings); err != nil {
+		logger.WithError(err).Error("Error getting Elo ratings")
+		return
+	}
+	item.UpsetWinnerSeed, item.UpsetLoserSeed = ratings.update(item.WinnerTeamID, item.LoserTeamID)
+	if err := bot.store.Set(ctx, eloKey(leagueID), ratings, resultTTL); err != nil {
+		logger.WithError(err).Error("Error storing Elo ratings")
+	}
+}
@@ -0,0 +1,47 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEloUpsets(t *testing.T) {
+	var ratings eloRatings
+	// Team 1 wins its first games against 2 and 3, which are not upsets
+	// while the teams have played too few games
+	for i := 0; i < 4; i++ {
+		ratings.update(1, 2)
+		if winnerSeed, _ := ratings.update(3, 2); winnerSeed != 0 {
+			t.Fatalf("update() = upset before %d games", eloMinGames)
+		}
+		ratings.update(1, 3)
+	}
+	if got := ratings.seed(1); got != 1 {
+		t.Errorf("seed(1) = %d, want 1", got)
+	}
+	if ratings.rating(1) <= ratings.rating(3) || ratings.rating(3) <= ratings.rating(2) {
+		t.Errorf("ratings = %v, want 1 > 3 > 2", ratings.Ratings)
+	}
+	if winnerSeed, loserSeed := ratings.update(2, 1); winnerSeed != 3 || loserSeed != 1 {
+		t.Errorf("update(2, 1) = %d, %d, want upset of seed 3 over seed 1", winnerSeed, loserSeed)
+	}
+	if winnerSeed, _ := ratings.update(3, 2); winnerSeed != 0 {
+		t.Error("update(3, 2) = upset, want expected result")
+	}
+}
+
+func TestRenderUpset(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 1, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17, UpsetWinnerSeed: 14, UpsetLoserSeed: 2}}
+	got, err := renderTemplate(tmplMatchesFinished, defaultTextFormat, items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "\nUpset! #14 seed defeats #2 seed"; !strings.HasSuffix(got, want) {
+		t.Errorf("renderTemplate() of upset = %q, want suffix %q", got, want)
+	}
+	hidden := defaultTextFormat
+	hidden.resultDetail = resultDetailSeries
+	if got, _ := renderTemplate(tmplMatchesFinished, hidden, items); strings.Contains(got, "Upset") {
+		t.Errorf("renderTemplate() without winners = %q, want the upset left out", got)
+	}
+}
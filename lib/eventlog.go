@@ -0,0 +1,161 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// eventLogMaxSize is the size of the event log, in bytes, from which it
+// is rotated
+const eventLogMaxSize = 64 << 20
+
+// eventLogBackups is the number of rotated event logs kept, as the path
+// of the log suffixed with .1 for the newest to .5 for the oldest
+const eventLogBackups = 5
+
+// eventLogEntry is a line of the event log: a game drafting, started or
+// finished, with the games and details as got from the API
+type eventLogEntry struct {
+	Time    time.Time                `json:"time"`
+	Event   string                   `json:"event"`
+	MatchID int64                    `json:"match_id"`
+	Game    *dota.LiveLeagueGame     `json:"game,omitempty"`
+	Result  *matchesFinishedDataItem `json:"result,omitempty"`
+	Details *dota.MatchDetails       `json:"details,omitempty"`
+}
+
+// eventLog appends the games of the event bus to a file of JSON lines,
+// for auditing and later analysis of the games seen by the bot
+type eventLog struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openEventLog opens the event log at path, appending to it if it exists
+func openEventLog(path string) (*eventLog, error) {
+	log := &eventLog{path: path}
+	if err := log.open(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func (log *eventLog) open() error {
+	file, err := os.OpenFile(log.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "Error opening event log")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "Error getting size of event log")
+	}
+	log.file = file
+	log.size = info.Size()
+	return nil
+}
+
+// write appends an entry to the log, rotating the log first if the
+// entry would take it past eventLogMaxSize
+func (log *eventLog) write(entry eventLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "Error encoding event")
+	}
+	line = append(line, '\n')
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.file == nil {
+		return errors.New("Error writing event: event log closed")
+	}
+	var rotateErr error
+	if log.size > 0 && log.size+int64(len(line)) > eventLogMaxSize {
+		// The entry is still written to the log if it could not be
+		// rotated but is open
+		if rotateErr = log.rotate(); rotateErr != nil && log.file == nil {
+			return rotateErr
+		}
+	}
+	n, err := log.file.Write(line)
+	log.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "Error writing event")
+	}
+	return rotateErr
+}
+
+// rotate moves the log to the first backup, shifting the older backups
+// and removing the oldest, and starts a new log. The log is reopened for
+// appending if it could not be moved, so that logging carries on.
+func (log *eventLog) rotate() error {
+	if err := log.file.Close(); err != nil {
+		return errors.Wrap(err, "Error closing event log")
+	}
+	log.file = nil
+	if err := log.shiftBackups(); err != nil {
+		if openErr := log.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	return log.open()
+}
+
+// shiftBackups renames the backups of the log, and the log itself, to the
+// next backup
+func (log *eventLog) shiftBackups() error {
+	for i := eventLogBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", log.path, i), fmt.Sprintf("%s.%d", log.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Error rotating event log")
+		}
+	}
+	if err := os.Rename(log.path, log.path+".1"); err != nil {
+		return errors.Wrap(err, "Error rotating event log")
+	}
+	return nil
+}
+
+func (log *eventLog) close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.file == nil {
+		return nil
+	}
+	err := log.file.Close()
+	log.file = nil
+	return err
+}
+
+// logEvents is the subscriber of the event bus writing the games
+// drafting, started and finished to the event log
+func (bot *bot) logEvents(ctx context.Context, events []WatcherEvent) {
+	for _, event := range events {
+		entry := eventLogEntry{Time: time.Now().UTC()}
+		switch event := event.(type) {
+		case *DraftStarted:
+			entry.Event, entry.MatchID, entry.Game = eventDrafting, event.Game.MatchID, &event.Game
+		case *GameStarted:
+			entry.Event, entry.MatchID, entry.Game = eventStarted, event.Game.MatchID, &event.Game
+		case *GameFinished:
+			result := bot.finishedResult(ctx, event)
+			entry.Event, entry.MatchID = eventFinished, event.Game.MatchID
+			entry.Result, entry.Details = &result, &event.Details
+		default:
+			continue
+		}
+		if err := bot.eventLog.write(entry); err != nil {
+			bot.logger.WithField(logFieldMatchID, entry.MatchID).WithError(err).Error("Error writing to the event log")
+		}
+	}
+}
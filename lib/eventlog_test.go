@@ -0,0 +1,127 @@
+package timatch
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestEventLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+	log, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("Error opening event log: %+v", err)
+	}
+	entries := []eventLogEntry{
+		{Event: eventStarted, MatchID: 1, Game: &dota.LiveLeagueGame{MatchID: 1}},
+		{Event: eventFinished, MatchID: 1, Result: &matchesFinishedDataItem{MatchID: 1, WinnerName: "OG"}, Details: &dota.MatchDetails{RadiantWin: true}},
+	}
+	for _, entry := range entries {
+		if err := log.write(entry); err != nil {
+			t.Fatalf("Error writing event: %+v", err)
+		}
+	}
+	if err := log.close(); err != nil {
+		t.Fatal(err)
+	}
+	// Reopened logs are appended to
+	if log, err = openEventLog(path); err != nil {
+		t.Fatalf("Error reopening event log: %+v", err)
+	}
+	defer log.close()
+	if err := log.write(eventLogEntry{Event: eventDrafting, MatchID: 2}); err != nil {
+		t.Fatalf("Error writing event: %+v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var events []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry eventLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Error decoding line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, entry.Event)
+	}
+	if got := strings.Join(events, ", "); got != "started, finished, drafting" {
+		t.Errorf("events = %s, want started, finished, drafting", got)
+	}
+}
+
+func TestEventLogRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+	log, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("Error opening event log: %+v", err)
+	}
+	defer log.close()
+	for i := 0; i < eventLogBackups+2; i++ {
+		// Pretend the log is full, so that each write rotates it
+		log.size = eventLogMaxSize
+		if err := log.write(eventLogEntry{Event: eventStarted, MatchID: int64(i)}); err != nil {
+			t.Fatalf("Error writing event: %+v", err)
+		}
+	}
+	for _, name := range []string{"events.jsonl", "events.jsonl.1", "events.jsonl.5"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "events.jsonl.6")); !os.IsNotExist(err) {
+		t.Errorf("expected no more than %d backups", eventLogBackups)
+	}
+}
+
+func TestEventLogRotateError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eventlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+	// Backups that are non-empty directories cannot be renamed over
+	for _, name := range []string{"events.jsonl.4", "events.jsonl.5"} {
+		if err := os.MkdirAll(filepath.Join(dir, name, "x"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log, err := openEventLog(path)
+	if err != nil {
+		t.Fatalf("Error opening event log: %+v", err)
+	}
+	defer log.close()
+	log.size = eventLogMaxSize
+	if err := log.write(eventLogEntry{Event: eventStarted, MatchID: 1}); err == nil {
+		t.Error("expected an error rotating the event log")
+	}
+	log.size = 0
+	if err := log.write(eventLogEntry{Event: eventStarted, MatchID: 2}); err != nil {
+		t.Fatalf("Error writing event after failed rotation: %+v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("expected both events in the log, got %d lines", lines)
+	}
+}
@@ -0,0 +1,193 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of announcements, which channels can opt out of and guilds can
+// turn TTS on or off for
+const (
+	eventDrafting     = matchStateDrafting
+	eventStarted      = matchStateStarted
+	eventScoreUpdates = "scoreupdates"
+	eventFinished     = matchStateFinished
+	eventFloodDigest  = "flooddigest"
+	eventSeries       = announcementSeries
+	eventDraftRead    = "draftread"
+	eventRecap        = "recap"
+	eventRecords      = "records"
+	eventPrizePool    = "prizepool"
+	eventMatchStats   = "matchstats"
+	eventHype         = "hype"
+	eventSchedule     = "schedule"
+	eventTopLive      = matchStateTopLive
+	// eventFollowUp is used for messages following an announcement,
+	// such as mentions and links. They are sent to the channels the
+	// announcement was sent to, and never as TTS
+	eventFollowUp = ""
+)
+
+// announcementEvent is a kind of announcement and whether it is sent as
+// TTS unless changed with /settings tts
+type announcementEvent struct {
+	name       string
+	defaultTTS bool
+}
+
+var announcementEvents = []announcementEvent{
+	{name: eventDrafting},
+	{name: eventStarted, defaultTTS: true},
+	{name: eventScoreUpdates},
+	{name: eventFinished, defaultTTS: true},
+	{name: eventFloodDigest},
+	{name: eventSeries},
+	{name: eventDraftRead},
+	{name: eventRecap},
+	{name: eventRecords},
+	{name: eventPrizePool},
+	{name: eventMatchStats},
+	{name: eventHype},
+	{name: eventSchedule},
+	{name: eventTopLive},
+}
+
+func findAnnouncementEvent(name string) (announcementEvent, bool) {
+	for _, event := range announcementEvents {
+		if strings.EqualFold(event.name, name) {
+			return event, true
+		}
+	}
+	return announcementEvent{}, false
+}
+
+func announcementEventNames() string {
+	names := make([]string, len(announcementEvents))
+	for i, event := range announcementEvents {
+		names[i] = event.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// includesEvent tests if announcements of event are sent to the channel
+// of the subscription. All announcements are sent to channels without a
+// subscription.
+func (sub *channelSubscription) includesEvent(event string) bool {
+	if sub == nil || event == eventFollowUp {
+		return true
+	}
+	for _, excluded := range sub.ExcludedEvents {
+		if excluded == event {
+			return false
+		}
+	}
+	return true
+}
+
+// setEvent opts the subscription in or out of announcements of event.
+// Returns false if the subscription already was.
+func (sub *channelSubscription) setEvent(event string, include bool) bool {
+	if sub.includesEvent(event) == include {
+		return false
+	}
+	if !include {
+		sub.ExcludedEvents = append(sub.ExcludedEvents, event)
+		return true
+	}
+	excluded := make([]string, 0, len(sub.ExcludedEvents))
+	for _, e := range sub.ExcludedEvents {
+		if e != event {
+			excluded = append(excluded, e)
+		}
+	}
+	sub.ExcludedEvents = excluded
+	if len(excluded) == 0 {
+		sub.ExcludedEvents = nil
+	}
+	return true
+}
+
+// eventsString describes the announcements sent to the channel of the
+// subscription
+func (sub *channelSubscription) eventsString() string {
+	names := make([]string, 0, len(announcementEvents))
+	for _, event := range announcementEvents {
+		if sub.includesEvent(event.name) {
+			names = append(names, event.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// interactionSubscription returns the subscription of the channel the
+// interaction is in, or nil if the channel is not subscribed. Channels of
+// guilds that have not changed their subscriptions get the default
+// subscription, as any of them may be the channel announced to.
+func (bot *bot) interactionSubscription(ctx context.Context, in *interaction) (*channelSubscription, error) {
+	if in.GuildID == "" {
+		var sub channelSubscription
+		found, err := bot.store.Get(ctx, dmSubscriptionKey(in.userID()), &sub)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error getting direct message subscription")
+		}
+		if !found {
+			return nil, nil
+		}
+		return &sub, nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	if !settings.SubscriptionsSet {
+		return &channelSubscription{ChannelID: in.ChannelID}, nil
+	}
+	return settings.subscription(in.ChannelID), nil
+}
+
+// handleEventsCommand shows the kinds of announcements sent to the channel
+// the command is used in, or opts the channel in or out of a kind of
+// announcement if given one
+func (bot *bot) handleEventsCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	name := in.stringOption("event")
+	var event announcementEvent
+	if name != "" {
+		var ok bool
+		if event, ok = findAnnouncementEvent(name); !ok {
+			return textResponse(fmt.Sprintf("Unknown announcement %q, give one of %s.", name, announcementEventNames())), nil
+		}
+	}
+	state := strings.ToLower(in.stringOption("state"))
+	if name == "" || state == "" {
+		if name != "" || state != "" {
+			return textResponse("Give both an announcement and \"on\" or \"off\"."), nil
+		}
+		sub, err := bot.interactionSubscription(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil {
+			return textResponse("This channel is not subscribed to announcements."), nil
+		}
+		return textResponse("This channel gets announcements of: " + sub.eventsString()), nil
+	}
+	if state != "on" && state != "off" {
+		return textResponse("Give \"on\" or \"off\"."), nil
+	}
+	return bot.changeSubscription(ctx, in, func(settings *guildSettings) string {
+		sub := settings.subscription(in.ChannelID)
+		if sub == nil {
+			return "This channel is not subscribed to announcements."
+		}
+		if !sub.setEvent(event.name, state == "on") {
+			return fmt.Sprintf("This channel already has %s announcements %s.", event.name, state)
+		}
+		return "This channel now gets announcements of: " + sub.eventsString()
+	})
+}
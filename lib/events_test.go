@@ -0,0 +1,26 @@
+package timatch
+
+import "testing"
+
+func TestChannelSubscriptionEvents(t *testing.T) {
+	var none *channelSubscription
+	if !none.includesEvent(eventDrafting) {
+		t.Error("includesEvent() without subscription = false, want true")
+	}
+	sub := &channelSubscription{ChannelID: "1"}
+	if !sub.setEvent(eventDrafting, false) || sub.setEvent(eventDrafting, false) {
+		t.Error("setEvent(drafting, off) twice, want changed only the first time")
+	}
+	sub.setEvent(eventScoreUpdates, false)
+	if sub.includesEvent(eventDrafting) || !sub.includesEvent(eventFinished) || !sub.includesEvent(eventFollowUp) {
+		t.Errorf("includesEvent() wrong for excluded %v", sub.ExcludedEvents)
+	}
+	if got, want := sub.eventsString(), "started, finished, flooddigest, series, draftread, recap, records, prizepool, matchstats, hype, schedule, toplive"; got != want {
+		t.Errorf("eventsString() = %q, want %q", got, want)
+	}
+	sub.setEvent(eventDrafting, true)
+	sub.setEvent(eventScoreUpdates, true)
+	if sub.ExcludedEvents != nil {
+		t.Errorf("ExcludedEvents = %v after opting back in, want nil", sub.ExcludedEvents)
+	}
+}
@@ -0,0 +1,196 @@
+package timatch
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pickKindPrediction and pickKindBet are the kinds of picks in the pick
+// history
+const (
+	pickKindPrediction = "prediction"
+	pickKindBet        = "bet"
+)
+
+// pickRecord is a scored prediction or settled bet in the pick history
+// of a guild. Amount and Payout are only set for bets.
+type pickRecord struct {
+	Kind     string    `json:"kind"`
+	MatchID  int64     `json:"match_id"`
+	UserID   string    `json:"user_id"`
+	Name     string    `json:"name"`
+	Team     string    `json:"team"`
+	Correct  bool      `json:"correct"`
+	Amount   int       `json:"amount,omitempty"`
+	Payout   int       `json:"payout,omitempty"`
+	ScoredAt time.Time `json:"scored_at"`
+}
+
+// exportLeaderboardRow is a user in an exported leaderboard. Net is the
+// points won (or lost, if negative) betting.
+type exportLeaderboardRow struct {
+	UserID  string `json:"user_id"`
+	Name    string `json:"name"`
+	Correct int    `json:"correct"`
+	Total   int    `json:"total"`
+	Bets    int    `json:"bets"`
+	Net     int    `json:"net"`
+}
+
+// pickHistoryKey is the key of the pick history of a guild in a league.
+// Unlike the leaderboard, the history is kept per league so that a
+// league can be exported on its own.
+func pickHistoryKey(guildID guildID, leagueID int) string {
+	return "picks/" + string(guildID) + "/" + strconv.Itoa(leagueID)
+}
+
+// loadPickHistory loads the pick history of a guild in a league, oldest
+// first
+func (bot *bot) loadPickHistory(ctx context.Context, guildID guildID, leagueID int) ([]pickRecord, error) {
+	var records []pickRecord
+	if _, err := bot.store.Get(ctx, pickHistoryKey(guildID, leagueID), &records); err != nil {
+		return nil, errors.Wrap(err, "Error getting pick history")
+	}
+	return records, nil
+}
+
+// recordPicks adds picks to the pick history of a guild in the current
+// league
+func (bot *bot) recordPicks(ctx context.Context, guildID guildID, records []pickRecord) {
+	leagueID := bot.currentLeagueID()
+	logger := bot.logger.WithField(logFieldGuildID, guildID)
+	history, err := bot.loadPickHistory(ctx, guildID, leagueID)
+	if err != nil {
+		logger.WithError(err).Error("Error loading pick history")
+		return
+	}
+	history = append(history, records...)
+	if err := bot.store.Set(ctx, pickHistoryKey(guildID, leagueID), history, 0); err != nil {
+		logger.WithError(err).Error("Error storing pick history")
+	}
+}
+
+// exportLeaderboard sums up a pick history by user, ordered like
+// /leaderboard
+func exportLeaderboard(history []pickRecord) []exportLeaderboardRow {
+	byUser := make(map[string]*exportLeaderboardRow)
+	for _, record := range history {
+		row, ok := byUser[record.UserID]
+		if !ok {
+			row = &exportLeaderboardRow{UserID: record.UserID}
+			byUser[record.UserID] = row
+		}
+		row.Name = record.Name
+		switch record.Kind {
+		case pickKindPrediction:
+			row.Total++
+			if record.Correct {
+				row.Correct++
+			}
+		case pickKindBet:
+			row.Bets++
+			row.Net += record.Payout - record.Amount
+		}
+	}
+	rows := make([]exportLeaderboardRow, 0, len(byUser))
+	for _, row := range byUser {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Correct != rows[j].Correct {
+			return rows[i].Correct > rows[j].Correct
+		}
+		if rows[i].Total != rows[j].Total {
+			return rows[i].Total < rows[j].Total
+		}
+		if rows[i].Net != rows[j].Net {
+			return rows[i].Net > rows[j].Net
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows
+}
+
+// handleExport exports the leaderboard (/export/leaderboard) or the pick
+// history (/export/picks) of a guild in a league as CSV or JSON, e.g.
+// /export/picks?guild=123&league=456&format=csv. The league defaults to
+// the watched league and the format to JSON.
+func (bot *bot) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("Method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	guild := r.FormValue("guild")
+	if guild == "" {
+		http.Error(w, "Missing guild", http.StatusBadRequest)
+		return
+	}
+	leagueID := bot.currentLeagueID()
+	if league := r.FormValue("league"); league != "" {
+		var err error
+		if leagueID, err = strconv.Atoi(league); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid league %q", league), http.StatusBadRequest)
+			return
+		}
+	}
+	format := r.FormValue("format")
+	if format != "" && format != "csv" && format != "json" {
+		http.Error(w, fmt.Sprintf("Invalid format %q, want csv or json", format), http.StatusBadRequest)
+		return
+	}
+	history, err := bot.loadPickHistory(r.Context(), guildID(guild), leagueID)
+	if err != nil {
+		bot.logger.WithError(err).Error("Error loading pick history")
+		http.Error(w, "Error loading pick history", http.StatusInternalServerError)
+		return
+	}
+	var header []string
+	var rows [][]string
+	var data interface{}
+	switch r.URL.Path {
+	case "/export/leaderboard":
+		leaderboard := exportLeaderboard(history)
+		data = leaderboard
+		header = []string{"user_id", "name", "correct", "total", "bets", "net"}
+		for _, row := range leaderboard {
+			rows = append(rows, []string{row.UserID, row.Name, strconv.Itoa(row.Correct),
+				strconv.Itoa(row.Total), strconv.Itoa(row.Bets), strconv.Itoa(row.Net)})
+		}
+	case "/export/picks":
+		if history == nil {
+			history = []pickRecord{}
+		}
+		data = history
+		header = []string{"kind", "match_id", "user_id", "name", "team", "correct", "amount", "payout", "scored_at"}
+		for _, record := range history {
+			rows = append(rows, []string{record.Kind, strconv.FormatInt(record.MatchID, 10), record.UserID,
+				record.Name, record.Team, strconv.FormatBool(record.Correct), strconv.Itoa(record.Amount),
+				strconv.Itoa(record.Payout), record.ScoredAt.Format(time.RFC3339)})
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		// Errors are sticky, WriteAll reports any error of the header
+		cw.Write(header)
+		if err := cw.WriteAll(rows); err != nil {
+			bot.logger.WithError(err).Error("Error writing export response")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		bot.logger.WithError(err).Error("Error writing export response")
+	}
+}
@@ -0,0 +1,59 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestExportLeaderboard(t *testing.T) {
+	history := []pickRecord{
+		{Kind: pickKindPrediction, UserID: "1", Name: "alice", Correct: false},
+		{Kind: pickKindPrediction, UserID: "2", Name: "bob", Correct: true},
+		{Kind: pickKindBet, UserID: "1", Name: "alice", Amount: 100, Payout: 250},
+		{Kind: pickKindBet, UserID: "2", Name: "bob", Amount: 100},
+	}
+	rows := exportLeaderboard(history)
+	want := []exportLeaderboardRow{
+		{UserID: "2", Name: "bob", Correct: 1, Total: 1, Bets: 1, Net: -100},
+		{UserID: "1", Name: "alice", Correct: 0, Total: 1, Bets: 1, Net: 150},
+	}
+	if len(rows) != len(want) || rows[0] != want[0] || rows[1] != want[1] {
+		t.Errorf("exportLeaderboard() = %+v, want %+v", rows, want)
+	}
+}
+
+func TestHandleExport(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, store: storage.NewMemoryStore(), leagueID: 456}
+	scoredAt := time.Date(2019, 8, 20, 12, 0, 0, 0, time.UTC)
+	bot.recordPicks(context.Background(), "123", []pickRecord{
+		{Kind: pickKindPrediction, MatchID: 1, UserID: "1", Name: "alice", Team: "OG", Correct: true, ScoredAt: scoredAt},
+	})
+
+	rec := httptest.NewRecorder()
+	bot.handleExport(rec, httptest.NewRequest("GET", "/export/picks?guild=123&format=csv", nil))
+	want := "kind,match_id,user_id,name,team,correct,amount,payout,scored_at\n" +
+		"prediction,1,1,alice,OG,true,0,0,2019-08-20T12:00:00Z\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("Export of picks = %q, want %q", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	bot.handleExport(rec, httptest.NewRequest("GET", "/export/leaderboard?guild=123&league=1", nil))
+	if got, want := rec.Body.String(), "[]\n"; got != want {
+		t.Errorf("Export of another league = %q, want %q", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	bot.handleExport(rec, httptest.NewRequest("GET", "/export/picks", nil))
+	if rec.Code != 400 {
+		t.Errorf("Export without guild status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package timatch
+
+import (
+	"context"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// floodDigestInterval is the time between digests of games that were
+// not announced individually due to flood control
+const floodDigestInterval = 1 * time.Hour
+
+// floodDigest collects the games held back by flood control until the
+// next digest is sent.
+type floodDigest struct {
+	lastSent time.Time
+	Started  []dota.LiveLeagueGame
+	Finished []matchesFinishedDataItem
+}
+
+func (digest *floodDigest) empty() bool {
+	return len(digest.Started) == 0 && len(digest.Finished) == 0
+}
+
+// isNotableTeam tests if any of the given team ids is a notable team
+func (bot *bot) isNotableTeam(teamIDs ...int) bool {
+	for _, teamID := range teamIDs {
+		if _, ok := bot.notableTeams[teamID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nextUpdateInterval returns the time to wait until the next update.
+// Updates are more frequent while a notable team is playing, so that
+// their games are announced, and their scores updated, as soon as
+// possible.
+func (bot *bot) nextUpdateInterval() time.Duration {
+	if bot.notableLive {
+		return notableUpdateInterval
+	}
+	return updateInterval
+}
+
+// filterNotableGames splits games into those that should be announced
+// right away and those held back for the digest. All games are notable
+// unless flood control is enabled.
+func (bot *bot) filterNotableGames(games []dota.LiveLeagueGame) (notable, rest []dota.LiveLeagueGame) {
+	if !bot.floodControl {
+		return games, nil
+	}
+	for _, game := range games {
+		if bot.isNotableTeam(game.RadiantTeam.TeamID, game.DireTeam.TeamID) {
+			notable = append(notable, game)
+		} else {
+			rest = append(rest, game)
+		}
+	}
+	return notable, rest
+}
+
+// filterNotableFinished is the filterNotableGames equivalent for
+// finished games.
+func (bot *bot) filterNotableFinished(items []matchesFinishedDataItem) (notable, rest []matchesFinishedDataItem) {
+	if !bot.floodControl {
+		return items, nil
+	}
+	for _, item := range items {
+		if bot.isNotableTeam(item.WinnerTeamID, item.LoserTeamID) {
+			notable = append(notable, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+	return notable, rest
+}
+
+// sendFloodDigest sends the digest of held back games, if there are any
+// and floodDigestInterval has passed since the last digest.
+func (bot *bot) sendFloodDigest(ctx context.Context) {
+	if bot.floodDigest.lastSent.IsZero() {
+		bot.floodDigest.lastSent = time.Now()
+	}
+	if time.Since(bot.floodDigest.lastSent) < floodDigestInterval {
+		return
+	}
+	bot.floodDigest.lastSent = time.Now()
+	bot.flushFloodDigest(ctx)
+}
+
+// flushFloodDigest sends the digest of held back games right away, if
+// there are any
+func (bot *bot) flushFloodDigest(ctx context.Context) {
+	bot.stateMu.Lock()
+	held := floodDigest{Started: bot.floodDigest.Started, Finished: bot.floodDigest.Finished}
+	bot.floodDigest.Started = nil
+	bot.floodDigest.Finished = nil
+	bot.stateMu.Unlock()
+	if held.empty() {
+		return
+	}
+	bot.sendTemplateGuildMessage(ctx, tmplFloodDigest, eventFloodDigest, func(channelID channelID, settings *guildSettings, sub *channelSubscription) interface{} {
+		digest := floodDigest{
+			Started:  bot.announcedGames(held.Started, settings, sub),
+			Finished: bot.announcedFinished(held.Finished, settings, sub),
+		}
+		if digest.empty() {
+			return nil
+		}
+		return digest
+	})
+}
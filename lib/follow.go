@@ -0,0 +1,60 @@
+package timatch
+
+import (
+	"sort"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// matchIDSet returns the set of the given match ids
+func matchIDSet(matchIDs []int64) map[int64]struct{} {
+	set := make(map[int64]struct{}, len(matchIDs))
+	for _, matchID := range matchIDs {
+		set[matchID] = struct{}{}
+	}
+	return set
+}
+
+// isFollowing tests if games are followed in any league, by their teams
+// or match ids, rather than the games of a league being watched
+func (bot *bot) isFollowing() bool {
+	return bot.followTeams || len(bot.watchMatches) > 0
+}
+
+// isFollowedGame tests if a live game of any league is followed: one of
+// the watched match ids, or a game of the followed teams. All games are
+// followed when watching a league.
+func (bot *bot) isFollowedGame(game dota.LiveLeagueGame) bool {
+	if len(bot.watchMatches) > 0 {
+		_, ok := bot.watchMatches[game.MatchID]
+		return ok
+	}
+	if !bot.followTeams {
+		return true
+	}
+	return game.LeagueID != 0 && bot.isAnnouncedTeam(game.RadiantTeam.TeamID, game.DireTeam.TeamID)
+}
+
+// historyLeagues returns the leagues whose match history is polled for
+// finished games: the watched league, or when following games the
+// leagues of the followed games started and yet to finish
+func (watcher *Watcher) historyLeagues() []int {
+	if !watcher.following {
+		return []int{watcher.leagueID}
+	}
+	seen := make(map[int]struct{})
+	var leagueIDs []int
+	for matchID, leagueID := range watcher.matchLeagues {
+		_, isStarted := watcher.started[matchID]
+		_, isFinished := watcher.finished[matchID]
+		if !isStarted || isFinished {
+			continue
+		}
+		if _, ok := seen[leagueID]; !ok {
+			seen[leagueID] = struct{}{}
+			leagueIDs = append(leagueIDs, leagueID)
+		}
+	}
+	sort.Ints(leagueIDs)
+	return leagueIDs
+}
@@ -0,0 +1,61 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestIsFollowedGame(t *testing.T) {
+	bot := &bot{followTeams: true, teams: teamIDSet([]int{15})}
+	game := func(leagueID, radiantTeamID, direTeamID int) dota.LiveLeagueGame {
+		return dota.LiveLeagueGame{
+			LeagueID:    leagueID,
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: radiantTeamID},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamID: direTeamID},
+		}
+	}
+	if !bot.isFollowedGame(game(10749, 2, 15)) {
+		t.Error("expected a game of a followed team to be followed")
+	}
+	if bot.isFollowedGame(game(10749, 2, 39)) {
+		t.Error("expected a game of other teams not to be followed")
+	}
+	if bot.isFollowedGame(game(0, 15, 39)) {
+		t.Error("expected a game outside of leagues not to be followed")
+	}
+	bot.followTeams = false
+	if !bot.isFollowedGame(game(10749, 2, 39)) {
+		t.Error("expected all games to be followed when not following teams")
+	}
+}
+
+func TestIsFollowedGameWatchMatches(t *testing.T) {
+	bot := &bot{watchMatches: matchIDSet([]int64{5012345678})}
+	if !bot.isFollowing() {
+		t.Error("expected watching match ids to be following")
+	}
+	if !bot.isFollowedGame(dota.LiveLeagueGame{MatchID: 5012345678, LeagueID: 10749}) {
+		t.Error("expected a watched match to be followed")
+	}
+	if bot.isFollowedGame(dota.LiveLeagueGame{MatchID: 5012345679, LeagueID: 10749}) {
+		t.Error("expected a match not watched not to be followed")
+	}
+}
+
+func TestHistoryLeagues(t *testing.T) {
+	watcher := &Watcher{
+		leagueID:     10749,
+		matchLeagues: map[int64]int{1: 300, 2: 200, 3: 300, 4: 100, 5: 400},
+		started:      map[int64]struct{}{1: {}, 2: {}, 3: {}, 4: {}},
+		finished:     map[int64]struct{}{4: {}},
+	}
+	if leagues := watcher.historyLeagues(); !reflect.DeepEqual(leagues, []int{10749}) {
+		t.Errorf("expected the watched league, got %v", leagues)
+	}
+	watcher.following = true
+	if leagues := watcher.historyLeagues(); !reflect.DeepEqual(leagues, []int{200, 300}) {
+		t.Errorf("expected the leagues of the unfinished games, got %v", leagues)
+	}
+}
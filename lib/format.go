@@ -0,0 +1,256 @@
+package timatch
+
+import (
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// scoreStyle is a way of writing a score, e.g. "2 - 0" or "2:0"
+type scoreStyle struct {
+	name      string
+	separator string
+}
+
+// scoreStyles are the score styles a guild can choose from. The first
+// one is the default.
+var scoreStyles = []scoreStyle{
+	{name: "dash", separator: " - "},
+	{name: "hyphen", separator: "-"},
+	{name: "endash", separator: "–"},
+	{name: "colon", separator: ":"},
+}
+
+// numberLocale is a way of writing numbers, e.g. "1,234.5" or "1.234,5"
+type numberLocale struct {
+	name      string
+	thousands string
+	decimal   string
+}
+
+// numberLocales are the number locales a guild can choose from. The
+// first one is the default.
+var numberLocales = []numberLocale{
+	{name: "en", thousands: ",", decimal: "."},
+	{name: "de", thousands: ".", decimal: ","},
+	// Narrow no-break space, as recommended for French
+	{name: "fr", thousands: "\u202f", decimal: ","},
+	{name: "ch", thousands: "'", decimal: "."},
+}
+
+// textFormat formats scores, numbers and times in the style chosen by a
+// guild
+type textFormat struct {
+	score  scoreStyle
+	number numberLocale
+	// location is the time zone of the guild, or nil if the guild has not
+	// set one. Times are then given in UTC, if at all
+	location *time.Location
+	// language is the language templates are rendered in
+	language messageLanguage
+	// accessible is true in accessibility mode, rendering screen reader
+	// friendly messages, see accessibleTemplates
+	accessible bool
+	// compact is true in the compact layout, rendering a short line per
+	// game, see compactTemplates
+	compact bool
+	// resultDetail is how much of the results of games is given, see
+	// resultDetails. Empty is resultDetailFull
+	resultDetail string
+	// heroNames are the names of the heroes in the language, for the
+	// lineups of started games. Lineups leave out the heroes if nil
+	heroNames map[int]string
+}
+
+// defaultTextFormat is used for guilds that have not chosen a style, and
+// outside of guilds
+var defaultTextFormat = textFormat{score: scoreStyles[0], number: numberLocales[0], language: messageLanguages[0]}
+
+func findScoreStyle(name string) (scoreStyle, bool) {
+	for _, style := range scoreStyles {
+		if strings.EqualFold(style.name, name) {
+			return style, true
+		}
+	}
+	return scoreStyle{}, false
+}
+
+func findNumberLocale(name string) (numberLocale, bool) {
+	for _, locale := range numberLocales {
+		if strings.EqualFold(locale.name, name) {
+			return locale, true
+		}
+	}
+	return numberLocale{}, false
+}
+
+// textFormat returns the text format chosen by the guild
+func (settings *guildSettings) textFormat() textFormat {
+	format := defaultTextFormat
+	if style, ok := findScoreStyle(settings.ScoreStyle); ok {
+		format.score = style
+	}
+	if locale, ok := findNumberLocale(settings.NumberLocale); ok {
+		format.number = locale
+	}
+	if settings.Timezone != "" {
+		if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+			format.location = loc
+		}
+	}
+	format.language = settings.language("")
+	format.compact = settings.layout("") == layoutCompact
+	format.resultDetail = settings.resultDetail()
+	if settings.Accessible {
+		format.accessible = true
+		format.score = accessibleScoreStyle
+	}
+	return format
+}
+
+// channelTextFormat returns the text format of a channel of the guild,
+// which differs from that of the guild only if the channel has its own
+// language or layout
+func (settings *guildSettings) channelTextFormat(channelID string) textFormat {
+	format := settings.textFormat()
+	format.language = settings.language(channelID)
+	format.compact = settings.layout(channelID) == layoutCompact
+	return format
+}
+
+// Score formats a score, e.g. a series score or the kill score of a game
+func (format textFormat) Score(a, b int) string {
+	return strconv.Itoa(a) + format.score.separator + strconv.Itoa(b)
+}
+
+// Integer formats n with thousands separators
+func (format textFormat) Integer(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	return sign + format.groupThousands(digits)
+}
+
+// Decimal formats x with the given number of decimals
+func (format textFormat) Decimal(x float64, decimals int) string {
+	s := strconv.FormatFloat(x, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, fracPart := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		intPart, fracPart = s[:dot], s[dot+1:]
+	}
+	s = sign + format.groupThousands(intPart)
+	if fracPart != "" {
+		s += format.number.decimal + fracPart
+	}
+	return s
+}
+
+// Dollars formats an amount of dollars, e.g. "$1,234,567"
+func (format textFormat) Dollars(amount int64) string {
+	return "$" + format.Integer(amount)
+}
+
+func (format textFormat) groupThousands(digits string) string {
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(format.number.thousands)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Time formats the time of day of t, e.g. "18:04 CEST"
+func (format textFormat) Time(t time.Time) string {
+	return t.In(format.timeLocation()).Format("15:04 MST")
+}
+
+// DateTime formats the date and time of t, e.g. "2019-08-20 18:04 CEST"
+func (format textFormat) DateTime(t time.Time) string {
+	return t.In(format.timeLocation()).Format("2006-01-02 15:04 MST")
+}
+
+// StartOfDay returns the start of the day of t
+func (format textFormat) StartOfDay(t time.Time) time.Time {
+	t = t.In(format.timeLocation())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (format textFormat) timeLocation() *time.Location {
+	if format.location == nil {
+		return time.UTC
+	}
+	return format.location
+}
+
+// scoreStyleExamples lists the score styles with examples, e.g.
+// "dash (2 - 0), colon (2:0)"
+func scoreStyleExamples() string {
+	examples := make([]string, 0, len(scoreStyles))
+	for _, style := range scoreStyles {
+		format := textFormat{score: style}
+		examples = append(examples, style.name+" ("+format.Score(2, 0)+")")
+	}
+	return strings.Join(examples, ", ")
+}
+
+// numberLocaleExamples lists the number locales with examples, e.g.
+// "en (1,234.5), de (1.234,5)"
+func numberLocaleExamples() string {
+	examples := make([]string, 0, len(numberLocales))
+	for _, locale := range numberLocales {
+		format := textFormat{number: locale}
+		examples = append(examples, locale.name+" ("+format.Decimal(1234.5, 1)+")")
+	}
+	return strings.Join(examples, ", ")
+}
+
+// Lineup lists the players of a team with their heroes, e.g. "OG: Topson
+// (Mars), Ana (Io)", or returns "" if the players are not known
+func (format textFormat) Lineup(team dota.LiveLeagueGamesTeam, players []dota.LiveLeagueGamePlayer) string {
+	if len(players) == 0 {
+		return ""
+	}
+	names := make([]string, len(players))
+	for i, player := range players {
+		names[i] = player.Name
+		if name, ok := format.heroNames[player.HeroID]; ok {
+			names[i] += " (" + name + ")"
+		}
+	}
+	return format.language.teamName(team.TeamName) + ": " + strings.Join(names, ", ")
+}
+
+// templateFuncs returns the functions available to the announcement
+// templates for formatting in this format. clock is the current time of
+// day, or "" unless the guild has set a time zone. team translates a team
+// name, for languages with team names. bestOf names a series type, e.g.
+// "Bo3". winners and kills test if the winners and kill scores of
+// finished games are given, see resultDetails. lineup lists the players
+// of a team, see textFormat.Lineup.
+func (format textFormat) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"score":   format.Score,
+		"team":    format.language.teamName,
+		"bestOf":  bestOf,
+		"winners": format.showsWinners,
+		"kills":   format.showsKills,
+		"lineup":  format.Lineup,
+		"clock": func() string {
+			if format.location == nil {
+				return ""
+			}
+			return format.Time(time.Now())
+		},
+	}
+}
@@ -0,0 +1,132 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestFormatDollars(t *testing.T) {
+	tests := []struct {
+		amount int64
+		want   string
+	}{
+		{0, "$0"},
+		{999, "$999"},
+		{1000, "$1,000"},
+		{123456, "$123,456"},
+		{1234567, "$1,234,567"},
+		{34330068, "$34,330,068"},
+	}
+	for _, tt := range tests {
+		if got := defaultTextFormat.Dollars(tt.amount); got != tt.want {
+			t.Errorf("Dollars(%d) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	tests := []struct {
+		settings guildSettings
+		score    string
+		integer  string
+		decimal  string
+	}{
+		{guildSettings{}, "2 - 0", "-1,234,567", "1,234.5"},
+		{guildSettings{ScoreStyle: "colon", NumberLocale: "de"}, "2:0", "-1.234.567", "1.234,5"},
+		{guildSettings{ScoreStyle: "endash", NumberLocale: "fr"}, "2–0", "-1\u202f234\u202f567", "1\u202f234,5"},
+		{guildSettings{ScoreStyle: "hyphen", NumberLocale: "ch"}, "2-0", "-1'234'567", "1'234.5"},
+		{guildSettings{ScoreStyle: "unknown", NumberLocale: "unknown"}, "2 - 0", "-1,234,567", "1,234.5"},
+	}
+	for _, tt := range tests {
+		format := tt.settings.textFormat()
+		if got := format.Score(2, 0); got != tt.score {
+			t.Errorf("Score(2, 0) with %+v = %q, want %q", tt.settings, got, tt.score)
+		}
+		if got := format.Integer(-1234567); got != tt.integer {
+			t.Errorf("Integer(-1234567) with %+v = %q, want %q", tt.settings, got, tt.integer)
+		}
+		if got := format.Decimal(1234.5, 1); got != tt.decimal {
+			t.Errorf("Decimal(1234.5, 1) with %+v = %q, want %q", tt.settings, got, tt.decimal)
+		}
+	}
+	if got := defaultTextFormat.Decimal(-0.25, 0); got != "-0" {
+		t.Errorf("Decimal(-0.25, 0) = %q, want \"-0\"", got)
+	}
+}
+
+func TestRenderTemplateFormat(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 1, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17}}
+	format := (&guildSettings{ScoreStyle: "colon"}).textFormat()
+	got, err := renderTemplate(tmplMatchesFinished, format, items)
+	got = strings.TrimSpace(got)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "Match Ended: OG defeated Liquid (32:17, Game 1)"; got != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+	// The template itself is not changed by rendering in another format
+	got, err = renderTemplate(tmplMatchesFinished, defaultTextFormat, items)
+	got = strings.TrimSpace(got)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "Match Ended: OG defeated Liquid (32 - 17, Game 1)"; got != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	at := time.Date(2019, 8, 20, 23, 4, 0, 0, time.UTC)
+	if got, want := defaultTextFormat.DateTime(at), "2019-08-20 23:04 UTC"; got != want {
+		t.Errorf("DateTime() without time zone = %q, want %q", got, want)
+	}
+	format := textFormat{location: time.FixedZone("UTC+2", 2*60*60)}
+	if got, want := format.Time(at), "01:04 UTC+2"; got != want {
+		t.Errorf("Time() = %q, want %q", got, want)
+	}
+	if got, want := format.StartOfDay(at), time.Date(2019, 8, 20, 22, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("StartOfDay() = %v, want %v", got, want)
+	}
+	if got, want := (&guildSettings{Timezone: "UTC"}).textFormat().location, time.UTC; got != want {
+		t.Errorf("location of UTC = %v, want %v", got, want)
+	}
+}
+
+func TestRenderTemplateClock(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 1, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17}}
+	format := textFormat{score: scoreStyles[0], location: time.UTC}
+	got, err := renderTemplate(tmplMatchesFinished, format, items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	prefix := "Match Ended: OG defeated Liquid (32 - 17, Game 1, "
+	if got = strings.TrimSpace(got); !strings.HasPrefix(got, prefix) || !strings.HasSuffix(got, " UTC)") {
+		t.Errorf("renderTemplate() with time zone = %q, want time of day", got)
+	}
+}
+
+func TestRenderTemplateLineups(t *testing.T) {
+	games := []dota.LiveLeagueGame{{
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+		GameNumber:  1,
+		Players: []dota.LiveLeagueGamePlayer{
+			{Name: "Topson", HeroID: 129, Team: dota.PlayerTeamRadiant},
+			{Name: "Miracle-", HeroID: 1, Team: dota.PlayerTeamDire},
+			{Name: "Caster", Team: 4},
+		},
+	}}
+	format := textFormat{score: scoreStyles[0], heroNames: map[int]string{129: "Mars"}}
+	got, err := renderTemplate(tmplMatchesStarted, format, games)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	want := "Match Started: OG vs. Liquid (Game 1)\nOG: Topson (Mars)\nLiquid: Miracle-"
+	if got = strings.TrimSpace(got); got != want {
+		t.Errorf("renderTemplate() with players = %q, want %q", got, want)
+	}
+}
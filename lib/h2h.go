@@ -0,0 +1,89 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// h2hRecentGames is the number of most recent games between the teams
+// listed by /h2h
+const h2hRecentGames = 5
+
+// headToHead returns the results of the games between two teams, most
+// recently finished first, and the number of games won by each team
+func headToHead(results []matchResult, teamA, teamB int) (games []matchResult, winsA, winsB int) {
+	for _, result := range results {
+		switch {
+		case result.WinnerTeamID == teamA && result.LoserTeamID == teamB:
+			winsA++
+		case result.WinnerTeamID == teamB && result.LoserTeamID == teamA:
+			winsB++
+		default:
+			continue
+		}
+		games = append(games, result)
+	}
+	return games, winsA, winsB
+}
+
+// renderHeadToHead renders the record of two teams against each other,
+// followed by their most recent games
+func renderHeadToHead(nameA, nameB string, games []matchResult, winsA, winsB int, format textFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s vs. %s**\n", nameA, nameB)
+	switch {
+	case winsA > winsB:
+		fmt.Fprintf(&b, "%s leads %s in games\n", nameA, format.Score(winsA, winsB))
+	case winsB > winsA:
+		fmt.Fprintf(&b, "%s leads %s in games\n", nameB, format.Score(winsB, winsA))
+	default:
+		fmt.Fprintf(&b, "Tied %s in games\n", format.Score(winsA, winsB))
+	}
+	if len(games) > h2hRecentGames {
+		games = games[:h2hRecentGames]
+	}
+	for _, result := range games {
+		b.WriteString(renderResult(result, false, format) + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// handleH2HCommand responds with the record of two teams against each
+// other in the watched league, or in all stored leagues
+func (bot *bot) handleH2HCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if in.boolOption("all", false) {
+		leagueID = 0
+	} else if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	var teamIDs [2]int
+	for i, option := range []string{"team1", "team2"} {
+		query := in.stringOption(option)
+		teamID, err := bot.findTeamID(ctx, query)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error finding team")
+		}
+		if teamID == 0 {
+			return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+		}
+		teamIDs[i] = teamID
+	}
+	nameA, nameB := bot.teamName(teamIDs[0]), bot.teamName(teamIDs[1])
+	if teamIDs[0] == teamIDs[1] {
+		return textResponse("Give two different teams."), nil
+	}
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	games, winsA, winsB := headToHead(results, teamIDs[0], teamIDs[1])
+	if len(games) == 0 {
+		return textResponse(fmt.Sprintf("%s and %s have not played each other yet.", nameA, nameB)), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	return textResponse(renderHeadToHead(nameA, nameB, games, winsA, winsB, format)), nil
+}
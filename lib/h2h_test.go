@@ -0,0 +1,29 @@
+package timatch
+
+import "testing"
+
+func TestHeadToHead(t *testing.T) {
+	game := func(matchID int64, winner, loser int, winnerName, loserName string) matchResult {
+		return matchResult{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: matchID, GameNumber: 1,
+			WinnerTeamID: winner, LoserTeamID: loser, WinnerName: winnerName, LoserName: loserName,
+			WinnerScore: 30, LoserScore: 20}}
+	}
+	results := []matchResult{
+		game(4, 2, 1, "Team Liquid", "OG"),
+		game(3, 1, 3, "OG", "Secret"),
+		game(2, 1, 2, "OG", "Team Liquid"),
+		game(1, 1, 2, "OG", "Team Liquid"),
+	}
+	games, winsA, winsB := headToHead(results, 1, 2)
+	if len(games) != 3 || winsA != 2 || winsB != 1 {
+		t.Fatalf("headToHead() = %d games, %d - %d, want 3 games, 2 - 1", len(games), winsA, winsB)
+	}
+	want := "**OG vs. Team Liquid**\n" +
+		"OG leads 2 - 1 in games\n" +
+		"Team Liquid defeated OG (30 - 20, Game 1)\n" +
+		"OG defeated Team Liquid (30 - 20, Game 1)\n" +
+		"OG defeated Team Liquid (30 - 20, Game 1)"
+	if got := renderHeadToHead("OG", "Team Liquid", games, winsA, winsB, defaultTextFormat); got != want {
+		t.Errorf("renderHeadToHead() = %q, want %q", got, want)
+	}
+}
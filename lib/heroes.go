@@ -0,0 +1,190 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// heroesCache caches the localized names of the heroes, by language code
+// and hero id
+type heroesCache struct {
+	mu    sync.Mutex
+	names map[string]map[int]string
+}
+
+// heroNames returns the names of all heroes in the given language, by
+// hero id. The names are fetched once per language, on first use, unless
+// given by a language bundle.
+func (bot *bot) heroNames(ctx context.Context, language string) (map[int]string, error) {
+	if l, ok := findMessageLanguage(language); ok && len(l.heroNames) > 0 {
+		return l.heroNames, nil
+	}
+	bot.heroes.mu.Lock()
+	defer bot.heroes.mu.Unlock()
+	if names, ok := bot.heroes.names[language]; ok {
+		return names, nil
+	}
+	heroesRes, err := bot.dotaClient.GetHeroes(ctx, language)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting heroes")
+	}
+	names := make(map[int]string, len(heroesRes.Result.Heroes))
+	for _, hero := range heroesRes.Result.Heroes {
+		names[hero.ID] = hero.LocalizedName
+	}
+	if bot.heroes.names == nil {
+		bot.heroes.names = make(map[string]map[int]string)
+	}
+	bot.heroes.names[language] = names
+	return names, nil
+}
+
+// heroName returns the name of a hero, or a placeholder if not known
+func heroName(names map[int]string, heroID int) string {
+	if name, ok := names[heroID]; ok {
+		return name
+	}
+	return fmt.Sprintf("Hero %d", heroID)
+}
+
+// normalizeHeroName normalizes a hero name for matching, ignoring case
+// and anything but letters and digits, so that e.g. "antimage" matches
+// "Anti-Mage"
+func normalizeHeroName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// findHero returns the id of the hero named query, or 0 if there is no
+// such hero
+func findHero(names map[int]string, query string) int {
+	query = normalizeHeroName(query)
+	for heroID, name := range names {
+		if normalizeHeroName(name) == query {
+			return heroID
+		}
+	}
+	return 0
+}
+
+// heroStats are the tournament stats of a hero
+type heroStats struct {
+	Picks int
+	Bans  int
+	Wins  int
+	// BestPlayer is the account id of the player with the most wins on
+	// the hero, 0 if the hero has not been won with
+	BestPlayer       int64
+	BestPlayerWins   int
+	BestPlayerLosses int
+	// LastGame is the most recent game the hero was picked in, nil if
+	// never picked
+	LastGame *matchResult
+}
+
+// computeHeroStats computes the stats of a hero from the results of a
+// league, most recently finished first
+func computeHeroStats(results []matchResult, heroID int) heroStats {
+	var stats heroStats
+	wins := make(map[int64]int)
+	losses := make(map[int64]int)
+	for i := range results {
+		result := &results[i]
+		for _, ban := range result.Bans {
+			if ban == heroID {
+				stats.Bans++
+			}
+		}
+		for _, player := range result.Players {
+			if player.HeroID != heroID {
+				continue
+			}
+			stats.Picks++
+			if stats.LastGame == nil {
+				stats.LastGame = result
+			}
+			if player.Won {
+				stats.Wins++
+				wins[player.AccountID]++
+			} else {
+				losses[player.AccountID]++
+			}
+		}
+	}
+	// Ties are won by the player with the fewest losses, then by the
+	// lowest account id, so that the result does not depend on map order
+	for accountID, w := range wins {
+		l := losses[accountID]
+		better := w > stats.BestPlayerWins ||
+			(w == stats.BestPlayerWins && (l < stats.BestPlayerLosses ||
+				(l == stats.BestPlayerLosses && accountID < stats.BestPlayer)))
+		if stats.BestPlayer == 0 || better {
+			stats.BestPlayer = accountID
+			stats.BestPlayerWins = w
+			stats.BestPlayerLosses = l
+		}
+	}
+	return stats
+}
+
+// handleHeroCommand responds with the tournament stats of a hero
+func (bot *bot) handleHeroCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	names, err := bot.heroNames(ctx, format.language.code)
+	if err != nil {
+		return nil, err
+	}
+	query := in.stringOption("name")
+	heroID := findHero(names, query)
+	if heroID == 0 && format.language.code != defaultTextFormat.language.code {
+		// Heroes are often called by their English names regardless
+		englishNames, err := bot.heroNames(ctx, defaultTextFormat.language.code)
+		if err != nil {
+			return nil, err
+		}
+		heroID = findHero(englishNames, query)
+	}
+	if heroID == 0 {
+		return textResponse(fmt.Sprintf("Could not find a hero named %q.", query)), nil
+	}
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	stats := computeHeroStats(results, heroID)
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", heroName(names, heroID))
+	fmt.Fprintf(&b, "Picked %d, banned %d times\n", stats.Picks, stats.Bans)
+	if stats.Picks > 0 {
+		fmt.Fprintf(&b, "Win rate: %s%% (%s)\n", format.Decimal(100*float64(stats.Wins)/float64(stats.Picks), 0),
+			format.Score(stats.Wins, stats.Picks-stats.Wins))
+	}
+	if stats.BestPlayer != 0 {
+		name := fmt.Sprintf("Player %d", stats.BestPlayer)
+		players, err := bot.getProPlayers(ctx)
+		if err != nil {
+			bot.logger.WithError(err).Warn("Error getting pro players")
+		} else if player, ok := players[stats.BestPlayer]; ok && player.Name != "" {
+			name = player.Name
+		}
+		fmt.Fprintf(&b, "Most successful player: %s (%s)\n", name, format.Score(stats.BestPlayerWins, stats.BestPlayerLosses))
+	}
+	if stats.LastGame != nil {
+		fmt.Fprintf(&b, "Last played: %s vs. %s (match %d)\n",
+			stats.LastGame.WinnerName, stats.LastGame.LoserName, stats.LastGame.MatchID)
+	}
+	return textResponse(b.String()), nil
+}
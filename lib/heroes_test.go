@@ -0,0 +1,47 @@
+package timatch
+
+import "testing"
+
+func TestFindHero(t *testing.T) {
+	names := map[int]string{1: "Anti-Mage", 2: "Axe", 86: "Rubick"}
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"Anti-Mage", 1},
+		{"antimage", 1},
+		{" AXE ", 2},
+		{"rubick", 86},
+		{"anti", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := findHero(names, tt.query); got != tt.want {
+			t.Errorf("findHero(%q) = %d, want %d", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestComputeHeroStats(t *testing.T) {
+	results := []matchResult{
+		{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: 3}, Bans: []int{1}, Players: []resultPlayer{{AccountID: 10, HeroID: 2, Won: true}}},
+		{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: 2}, Players: []resultPlayer{{AccountID: 20, HeroID: 1, Won: false}, {AccountID: 30, HeroID: 2, Won: false}}},
+		{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: 1}, Bans: []int{1, 2}, Players: []resultPlayer{{AccountID: 20, HeroID: 1, Won: true}, {AccountID: 10, HeroID: 3, Won: true}}},
+		{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: 0}, Players: []resultPlayer{{AccountID: 40, HeroID: 1, Won: true}}},
+	}
+	stats := computeHeroStats(results, 1)
+	if stats.Picks != 3 || stats.Bans != 2 || stats.Wins != 2 {
+		t.Errorf("Picks, Bans, Wins = %d, %d, %d, want 3, 2, 2", stats.Picks, stats.Bans, stats.Wins)
+	}
+	// Both 20 and 40 have one win, 40 has no losses
+	if stats.BestPlayer != 40 || stats.BestPlayerWins != 1 || stats.BestPlayerLosses != 0 {
+		t.Errorf("BestPlayer = %d (%d - %d), want 40 (1 - 0)", stats.BestPlayer, stats.BestPlayerWins, stats.BestPlayerLosses)
+	}
+	if stats.LastGame == nil || stats.LastGame.MatchID != 2 {
+		t.Errorf("LastGame = %+v, want match 2", stats.LastGame)
+	}
+	stats = computeHeroStats(results, 4)
+	if stats.Picks != 0 || stats.BestPlayer != 0 || stats.LastGame != nil {
+		t.Errorf("computeHeroStats() of unplayed hero = %+v, want empty stats", stats)
+	}
+}
@@ -0,0 +1,156 @@
+package timatch
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// historyPageSize is the most messages Discord gives per request of the
+// messages of a channel
+const historyPageSize = 100
+
+// historyMarkerDelim delimits the markers of announcements
+const historyMarkerDelim = '\u2063'
+
+// historyMarkerChars are the characters of the text of a marker: the
+// index of the match state in historyStates, a colon and the
+// comma separated match ids, e.g. "1:5012345678"
+const historyMarkerChars = "0123456789:,"
+
+// historyMarkerSymbols are the invisible characters markers are written
+// with, each character of the text of a marker as two of them
+var historyMarkerSymbols = []rune{'\u200B', '\u200C', '\u200D', '\u2060'}
+
+// historyStates are the match states announcements are marked with
+var historyStates = []string{matchStateDrafting, matchStateStarted, matchStateFinished}
+
+// historyMarker returns the invisible marker of an announcement of event
+// for the games or results of data, so that the announced games can be
+// recovered from the channel by scanChannelHistory. Empty for events
+// without a match state or data without games.
+func historyMarker(event string, data interface{}) string {
+	state := -1
+	for i, s := range historyStates {
+		if s == event {
+			state = i
+		}
+	}
+	var matchIDs []string
+	switch data := data.(type) {
+	case []dota.LiveLeagueGame:
+		for _, game := range data {
+			matchIDs = append(matchIDs, strconv.FormatInt(game.MatchID, 10))
+		}
+	case []matchesFinishedDataItem:
+		for _, item := range data {
+			matchIDs = append(matchIDs, strconv.FormatInt(item.MatchID, 10))
+		}
+	}
+	if state < 0 || len(matchIDs) == 0 {
+		return ""
+	}
+	text := strconv.Itoa(state) + ":" + strings.Join(matchIDs, ",")
+	var marker strings.Builder
+	marker.WriteRune(historyMarkerDelim)
+	for _, c := range text {
+		i := strings.IndexRune(historyMarkerChars, c)
+		marker.WriteRune(historyMarkerSymbols[i/len(historyMarkerSymbols)])
+		marker.WriteRune(historyMarkerSymbols[i%len(historyMarkerSymbols)])
+	}
+	marker.WriteRune(historyMarkerDelim)
+	return marker.String()
+}
+
+// parseHistoryMarkers returns the match ids of the markers of a message,
+// by match state. Malformed markers are skipped.
+func parseHistoryMarkers(content string) map[string][]int64 {
+	matchIDs := make(map[string][]int64)
+	parts := strings.Split(content, string(historyMarkerDelim))
+	// Markers are every other part, as each is enclosed in delimiters
+	for i := 1; i < len(parts)-1; i += 2 {
+		symbols := []rune(parts[i])
+		if len(symbols)%2 != 0 {
+			continue
+		}
+		var text strings.Builder
+		for j := 0; j < len(symbols); j += 2 {
+			hi := indexRune(historyMarkerSymbols, symbols[j])
+			lo := indexRune(historyMarkerSymbols, symbols[j+1])
+			c := hi*len(historyMarkerSymbols) + lo
+			if hi < 0 || lo < 0 || c >= len(historyMarkerChars) {
+				text.Reset()
+				break
+			}
+			text.WriteByte(historyMarkerChars[c])
+		}
+		fields := strings.SplitN(text.String(), ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		state, err := strconv.Atoi(fields[0])
+		if err != nil || state < 0 || state >= len(historyStates) {
+			continue
+		}
+		for _, id := range strings.Split(fields[1], ",") {
+			if matchID, err := strconv.ParseInt(id, 10, 64); err == nil {
+				matchIDs[historyStates[state]] = append(matchIDs[historyStates[state]], matchID)
+			}
+		}
+	}
+	return matchIDs
+}
+
+func indexRune(runes []rune, r rune) int {
+	for i, c := range runes {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// scanChannelHistory records the games announced by the bot in the recent
+// messages of a channel as announced, by the markers of the messages, so
+// that a restart without the state of the bot does not repeat them.
+// Recorded through the store, as called from the Discord event handlers.
+func (bot *bot) scanChannelHistory(ctx context.Context, s *discordgo.Session, channelID channelID) {
+	logger := bot.logger.WithField(logFieldChannelID, channelID)
+	seeded := 0
+	beforeID := ""
+	for scanned := 0; scanned < bot.historyScan; {
+		limit := bot.historyScan - scanned
+		if limit > historyPageSize {
+			limit = historyPageSize
+		}
+		messages, err := s.ChannelMessages(string(channelID), limit, beforeID, "", "")
+		if err != nil {
+			logger.WithError(err).Warnf("Error getting the messages of channel %s", channelID)
+			return
+		}
+		for _, msg := range messages {
+			if msg.Author == nil || s.State.User == nil || msg.Author.ID != s.State.User.ID {
+				continue
+			}
+			for state, matchIDs := range parseHistoryMarkers(msg.Content) {
+				for _, matchID := range matchIDs {
+					bot.claimMatchState(ctx, state, matchID)
+					if bot.claimAnnouncement(ctx, state, matchID, channelID) {
+						seeded++
+					}
+				}
+			}
+		}
+		if len(messages) < limit {
+			break
+		}
+		scanned += len(messages)
+		beforeID = messages[len(messages)-1].ID
+	}
+	if seeded > 0 {
+		logger.Infof("Recorded %d announcements found in channel %s", seeded, channelID)
+	}
+}
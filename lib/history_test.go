@@ -0,0 +1,42 @@
+package timatch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestHistoryMarker(t *testing.T) {
+	games := []dota.LiveLeagueGame{{MatchID: 5012345678}, {MatchID: 5012345679}}
+	started := historyMarker(eventStarted, games)
+	if started == "" {
+		t.Fatal("expected a marker for started games")
+	}
+	if strings.ContainsAny(started, historyMarkerChars) {
+		t.Errorf("expected the marker to be invisible, got %q", started)
+	}
+	finished := historyMarker(eventFinished, []matchesFinishedDataItem{{MatchID: 5012340000}})
+	content := "OG vs. Team Liquid started" + started + "\nOG won" + finished
+	got := parseHistoryMarkers(content)
+	want := map[string][]int64{
+		matchStateStarted:  {5012345678, 5012345679},
+		matchStateFinished: {5012340000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestHistoryMarkerNoGames(t *testing.T) {
+	if marker := historyMarker(eventScoreUpdates, []dota.LiveLeagueGame{{MatchID: 1}}); marker != "" {
+		t.Errorf("expected no marker for score updates, got %q", marker)
+	}
+	if marker := historyMarker(eventStarted, []dota.LiveLeagueGame{}); marker != "" {
+		t.Errorf("expected no marker without games, got %q", marker)
+	}
+	if got := parseHistoryMarkers("no markers" + string(historyMarkerDelim) + "x" + string(historyMarkerDelim)); len(got) != 0 {
+		t.Errorf("expected no match ids from a malformed marker, got %v", got)
+	}
+}
@@ -0,0 +1,153 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// healthMaxPollAge is the maximum time since the last successful poll of
+// the Steam API before the bot is considered unhealthy
+const healthMaxPollAge = 5 * updateInterval
+
+// httpShutdownTimeout is the time given to the admin HTTP server to
+// finish ongoing requests when shutting down
+const httpShutdownTimeout = 5 * time.Second
+
+// health keeps track of the state reported by the /healthz endpoint. It
+// is updated by the run loop and read by the HTTP handlers.
+type health struct {
+	mu sync.RWMutex
+	// startedAt is the time the bot was started
+	startedAt time.Time
+	// lastSteamPoll is the time of the last successful poll of live games
+	lastSteamPoll time.Time
+	// finishedQueueLen is the length of the finished queue after the
+	// last update
+	finishedQueueLen int
+}
+
+type healthResponse struct {
+	OK                    bool    `json:"ok"`
+	DiscordConnected      bool    `json:"discord_connected"`
+	LastSteamPoll         string  `json:"last_steam_poll,omitempty"`
+	SecondsSinceSteamPoll float64 `json:"seconds_since_steam_poll"`
+	FinishedQueue         int     `json:"finished_queue"`
+	Channels              int     `json:"channels"`
+	Maintenance           bool    `json:"maintenance"`
+	// The counts of messages sent to Discord, retried after failing
+	// and dropped after failing for good, and of messages waiting
+	MessagesSent    int64 `json:"messages_sent"`
+	MessagesRetried int64 `json:"messages_retried"`
+	MessagesDropped int64 `json:"messages_dropped"`
+	SendQueue       int   `json:"send_queue"`
+}
+
+func (h *health) setSteamPolled() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSteamPoll = time.Now()
+}
+
+func (h *health) setFinishedQueueLen(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.finishedQueueLen = n
+}
+
+// newAdminMux creates the handler for the admin HTTP listener
+func (bot *bot) newAdminMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", bot.handleHealthz)
+	mux.HandleFunc("/report.md", bot.handleReport)
+	mux.HandleFunc("/report.html", bot.handleReport)
+	mux.HandleFunc("/apihealth", bot.handleAPIHealth)
+	mux.HandleFunc("/apihealth.html", bot.handleAPIHealth)
+	mux.HandleFunc("/maintenance", bot.handleMaintenance)
+	mux.HandleFunc("/export/", bot.handleExport)
+	if bot.pprof {
+		// Registered explicitly, as importing net/http/pprof only
+		// registers the handlers on http.DefaultServeMux
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// serveHTTP serves the admin HTTP endpoints on addr until ctx is done
+func (bot *bot) serveHTTP(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: bot.newAdminMux(),
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	bot.logger.Infof("Serving admin HTTP on %s", addr)
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "Error serving HTTP")
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return errors.Wrap(err, "Error shutting down HTTP server")
+	}
+	return ctx.Err()
+}
+
+// handleHealthz reports whether the bot is connected to Discord and
+// is successfully polling the Steam API. Responds with status 503 if
+// the bot is considered stuck.
+func (bot *bot) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	bot.discordSession.RLock()
+	discordConnected := bot.discordSession.DataReady
+	bot.discordSession.RUnlock()
+	bot.channelsMu.RLock()
+	numChannels := len(bot.channels)
+	bot.channelsMu.RUnlock()
+
+	bot.health.mu.RLock()
+	res := healthResponse{
+		DiscordConnected: discordConnected,
+		FinishedQueue:    bot.health.finishedQueueLen,
+		Channels:         numChannels,
+	}
+	lastPoll := bot.health.lastSteamPoll
+	if lastPoll.IsZero() {
+		// Measure from start, so that we are not reported as unhealthy
+		// before the first poll has had a chance to complete
+		lastPoll = bot.health.startedAt
+	} else {
+		res.LastSteamPoll = bot.health.lastSteamPoll.Format(time.RFC3339)
+	}
+	bot.health.mu.RUnlock()
+
+	sincePoll := time.Since(lastPoll)
+	res.SecondsSinceSteamPoll = sincePoll.Seconds()
+	sends := bot.sends.getStats()
+	res.MessagesSent, res.MessagesRetried, res.MessagesDropped = sends.Sent, sends.Retried, sends.Dropped
+	res.SendQueue = sends.Queued
+	res.Maintenance = bot.maintenance.active(time.Now())
+	// Failing polls are expected during maintenance, and should not get
+	// the bot restarted
+	res.OK = discordConnected && (sincePoll <= healthMaxPollAge || res.Maintenance)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !res.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		bot.logger.WithError(err).Error("Error writing health response")
+	}
+}
@@ -0,0 +1,223 @@
+package timatch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// Kinds of in-game hype alerts, see Config.HypeAlerts
+const (
+	// hypeKills is the total kills of a game reaching a milestone
+	hypeKills = "kills"
+	// hypeTied is a game still being even late in the game
+	hypeTied = "tied"
+	// hypeComeback is a team taking the kill lead after being far behind
+	hypeComeback = "comeback"
+	// hypeRapier is a player getting a Divine Rapier
+	hypeRapier = "rapier"
+	// hypeMegaCreeps is a team losing its last barracks, giving the
+	// other team mega creeps
+	hypeMegaCreeps = "megacreeps"
+	// hypeRoshan is Roshan being taken late in the game
+	hypeRoshan = "roshan"
+)
+
+var hypeAlertKinds = []string{hypeKills, hypeTied, hypeComeback, hypeRapier, hypeMegaCreeps, hypeRoshan}
+
+// Thresholds of the hype alerts
+const (
+	// hypeKillStep is the kills between kill milestones, from
+	// hypeMinKills
+	hypeKillStep = 25
+	hypeMinKills = 50
+	// hypeTiedAfter is the game time, in seconds, after which an even
+	// game is announced, and hypeTiedMargin the largest net worth lead
+	// for which the game is even
+	hypeTiedAfter  = 50 * 60
+	hypeTiedMargin = 3000
+	// hypeComebackDeficit is the kill deficit a team must come back from
+	hypeComebackDeficit = 10
+	// hypeRoshanAfter is the game time, in seconds, after which Roshan
+	// being taken is announced
+	hypeRoshanAfter = 35 * 60
+)
+
+// parseHypeAlerts parses the kinds of hype alerts to send
+func parseHypeAlerts(kinds []string) (map[string]bool, error) {
+	alerts := make(map[string]bool)
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" {
+			continue
+		}
+		known := false
+		for _, k := range hypeAlertKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return nil, errors.Errorf("Unknown hype alert %q, want one of %s", kind, strings.Join(hypeAlertKinds, ", "))
+		}
+		alerts[kind] = true
+	}
+	return alerts, nil
+}
+
+// hypeState is what has been alerted of a live game
+type hypeState struct {
+	killMark int
+	tied     bool
+	// maxDeficit is the largest kill deficit of the radiant and the dire
+	// team since the last comeback
+	maxDeficit [2]int
+	// rapiers are the heroes carrying a Divine Rapier
+	rapiers map[int]bool
+	// barracks are the barracks states of the radiant and the dire team
+	barracks [2]int
+	// roshanAlive is true if Roshan was alive
+	roshanAlive bool
+}
+
+// hypeAlert is an alert of something exciting happening in a live game
+type hypeAlert struct {
+	Kind string
+	Game dota.LiveLeagueGame
+	// Kills is the kill milestone reached, or the deficit come back from
+	Kills int
+	// Radiant is true if the radiant team made the comeback, has the
+	// hero with the rapier or got mega creeps
+	Radiant bool
+	// HeroID is the hero that got a rapier
+	HeroID int
+}
+
+// checkHype returns the hype alerts of a live game since it was last
+// checked. Games first seen mid-game, e.g. after a restart, only get
+// alerts for what happens from then on.
+func (bot *bot) checkHype(game dota.LiveLeagueGame) []hypeAlert {
+	if len(bot.hypeAlerts) == 0 || !isGameStarted(game) {
+		return nil
+	}
+	scoreboard := game.Scoreboard
+	kills := scoreboard.Radiant.Score + scoreboard.Dire.Score
+	lead := scoreboard.Radiant.Score - scoreboard.Dire.Score
+	netWorthLead := 0
+	for _, player := range scoreboard.Radiant.Players {
+		netWorthLead += player.NetWorth
+	}
+	for _, player := range scoreboard.Dire.Players {
+		netWorthLead -= player.NetWorth
+	}
+	tied := int(scoreboard.Duration) >= hypeTiedAfter && len(scoreboard.Radiant.Players) > 0 &&
+		netWorthLead < hypeTiedMargin && -netWorthLead < hypeTiedMargin
+	teamPlayers := [][]dota.LiveLeagueGameScoreboardPlayer{scoreboard.Radiant.Players, scoreboard.Dire.Players}
+	rapiers := make(map[int]bool)
+	for _, players := range teamPlayers {
+		for _, player := range players {
+			if player.HasItem(dota.ItemDivineRapier) {
+				rapiers[player.HeroID] = true
+			}
+		}
+	}
+	barracks := [2]int{scoreboard.Radiant.BarracksState, scoreboard.Dire.BarracksState}
+	roshanAlive := scoreboard.RoshanRespawnTimer != nil && *scoreboard.RoshanRespawnTimer == 0
+	state, seen := bot.hype[game.MatchID]
+	if !seen {
+		bot.hype[game.MatchID] = &hypeState{killMark: kills / hypeKillStep, tied: tied, rapiers: rapiers, barracks: barracks, roshanAlive: roshanAlive}
+		return nil
+	}
+	var alerts []hypeAlert
+	if mark := kills / hypeKillStep; mark > state.killMark {
+		state.killMark = mark
+		if bot.hypeAlerts[hypeKills] && mark*hypeKillStep >= hypeMinKills {
+			alerts = append(alerts, hypeAlert{Kind: hypeKills, Game: game, Kills: mark * hypeKillStep})
+		}
+	}
+	if tied && !state.tied {
+		state.tied = true
+		if bot.hypeAlerts[hypeTied] {
+			alerts = append(alerts, hypeAlert{Kind: hypeTied, Game: game})
+		}
+	}
+	for i, radiant := range []bool{true, false} {
+		deficit := -lead
+		if !radiant {
+			deficit = lead
+		}
+		if deficit > state.maxDeficit[i] {
+			state.maxDeficit[i] = deficit
+		}
+		if deficit < 0 && state.maxDeficit[i] >= hypeComebackDeficit {
+			if bot.hypeAlerts[hypeComeback] {
+				alerts = append(alerts, hypeAlert{Kind: hypeComeback, Game: game, Kills: state.maxDeficit[i], Radiant: radiant})
+			}
+			state.maxDeficit[i] = 0
+		}
+	}
+	if bot.hypeAlerts[hypeRapier] {
+		for i, players := range teamPlayers {
+			for _, player := range players {
+				if rapiers[player.HeroID] && !state.rapiers[player.HeroID] {
+					alerts = append(alerts, hypeAlert{Kind: hypeRapier, Game: game, Radiant: i == 0, HeroID: player.HeroID})
+				}
+			}
+		}
+	}
+	state.rapiers = rapiers
+	for i, lost := range barracks {
+		// A barracks state of 0 after having barracks, rather than
+		// the state not being given
+		if lost == 0 && state.barracks[i] != 0 && bot.hypeAlerts[hypeMegaCreeps] {
+			alerts = append(alerts, hypeAlert{Kind: hypeMegaCreeps, Game: game, Radiant: i == 1})
+		}
+	}
+	state.barracks = barracks
+	roshanTaken := state.roshanAlive && scoreboard.RoshanRespawnTimer != nil && *scoreboard.RoshanRespawnTimer > 0
+	if roshanTaken && int(scoreboard.Duration) >= hypeRoshanAfter && bot.hypeAlerts[hypeRoshan] {
+		alerts = append(alerts, hypeAlert{Kind: hypeRoshan, Game: game})
+	}
+	state.roshanAlive = roshanAlive
+	return alerts
+}
+
+// renderHypeAlert renders a hype alert as a short message
+func renderHypeAlert(alert hypeAlert, format textFormat) string {
+	game := alert.Game
+	radiant, dire := game.RadiantTeam.TeamName, game.DireTeam.TeamName
+	duration := formatDuration(game.Scoreboard.Duration)
+	switch alert.Kind {
+	case hypeKills:
+		return fmt.Sprintf("🔥 %s vs. %s has reached %d kills at %s!", radiant, dire, alert.Kills, duration)
+	case hypeTied:
+		return fmt.Sprintf("⚖️ %s vs. %s is still even after %d minutes!", radiant, dire, hypeTiedAfter/60)
+	case hypeComeback:
+		team, opponent := radiant, dire
+		score := format.Score(game.Scoreboard.Radiant.Score, game.Scoreboard.Dire.Score)
+		if !alert.Radiant {
+			team, opponent = dire, radiant
+			score = format.Score(game.Scoreboard.Dire.Score, game.Scoreboard.Radiant.Score)
+		}
+		return fmt.Sprintf("🔄 %s came back from %d kills down against %s and leads %s at %s!", team, alert.Kills, opponent, score, duration)
+	case hypeRapier:
+		team, opponent := radiant, dire
+		if !alert.Radiant {
+			team, opponent = dire, radiant
+		}
+		hero, ok := format.heroNames[alert.HeroID]
+		if !ok {
+			hero = "a hero"
+		}
+		return fmt.Sprintf("⚔️ Divine Rapier! %s of %s has a Rapier against %s at %s!", hero, team, opponent, duration)
+	case hypeMegaCreeps:
+		team, opponent := radiant, dire
+		if !alert.Radiant {
+			team, opponent = dire, radiant
+		}
+		return fmt.Sprintf("🏚️ %s took the last barracks of %s and have mega creeps at %s!", team, opponent, duration)
+	case hypeRoshan:
+		return fmt.Sprintf("🐉 Roshan has been taken in %s vs. %s at %s!", radiant, dire, duration)
+	}
+	return ""
+}
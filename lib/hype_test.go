@@ -0,0 +1,131 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestCheckHype(t *testing.T) {
+	alerts, err := parseHypeAlerts([]string{"kills", " Comeback", ""})
+	if err != nil {
+		t.Fatalf("parseHypeAlerts() error: %v", err)
+	}
+	if _, err := parseHypeAlerts([]string{"aegis"}); err == nil {
+		t.Error("parseHypeAlerts(aegis), want error")
+	}
+	bot := &bot{hypeAlerts: alerts, hype: make(map[int64]*hypeState)}
+	game := func(radiant, dire int, minutes float32) dota.LiveLeagueGame {
+		game := dota.LiveLeagueGame{
+			MatchID:     1,
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+		}
+		game.Scoreboard.Duration = minutes * 60
+		game.Scoreboard.Radiant.Score = radiant
+		game.Scoreboard.Dire.Score = dire
+		return game
+	}
+	if got := bot.checkHype(game(5, 15, 20)); len(got) != 0 {
+		t.Errorf("checkHype() of first seen game = %v, want none", got)
+	}
+	if got := bot.checkHype(game(14, 26, 30)); len(got) != 0 {
+		t.Errorf("checkHype() = %v, want none", got)
+	}
+	got := bot.checkHype(game(27, 25, 40))
+	if len(got) != 2 || got[0].Kind != hypeKills || got[0].Kills != 50 || got[1].Kind != hypeComeback || got[1].Kills != 12 {
+		t.Fatalf("checkHype() = %+v, want 50 kills and a comeback from 12 down", got)
+	}
+	if got, want := renderHypeAlert(got[1], defaultTextFormat), "🔄 OG came back from 12 kills down against Liquid and leads 27 - 25 at 40:00!"; got != want {
+		t.Errorf("renderHypeAlert() = %q, want %q", got, want)
+	}
+	if got := bot.checkHype(game(28, 26, 41)); len(got) != 0 {
+		t.Errorf("checkHype() after the comeback = %v, want none", got)
+	}
+}
+
+func TestCheckHypeRapier(t *testing.T) {
+	bot := &bot{hypeAlerts: map[string]bool{hypeRapier: true}, hype: make(map[int64]*hypeState)}
+	game := dota.LiveLeagueGame{
+		MatchID:     1,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+	}
+	game.Scoreboard.Duration = 45 * 60
+	game.Scoreboard.Radiant.Players = []dota.LiveLeagueGameScoreboardPlayer{{HeroID: 129}}
+	game.Scoreboard.Dire.Players = []dota.LiveLeagueGameScoreboardPlayer{{HeroID: 1}}
+	bot.checkHype(game)
+	game.Scoreboard.Dire.Players[0].Item3 = dota.ItemDivineRapier
+	got := bot.checkHype(game)
+	if len(got) != 1 || got[0].Kind != hypeRapier || got[0].Radiant || got[0].HeroID != 1 {
+		t.Fatalf("checkHype() = %+v, want a dire rapier", got)
+	}
+	format := defaultTextFormat
+	format.heroNames = map[int]string{1: "Anti-Mage"}
+	if got, want := renderHypeAlert(got[0], format), "⚔️ Divine Rapier! Anti-Mage of Liquid has a Rapier against OG at 45:00!"; got != want {
+		t.Errorf("renderHypeAlert() = %q, want %q", got, want)
+	}
+	if got := bot.checkHype(game); len(got) != 0 {
+		t.Errorf("checkHype() of the same rapier = %v, want none", got)
+	}
+}
+
+func TestCheckHypeMegaCreeps(t *testing.T) {
+	bot := &bot{hypeAlerts: map[string]bool{hypeMegaCreeps: true}, hype: make(map[int64]*hypeState)}
+	game := dota.LiveLeagueGame{
+		MatchID:     1,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+	}
+	game.Scoreboard.Duration = 41 * 60
+	game.Scoreboard.Radiant.BarracksState = dota.BarracksAll
+	game.Scoreboard.Dire.BarracksState = dota.BarracksMidMelee | dota.BarracksMidRanged
+	bot.checkHype(game)
+	game.Scoreboard.Dire.BarracksState = 0
+	got := bot.checkHype(game)
+	if len(got) != 1 || got[0].Kind != hypeMegaCreeps || !got[0].Radiant {
+		t.Fatalf("checkHype() = %+v, want mega creeps for radiant", got)
+	}
+	if got, want := renderHypeAlert(got[0], defaultTextFormat), "🏚️ OG took the last barracks of Liquid and have mega creeps at 41:00!"; got != want {
+		t.Errorf("renderHypeAlert() = %q, want %q", got, want)
+	}
+	if got := bot.checkHype(game); len(got) != 0 {
+		t.Errorf("checkHype() after mega creeps = %v, want none", got)
+	}
+}
+
+func TestCheckHypeRoshan(t *testing.T) {
+	bot := &bot{hypeAlerts: map[string]bool{hypeRoshan: true}, hype: make(map[int64]*hypeState)}
+	game := func(minutes float32, roshanTimer int) dota.LiveLeagueGame {
+		game := dota.LiveLeagueGame{MatchID: 1, RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"}, DireTeam: dota.LiveLeagueGamesTeam{TeamName: "Liquid"}}
+		game.Scoreboard.Duration = minutes * 60
+		game.Scoreboard.RoshanRespawnTimer = &roshanTimer
+		return game
+	}
+	bot.checkHype(game(20, 0))
+	// Taken too early to be announced
+	if got := bot.checkHype(game(21, 660)); len(got) != 0 {
+		t.Errorf("checkHype() of early Roshan = %v, want none", got)
+	}
+	bot.checkHype(game(40, 0))
+	got := bot.checkHype(game(41, 660))
+	if len(got) != 1 || got[0].Kind != hypeRoshan {
+		t.Fatalf("checkHype() = %+v, want Roshan taken", got)
+	}
+	if got, want := renderHypeAlert(got[0], defaultTextFormat), "🐉 Roshan has been taken in OG vs. Liquid at 41:00!"; got != want {
+		t.Errorf("renderHypeAlert() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderScoreUpdateRoshan(t *testing.T) {
+	game := dota.LiveLeagueGame{RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"}, DireTeam: dota.LiveLeagueGamesTeam{TeamName: "Liquid"}, GameNumber: 1}
+	updates := []scoreUpdate{{Game: game, RadiantScore: 12, DireScore: 8, Duration: "25:00", RoshanRespawn: "5:12"}}
+	got, err := renderTemplate(tmplScoreUpdates, defaultTextFormat, updates)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "Score Update: OG 12 - 8 Liquid (25:00, Game 1), Roshan up in 5:12"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+package timatch
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// messageLanguage is a language announcements can be sent in
+type messageLanguage struct {
+	// code is the ISO 639-1 code of the language, as used by the Steam
+	// API for localized hero names
+	code string
+	name string
+	// templates are the translations of the announcement templates, by
+	// template name. Templates without a translation are sent in English
+	templates map[string]*template.Template
+	// heroNames and teamNames are the names of heroes and teams of
+	// language bundles, see LanguageBundle. Hero names of the built-in
+	// languages are fetched from the Steam API
+	heroNames map[int]string
+	teamNames map[string]string
+}
+
+// messageLanguages are the languages a guild or channel can choose from.
+// The first one is the default. Language bundles are appended on startup.
+var messageLanguages = []messageLanguage{
+	{code: "en", name: "English"},
+	{code: "ru", name: "Русский", templates: templateCatalog(
+		tmplMatchesDraftingRU,
+		tmplMatchesStartedRU,
+		tmplScoreUpdatesRU,
+		tmplMatchesFinishedRU,
+		tmplFloodDigestRU,
+	)},
+}
+
+// templateCatalog returns the templates by name
+func templateCatalog(tmpls ...*template.Template) map[string]*template.Template {
+	catalog := make(map[string]*template.Template, len(tmpls))
+	for _, tmpl := range tmpls {
+		catalog[tmpl.Name()] = tmpl
+	}
+	return catalog
+}
+
+func findMessageLanguage(code string) (messageLanguage, bool) {
+	for _, language := range messageLanguages {
+		if strings.EqualFold(language.code, code) {
+			return language, true
+		}
+	}
+	return messageLanguage{}, false
+}
+
+// localize returns the translation of tmpl into the language, or tmpl if
+// it has not been translated
+func (language messageLanguage) localize(tmpl *template.Template) *template.Template {
+	if translated, ok := language.templates[tmpl.Name()]; ok {
+		return translated
+	}
+	return tmpl
+}
+
+// messageLanguageExamples lists the languages, e.g. "en (English)"
+func messageLanguageExamples() string {
+	examples := make([]string, 0, len(messageLanguages))
+	for _, language := range messageLanguages {
+		examples = append(examples, language.code+" ("+language.name+")")
+	}
+	return strings.Join(examples, ", ")
+}
+
+// language returns the language of the announcements to a channel of the
+// guild: the language of the channel if set, or else that of the guild
+func (settings *guildSettings) language(channelID string) messageLanguage {
+	if code, ok := settings.ChannelLanguages[channelID]; ok {
+		if language, ok := findMessageLanguage(code); ok {
+			return language
+		}
+	}
+	if language, ok := findMessageLanguage(settings.Language); ok {
+		return language
+	}
+	return messageLanguages[0]
+}
+
+var tmplMatchesDraftingRU = template.Must(newTemplate("MatchesDrafting").Parse(strings.TrimSpace(`
+{{ range . }}
+Драфт: {{ .RadiantTeam.TeamName }} против {{ .DireTeam.TeamName }} ({{ with .Stage }}{{ . }}, {{ end }}игра {{ .GameNumber }})
+{{- end -}}
+`)))
+
+var tmplMatchesStartedRU = template.Must(newTemplate("MatchesStarted").Parse(strings.TrimSpace(`
+{{ range . }}
+Матч начался: {{ .RadiantTeam.TeamName }} против {{ .DireTeam.TeamName }} ({{ with .Stage }}{{ . }}, {{ end }}игра {{ .GameNumber }}{{ with clock }}, {{ . }}{{ end }})
+{{- with lineup .RadiantTeam .RadiantPlayers }}
+{{ . }}{{ end }}
+{{- with lineup .DireTeam .DirePlayers }}
+{{ . }}{{ end }}
+{{- end -}}
+`)))
+
+var tmplScoreUpdatesRU = template.Must(newTemplate("ScoreUpdates").Parse(strings.TrimSpace(`
+{{ range . }}
+Счёт: {{ .Game.RadiantTeam.TeamName }} {{ score .RadiantScore .DireScore }} {{ .Game.DireTeam.TeamName }} ({{ .Duration }}, игра {{ .Game.GameNumber }}){{ if .Favored }}, шансы {{ .Favored }} {{ .WinChance }}%{{ end }}
+{{- if .RoshanAlive }}, Рошан жив{{ else if .RoshanRespawn }}, Рошан через {{ .RoshanRespawn }}{{ end }}
+{{- end -}}
+`)))
+
+var tmplMatchesFinishedRU = template.Must(newTemplate("MatchesFinished").Parse(strings.TrimSpace(`
+{{ range . }}
+{{- if .Remake }}
+Переигровка: {{ .FirstTeam }} против {{ .SecondTeam }} (игра {{ .GameNumber }}) закончилась досрочно и будет переиграна
+{{- else }}
+Матч окончен{{ if .Delayed }} (с опозданием){{ end }}: {{ if winners }}победа {{ .WinnerName }} над {{ .LoserName }}{{ else }}{{ .FirstTeam }} против {{ .SecondTeam }}{{ end }} ({{ if kills }}{{ score .WinnerScore .LoserScore }}, {{ end }}{{ with .Stage }}{{ . }}, {{ end }}игра {{ .GameNumber }}{{ with clock }}, {{ . }}{{ end }})
+{{- if and winners .UpsetWinnerSeed }}
+Сенсация! Посев №{{ .UpsetWinnerSeed }} обыграл посев №{{ .UpsetLoserSeed }}{{ end }}
+{{- end }}
+{{- end -}}
+`)))
+
+var tmplFloodDigestRU = template.Must(newTemplate("FloodDigest").Parse(strings.TrimSpace(`
+{{ if .Started }}Другие игры, начавшиеся за последний час:
+{{- range .Started }}
+- {{ .RadiantTeam.TeamName }} против {{ .DireTeam.TeamName }} (игра {{ .GameNumber }})
+{{- end }}
+{{ end }}
+{{- if .Finished }}Другие игры, завершившиеся за последний час:
+{{- range .Finished }}
+- {{ if winners }}Победа {{ .WinnerName }} над {{ .LoserName }}{{ else }}{{ .FirstTeam }} против {{ .SecondTeam }}{{ end }} ({{ if kills }}{{ score .WinnerScore .LoserScore }}, {{ end }}игра {{ .GameNumber }})
+{{- end }}
+{{- end -}}
+`)))
+
+// LanguageBundle is a language added at startup, e.g. by a community
+// translator, without changing the bot
+type LanguageBundle struct {
+	// Code identifies the language in /settings language, and is passed
+	// to the Steam API for hero names not in HeroNames
+	Code string
+	Name string
+	// Templates are the translated templates, as template text by the
+	// names listed by PreviewTemplateNames. Templates without a
+	// translation are sent in English
+	Templates map[string]string
+	// HeroNames are the names of heroes by hero id, and TeamNames the
+	// names of teams by their English name, as used by the team
+	// template function
+	HeroNames map[int]string
+	TeamNames map[string]string
+}
+
+// addLanguageBundles parses the bundles, validating the templates as
+// parseCustomTemplates, and adds them to messageLanguages
+func addLanguageBundles(bundles []LanguageBundle) error {
+	for _, bundle := range bundles {
+		if bundle.Code == "" {
+			return errors.New("Language bundle without a code")
+		}
+		if _, ok := findMessageLanguage(bundle.Code); ok {
+			return errors.Errorf("Language %s already exists", bundle.Code)
+		}
+		templates := make(map[string]*template.Template, len(bundle.Templates))
+		for name, text := range bundle.Templates {
+			replaced, tmpl, err := parseCustomTemplate(name, text)
+			if err != nil {
+				return errors.Wrapf(err, "Error in language %s", bundle.Code)
+			}
+			templates[replaced.Name()] = tmpl
+		}
+		name := bundle.Name
+		if name == "" {
+			name = bundle.Code
+		}
+		messageLanguages = append(messageLanguages, messageLanguage{
+			code:      strings.ToLower(bundle.Code),
+			name:      name,
+			templates: templates,
+			heroNames: bundle.HeroNames,
+			teamNames: bundle.TeamNames,
+		})
+	}
+	return nil
+}
+
+// teamName returns the name of a team in the language, given its English
+// name
+func (language messageLanguage) teamName(name string) string {
+	if translated, ok := language.teamNames[name]; ok {
+		return translated
+	}
+	return name
+}
@@ -0,0 +1,98 @@
+package timatch
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSettingsLanguage(t *testing.T) {
+	settings := &guildSettings{Language: "ru", ChannelLanguages: map[string]string{"1": "en", "2": "unknown"}}
+	tests := []struct {
+		channelID string
+		want      string
+	}{
+		{"", "ru"},
+		{"1", "en"},
+		{"2", "ru"},
+		{"3", "ru"},
+	}
+	for _, tt := range tests {
+		if got := settings.language(tt.channelID).code; got != tt.want {
+			t.Errorf("language(%q) = %q, want %q", tt.channelID, got, tt.want)
+		}
+	}
+	if got := (&guildSettings{}).language("1").code; got != "en" {
+		t.Errorf("language() by default = %q, want en", got)
+	}
+}
+
+func TestTemplateCatalogs(t *testing.T) {
+	english := templateCatalog(tmplMatchesDrafting, tmplMatchesStarted, tmplScoreUpdates, tmplMatchesFinished, tmplFloodDigest)
+	for _, language := range messageLanguages {
+		for name := range language.templates {
+			if _, ok := english[name]; !ok {
+				t.Errorf("%s template %q does not translate any template", language.code, name)
+			}
+		}
+	}
+}
+
+func TestRenderTemplateLanguage(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 1, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17}}
+	settings := &guildSettings{ScoreStyle: "colon", ChannelLanguages: map[string]string{"1": "ru"}}
+	got, err := renderTemplate(tmplMatchesFinished, settings.channelTextFormat("1"), items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "Матч окончен: победа OG над Liquid (32:17, игра 1)"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() in ru = %q, want %q", got, want)
+	}
+	got, err = renderTemplate(tmplMatchesFinished, settings.channelTextFormat("2"), items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if want := "Match Ended: OG defeated Liquid (32:17, Game 1)"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() in en = %q, want %q", got, want)
+	}
+}
+
+func TestAddLanguageBundles(t *testing.T) {
+	defer func(languages []messageLanguage) { messageLanguages = languages }(messageLanguages)
+	err := addLanguageBundles([]LanguageBundle{{
+		Code: "de",
+		Name: "Deutsch",
+		Templates: map[string]string{
+			"finished": "{{ range . }}Sieg für {{ team .WinnerName }}{{ end }}",
+		},
+		HeroNames: map[int]string{1: "Antimagier"},
+		TeamNames: map[string]string{"Team Secret": "Team Geheimnis"},
+	}})
+	if err != nil {
+		t.Fatalf("addLanguageBundles() error: %v", err)
+	}
+	format := (&guildSettings{Language: "de"}).textFormat()
+	items := []matchesFinishedDataItem{{WinnerName: "Team Secret"}}
+	got, err := renderTemplate(tmplMatchesFinished, format, items)
+	if err != nil {
+		t.Fatalf("renderTemplate() error: %v", err)
+	}
+	if want := "Sieg für Team Geheimnis"; strings.TrimSpace(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+	bot := &bot{}
+	if names, err := bot.heroNames(context.Background(), "de"); err != nil || names[1] != "Antimagier" {
+		t.Errorf("heroNames(de) = %v, %v, want the bundled names", names, err)
+	}
+
+	tests := [][]LanguageBundle{
+		{{Code: ""}},
+		{{Code: "ru"}},
+		{{Code: "sv", Templates: map[string]string{"finished": "{{ .Nope }}"}}},
+	}
+	for _, bundles := range tests {
+		if err := addLanguageBundles(bundles); err == nil {
+			t.Errorf("addLanguageBundles(%+v) = no error, want error", bundles)
+		}
+	}
+}
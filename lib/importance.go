@@ -0,0 +1,142 @@
+package timatch
+
+import (
+	"github.com/verath/timatch/lib/dota"
+)
+
+// Importance scores range from 0 to maxImportance. The score of a game
+// is the sum of the components below, capped at maxImportance.
+const (
+	maxImportance = 100
+	// importanceBase is the score of any game
+	importanceBase = 10
+	// importanceNotable is added if a notable team is playing
+	importanceNotable = 40
+	// importanceBestOf3 and importanceBestOf5 are added for longer
+	// series, best of 5 typically only being played in grand finals
+	importanceBestOf3 = 10
+	importanceBestOf5 = 30
+	// importanceSpectatorsPer and importanceMaxSpectators make up the
+	// "hype" component, rewarding games with many in-client spectators
+	importanceSpectatorsPer = 2000
+	importanceMaxSpectators = 20
+	// importancePlayoffs is added for playoff series, importanceFinal
+	// instead for the series of the last round of a bracket
+	importancePlayoffs = 10
+	importanceFinal    = 20
+)
+
+// Series types, as reported in the series_type field
+const (
+	seriesTypeBestOf1 = 0
+	seriesTypeBestOf3 = 1
+	seriesTypeBestOf5 = 2
+)
+
+// gameImportance computes the importance score of a live game.
+func (bot *bot) gameImportance(game dota.LiveLeagueGame) int {
+	score := importanceBase
+	if bot.isNotableTeam(game.RadiantTeam.TeamID, game.DireTeam.TeamID) {
+		score += importanceNotable
+	}
+	switch game.SeriesType {
+	case seriesTypeBestOf3:
+		score += importanceBestOf3
+	case seriesTypeBestOf5:
+		score += importanceBestOf5
+	}
+	hype := game.Spectators / importanceSpectatorsPer
+	if hype > importanceMaxSpectators {
+		hype = importanceMaxSpectators
+	}
+	score += hype
+	bot.bracket.mu.Lock()
+	groups := bot.bracket.groups
+	bot.bracket.mu.Unlock()
+	score += stageImportance(groups, game)
+	if score > maxImportance {
+		score = maxImportance
+	}
+	return score
+}
+
+// stageImportance returns the bracket stage component of the importance
+// of a game, given the playoff bracket node groups of the league.
+func stageImportance(groups []dota.LeagueNodeGroup, game dota.LiveLeagueGame) int {
+	for _, group := range groups {
+		rounds := bracketRounds(group)
+		for i, round := range rounds {
+			for _, node := range round {
+				if !isSeriesNode(node, game) {
+					continue
+				}
+				if i == len(rounds)-1 {
+					return importanceFinal
+				}
+				return importancePlayoffs
+			}
+		}
+	}
+	return 0
+}
+
+// isSeriesNode tests if a bracket node is the series of a game. Nodes
+// are matched by series id, falling back to the teams of uncompleted
+// nodes as the series id is not always known.
+func isSeriesNode(node dota.LeagueNode, game dota.LiveLeagueGame) bool {
+	if game.SeriesID != 0 && node.SeriesID == game.SeriesID {
+		return true
+	}
+	radiant, dire := game.RadiantTeam.TeamID, game.DireTeam.TeamID
+	if node.IsCompleted || radiant == 0 || dire == 0 {
+		return false
+	}
+	return (node.TeamID1 == radiant && node.TeamID2 == dire) ||
+		(node.TeamID1 == dire && node.TeamID2 == radiant)
+}
+
+// updateImportance computes and records the importance of a live game.
+// The recorded importance never decreases, so that a game's finished
+// announcement is routed at least as widely as its started announcement.
+func (bot *bot) updateImportance(game dota.LiveLeagueGame) {
+	importance := bot.gameImportance(game)
+	if importance > bot.matchImportance[game.MatchID] {
+		bot.matchImportance[game.MatchID] = importance
+	}
+}
+
+// finishedImportance returns the importance of a finished game. Falls
+// back to a score based on the teams alone if the game was not seen live.
+func (bot *bot) finishedImportance(matchID int64, teamIDs ...int) int {
+	if importance, ok := bot.matchImportance[matchID]; ok {
+		return importance
+	}
+	if bot.isNotableTeam(teamIDs...) {
+		return importanceBase + importanceNotable
+	}
+	return importanceBase
+}
+
+// filterImportantGames returns the games with an importance of at least
+// minImportance.
+func (bot *bot) filterImportantGames(games []dota.LiveLeagueGame, minImportance int) []dota.LiveLeagueGame {
+	important := make([]dota.LiveLeagueGame, 0, len(games))
+	for _, game := range games {
+		if bot.matchImportance[game.MatchID] >= minImportance {
+			important = append(important, game)
+		}
+	}
+	return important
+}
+
+// filterImportantFinished is the filterImportantGames equivalent for
+// finished games.
+func filterImportantFinished(items []matchesFinishedDataItem, minImportance int) []matchesFinishedDataItem {
+	important := make([]matchesFinishedDataItem, 0, len(items))
+	for _, item := range items {
+		if item.Importance >= minImportance {
+			important = append(important, item)
+		}
+	}
+	return important
+}
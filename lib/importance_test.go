@@ -0,0 +1,64 @@
+package timatch
+
+import (
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestGameImportance(t *testing.T) {
+	bot := &bot{notableTeams: map[int]struct{}{1: {}}}
+	bot.bracket.groups = []dota.LeagueNodeGroup{testBracket}
+	game := func(radiant, dire int, seriesID int64, seriesType, spectators int) dota.LiveLeagueGame {
+		return dota.LiveLeagueGame{
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: radiant},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamID: dire},
+			SeriesID:    seriesID,
+			SeriesType:  seriesType,
+			Spectators:  spectators,
+		}
+	}
+	tests := []struct {
+		name string
+		game dota.LiveLeagueGame
+		want int
+	}{
+		{"base", game(5, 6, 0, seriesTypeBestOf1, 0), importanceBase},
+		{"notable", game(6, 1, 0, seriesTypeBestOf1, 0), importanceBase + importanceNotable},
+		{"best of 3", game(5, 6, 0, seriesTypeBestOf3, 0), importanceBase + importanceBestOf3},
+		{"spectators", game(5, 6, 0, seriesTypeBestOf1, 5*importanceSpectatorsPer), importanceBase + 5},
+		{"max spectators", game(5, 6, 0, seriesTypeBestOf1, 1000*importanceSpectatorsPer), importanceBase + importanceMaxSpectators},
+		{"playoffs", game(5, 6, 20, seriesTypeBestOf3, 0), importanceBase + importanceBestOf3 + importancePlayoffs},
+		{"final by teams", game(3, 1, 0, seriesTypeBestOf5, 0), importanceBase + importanceNotable + importanceBestOf5 + importanceFinal},
+		{"capped", game(3, 1, 30, seriesTypeBestOf5, 1000*importanceSpectatorsPer), maxImportance},
+	}
+	for _, tt := range tests {
+		if got := bot.gameImportance(tt.game); got != tt.want {
+			t.Errorf("gameImportance(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsSeriesNode(t *testing.T) {
+	node := dota.LeagueNode{TeamID1: 1, TeamID2: 2, SeriesID: 10}
+	game := func(radiant, dire int, seriesID int64) dota.LiveLeagueGame {
+		return dota.LiveLeagueGame{
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: radiant},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamID: dire},
+			SeriesID:    seriesID,
+		}
+	}
+	if !isSeriesNode(node, game(0, 0, 10)) {
+		t.Error("isSeriesNode() by series id = false, want true")
+	}
+	if !isSeriesNode(node, game(2, 1, 0)) {
+		t.Error("isSeriesNode() by teams = false, want true")
+	}
+	if isSeriesNode(node, game(1, 3, 0)) {
+		t.Error("isSeriesNode() of other teams = true, want false")
+	}
+	node.IsCompleted = true
+	if isSeriesNode(node, game(1, 2, 0)) {
+		t.Error("isSeriesNode() of completed node by teams = true, want false")
+	}
+}
@@ -0,0 +1,244 @@
+package timatch
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// The version of discordgo we use predates Discord interactions (slash
+// commands), so the types and endpoints required are defined
+// here, and requests are made using discordgo.Session.Request.
+
+// discordAPI is the base url of the Discord API version supporting
+// interactions
+const discordAPI = "https://discord.com/api/v10/"
+
+// eventInteractionCreate is the gateway event type of interactions
+const eventInteractionCreate = "INTERACTION_CREATE"
+
+// interactionTypeApplicationCommand is the interaction type of slash
+// commands
+const interactionTypeApplicationCommand = 2
+
+// interactionTypeMessageComponent is the interaction type of clicks on
+// buttons of messages sent by the bot
+const interactionTypeMessageComponent = 3
+
+// interactionResponseDeferredChannelMessage acknowledges an interaction,
+// with the response message to follow
+const interactionResponseDeferredChannelMessage = 5
+
+// Application command option types
+const (
+	commandOptionString  = 3
+	commandOptionInteger = 4
+	commandOptionBoolean = 5
+	commandOptionRole    = 8
+)
+
+// Message component types and button styles
+const (
+	componentTypeActionRow = 1
+	componentTypeButton    = 2
+	buttonStyleSecondary   = 2
+)
+
+// messageFlagEphemeral makes an interaction response visible only to the
+// user that triggered the interaction
+const messageFlagEphemeral = 1 << 6
+
+// permissionManageGuild is the "Manage Server" permission bit
+const permissionManageGuild = 1 << 5
+
+type applicationCommand struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Options     []applicationCommandOption `json:"options,omitempty"`
+}
+
+type applicationCommandOption struct {
+	Type        int                              `json:"type"`
+	Name        string                           `json:"name"`
+	Description string                           `json:"description"`
+	Required    bool                             `json:"required,omitempty"`
+	Choices     []applicationCommandOptionChoice `json:"choices,omitempty"`
+}
+
+type applicationCommandOptionChoice struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type interaction struct {
+	ID            string          `json:"id"`
+	ApplicationID string          `json:"application_id"`
+	Type          int             `json:"type"`
+	Data          interactionData `json:"data"`
+	GuildID       string          `json:"guild_id"`
+	ChannelID     string          `json:"channel_id"`
+	// Member is set for interactions in guilds, User for interactions
+	// in direct messages
+	Member *struct {
+		User        *discordgo.User `json:"user"`
+		Permissions string          `json:"permissions"`
+	} `json:"member"`
+	User  *discordgo.User `json:"user"`
+	Token string          `json:"token"`
+}
+
+type interactionData struct {
+	Name    string                  `json:"name"`
+	Options []interactionDataOption `json:"options"`
+	// CustomID is the id of the clicked component, for component
+	// interactions
+	CustomID string `json:"custom_id"`
+}
+
+type interactionDataOption struct {
+	Name  string          `json:"name"`
+	Type  int             `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type interactionResponse struct {
+	Type int                      `json:"type"`
+	Data *interactionResponseData `json:"data,omitempty"`
+}
+
+// interactionResponseData is the message sent in response to an
+// interaction
+type interactionResponseData struct {
+	Content    string                    `json:"content"`
+	Flags      int                       `json:"flags,omitempty"`
+	Components []messageComponent        `json:"components,omitempty"`
+	Embeds     []*discordgo.MessageEmbed `json:"embeds,omitempty"`
+}
+
+// messageComponent is an interactive component of a message, e.g. a row
+// of buttons
+type messageComponent struct {
+	Type       int                `json:"type"`
+	Style      int                `json:"style,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	CustomID   string             `json:"custom_id,omitempty"`
+	Components []messageComponent `json:"components,omitempty"`
+}
+
+// buttonRow returns a row holding a single button, with the given label
+// and custom id
+func buttonRow(label string, customID string) messageComponent {
+	return messageComponent{
+		Type: componentTypeActionRow,
+		Components: []messageComponent{{
+			Type:     componentTypeButton,
+			Style:    buttonStyleSecondary,
+			Label:    label,
+			CustomID: customID,
+		}},
+	}
+}
+
+// user returns the user that triggered the interaction, or nil if not
+// known
+func (in *interaction) user() *discordgo.User {
+	if in.Member != nil && in.Member.User != nil {
+		return in.Member.User
+	}
+	return in.User
+}
+
+// userID returns the id of the user that triggered the interaction
+func (in *interaction) userID() string {
+	if user := in.user(); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// hasPermission tests if the member that triggered the interaction has
+// the given permission in the channel. Always false outside of guilds.
+func (in *interaction) hasPermission(permission int64) bool {
+	if in.Member == nil {
+		return false
+	}
+	perms, err := strconv.ParseInt(in.Member.Permissions, 10, 64)
+	if err != nil {
+		return false
+	}
+	return perms&permission != 0
+}
+
+// option returns the option with the given name, or nil if not given
+func (in *interaction) option(name string) *interactionDataOption {
+	for i := range in.Data.Options {
+		if in.Data.Options[i].Name == name {
+			return &in.Data.Options[i]
+		}
+	}
+	return nil
+}
+
+// stringOption returns the string value of the named option, or "" if
+// the option was not given
+func (in *interaction) stringOption(name string) string {
+	opt := in.option(name)
+	if opt == nil {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(opt.Value, &s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// intOption returns the integer value of the named option, or def if
+// the option was not given
+func (in *interaction) intOption(name string, def int) int {
+	opt := in.option(name)
+	if opt == nil {
+		return def
+	}
+	var n int
+	if err := json.Unmarshal(opt.Value, &n); err != nil {
+		return def
+	}
+	return n
+}
+
+// boolOption returns the boolean value of the named option, or def if
+// the option was not given
+func (in *interaction) boolOption(name string, def bool) bool {
+	opt := in.option(name)
+	if opt == nil {
+		return def
+	}
+	var b bool
+	if err := json.Unmarshal(opt.Value, &b); err != nil {
+		return def
+	}
+	return b
+}
+
+// registerApplicationCommands overwrites the global application commands
+// of the application with the given commands
+func registerApplicationCommands(s *discordgo.Session, applicationID string, commands []applicationCommand) error {
+	_, err := s.Request("PUT", discordAPI+"applications/"+applicationID+"/commands", commands)
+	return errors.Wrap(err, "Error registering application commands")
+}
+
+// respondInteraction sends the initial response to an interaction
+func respondInteraction(s *discordgo.Session, in *interaction, res *interactionResponse) error {
+	_, err := s.Request("POST", discordAPI+"interactions/"+in.ID+"/"+in.Token+"/callback", res)
+	return errors.Wrap(err, "Error responding to interaction")
+}
+
+// editInteractionResponse replaces the (possibly deferred) initial
+// response to an interaction
+func editInteractionResponse(s *discordgo.Session, in *interaction, data *interactionResponseData) error {
+	_, err := s.Request("PATCH", discordAPI+"webhooks/"+in.ApplicationID+"/"+in.Token+"/messages/@original", data)
+	return errors.Wrap(err, "Error editing interaction response")
+}
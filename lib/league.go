@@ -0,0 +1,59 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// leagueDetectInterval is the time between queries of the league
+// listing when automatically detecting the league to watch
+const leagueDetectInterval = 6 * time.Hour
+
+// theInternationalName returns the name of The International league
+// held in the given year, as it appears in the league listing.
+func theInternationalName(year int) string {
+	return fmt.Sprintf("The International %d", year)
+}
+
+// detectLeague looks for "The International <year>" in the league
+// listing and, if found, switches the bot over to watching that league.
+// The league listing is queried at most once every leagueDetectInterval.
+func (bot *bot) detectLeague(ctx context.Context) {
+	if time.Since(bot.lastLeagueDetect) < leagueDetectInterval {
+		return
+	}
+	bot.lastLeagueDetect = time.Now()
+	listingRes, err := bot.dotaClient.GetLeagueListing(ctx, "en")
+	if err != nil {
+		bot.logger.WithError(err).Error("Error getting league listing")
+		return
+	}
+	name := theInternationalName(time.Now().Year())
+	for _, league := range listingRes.Result.Leagues {
+		if !strings.EqualFold(strings.TrimSpace(league.Name), name) {
+			continue
+		}
+		if league.LeagueID == bot.leagueID {
+			return
+		}
+		bot.logger.WithField(logFieldLeagueID, league.LeagueID).Infof("Detected league %s (%d), switching from %d", league.Name, league.LeagueID, bot.leagueID)
+		bot.leagueMu.Lock()
+		bot.leagueID = league.LeagueID
+		bot.leagueName = league.Name
+		bot.leagueMu.Unlock()
+		bot.watcher.setLeague(league.LeagueID, bot.isFollowing())
+		bot.updateStatus()
+		return
+	}
+	bot.logger.Debugf("No league named %s found", name)
+}
+
+// currentLeagueID returns the id of the league being watched. Must be
+// used instead of reading leagueID directly outside of the run loop.
+func (bot *bot) currentLeagueID() int {
+	bot.leagueMu.RLock()
+	defer bot.leagueMu.RUnlock()
+	return bot.leagueID
+}
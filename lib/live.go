@@ -0,0 +1,68 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// liveGamesCache holds the live games of the last successful poll, so
+// that commands can show them without querying the Steam API
+type liveGamesCache struct {
+	mu       sync.RWMutex
+	games    []dota.LiveLeagueGame
+	polledAt time.Time
+}
+
+func (cache *liveGamesCache) set(games []dota.LiveLeagueGame) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.games = games
+	cache.polledAt = time.Now()
+}
+
+func (cache *liveGamesCache) get() ([]dota.LiveLeagueGame, time.Time) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.games, cache.polledAt
+}
+
+// formatDuration formats a game duration in seconds as m:ss
+func formatDuration(seconds float32) string {
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// handleLiveCommand responds with the scoreboards of the currently live
+// games, as of the last poll
+func (bot *bot) handleLiveCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if bot.currentLeagueID() == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	games, polledAt := bot.liveGames.get()
+	if polledAt.IsZero() {
+		return textResponse("No live games fetched yet, please try again in a minute."), nil
+	}
+	if len(games) == 0 {
+		return textResponse("There are no live games right now."), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	var b strings.Builder
+	for _, game := range games {
+		if !isGameStarted(game) {
+			fmt.Fprintf(&b, "**%s** vs. **%s** (Game %d) - Drafting\n",
+				game.RadiantTeam.TeamName, game.DireTeam.TeamName, game.GameNumber)
+			continue
+		}
+		fmt.Fprintf(&b, "**%s** %s **%s** (Game %d) - %s\n",
+			game.RadiantTeam.TeamName, format.Score(game.Scoreboard.Radiant.Score, game.Scoreboard.Dire.Score),
+			game.DireTeam.TeamName,
+			game.GameNumber, formatDuration(game.Scoreboard.Duration))
+	}
+	fmt.Fprintf(&b, "_As of %s ago_", time.Since(polledAt).Truncate(time.Second))
+	return textResponse(b.String()), nil
+}
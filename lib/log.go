@@ -0,0 +1,12 @@
+package timatch
+
+import "github.com/verath/timatch/lib/dota"
+
+// Field names used for structured logging. Match ids are logged as int64,
+// league ids as int, as by the dota client.
+const (
+	logFieldMatchID   = dota.LogFieldMatchID
+	logFieldLeagueID  = dota.LogFieldLeagueID
+	logFieldGuildID   = "guild_id"
+	logFieldChannelID = "channel_id"
+)
@@ -0,0 +1,53 @@
+package timatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// teamLogoMaxAge is the time a resolved team logo url is used before
+// being resolved again, as teams rarely change their logos
+const teamLogoMaxAge = 24 * time.Hour
+
+// teamLogosCache caches the logo urls of teams
+type teamLogosCache struct {
+	mu     sync.Mutex
+	byTeam map[int]teamLogo
+}
+
+type teamLogo struct {
+	fetchedAt time.Time
+	// url is "" for teams without a logo
+	url string
+}
+
+// teamLogoURL returns the url of the logo of a team, or "" if the team
+// has no logo. The logo is resolved from the team info and the details
+// of the logo file, and cached for teamLogoMaxAge.
+func (bot *bot) teamLogoURL(ctx context.Context, teamID int) (string, error) {
+	bot.teamLogos.mu.Lock()
+	defer bot.teamLogos.mu.Unlock()
+	if logo, ok := bot.teamLogos.byTeam[teamID]; ok && time.Since(logo.fetchedAt) < teamLogoMaxAge {
+		return logo.url, nil
+	}
+	teamInfo, err := bot.dotaClient.GetTeamInfoByTeamID(ctx, teamID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error getting team info")
+	}
+	logo := teamLogo{fetchedAt: time.Now()}
+	if teams := teamInfo.Result.Teams; len(teams) > 0 && teams[0].TeamID == teamID && teams[0].Logo != 0 {
+		details, err := bot.dotaClient.GetUGCFileDetails(ctx, teams[0].Logo)
+		if err != nil {
+			return "", errors.Wrap(err, "Error getting logo file details")
+		}
+		logo.url = details.Data.URL
+	}
+	if bot.teamLogos.byTeam == nil {
+		bot.teamLogos.byTeam = make(map[int]teamLogo)
+	}
+	bot.teamLogos.byTeam[teamID] = logo
+	return logo.url, nil
+}
@@ -0,0 +1,220 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maintenanceKey is the store key of the scheduled maintenance window
+const maintenanceKey = "maintenance"
+
+// maintenanceDrainTime is how long before a maintenance window starts
+// that held back messages are sent
+const maintenanceDrainTime = 5 * time.Minute
+
+// maintenanceWindow is a period during which the bot or the Steam API is
+// expected to be unavailable, scheduled by the operator
+type maintenanceWindow struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+	// Drained and Started are set once the queues have been drained and
+	// once the start of the window has been announced
+	Drained bool `json:"drained,omitempty"`
+	Started bool `json:"started,omitempty"`
+}
+
+// maintenance keeps track of the scheduled maintenance window. It is set
+// by the HTTP handlers and read by the run loop.
+type maintenance struct {
+	mu     sync.Mutex
+	window *maintenanceWindow
+}
+
+// active tests if t is within the maintenance window, if any
+func (m *maintenance) active(t time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.window != nil && !t.Before(m.window.Start) && t.Before(m.window.End)
+}
+
+func (window *maintenanceWindow) String() string {
+	s := window.Start.UTC().Format("2006-01-02 15:04") + " to " + window.End.UTC().Format("2006-01-02 15:04") + " UTC"
+	if window.Reason != "" {
+		s += " (" + window.Reason + ")"
+	}
+	return s
+}
+
+// parseMaintenanceWindow parses a maintenance window from the start (RFC
+// 3339, or now if empty) and either the end (RFC 3339) or the duration of
+// the window
+func parseMaintenanceWindow(now time.Time, start, end, duration string) (*maintenanceWindow, error) {
+	window := &maintenanceWindow{Start: now}
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid start")
+		}
+		window.Start = t
+	}
+	switch {
+	case end != "" && duration != "":
+		return nil, errors.New("Give either end or duration, not both")
+	case end != "":
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid end")
+		}
+		window.End = t
+	case duration != "":
+		d, err := parseDuration(duration)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid duration")
+		}
+		window.End = window.Start.Add(d)
+	default:
+		return nil, errors.New("Give the end or the duration of the window")
+	}
+	if !window.End.After(window.Start) || !window.End.After(now) {
+		return nil, errors.New("The window must end after it starts, and in the future")
+	}
+	return window, nil
+}
+
+// loadMaintenance loads the scheduled maintenance window from the store
+func (bot *bot) loadMaintenance(ctx context.Context) error {
+	var window maintenanceWindow
+	found, err := bot.store.Get(ctx, maintenanceKey, &window)
+	if err != nil {
+		return errors.Wrap(err, "Error getting maintenance window")
+	}
+	if found {
+		bot.maintenance.mu.Lock()
+		bot.maintenance.window = &window
+		bot.maintenance.mu.Unlock()
+	}
+	return nil
+}
+
+// setMaintenance replaces the scheduled maintenance window, or removes
+// it if window is nil
+func (bot *bot) setMaintenance(ctx context.Context, window *maintenanceWindow) error {
+	var err error
+	if window == nil {
+		err = bot.store.Delete(ctx, maintenanceKey)
+	} else {
+		err = bot.store.Set(ctx, maintenanceKey, window, time.Until(window.End))
+	}
+	if err != nil {
+		return errors.Wrap(err, "Error saving maintenance window")
+	}
+	bot.maintenance.mu.Lock()
+	bot.maintenance.window = window
+	bot.maintenance.mu.Unlock()
+	return nil
+}
+
+// updateMaintenance drains the queues shortly before the maintenance
+// window starts, and posts notices to the admin channel as the window
+// starts and ends. Called from the run loop.
+func (bot *bot) updateMaintenance(ctx context.Context) {
+	now := time.Now()
+	bot.maintenance.mu.Lock()
+	window := bot.maintenance.window
+	var copied maintenanceWindow
+	if window != nil {
+		copied = *window
+	}
+	bot.maintenance.mu.Unlock()
+	if window == nil {
+		return
+	}
+	switch {
+	case !now.Before(copied.End):
+		bot.sendAdmin("Maintenance is over, alerts are back on.")
+		if err := bot.setMaintenance(ctx, nil); err != nil {
+			bot.logger.WithError(err).Error("Error ending maintenance")
+		}
+		return
+	case !copied.Drained && now.Add(maintenanceDrainTime).After(copied.Start):
+		// Held back games are sent now, rather than in a digest that
+		// might be lost if the bot is stopped for the maintenance
+		if bot.floodControl {
+			bot.flushFloodDigest(ctx)
+		}
+		copied.Drained = true
+	case !copied.Started && !now.Before(copied.Start):
+		bot.sendAdmin("Maintenance started, Steam API alerts are off until " +
+			copied.End.UTC().Format("15:04 UTC") + ".")
+		copied.Started = true
+	default:
+		return
+	}
+	if err := bot.setMaintenance(ctx, &copied); err != nil {
+		bot.logger.WithError(err).Error("Error updating maintenance window")
+	}
+}
+
+type maintenanceResponse struct {
+	Scheduled bool   `json:"scheduled"`
+	Active    bool   `json:"active"`
+	Start     string `json:"start,omitempty"`
+	End       string `json:"end,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// handleMaintenance shows the scheduled maintenance window on GET,
+// schedules a window on POST, given the form values start, end or
+// duration, and reason, and cancels the window on DELETE
+func (bot *bot) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		window, err := parseMaintenanceWindow(time.Now(), r.FormValue("start"), r.FormValue("end"), r.FormValue("duration"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		window.Reason = strings.TrimSpace(r.FormValue("reason"))
+		if err := bot.setMaintenance(r.Context(), window); err != nil {
+			bot.logger.WithError(err).Error("Error scheduling maintenance")
+			http.Error(w, "Error scheduling maintenance", http.StatusInternalServerError)
+			return
+		}
+		bot.sendAdmin("Maintenance scheduled " + window.String() + ".")
+	case http.MethodDelete:
+		if err := bot.setMaintenance(r.Context(), nil); err != nil {
+			bot.logger.WithError(err).Error("Error cancelling maintenance")
+			http.Error(w, "Error cancelling maintenance", http.StatusInternalServerError)
+			return
+		}
+		bot.sendAdmin("Maintenance cancelled.")
+	default:
+		http.Error(w, fmt.Sprintf("Method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	var res maintenanceResponse
+	bot.maintenance.mu.Lock()
+	if window := bot.maintenance.window; window != nil {
+		res = maintenanceResponse{
+			Scheduled: true,
+			Start:     window.Start.Format(time.RFC3339),
+			End:       window.End.Format(time.RFC3339),
+			Reason:    window.Reason,
+		}
+	}
+	bot.maintenance.mu.Unlock()
+	res.Active = bot.maintenance.active(time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		bot.logger.WithError(err).Error("Error writing maintenance response")
+	}
+}
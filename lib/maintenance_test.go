@@ -0,0 +1,71 @@
+package timatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	now := time.Date(2019, 8, 20, 12, 0, 0, 0, time.UTC)
+	window, err := parseMaintenanceWindow(now, "", "", "2h")
+	if err != nil {
+		t.Fatalf("parseMaintenanceWindow() error: %v", err)
+	}
+	if !window.Start.Equal(now) || !window.End.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("parseMaintenanceWindow() = %v, want now for two hours", window)
+	}
+	window, err = parseMaintenanceWindow(now, "2019-08-21T01:00:00Z", "2019-08-21T03:00:00Z", "")
+	if err != nil || window.End.Sub(window.Start) != 2*time.Hour {
+		t.Errorf("parseMaintenanceWindow() = %v, %v, want two hours", window, err)
+	}
+	tests := [][3]string{
+		{"", "", ""},
+		{"", "2019-08-21T03:00:00Z", "1h"},
+		{"", "2019-08-20T11:00:00Z", ""},
+		{"tomorrow", "", "1h"},
+		{"", "", "-1h"},
+	}
+	for _, tt := range tests {
+		if _, err := parseMaintenanceWindow(now, tt[0], tt[1], tt[2]); err == nil {
+			t.Errorf("parseMaintenanceWindow(%q) = no error, want error", tt)
+		}
+	}
+}
+
+func TestUpdateMaintenance(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	restarted := &bot{store: store}
+	bot := &bot{store: store}
+	now := time.Now()
+	window := &maintenanceWindow{Start: now.Add(time.Minute), End: now.Add(time.Hour)}
+	if err := bot.setMaintenance(ctx, window); err != nil {
+		t.Fatalf("setMaintenance() error: %v", err)
+	}
+	if bot.maintenance.active(now) {
+		t.Error("active() before the window = true, want false")
+	}
+	// The queues are drained shortly before the window starts
+	bot.updateMaintenance(ctx)
+	if !bot.maintenance.window.Drained || bot.maintenance.window.Started {
+		t.Errorf("window after update = %+v, want drained, not started", bot.maintenance.window)
+	}
+	// The window survives a restart
+	if err := restarted.loadMaintenance(ctx); err != nil || !restarted.maintenance.active(now.Add(2*time.Minute)) {
+		t.Errorf("loadMaintenance() = %v, want the window to be loaded", err)
+	}
+	// Failed polls during the window are not counted
+	bot.maintenance.window.Start = now
+	bot.steamPollFailed(nil)
+	if bot.alerts.steamFailures != 0 {
+		t.Errorf("steamFailures = %d during maintenance, want 0", bot.alerts.steamFailures)
+	}
+	bot.maintenance.window.End = now
+	bot.updateMaintenance(ctx)
+	if bot.maintenance.window != nil {
+		t.Errorf("window after it ended = %+v, want nil", bot.maintenance.window)
+	}
+}
@@ -0,0 +1,217 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/verath/timatch/lib/opendota"
+)
+
+// matchStatsInterval is the time between checking OpenDota for the
+// parsed replays of finished games
+const matchStatsInterval = 5 * time.Minute
+
+// matchStatsMaxWait is the time the stats of a finished game are waited
+// for. Replays are usually parsed within minutes, but OpenDota's parse
+// queue can fall behind during big tournaments
+const matchStatsMaxWait = 6 * time.Hour
+
+// laneMargin is the difference in lane efficiency, in percent, below which
+// a lane is even
+const laneMargin = 10
+
+// matchStatsColor is the color of the match stats embeds
+const matchStatsColor = 0x2A7FC2
+
+type matchStatsEntry struct {
+	Item    matchesFinishedDataItem
+	AddedAt time.Time
+}
+
+// queueMatchStats queues the finished games for their stats to be posted
+// once parsed by OpenDota
+func (bot *bot) queueMatchStats(items []matchesFinishedDataItem) {
+	if !bot.matchStats {
+		return
+	}
+	for _, item := range items {
+		bot.statsQueue = append(bot.statsQueue, matchStatsEntry{Item: item, AddedAt: time.Now()})
+	}
+}
+
+// checkMatchStats posts the stats of the queued games whose replays have
+// been parsed, giving up on games after matchStatsMaxWait
+func (bot *bot) checkMatchStats(ctx context.Context) {
+	if time.Since(bot.statsCheckedAt) < matchStatsInterval {
+		return
+	}
+	// Taken from the queue while the requests are made, as games are
+	// queued by the handling of the events
+	bot.stateMu.Lock()
+	queue := bot.statsQueue
+	bot.statsQueue = nil
+	bot.stateMu.Unlock()
+	if len(queue) == 0 {
+		return
+	}
+	bot.statsCheckedAt = time.Now()
+	var remaining []matchStatsEntry
+	for _, entry := range queue {
+		logger := bot.logger.WithField(logFieldMatchID, entry.Item.MatchID)
+		match, err := bot.openDotaClient.GetMatch(ctx, entry.Item.MatchID)
+		if err != nil || !match.Parsed() {
+			if err != nil {
+				logger.WithError(err).Debugf("Error getting OpenDota match %d", entry.Item.MatchID)
+			}
+			if time.Since(entry.AddedAt) <= matchStatsMaxWait {
+				remaining = append(remaining, entry)
+			} else {
+				logger.Warnf("Giving up on the stats of match %d", entry.Item.MatchID)
+			}
+			continue
+		}
+		bot.announceMatchStats(ctx, entry.Item, match)
+	}
+	bot.stateMu.Lock()
+	bot.statsQueue = append(remaining, bot.statsQueue...)
+	bot.stateMu.Unlock()
+}
+
+// announceMatchStats posts the stats of a game as a follow-up to the
+// channels its result was announced to
+func (bot *bot) announceMatchStats(ctx context.Context, item matchesFinishedDataItem, match *opendota.Match) {
+	heroNames := make(map[string]map[int]string)
+	bot.forEachChannel(ctx, func(channelID channelID, settings *guildSettings, sub *channelSubscription) {
+		if !sub.includesEvent(eventMatchStats) {
+			return
+		}
+		var announcedAt time.Time
+		found, err := bot.store.Get(ctx, announcementKey(matchStateFinished, item.MatchID, channelID), &announcedAt)
+		if err != nil || !found {
+			return
+		}
+		if !bot.claimAnnouncement(ctx, eventMatchStats, item.MatchID, channelID) {
+			return
+		}
+		format := settings.channelTextFormat(string(channelID))
+		names, ok := heroNames[format.language.code]
+		if !ok {
+			if names, err = bot.heroNames(ctx, format.language.code); err != nil {
+				bot.logger.WithError(err).Warn("Error getting hero names")
+			}
+			heroNames[format.language.code] = names
+		}
+		embed := renderMatchStats(item, match, names)
+		bot.sendChannelEmbeds(channelID, []*discordgo.MessageEmbed{embed})
+	})
+}
+
+// renderMatchStats renders the stats of a game as an embed: the KDA,
+// GPM and XPM of the players of each team, winner first, and the
+// outcome of the lanes
+func renderMatchStats(item matchesFinishedDataItem, match *opendota.Match, heroNames map[int]string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("📊 %s vs. %s, Game %d", item.WinnerName, item.LoserName, item.GameNumber),
+		URL:   fmt.Sprintf("https://www.opendota.com/matches/%d", match.MatchID),
+		Color: matchStatsColor,
+	}
+	teams := []struct {
+		name    string
+		radiant bool
+	}{
+		{item.WinnerName, match.RadiantWin},
+		{item.LoserName, !match.RadiantWin},
+	}
+	for _, team := range teams {
+		var lines []string
+		for _, player := range match.Players {
+			if player.IsRadiant != team.radiant {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s %d/%d/%d, %d GPM, %d XPM",
+				matchPlayerName(player, heroNames), player.Kills, player.Deaths, player.Assists,
+				player.GoldPerMin, player.XPPerMin))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  team.name,
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+	radiantName, direName := item.WinnerName, item.LoserName
+	if !match.RadiantWin {
+		radiantName, direName = direName, radiantName
+	}
+	if lanes := laneOutcomes(match, radiantName, direName); len(lanes) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Lanes",
+			Value: strings.Join(lanes, "\n"),
+		})
+	}
+	return embed
+}
+
+// matchPlayerName returns the name of a player with their hero, falling
+// back to the Steam name, or only the hero, for players without a pro name
+func matchPlayerName(player opendota.MatchPlayer, heroNames map[int]string) string {
+	name := player.Name
+	if name == "" {
+		name = player.PersonaName
+	}
+	hero, ok := heroNames[player.HeroID]
+	switch {
+	case name == "" && !ok:
+		return "Anonymous"
+	case name == "":
+		return hero
+	case !ok:
+		return name
+	}
+	return name + " (" + hero + ")"
+}
+
+// laneOutcomes returns the outcome of each lane, e.g. "Top: OG won", by
+// the summed lane efficiency of the players of each team in the lane.
+// Lanes without players are left out.
+func laneOutcomes(match *opendota.Match, radiantName, direName string) []string {
+	lanes := []struct {
+		lane int
+		name string
+	}{
+		{opendota.LaneTop, "Top"},
+		{opendota.LaneMiddle, "Mid"},
+		{opendota.LaneBottom, "Bottom"},
+	}
+	var outcomes []string
+	for _, lane := range lanes {
+		var radiant, dire float64
+		var players int
+		for _, player := range match.Players {
+			if player.Lane != lane.lane {
+				continue
+			}
+			players++
+			if player.IsRadiant {
+				radiant += player.LaneEfficiencyPct
+			} else {
+				dire += player.LaneEfficiencyPct
+			}
+		}
+		if players == 0 {
+			continue
+		}
+		outcome := "even"
+		if radiant-dire >= laneMargin {
+			outcome = radiantName + " won"
+		} else if dire-radiant >= laneMargin {
+			outcome = direName + " won"
+		}
+		outcomes = append(outcomes, lane.name+": "+outcome)
+	}
+	return outcomes
+}
@@ -0,0 +1,36 @@
+package timatch
+
+import (
+	"testing"
+
+	"github.com/verath/timatch/lib/opendota"
+)
+
+func TestRenderMatchStats(t *testing.T) {
+	item := matchesFinishedDataItem{MatchID: 1, GameNumber: 2, WinnerName: "OG", LoserName: "Liquid"}
+	match := &opendota.Match{MatchID: 1, RadiantWin: false, Players: []opendota.MatchPlayer{
+		{Name: "Miracle-", HeroID: 1, IsRadiant: true, Kills: 3, Deaths: 7, Assists: 2, GoldPerMin: 480, XPPerMin: 510, Lane: opendota.LaneBottom, LaneEfficiencyPct: 70},
+		{PersonaName: "ana", HeroID: 2, Kills: 12, Deaths: 1, Assists: 9, GoldPerMin: 720, XPPerMin: 800, Lane: opendota.LaneTop, LaneEfficiencyPct: 80},
+		{HeroID: 3, Lane: opendota.LaneBottom, LaneEfficiencyPct: 65},
+	}}
+	embed := renderMatchStats(item, match, map[int]string{1: "Anti-Mage", 2: "Axe"})
+	if want := "📊 OG vs. Liquid, Game 2"; embed.Title != want {
+		t.Errorf("Title = %q, want %q", embed.Title, want)
+	}
+	if len(embed.Fields) != 3 {
+		t.Fatalf("renderMatchStats() has %d fields, want 3", len(embed.Fields))
+	}
+	// The winner, dire, is listed first
+	if got, want := embed.Fields[0].Name, "OG"; got != want {
+		t.Errorf("First team = %q, want %q", got, want)
+	}
+	if got, want := embed.Fields[0].Value, "ana (Axe) 12/1/9, 720 GPM, 800 XPM\nAnonymous 0/0/0, 0 GPM, 0 XPM"; got != want {
+		t.Errorf("Winner players = %q, want %q", got, want)
+	}
+	if got, want := embed.Fields[1].Value, "Miracle- (Anti-Mage) 3/7/2, 480 GPM, 510 XPM"; got != want {
+		t.Errorf("Loser players = %q, want %q", got, want)
+	}
+	if got, want := embed.Fields[2].Value, "Top: OG won\nBottom: even"; got != want {
+		t.Errorf("Lanes = %q, want %q", got, want)
+	}
+}
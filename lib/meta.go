@@ -0,0 +1,118 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// metaMinPicks is the number of times a hero must have been picked to be
+// ranked by win rate, so that a single won game is not a 100% win rate
+const metaMinPicks = 3
+
+// heroWinRate is the number of games a hero was picked in and won
+type heroWinRate struct {
+	Name  string
+	Wins  int
+	Picks int
+}
+
+// heroMeta is the pick and ban meta of a league
+type heroMeta struct {
+	Games      int
+	MostPicked []heroCount
+	MostBanned []heroCount
+	// BestWinRate are the heroes picked at least metaMinPicks times
+	// with the highest win rates
+	BestWinRate []heroWinRate
+}
+
+// computeMeta computes the meta of a league from its results. Picks are
+// counted from the heroes played, like computeHeroStats.
+func computeMeta(results []matchResult, heroNames map[int]string) heroMeta {
+	picks := make(map[int]int)
+	bans := make(map[int]int)
+	wins := make(map[int]int)
+	for _, result := range results {
+		for _, heroID := range result.Bans {
+			bans[heroID]++
+		}
+		for _, player := range result.Players {
+			picks[player.HeroID]++
+			if player.Won {
+				wins[player.HeroID]++
+			}
+		}
+	}
+	meta := heroMeta{
+		Games:      len(results),
+		MostPicked: topHeroes(picks, heroNames),
+		MostBanned: topHeroes(bans, heroNames),
+	}
+	for heroID, n := range picks {
+		if n >= metaMinPicks {
+			meta.BestWinRate = append(meta.BestWinRate, heroWinRate{Name: heroName(heroNames, heroID), Wins: wins[heroID], Picks: n})
+		}
+	}
+	// Ties are ordered by the number of picks, then by name
+	sort.Slice(meta.BestWinRate, func(i, j int) bool {
+		a, b := meta.BestWinRate[i], meta.BestWinRate[j]
+		if a.Wins*b.Picks != b.Wins*a.Picks {
+			return a.Wins*b.Picks > b.Wins*a.Picks
+		}
+		if a.Picks != b.Picks {
+			return a.Picks > b.Picks
+		}
+		return a.Name < b.Name
+	})
+	if len(meta.BestWinRate) > reportTopHeroes {
+		meta.BestWinRate = meta.BestWinRate[:reportTopHeroes]
+	}
+	return meta
+}
+
+// renderMeta renders the meta as lists of heroes
+func renderMeta(meta heroMeta, format textFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Meta of %s games**\n", format.Integer(int64(meta.Games)))
+	b.WriteString("Most picked:\n" + renderHeroCounts(meta.MostPicked) + "\n")
+	b.WriteString("Most banned:\n" + renderHeroCounts(meta.MostBanned) + "\n")
+	fmt.Fprintf(&b, "Highest win rate (%d+ picks):\n", metaMinPicks)
+	if len(meta.BestWinRate) == 0 {
+		b.WriteString("-")
+	}
+	for i, hero := range meta.BestWinRate {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s%% (%s)", hero.Name, format.Decimal(100*float64(hero.Wins)/float64(hero.Picks), 0),
+			format.Score(hero.Wins, hero.Picks-hero.Wins))
+	}
+	return b.String()
+}
+
+// handleMetaCommand responds with the most picked, banned and winning
+// heroes of the league
+func (bot *bot) handleMetaCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	if len(results) == 0 {
+		return textResponse("No games have finished yet."), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	names, err := bot.heroNames(ctx, format.language.code)
+	if err != nil {
+		// Heroes are shown by id rather than not responding at all
+		bot.logger.WithError(err).Warn("Error getting hero names")
+	}
+	return textResponse(renderMeta(computeMeta(results, names), format)), nil
+}
@@ -0,0 +1,36 @@
+package timatch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComputeMeta(t *testing.T) {
+	names := map[int]string{1: "Anti-Mage", 2: "Axe", 3: "Bane"}
+	game := func(bans []int, winner, loser int) matchResult {
+		return matchResult{Bans: bans, Players: []resultPlayer{{HeroID: winner, Won: true}, {HeroID: loser}}}
+	}
+	results := []matchResult{
+		game([]int{3}, 1, 2),
+		game([]int{3}, 1, 2),
+		game([]int{2}, 2, 1),
+		game(nil, 2, 3),
+	}
+	meta := computeMeta(results, names)
+	if want := []heroCount{{"Axe", 4}, {"Anti-Mage", 3}, {"Bane", 1}}; !reflect.DeepEqual(meta.MostPicked, want) {
+		t.Errorf("MostPicked = %v, want %v", meta.MostPicked, want)
+	}
+	if want := []heroCount{{"Bane", 2}, {"Axe", 1}}; !reflect.DeepEqual(meta.MostBanned, want) {
+		t.Errorf("MostBanned = %v, want %v", meta.MostBanned, want)
+	}
+	// Bane was picked too few times to be ranked
+	want := []heroWinRate{{"Anti-Mage", 2, 3}, {"Axe", 2, 4}}
+	if !reflect.DeepEqual(meta.BestWinRate, want) {
+		t.Errorf("BestWinRate = %v, want %v", meta.BestWinRate, want)
+	}
+	rendered := renderMeta(meta, defaultTextFormat)
+	if wantLine := "Anti-Mage 67% (2 - 1)"; !strings.Contains(rendered, wantLine) {
+		t.Errorf("renderMeta() = %q, want it to contain %q", rendered, wantLine)
+	}
+}
@@ -0,0 +1,100 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// prizeMilestoneInterval is the time between checks of the prize pool
+// for milestones
+const prizeMilestoneInterval = 10 * time.Minute
+
+// prizeMilestoneKey returns the store key of the prize pool milestones
+// reached by a league
+func prizeMilestoneKey(leagueID int) string {
+	return "prizepool/" + strconv.Itoa(leagueID)
+}
+
+// prizeMilestones are the prize pool milestones reached by a league
+type prizeMilestones struct {
+	// Milestone is the highest multiple of the milestone step reached
+	Milestone int64 `json:"milestone"`
+	// Record is true once the prize pool has passed the record
+	Record bool `json:"record"`
+}
+
+// reach updates the milestones with the current prize pool, returning
+// the milestone passed (0 if none) and whether the record was broken.
+// step and record are 0 if not announced.
+func (milestones *prizeMilestones) reach(amount, step, record int64) (passed int64, recordBroken bool) {
+	if step > 0 {
+		if reached := amount / step * step; reached > milestones.Milestone {
+			milestones.Milestone = reached
+			passed = reached
+		}
+	}
+	if record > 0 && amount > record && !milestones.Record {
+		milestones.Record = true
+		recordBroken = true
+	}
+	return passed, recordBroken
+}
+
+// renderPrizeMilestone renders the announcement of a prize pool passing
+// a milestone or breaking the record. A broken record takes precedence,
+// being the bigger news.
+func renderPrizeMilestone(leagueName string, amount, passed int64, recordBroken bool, format textFormat) string {
+	if recordBroken {
+		return fmt.Sprintf("💰 The prize pool of %s is now the largest ever, at %s!", leagueName, format.Dollars(amount))
+	}
+	return fmt.Sprintf("💰 The prize pool of %s passed %s!", leagueName, format.Dollars(passed))
+}
+
+// checkPrizeMilestones announces the prize pool of the watched league
+// passing a multiple of prizeStep or prizeRecord. Milestones
+// already passed when the bot first sees the league are not announced.
+func (bot *bot) checkPrizeMilestones(ctx context.Context) {
+	if bot.prizeStep <= 0 && bot.prizeRecord <= 0 {
+		return
+	}
+	if time.Since(bot.prizeCheckedAt) < prizeMilestoneInterval {
+		return
+	}
+	bot.prizeCheckedAt = time.Now()
+	bot.leagueMu.RLock()
+	leagueID, leagueName := bot.leagueID, bot.leagueName
+	bot.leagueMu.RUnlock()
+	if leagueName == "" {
+		leagueName = fmt.Sprintf("League %d", leagueID)
+	}
+	logger := bot.logger.WithField(logFieldLeagueID, leagueID)
+	amount, err := bot.getPrizePool(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("Error getting prize pool")
+		return
+	}
+	var milestones prizeMilestones
+	found, err := bot.store.Get(ctx, prizeMilestoneKey(leagueID), &milestones)
+	if err != nil {
+		logger.WithError(err).Error("Error getting prize pool milestones")
+		return
+	}
+	passed, recordBroken := milestones.reach(amount, bot.prizeStep, bot.prizeRecord)
+	// Stored when first seen even if nothing was passed, so that the
+	// milestones passed next are announced
+	if found && passed == 0 && !recordBroken {
+		return
+	}
+	if err := bot.store.Set(ctx, prizeMilestoneKey(leagueID), milestones, resultTTL); err != nil {
+		logger.WithError(err).Error("Error storing prize pool milestones")
+		return
+	}
+	if !found {
+		return
+	}
+	bot.sendGuildMessage(ctx, eventPrizePool, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+		return renderPrizeMilestone(leagueName, amount, passed, recordBroken, settings.channelTextFormat(string(channelID)))
+	})
+}
@@ -0,0 +1,28 @@
+package timatch
+
+import "testing"
+
+func TestPrizeMilestonesReach(t *testing.T) {
+	var milestones prizeMilestones
+	if passed, record := milestones.reach(2500000, 1000000, 0); passed != 2000000 || record {
+		t.Errorf("reach(2.5M) = %d, %t, want 2000000, false", passed, record)
+	}
+	if passed, record := milestones.reach(2900000, 1000000, 0); passed != 0 || record {
+		t.Errorf("reach(2.9M) = %d, %t, want no milestone", passed, record)
+	}
+	if passed, record := milestones.reach(3100000, 1000000, 3000000); passed != 3000000 || !record {
+		t.Errorf("reach(3.1M) = %d, %t, want 3000000, true", passed, record)
+	}
+	if passed, record := milestones.reach(3200000, 1000000, 3000000); passed != 0 || record {
+		t.Errorf("reach(3.2M) = %d, %t, want the record announced once", passed, record)
+	}
+}
+
+func TestRenderPrizeMilestone(t *testing.T) {
+	if got, want := renderPrizeMilestone("TI", 3100000, 3000000, false, defaultTextFormat), "💰 The prize pool of TI passed $3,000,000!"; got != want {
+		t.Errorf("renderPrizeMilestone() = %q, want %q", got, want)
+	}
+	if got, want := renderPrizeMilestone("TI", 3100000, 3000000, true, defaultTextFormat), "💰 The prize pool of TI is now the largest ever, at $3,100,000!"; got != want {
+		t.Errorf("renderPrizeMilestone() of a record = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// muteKeyPrefix is the prefix of the store keys of all muted channels.
+// Mutes are stored with the mute period as ttl, so that they expire
+// without the bot having to lift them.
+const muteKeyPrefix = "mute/"
+
+// muteKey returns the store key of the mute of a channel
+func muteKey(channelID channelID) string {
+	return muteKeyPrefix + string(channelID)
+}
+
+// mutedChannels returns the channels that are currently muted
+func (bot *bot) mutedChannels(ctx context.Context) (map[channelID]struct{}, error) {
+	keys, err := bot.store.Keys(ctx, muteKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error listing muted channels")
+	}
+	muted := make(map[channelID]struct{}, len(keys))
+	for _, key := range keys {
+		muted[channelID(strings.TrimPrefix(key, muteKeyPrefix))] = struct{}{}
+	}
+	return muted, nil
+}
+
+// handleMuteCommand mutes announcements to the channel the command is
+// used in for the given period, or lifts the mute if the period is "off".
+// Muting a guild channel requires the Manage Server permission.
+func (bot *bot) handleMuteCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID != "" && !in.hasPermission(permissionManageGuild) {
+		return textResponse("Muting announcements requires the Manage Server permission."), nil
+	}
+	value := in.stringOption("duration")
+	if strings.EqualFold(strings.TrimSpace(value), "off") {
+		if err := bot.store.Delete(ctx, muteKey(channelID(in.ChannelID))); err != nil {
+			return nil, errors.Wrap(err, "Error deleting mute")
+		}
+		return textResponse("Announcements to this channel are no longer muted."), nil
+	}
+	d, err := parseDuration(value)
+	if err != nil {
+		return textResponse("Give the duration to mute for, e.g. 8h or 2d, or \"off\"."), nil
+	}
+	until := time.Now().Add(d)
+	if err := bot.store.Set(ctx, muteKey(channelID(in.ChannelID)), until, d); err != nil {
+		return nil, errors.Wrap(err, "Error saving mute")
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	return textResponse(fmt.Sprintf("Announcements to this channel are muted until %s.", format.DateTime(until))), nil
+}
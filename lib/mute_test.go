@@ -0,0 +1,30 @@
+package timatch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestMutedChannels(t *testing.T) {
+	ctx := context.Background()
+	bot := &bot{store: storage.NewMemoryStore()}
+	if err := bot.store.Set(ctx, muteKey("1"), time.Now(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.store.Set(ctx, muteKey("2"), time.Now(), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	muted, err := bot.mutedChannels(ctx)
+	if err != nil {
+		t.Fatalf("mutedChannels() error: %v", err)
+	}
+	want := map[channelID]struct{}{"1": {}}
+	if !reflect.DeepEqual(muted, want) {
+		t.Errorf("mutedChannels() = %v, want %v", muted, want)
+	}
+}
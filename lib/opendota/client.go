@@ -0,0 +1,78 @@
+// Package opendota is a client for the OpenDota API (https://docs.opendota.com).
+package opendota
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/apiclient"
+)
+
+const apiBaseURL = "https://api.opendota.com"
+const pathProPlayers = "/api/proPlayers"
+const pathTeams = "/api/teams/"
+const pathMatches = "/api/matches/"
+
+// requestInterval is the minimum time between requests, as the free
+// OpenDota API tier allows 60 requests per minute
+const requestInterval = 1 * time.Second
+
+type Client struct {
+	baseURL *url.URL
+	api     *apiclient.Client
+}
+
+func NewClient(logger *logrus.Logger) (*Client, error) {
+	baseURL, err := url.Parse(apiBaseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing apiBaseURL")
+	}
+	return &Client{
+		baseURL: baseURL,
+		api:     apiclient.NewClient(logger, requestInterval),
+	}, nil
+}
+
+// GetProPlayers returns all players marked as professional players
+func (client *Client) GetProPlayers(ctx context.Context) ([]ProPlayer, error) {
+	req, err := apiclient.NewRequest(ctx, client.baseURL, pathProPlayers)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	data := make([]ProPlayer, 0)
+	if err := client.api.GetJSON(ctx, req, &data, nil); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetTeamMatches returns the matches played by a team, most recent first
+func (client *Client) GetTeamMatches(ctx context.Context, teamID int) ([]TeamMatch, error) {
+	req, err := apiclient.NewRequest(ctx, client.baseURL, pathTeams+strconv.Itoa(teamID)+"/matches")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	data := make([]TeamMatch, 0)
+	if err := client.api.GetJSON(ctx, req, &data, nil); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
+
+// GetMatch returns a match. The parsed stats of the match are only given
+// once its replay is parsed, see Match.Parsed
+func (client *Client) GetMatch(ctx context.Context, matchID int64) (*Match, error) {
+	req, err := apiclient.NewRequest(ctx, client.baseURL, pathMatches+strconv.FormatInt(matchID, 10))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating new request")
+	}
+	data := &Match{}
+	if err := client.api.GetJSON(ctx, req, data, nil); err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	return data, nil
+}
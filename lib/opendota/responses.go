@@ -0,0 +1,60 @@
+package opendota
+
+type ProPlayer struct {
+	AccountID   int64  `json:"account_id"`
+	Name        string `json:"name"`
+	PersonaName string `json:"personaname"`
+	CountryCode string `json:"country_code"`
+	FantasyRole int    `json:"fantasy_role"`
+	TeamID      int    `json:"team_id"`
+	TeamName    string `json:"team_name"`
+	TeamTag     string `json:"team_tag"`
+	IsPro       bool   `json:"is_pro"`
+}
+
+type TeamMatch struct {
+	MatchID    int64 `json:"match_id"`
+	Radiant    bool  `json:"radiant"`
+	RadiantWin bool  `json:"radiant_win"`
+	StartTime  int64 `json:"start_time"`
+	LeagueID   int   `json:"leagueid"`
+}
+
+type Match struct {
+	MatchID    int64 `json:"match_id"`
+	RadiantWin bool  `json:"radiant_win"`
+	Duration   int   `json:"duration"`
+	// Version is the version of the replay parser, nil if the replay
+	// is not parsed yet
+	Version *int          `json:"version"`
+	Players []MatchPlayer `json:"players"`
+}
+
+// Parsed tests if the replay of the match is parsed, i.e. if the parsed
+// stats, such as the lanes, are given
+func (match *Match) Parsed() bool {
+	return match.Version != nil
+}
+
+// Lanes of players, as given by MatchPlayer.Lane
+const (
+	LaneBottom = 1
+	LaneMiddle = 2
+	LaneTop    = 3
+)
+
+type MatchPlayer struct {
+	AccountID   int64  `json:"account_id"`
+	Name        string `json:"name"`
+	PersonaName string `json:"personaname"`
+	HeroID      int    `json:"hero_id"`
+	IsRadiant   bool   `json:"isRadiant"`
+	Kills       int    `json:"kills"`
+	Deaths      int    `json:"deaths"`
+	Assists     int    `json:"assists"`
+	GoldPerMin  int    `json:"gold_per_min"`
+	XPPerMin    int    `json:"xp_per_min"`
+	// Lane and LaneEfficiencyPct are only given for parsed matches
+	Lane              int     `json:"lane"`
+	LaneEfficiencyPct float64 `json:"lane_efficiency_pct"`
+}
@@ -0,0 +1,76 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// pinnedMessageTTL is the time a pinned started announcement is kept
+// track of, after which it is left pinned should its games never finish
+const pinnedMessageTTL = 2 * finishedMatchTTL
+
+// pinnedMessageKey is the key of a pinned started announcement, storing
+// the ids of the games of the announcement not yet finished
+func pinnedMessageKey(channelID channelID, messageID string) string {
+	return fmt.Sprintf("pin/%s/%s", channelID, messageID)
+}
+
+// pinMessage pins a started announcement, recording its games so that it
+// is unpinned once they finish
+func (bot *bot) pinMessage(channelID channelID, messageID string, matchIDs []int64) {
+	logger := bot.logger.WithField(logFieldChannelID, channelID)
+	if err := bot.discordSession.ChannelMessagePin(string(channelID), messageID); err != nil {
+		logger.WithError(err).Errorf("Failed pinning message %s", messageID)
+		return
+	}
+	if err := bot.store.Set(context.Background(), pinnedMessageKey(channelID, messageID), matchIDs, pinnedMessageTTL); err != nil {
+		logger.WithError(err).Errorf("Error storing pinned message %s", messageID)
+	}
+}
+
+// unpinFinished unpins the started announcements whose games have all
+// finished
+func (bot *bot) unpinFinished(ctx context.Context, items []matchesFinishedDataItem) {
+	finished := make(map[int64]bool, len(items))
+	for _, item := range items {
+		finished[item.MatchID] = true
+	}
+	keys, err := bot.store.Keys(ctx, "pin/")
+	if err != nil {
+		bot.logger.WithError(err).Error("Error getting pinned messages")
+		return
+	}
+	for _, key := range keys {
+		var matchIDs []int64
+		if found, err := bot.store.Get(ctx, key, &matchIDs); err != nil || !found {
+			continue
+		}
+		live := make([]int64, 0, len(matchIDs))
+		for _, matchID := range matchIDs {
+			if !finished[matchID] {
+				live = append(live, matchID)
+			}
+		}
+		if len(live) == len(matchIDs) {
+			continue
+		}
+		if len(live) > 0 {
+			if err := bot.store.Set(ctx, key, live, pinnedMessageTTL); err != nil {
+				bot.logger.WithError(err).Errorf("Error storing pinned message %s", key)
+			}
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(key, "pin/"), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		channelID, messageID := parts[0], parts[1]
+		if err := bot.discordSession.ChannelMessageUnpin(channelID, messageID); err != nil {
+			bot.logger.WithField(logFieldChannelID, channelID).WithError(err).Errorf("Failed unpinning message %s", messageID)
+		}
+		if err := bot.store.Delete(ctx, key); err != nil {
+			bot.logger.WithError(err).Errorf("Error deleting pinned message %s", key)
+		}
+	}
+}
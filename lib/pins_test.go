@@ -0,0 +1,33 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestUnpinFinishedKeepsLiveGames(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, store: storage.NewMemoryStore()}
+	ctx := context.Background()
+	key := pinnedMessageKey("1", "100")
+	if err := bot.store.Set(ctx, key, []int64{10, 11}, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Unrelated games finishing leave the message as is, and one of its
+	// games finishing keeps it pinned for the other
+	bot.unpinFinished(ctx, []matchesFinishedDataItem{{MatchID: 12}})
+	bot.unpinFinished(ctx, []matchesFinishedDataItem{{MatchID: 10}})
+	var live []int64
+	if found, err := bot.store.Get(ctx, key, &live); err != nil || !found {
+		t.Fatalf("Get() = %v, %v, want the message still pinned", found, err)
+	}
+	if want := []int64{11}; !reflect.DeepEqual(live, want) {
+		t.Errorf("Live games of the pinned message = %v, want %v", live, want)
+	}
+}
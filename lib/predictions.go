@@ -0,0 +1,232 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// componentPredict is the name of the buttons of prediction prompts. The
+// custom id of a button is followed by the ids of the match and the team
+// predicted to win, e.g. "predict:123:456"
+const componentPredict = "predict"
+
+// leaderboardSize is the number of users shown by /leaderboard
+const leaderboardSize = 10
+
+// prediction is the team a user predicted to win a game
+type prediction struct {
+	TeamID int `json:"team_id"`
+	// Name is the name of the user, for the leaderboard
+	Name string `json:"name"`
+}
+
+// predictorScore is the prediction record of a user in a guild
+type predictorScore struct {
+	Name    string `json:"name"`
+	Correct int    `json:"correct"`
+	Total   int    `json:"total"`
+}
+
+func predictionPrefix(matchID int64) string {
+	return "prediction/" + strconv.FormatInt(matchID, 10) + "/"
+}
+
+func predictionKey(matchID int64, guildID guildID, userID string) string {
+	return predictionPrefix(matchID) + string(guildID) + "/" + userID
+}
+
+func leaderboardKey(guildID guildID) string {
+	return "leaderboard/" + string(guildID)
+}
+
+// usesPredictions tests if prediction prompts are posted to the guild,
+// which they are not during quiet hours as the games would have started
+// by the time the prompts are sent
+func (settings *guildSettings) usesPredictions() bool {
+	return settings.Predictions && settings.quietHoursEnd(time.Now()).IsZero()
+}
+
+// renderPredictionPrompt renders the prompt asking who wins a drafting
+// game, with a button per team. ok is false for games between teams not
+// known by id, as predictions are recorded by team id.
+func renderPredictionPrompt(game dota.LiveLeagueGame) (content string, components []messageComponent, ok bool) {
+	if game.RadiantTeam.TeamID == 0 || game.DireTeam.TeamID == 0 {
+		return "", nil, false
+	}
+	row := messageComponent{Type: componentTypeActionRow}
+	for _, team := range []dota.LiveLeagueGamesTeam{game.RadiantTeam, game.DireTeam} {
+		label := team.TeamName
+		if runes := []rune(label); len(runes) > maxButtonLabelLength {
+			label = string(runes[:maxButtonLabelLength])
+		}
+		row.Components = append(row.Components, messageComponent{
+			Type:     componentTypeButton,
+			Style:    buttonStyleSecondary,
+			Label:    label,
+			CustomID: fmt.Sprintf("%s:%d:%d", componentPredict, game.MatchID, team.TeamID),
+		})
+	}
+	content = fmt.Sprintf("Who wins %s vs. %s (Game %d)? Predictions lock when the game starts.",
+		game.RadiantTeam.TeamName, game.DireTeam.TeamName, game.GameNumber)
+	return content, []messageComponent{row}, true
+}
+
+// postPredictionPrompts posts a prediction prompt for each of the
+// drafting games to a channel
+func (bot *bot) postPredictionPrompts(channelID channelID, games []dota.LiveLeagueGame) {
+	for _, game := range games {
+		if content, components, ok := renderPredictionPrompt(game); ok {
+			bot.sendAnnouncement(channelID, content, false, components, nil)
+		}
+	}
+}
+
+// handlePredict records the prediction of the user clicking a button of
+// a prediction prompt, until the game starts
+func (bot *bot) handlePredict(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	var matchID int64
+	var teamID int
+	if _, err := fmt.Sscanf(in.Data.CustomID, componentPredict+":%d:%d", &matchID, &teamID); err != nil {
+		return nil, errors.Wrapf(err, "Error parsing custom id %q", in.Data.CustomID)
+	}
+	user := in.user()
+	if in.GuildID == "" || user == nil {
+		return textResponse("Predictions are only available in servers."), nil
+	}
+	var started interface{}
+	locked, err := bot.store.Get(ctx, matchStateKey(matchStateStarted, matchID), &started)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting match state")
+	}
+	if locked {
+		return textResponse("The game has started, predictions are locked."), nil
+	}
+	p := prediction{TeamID: teamID, Name: user.Username}
+	if err := bot.store.Set(ctx, predictionKey(matchID, guildID(in.GuildID), user.ID), p, finishedMatchTTL); err != nil {
+		return nil, errors.Wrap(err, "Error storing prediction")
+	}
+	return textResponse(fmt.Sprintf("You predicted %s to win.", bot.teamName(teamID))), nil
+}
+
+// scorePredictions scores the predictions of a finished game, adding them
+// to the leaderboards of the guilds they were made in
+func (bot *bot) scorePredictions(ctx context.Context, item matchesFinishedDataItem) {
+	logger := bot.logger.WithField(logFieldMatchID, item.MatchID)
+	keys, err := bot.store.Keys(ctx, predictionPrefix(item.MatchID))
+	if err != nil {
+		logger.WithError(err).Error("Error getting predictions")
+		return
+	}
+	// Loaded once per guild, as most predictions of a game are made in
+	// the same few guilds
+	leaderboards := make(map[guildID]map[string]*predictorScore)
+	picks := make(map[guildID][]pickRecord)
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimPrefix(key, predictionPrefix(item.MatchID)), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		guildID, userID := guildID(parts[0]), parts[1]
+		var p prediction
+		if found, err := bot.store.Get(ctx, key, &p); err != nil || !found {
+			continue
+		}
+		leaderboard, ok := leaderboards[guildID]
+		if !ok {
+			if leaderboard, err = bot.loadLeaderboard(ctx, guildID); err != nil {
+				logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error loading leaderboard")
+				continue
+			}
+			leaderboards[guildID] = leaderboard
+		}
+		score, ok := leaderboard[userID]
+		if !ok {
+			score = &predictorScore{}
+			leaderboard[userID] = score
+		}
+		correct := p.TeamID == item.WinnerTeamID
+		score.Name = p.Name
+		score.Total++
+		if correct {
+			score.Correct++
+		}
+		picks[guildID] = append(picks[guildID], pickRecord{Kind: pickKindPrediction, MatchID: item.MatchID,
+			UserID: userID, Name: p.Name, Team: bot.teamName(p.TeamID), Correct: correct, ScoredAt: time.Now()})
+		// Deleted as scored, so that a game is not scored twice
+		if err := bot.store.Delete(ctx, key); err != nil {
+			logger.WithError(err).Errorf("Error deleting prediction %s", key)
+		}
+	}
+	for guildID, leaderboard := range leaderboards {
+		if err := bot.store.Set(ctx, leaderboardKey(guildID), leaderboard, 0); err != nil {
+			logger.WithField(logFieldGuildID, guildID).WithError(err).Error("Error storing leaderboard")
+		}
+	}
+	for guildID, records := range picks {
+		bot.recordPicks(ctx, guildID, records)
+	}
+}
+
+// loadLeaderboard loads the prediction records of the users of a guild,
+// by user id
+func (bot *bot) loadLeaderboard(ctx context.Context, guildID guildID) (map[string]*predictorScore, error) {
+	leaderboard := make(map[string]*predictorScore)
+	if _, err := bot.store.Get(ctx, leaderboardKey(guildID), &leaderboard); err != nil {
+		return nil, err
+	}
+	return leaderboard, nil
+}
+
+// renderLeaderboard renders the best predictors of a leaderboard, by
+// number of correct predictions, ties going to the fewest predictions
+func renderLeaderboard(leaderboard map[string]*predictorScore) string {
+	scores := make([]*predictorScore, 0, len(leaderboard))
+	for _, score := range leaderboard {
+		scores = append(scores, score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Correct != scores[j].Correct {
+			return scores[i].Correct > scores[j].Correct
+		}
+		if scores[i].Total != scores[j].Total {
+			return scores[i].Total < scores[j].Total
+		}
+		return scores[i].Name < scores[j].Name
+	})
+	if len(scores) > leaderboardSize {
+		scores = scores[:leaderboardSize]
+	}
+	var b strings.Builder
+	b.WriteString("**Prediction leaderboard**\n")
+	for i, score := range scores {
+		fmt.Fprintf(&b, "%d. %s: %d of %d correct\n", i+1, score.Name, score.Correct, score.Total)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// handleLeaderboardCommand responds with the prediction leaderboard of the
+// guild
+func (bot *bot) handleLeaderboardCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Predictions are only available in servers."), nil
+	}
+	leaderboard, err := bot.loadLeaderboard(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading leaderboard")
+	}
+	if len(leaderboard) == 0 {
+		return textResponse("No predictions have been scored yet."), nil
+	}
+	content := renderLeaderboard(leaderboard)
+	if comparison := bot.communityComparison(ctx, guildID(in.GuildID)); comparison != "" {
+		content += "\n\n" + comparison
+	}
+	return textResponse(content), nil
+}
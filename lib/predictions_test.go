@@ -0,0 +1,70 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestRenderPredictionPrompt(t *testing.T) {
+	game := dota.LiveLeagueGame{MatchID: 1, GameNumber: 2,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: 10, TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamID: 20, TeamName: "Team Liquid"}}
+	content, rows, ok := renderPredictionPrompt(game)
+	if !ok {
+		t.Fatal("renderPredictionPrompt() not ok, want a prompt")
+	}
+	if want := "Who wins OG vs. Team Liquid (Game 2)? Predictions lock when the game starts."; content != want {
+		t.Errorf("renderPredictionPrompt() = %q, want %q", content, want)
+	}
+	if len(rows) != 1 || len(rows[0].Components) != 2 || rows[0].Components[1].CustomID != "predict:1:20" ||
+		rows[0].Components[1].Label != "Team Liquid" {
+		t.Errorf("renderPredictionPrompt() buttons = %+v, want a button per team", rows)
+	}
+	game.DireTeam.TeamID = 0
+	if _, _, ok := renderPredictionPrompt(game); ok {
+		t.Error("renderPredictionPrompt() of a team without id is ok, want no prompt")
+	}
+}
+
+func TestPredictions(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, store: storage.NewMemoryStore()}
+	ctx := context.Background()
+	predict := func(userID, name, customID string) string {
+		in := &interaction{GuildID: "1", User: &discordgo.User{ID: userID, Username: name}}
+		in.Data.CustomID = customID
+		res, err := bot.handlePredict(ctx, in)
+		if err != nil {
+			t.Fatalf("handlePredict() error: %v", err)
+		}
+		return res.Content
+	}
+	predict("100", "alice", "predict:1:10")
+	predict("100", "alice", "predict:1:20")
+	predict("200", "bob", "predict:1:10")
+	if err := bot.store.Set(ctx, matchStateKey(matchStateStarted, 1), true, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := predict("300", "carol", "predict:1:20"); got != "The game has started, predictions are locked." {
+		t.Errorf("handlePredict() of a started game = %q, want locked", got)
+	}
+
+	bot.scorePredictions(ctx, matchesFinishedDataItem{MatchID: 1, WinnerTeamID: 20, LoserTeamID: 10})
+	// Scoring again must not count the predictions twice
+	bot.scorePredictions(ctx, matchesFinishedDataItem{MatchID: 1, WinnerTeamID: 20, LoserTeamID: 10})
+	leaderboard, err := bot.loadLeaderboard(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "**Prediction leaderboard**\n1. alice: 1 of 1 correct\n2. bob: 0 of 1 correct"
+	if got := renderLeaderboard(leaderboard); got != want {
+		t.Errorf("renderLeaderboard() = %q, want %q", got, want)
+	}
+}
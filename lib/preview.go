@@ -0,0 +1,173 @@
+package timatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// previewTemplate is an announcement template that can be rendered by
+// RenderPreview
+type previewTemplate struct {
+	name string
+	tmpl *template.Template
+	// decode decodes a JSON fixture into the data of the template
+	decode func(fixture []byte) (interface{}, error)
+	// fixture is the data used when no fixture is given
+	fixture interface{}
+}
+
+var previewGames = []dota.LiveLeagueGame{{
+	GameNumber:  1,
+	RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG", TeamID: 2586976},
+	DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Team Liquid", TeamID: 2163},
+}, {
+	GameNumber:  2,
+	RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "PSG.LGD", TeamID: 15},
+	DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Team Secret", TeamID: 1838315},
+}}
+
+var previewFinished = []matchesFinishedDataItem{{
+	GameNumber:  3,
+	WinnerName:  "OG",
+	LoserName:   "Team Liquid",
+	WinnerScore: 2,
+	LoserScore:  1,
+}}
+
+var previewTemplates = []previewTemplate{
+	{name: "drafting", tmpl: tmplMatchesDrafting, decode: decodeGamesFixture, fixture: previewGames},
+	{name: "started", tmpl: tmplMatchesStarted, decode: decodeGamesFixture, fixture: previewGames},
+	{
+		name: "scoreupdates",
+		tmpl: tmplScoreUpdates,
+		decode: func(fixture []byte) (interface{}, error) {
+			var updates []scoreUpdate
+			err := json.Unmarshal(fixture, &updates)
+			return updates, err
+		},
+		fixture: []scoreUpdate{{Game: previewGames[0], RadiantScore: 12, DireScore: 9, Duration: "20:00"}},
+	},
+	{
+		name: "finished",
+		tmpl: tmplMatchesFinished,
+		decode: func(fixture []byte) (interface{}, error) {
+			var items []matchesFinishedDataItem
+			err := json.Unmarshal(fixture, &items)
+			return items, err
+		},
+		fixture: previewFinished,
+	},
+	{
+		name: "flooddigest",
+		tmpl: tmplFloodDigest,
+		decode: func(fixture []byte) (interface{}, error) {
+			var digest floodDigest
+			err := json.Unmarshal(fixture, &digest)
+			return &digest, err
+		},
+		fixture: &floodDigest{Started: previewGames, Finished: previewFinished},
+	},
+}
+
+// decodeGamesFixture decodes a list of live league games, or a recorded
+// GetLiveLeagueGames response
+func decodeGamesFixture(fixture []byte) (interface{}, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(fixture), []byte("{")) {
+		var resp dota.LiveLeagueGamesResponse
+		err := json.Unmarshal(fixture, &resp)
+		return resp.Result.Games, err
+	}
+	var games []dota.LiveLeagueGame
+	err := json.Unmarshal(fixture, &games)
+	return games, err
+}
+
+// PreviewTemplateNames returns the names of the templates that can be
+// rendered by RenderPreview
+func PreviewTemplateNames() []string {
+	names := make([]string, 0, len(previewTemplates))
+	for _, preview := range previewTemplates {
+		names = append(names, preview.name)
+	}
+	return names
+}
+
+func findPreviewTemplate(name string) (*previewTemplate, error) {
+	for i := range previewTemplates {
+		if previewTemplates[i].name == name {
+			return &previewTemplates[i], nil
+		}
+	}
+	return nil, errors.Errorf("Unknown template %q, expected one of %s", name, strings.Join(PreviewTemplateNames(), ", "))
+}
+
+// PreviewFormat are the settings of the guild to render a preview for, by
+// the names used by /settings. Empty settings are left at their default.
+type PreviewFormat struct {
+	ScoreStyle   string
+	NumberLocale string
+	Language     string
+	Layout       string
+}
+
+// RenderPreview renders the announcement template name as it would be
+// sent to a guild with the given settings. If text is not empty it
+// replaces the bot's own template, and if fixture is nil a bundled
+// fixture is used as the data of the template.
+func RenderPreview(name, text string, fixture []byte, settings PreviewFormat) (string, error) {
+	preview, err := findPreviewTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	format := defaultTextFormat
+	if settings.ScoreStyle != "" {
+		style, ok := findScoreStyle(settings.ScoreStyle)
+		if !ok {
+			return "", errors.Errorf("Unknown score style %q", settings.ScoreStyle)
+		}
+		format.score = style
+	}
+	if settings.NumberLocale != "" {
+		locale, ok := findNumberLocale(settings.NumberLocale)
+		if !ok {
+			return "", errors.Errorf("Unknown number locale %q", settings.NumberLocale)
+		}
+		format.number = locale
+	}
+	if settings.Language != "" {
+		language, ok := findMessageLanguage(settings.Language)
+		if !ok {
+			return "", errors.Errorf("Unknown language %q", settings.Language)
+		}
+		format.language = language
+	}
+	if settings.Layout != "" {
+		layout, ok := findLayout(settings.Layout)
+		if !ok {
+			return "", errors.Errorf("Unknown layout %q", settings.Layout)
+		}
+		format.compact = layout == layoutCompact
+	}
+	tmpl := preview.tmpl
+	if text != "" {
+		if tmpl, err = newTemplate(name).Parse(strings.TrimSpace(text)); err != nil {
+			return "", errors.Wrap(err, "Error parsing template")
+		}
+	}
+	data := preview.fixture
+	if fixture != nil {
+		if data, err = preview.decode(fixture); err != nil {
+			return "", errors.Wrap(err, "Error decoding fixture")
+		}
+	}
+	msg, err := renderTemplate(tmpl, format, data)
+	if err != nil {
+		return "", errors.Wrap(err, "Error rendering template")
+	}
+	return msg, nil
+}
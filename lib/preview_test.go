@@ -0,0 +1,54 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPreviewBundled(t *testing.T) {
+	for _, name := range PreviewTemplateNames() {
+		msg, err := RenderPreview(name, "", nil, PreviewFormat{})
+		if err != nil {
+			t.Errorf("RenderPreview(%q) error: %v", name, err)
+			continue
+		}
+		if strings.TrimSpace(msg) == "" {
+			t.Errorf("RenderPreview(%q) = empty message", name)
+		}
+	}
+}
+
+func TestRenderPreview(t *testing.T) {
+	const fixture = `[{"WinnerName": "OG", "LoserName": "Team Liquid", "WinnerScore": 2, "LoserScore": 0, "GameNumber": 2}]`
+	const text = `{{ range . }}{{ .WinnerName }} {{ score .WinnerScore .LoserScore }} {{ .LoserName }}{{ end }}`
+	msg, err := RenderPreview("finished", text, []byte(fixture), PreviewFormat{ScoreStyle: "colon"})
+	if err != nil {
+		t.Fatalf("RenderPreview() error: %v", err)
+	}
+	if want := "OG 2:0 Team Liquid"; msg != want {
+		t.Errorf("RenderPreview() = %q, want %q", msg, want)
+	}
+}
+
+func TestRenderPreviewRecordedGames(t *testing.T) {
+	const fixture = `{"result": {"status": 200, "games": [{"game_number": 1, "radiant_team": {"team_name": "OG"}, "dire_team": {"team_name": "Team Liquid"}}]}}`
+	msg, err := RenderPreview("started", "", []byte(fixture), PreviewFormat{})
+	if err != nil {
+		t.Fatalf("RenderPreview() error: %v", err)
+	}
+	if want := "Match Started: OG vs. Team Liquid (Game 1)"; strings.TrimSpace(msg) != want {
+		t.Errorf("RenderPreview() = %q, want %q", msg, want)
+	}
+}
+
+func TestRenderPreviewErrors(t *testing.T) {
+	if _, err := RenderPreview("unknown", "", nil, PreviewFormat{}); err == nil {
+		t.Error("RenderPreview() of an unknown template, want error")
+	}
+	if _, err := RenderPreview("finished", "", nil, PreviewFormat{ScoreStyle: "unknown"}); err == nil {
+		t.Error("RenderPreview() with an unknown score style, want error")
+	}
+	if _, err := RenderPreview("finished", "", []byte("{"), PreviewFormat{}); err == nil {
+		t.Error("RenderPreview() with an invalid fixture, want error")
+	}
+}
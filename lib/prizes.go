@@ -0,0 +1,168 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultPrizeDistribution approximates the prize distribution of recent
+// editions of The International, for 18 teams. It is used if no other
+// distribution is configured; the announced distribution of a league
+// should be given instead when known. See ParsePrizeDistribution for the
+// format.
+const DefaultPrizeDistribution = "1:45.5,2:13,3:9,4:6,5-6:4.5,7-8:3,9-12:2,13-16:0.75,17-18:0.25"
+
+// prizePlacement is the share of the prize pool awarded to each of the
+// teams placing between FirstPlace and LastPlace
+type prizePlacement struct {
+	FirstPlace int
+	LastPlace  int
+	// Percent is the percentage of the prize pool awarded to each team
+	Percent float64
+}
+
+// PrizeDistribution is the distribution of the prize pool over the
+// placements of a tournament
+type PrizeDistribution []prizePlacement
+
+// ParsePrizeDistribution parses a comma separated list of prize
+// placements, each on the form "<place>:<percent>" or
+// "<first place>-<last place>:<percent per team>". Placements may not
+// overlap, and may not add up to more than 100%.
+func ParsePrizeDistribution(s string) (PrizeDistribution, error) {
+	distribution := make(PrizeDistribution, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		colon := strings.Index(part, ":")
+		if colon < 0 {
+			return nil, errors.Errorf("Missing ':' in placement %q", part)
+		}
+		places, percentStr := part[:colon], part[colon+1:]
+		percent, err := strconv.ParseFloat(percentStr, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid percentage in placement %q", part)
+		}
+		placement := prizePlacement{Percent: percent}
+		firstStr, lastStr := places, places
+		if dash := strings.Index(places, "-"); dash >= 0 {
+			firstStr, lastStr = places[:dash], places[dash+1:]
+		}
+		if placement.FirstPlace, err = strconv.Atoi(firstStr); err != nil {
+			return nil, errors.Wrapf(err, "Invalid place in placement %q", part)
+		}
+		if placement.LastPlace, err = strconv.Atoi(lastStr); err != nil {
+			return nil, errors.Wrapf(err, "Invalid place in placement %q", part)
+		}
+		if placement.FirstPlace < 1 || placement.LastPlace < placement.FirstPlace {
+			return nil, errors.Errorf("Invalid place range in placement %q", part)
+		}
+		if percent < 0 {
+			return nil, errors.Errorf("Negative percentage in placement %q", part)
+		}
+		for _, other := range distribution {
+			if placement.FirstPlace <= other.LastPlace && other.FirstPlace <= placement.LastPlace {
+				return nil, errors.Errorf("Placement %q overlaps another placement", part)
+			}
+		}
+		distribution = append(distribution, placement)
+	}
+	// Allow for rounding errors in the given percentages
+	if total := distribution.totalPercent(); total > 100.001 {
+		return nil, errors.Errorf("Placements add up to %.2f%%, more than 100%%", total)
+	}
+	return distribution, nil
+}
+
+// totalPercent returns the percentage of the prize pool awarded in total
+func (distribution PrizeDistribution) totalPercent() float64 {
+	total := 0.0
+	for _, placement := range distribution {
+		total += placement.Percent * float64(placement.LastPlace-placement.FirstPlace+1)
+	}
+	return total
+}
+
+// renderPrizeTable renders the prize pool and the prize of each placement
+// as a monospaced table
+func renderPrizeTable(prizePool int64, distribution PrizeDistribution, format textFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Prize pool: %s**\n```\n", format.Dollars(prizePool))
+	for _, placement := range distribution {
+		places := ordinal(placement.FirstPlace)
+		if placement.LastPlace != placement.FirstPlace {
+			places += "-" + ordinal(placement.LastPlace)
+		}
+		prize := int64(float64(prizePool) * placement.Percent / 100)
+		fmt.Fprintf(&b, "%-10s %5s%% %14s\n", places, format.Decimal(placement.Percent, 1), format.Dollars(prize))
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// ordinal returns the ordinal form of n, e.g. "1st", "12th", "23rd"
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// prizePoolCacheTime is the time a fetched prize pool is used before
+// being fetched again
+const prizePoolCacheTime = 1 * time.Minute
+
+// prizePoolCache caches the prize pool of the watched league
+type prizePoolCache struct {
+	mu        sync.Mutex
+	leagueID  int
+	amount    int64
+	fetchedAt time.Time
+}
+
+// getPrizePool returns the current prize pool of the watched league
+func (bot *bot) getPrizePool(ctx context.Context) (int64, error) {
+	leagueID := bot.currentLeagueID()
+	bot.prizePool.mu.Lock()
+	defer bot.prizePool.mu.Unlock()
+	if bot.prizePool.leagueID == leagueID && time.Since(bot.prizePool.fetchedAt) < prizePoolCacheTime {
+		return bot.prizePool.amount, nil
+	}
+	res, err := bot.dotaClient.GetTournamentPrizePool(ctx, leagueID)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error getting prize pool")
+	}
+	bot.prizePool.leagueID = leagueID
+	bot.prizePool.amount = res.Result.PrizePool
+	bot.prizePool.fetchedAt = time.Now()
+	return res.Result.PrizePool, nil
+}
+
+// handlePrizesCommand responds with the prize table of the watched league
+func (bot *bot) handlePrizesCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if bot.currentLeagueID() == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	prizePool, err := bot.getPrizePool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textResponse(renderPrizeTable(prizePool, bot.prizeDistribution, bot.interactionTextFormat(ctx, in))), nil
+}
@@ -0,0 +1,62 @@
+package timatch
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParsePrizeDistribution(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PrizeDistribution
+		wantErr bool
+	}{
+		{"1:50,2:30,3-4:10", PrizeDistribution{{1, 1, 50}, {2, 2, 30}, {3, 4, 10}}, false},
+		{" 1:60 , 2:40 ,", PrizeDistribution{{1, 1, 60}, {2, 2, 40}}, false},
+		{"", PrizeDistribution{}, false},
+		{"1", nil, true},
+		{"1:x", nil, true},
+		{"a:10", nil, true},
+		{"1-a:10", nil, true},
+		{"2-1:10", nil, true},
+		{"0:10", nil, true},
+		{"1:-10", nil, true},
+		{"1:50,1:10", nil, true},
+		{"1-3:10,3-4:10", nil, true},
+		{"1:60,2:50", nil, true},
+		{"1:50,2-3:30", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePrizeDistribution(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePrizeDistribution(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePrizeDistribution(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPrizeDistribution(t *testing.T) {
+	distribution, err := ParsePrizeDistribution(DefaultPrizeDistribution)
+	if err != nil {
+		t.Fatalf("Error parsing default distribution: %v", err)
+	}
+	if total := distribution.totalPercent(); math.Abs(total-100) > 0.001 {
+		t.Errorf("Default distribution adds up to %.2f%%, want 100%%", total)
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := map[int]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th",
+		13: "13th", 21: "21st", 22: "22nd", 23: "23rd", 101: "101st", 111: "111th",
+	}
+	for n, want := range tests {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package timatch
+
+import "time"
+
+// pruneInterval is the time between prunes of the state of finished
+// matches, see prune
+const pruneInterval = time.Hour
+
+// defaultPruneAfter is the default of Config.PruneAfter
+const defaultPruneAfter = 24 * time.Hour
+
+// markFinished records a match as finished at now, for its state to be
+// pruned once finished for long enough
+func (watcher *Watcher) markFinished(matchID int64, now time.Time) {
+	watcher.finished[matchID] = struct{}{}
+	watcher.finishedAt[matchID] = now
+}
+
+// prune forgets the state of the matches finished more than pruneAfter
+// ago, so that a watcher running for months does not keep every match it
+// has seen in memory. Matches that never finish, e.g. games seen drafting
+// that were remade, are forgotten once not seen live for pruneAfter.
+// Pruned matches are not sent again, as they are no longer live, the match
+// history is only searched for matches seen started, and their states are
+// still claimed. Matches yet to be sent as finished are kept.
+func (watcher *Watcher) prune(now time.Time) {
+	if now.Sub(watcher.prunedAt) < pruneInterval {
+		return
+	}
+	watcher.prunedAt = now
+	queued := make(map[int64]struct{}, len(watcher.finishedQueue))
+	for _, entry := range watcher.finishedQueue {
+		queued[entry.MatchID] = struct{}{}
+	}
+	pruned := 0
+	for matchID, finishedAt := range watcher.finishedAt {
+		if _, ok := queued[matchID]; ok || now.Sub(finishedAt) < watcher.pruneAfter {
+			continue
+		}
+		watcher.forget(matchID)
+		pruned++
+	}
+	for matchID, seenAt := range watcher.seenAt {
+		if _, ok := watcher.finishedAt[matchID]; ok {
+			continue
+		}
+		if _, ok := queued[matchID]; ok || now.Sub(seenAt) < watcher.pruneAfter {
+			continue
+		}
+		watcher.forget(matchID)
+		pruned++
+	}
+	if pruned > 0 {
+		watcher.logger.Debugf("Pruned the state of %d matches", pruned)
+	}
+}
+
+// forget deletes the state of a match kept in memory
+func (watcher *Watcher) forget(matchID int64) {
+	delete(watcher.finishedAt, matchID)
+	delete(watcher.seenAt, matchID)
+	delete(watcher.drafting, matchID)
+	delete(watcher.started, matchID)
+	delete(watcher.finished, matchID)
+	delete(watcher.live, matchID)
+	delete(watcher.startTimes, matchID)
+	delete(watcher.matchLeagues, matchID)
+	delete(watcher.backfilled, matchID)
+}
+
+// pruneMatches forgets the state the bot keeps of the matches the watcher
+// no longer tracks, see Watcher.prune, and the top live games seen more
+// than pruneAfter ago
+func (bot *bot) pruneMatches(now time.Time) {
+	if now.Sub(bot.prunedAt) < pruneInterval {
+		return
+	}
+	bot.prunedAt = now
+	pruned := 0
+	for matchID := range bot.gameNumbers {
+		if !bot.watcher.isTracked(matchID) {
+			bot.forgetMatch(matchID)
+			pruned++
+		}
+	}
+	for matchID, seenAt := range bot.topLiveSeen {
+		if now.Sub(seenAt) >= bot.pruneAfter {
+			delete(bot.topLiveSeen, matchID)
+			pruned++
+		}
+	}
+	if pruned > 0 {
+		bot.logger.Debugf("Pruned the state of %d matches", pruned)
+	}
+}
+
+// forgetMatch deletes the state of a match kept in memory
+func (bot *bot) forgetMatch(matchID int64) {
+	delete(bot.gameNumbers, matchID)
+	delete(bot.seriesIDs, matchID)
+	delete(bot.stages, matchID)
+	delete(bot.deficits, matchID)
+	delete(bot.matchImportance, matchID)
+	delete(bot.scoreUpdates, matchID)
+	delete(bot.hype, matchID)
+}
@@ -0,0 +1,83 @@
+package timatch
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPrune(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	now := time.Now()
+	watcher := newWatcher(logger, &stubDataSource{})
+	watcher.drafting = map[int64]struct{}{5: {}}
+	watcher.started = map[int64]struct{}{1: {}, 2: {}, 3: {}, 4: {}, 6: {}}
+	watcher.seenAt = map[int64]time.Time{
+		1: now.Add(-26 * time.Hour), 4: now.Add(-time.Minute),
+		5: now.Add(-25 * time.Hour), 6: now.Add(-25 * time.Hour),
+	}
+	watcher.finishedQueue = []finishedQueueEntry{{MatchID: 3}, {MatchID: 6}}
+	watcher.markFinished(1, now.Add(-25*time.Hour))
+	watcher.markFinished(2, now.Add(-time.Hour))
+	watcher.markFinished(3, now.Add(-25*time.Hour))
+	watcher.prune(now)
+	if _, ok := watcher.started[1]; ok {
+		t.Error("expected match 1, finished 25 hours ago, to be pruned")
+	}
+	if _, ok := watcher.finished[2]; !ok {
+		t.Error("expected match 2, finished an hour ago, to be kept")
+	}
+	if _, ok := watcher.finished[3]; !ok {
+		t.Error("expected match 3, yet to be sent, to be kept")
+	}
+	if _, ok := watcher.started[4]; !ok {
+		t.Error("expected match 4, yet to finish, to be kept")
+	}
+	if _, ok := watcher.drafting[5]; ok {
+		t.Error("expected match 5, last seen drafting 25 hours ago, to be pruned")
+	}
+	if _, ok := watcher.started[6]; !ok {
+		t.Error("expected match 6, yet to be sent, to be kept")
+	}
+	// Pruned at most every pruneInterval
+	watcher.finishedQueue = nil
+	watcher.prune(now.Add(time.Minute))
+	if _, ok := watcher.finished[3]; !ok {
+		t.Error("expected no prune within the prune interval")
+	}
+}
+
+func TestPruneMatches(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	now := time.Now()
+	watcher := newWatcher(logger, &stubDataSource{})
+	watcher.started[2] = struct{}{}
+	bot := &bot{
+		logger:      logger,
+		watcher:     watcher,
+		pruneAfter:  24 * time.Hour,
+		gameNumbers: map[int64]int{1: 1, 2: 2},
+		seriesIDs:   map[int64]int64{1: 10},
+		topLiveSeen: map[int64]time.Time{7: now.Add(-25 * time.Hour), 8: now.Add(-time.Hour)},
+	}
+	bot.pruneMatches(now)
+	if _, ok := bot.gameNumbers[1]; ok {
+		t.Error("expected match 1, no longer tracked by the watcher, to be pruned")
+	}
+	if _, ok := bot.seriesIDs[1]; ok {
+		t.Error("expected the series of match 1 to be pruned")
+	}
+	if _, ok := bot.gameNumbers[2]; !ok {
+		t.Error("expected match 2, tracked by the watcher, to be kept")
+	}
+	if _, ok := bot.topLiveSeen[7]; ok {
+		t.Error("expected top live game 7, seen 25 hours ago, to be pruned")
+	}
+	if _, ok := bot.topLiveSeen[8]; !ok {
+		t.Error("expected top live game 8, seen an hour ago, to be kept")
+	}
+}
@@ -0,0 +1,164 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// quietQueueKeyPrefix is the prefix of the store keys of the messages held
+// back from channels during quiet hours
+const quietQueueKeyPrefix = "quiet/"
+
+// quietQueueKey returns the store key of the messages held back from a
+// channel
+func quietQueueKey(channelID channelID) string {
+	return quietQueueKeyPrefix + string(channelID)
+}
+
+// quietHours is a daily period during which announcements to a guild are
+// held back, to be sent as a digest when the period ends
+type quietHours struct {
+	// Start and End are minutes after midnight. Quiet hours where End is
+	// before Start span midnight
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// quietQueue are the messages held back from a channel until Until
+type quietQueue struct {
+	Until    time.Time `json:"until"`
+	Messages []string  `json:"messages"`
+}
+
+// parseQuietHours parses quiet hours given as "HH:MM-HH:MM", optionally
+// followed by an IANA time zone, e.g. "01:00-09:00 Europe/Stockholm".
+// The time zone defaults to UTC.
+func parseQuietHours(value string) (*quietHours, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, errors.New("Give the quiet hours as e.g. 01:00-09:00 Europe/Stockholm")
+	}
+	parts := strings.Split(fields[0], "-")
+	if len(parts) != 2 {
+		return nil, errors.New("Give the quiet hours as e.g. 01:00-09:00 Europe/Stockholm")
+	}
+	quiet := &quietHours{}
+	for i, part := range parts {
+		t, err := time.Parse("15:04", part)
+		if err != nil {
+			return nil, errors.Errorf("Invalid time %q, give times as HH:MM", part)
+		}
+		minutes := t.Hour()*60 + t.Minute()
+		if i == 0 {
+			quiet.Start = minutes
+		} else {
+			quiet.End = minutes
+		}
+	}
+	if quiet.Start == quiet.End {
+		return nil, errors.New("The quiet hours must not start and end at the same time")
+	}
+	if len(fields) == 2 {
+		if _, err := time.LoadLocation(fields[1]); err != nil {
+			return nil, errors.Errorf("Unknown time zone %q, give e.g. Europe/Stockholm", fields[1])
+		}
+		quiet.Timezone = fields[1]
+	}
+	return quiet, nil
+}
+
+func (quiet *quietHours) String() string {
+	s := fmt.Sprintf("%02d:%02d-%02d:%02d", quiet.Start/60, quiet.Start%60, quiet.End/60, quiet.End%60)
+	if quiet.Timezone != "" {
+		s += " " + quiet.Timezone
+	}
+	return s
+}
+
+// end returns the time the quiet hours that t is within end, or the zero
+// time if t is not within the quiet hours
+func (quiet *quietHours) end(t time.Time) time.Time {
+	loc := time.UTC
+	if quiet.Timezone != "" {
+		if l, err := time.LoadLocation(quiet.Timezone); err == nil {
+			loc = l
+		}
+	}
+	t = t.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	endOn := func(days int) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day()+days, quiet.End/60, quiet.End%60, 0, 0, loc)
+	}
+	if quiet.Start < quiet.End {
+		if minute >= quiet.Start && minute < quiet.End {
+			return endOn(0)
+		}
+		return time.Time{}
+	}
+	if minute >= quiet.Start {
+		return endOn(1)
+	}
+	if minute < quiet.End {
+		return endOn(0)
+	}
+	return time.Time{}
+}
+
+// quietHoursEnd returns the time the current quiet hours of the guild
+// end, or the zero time if the guild is not in quiet hours at now
+func (settings *guildSettings) quietHoursEnd(now time.Time) time.Time {
+	if settings.QuietHours == nil {
+		return time.Time{}
+	}
+	return settings.QuietHours.end(now)
+}
+
+// queueQuietMessage holds back a message from a channel in quiet hours,
+// until the quiet hours end at until
+func (bot *bot) queueQuietMessage(ctx context.Context, channelID channelID, until time.Time, content string) error {
+	var queue quietQueue
+	if _, err := bot.store.Get(ctx, quietQueueKey(channelID), &queue); err != nil {
+		return errors.Wrap(err, "Error getting quiet hours queue")
+	}
+	queue.Until = until
+	queue.Messages = append(queue.Messages, content)
+	err := bot.store.Set(ctx, quietQueueKey(channelID), queue, 0)
+	return errors.Wrap(err, "Error saving quiet hours queue")
+}
+
+// sendQuietDigests sends the messages held back during quiet hours that
+// have ended as a digest, one per channel
+func (bot *bot) sendQuietDigests(ctx context.Context) {
+	keys, err := bot.store.Keys(ctx, quietQueueKeyPrefix)
+	if err != nil {
+		bot.logger.WithError(err).Error("Error listing quiet hours queues")
+		return
+	}
+	now := time.Now()
+	for _, key := range keys {
+		channelID := channelID(strings.TrimPrefix(key, quietQueueKeyPrefix))
+		logger := bot.logger.WithField(logFieldChannelID, channelID)
+		var queue quietQueue
+		found, err := bot.store.Get(ctx, key, &queue)
+		if err != nil {
+			logger.WithError(err).Error("Error getting quiet hours queue")
+			continue
+		}
+		if !found || now.Before(queue.Until) {
+			continue
+		}
+		// The queue is deleted before sending, so that the digest is
+		// not sent again should sending fail part way
+		if err := bot.store.Delete(ctx, key); err != nil {
+			logger.WithError(err).Error("Error deleting quiet hours queue")
+			continue
+		}
+		content := "Announcements during quiet hours:\n" + strings.Join(queue.Messages, "\n")
+		bot.sendChannelMessage(channelID, content, false)
+	}
+}
@@ -0,0 +1,59 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *quietHours
+		wantErr bool
+	}{
+		{"01:00-09:00", &quietHours{Start: 60, End: 540}, false},
+		{"23:30-07:15 UTC", &quietHours{Start: 1410, End: 435, Timezone: "UTC"}, false},
+		{"09:00-09:00", nil, true},
+		{"9-10", nil, true},
+		{"01:00", nil, true},
+		{"01:00-09:00 Nowhere/City", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseQuietHours(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseQuietHours(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuietHours(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	day := func(hour, min int) time.Time {
+		return time.Date(2019, 8, 20, hour, min, 0, 0, time.UTC)
+	}
+	morning := &quietHours{Start: 60, End: 540}
+	overnight := &quietHours{Start: 22 * 60, End: 6 * 60}
+	tests := []struct {
+		quiet *quietHours
+		t     time.Time
+		want  time.Time
+	}{
+		{morning, day(0, 59), time.Time{}},
+		{morning, day(1, 0), day(9, 0)},
+		{morning, day(8, 59), day(9, 0)},
+		{morning, day(9, 0), time.Time{}},
+		{overnight, day(21, 0), time.Time{}},
+		{overnight, day(23, 0), day(6, 0).AddDate(0, 0, 1)},
+		{overnight, day(5, 0), day(6, 0)},
+		{overnight, day(6, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := tt.quiet.end(tt.t); !got.Equal(tt.want) {
+			t.Errorf("end(%v) of %v = %v, want %v", tt.t, tt.quiet, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recapSentKey returns the store key of the day the last recap was sent
+// to a channel, as "2006-01-02" in the time zone of the guild
+func recapSentKey(channelID channelID) string {
+	return "recap/" + string(channelID)
+}
+
+// parseRecapTime parses the time of day of recaps, given as "HH:MM"
+func parseRecapTime(value string) (string, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return "", errors.Errorf("Invalid time %q, give the time of the recap as HH:MM", value)
+	}
+	return t.Format("15:04"), nil
+}
+
+// recapDue returns the start of the day of the recap due at now, or
+// the zero time if the recap of the day is not due yet
+func (settings *guildSettings) recapDue(now time.Time) time.Time {
+	if settings.Recap == "" {
+		return time.Time{}
+	}
+	at, err := time.Parse("15:04", settings.Recap)
+	if err != nil {
+		return time.Time{}
+	}
+	startOfDay := settings.textFormat().StartOfDay(now)
+	if now.Before(startOfDay.Add(time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute)) {
+		return time.Time{}
+	}
+	return startOfDay
+}
+
+// renderRecap renders the recap of the results of a day, with the games
+// of each series together. results are the results of the day, most
+// recently finished first as given by loadResults.
+func renderRecap(results []matchResult, format textFormat) string {
+	var b strings.Builder
+	b.WriteString("**Recap of today's games**\n")
+	// Series are listed in the order they finished their first game of
+	// the day, and games without a series on their own
+	var order []int64
+	bySeries := make(map[int64][]matchResult)
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		key := result.SeriesID
+		if key == 0 {
+			key = -result.MatchID
+		}
+		if _, ok := bySeries[key]; !ok {
+			order = append(order, key)
+		}
+		bySeries[key] = append(bySeries[key], result)
+	}
+	for _, key := range order {
+		games := bySeries[key]
+		if len(games) > 1 {
+			first, second := games[0].FirstTeam(), games[0].SecondTeam()
+			fmt.Fprintf(&b, "%s vs. %s:\n", first, second)
+		}
+		for _, result := range games {
+			line := renderResult(result, false, format)
+			if result.Duration > 0 {
+				line += " in " + formatDuration(float32(result.Duration))
+			}
+			if len(games) > 1 {
+				line = "• " + line
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sendRecaps sends the recap of the day to the channels of guilds whose
+// recap time has passed, once per day. Recaps are rendered from the
+// stored results.
+func (bot *bot) sendRecaps(ctx context.Context) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return
+	}
+	now := time.Now()
+	var results []matchResult
+	loaded := false
+	bot.forEachChannel(ctx, func(channelID channelID, settings *guildSettings, sub *channelSubscription) {
+		day := settings.recapDue(now)
+		if day.IsZero() || !sub.includesEvent(eventRecap) {
+			return
+		}
+		logger := bot.logger.WithField(logFieldChannelID, channelID)
+		var sentDay string
+		if _, err := bot.store.Get(ctx, recapSentKey(channelID), &sentDay); err != nil {
+			logger.WithError(err).Error("Error getting recap state")
+			return
+		}
+		if sentDay == day.Format("2006-01-02") {
+			return
+		}
+		if err := bot.store.Set(ctx, recapSentKey(channelID), day.Format("2006-01-02"), 2*24*time.Hour); err != nil {
+			logger.WithError(err).Error("Error storing recap state")
+			return
+		}
+		if !loaded {
+			var err error
+			if results, err = bot.loadResults(ctx, leagueID); err != nil {
+				logger.WithError(err).Error("Error loading results")
+				return
+			}
+			loaded = true
+		}
+		var today []matchResult
+		for _, result := range results {
+			if !result.FinishedAt.Before(day) && result.FinishedAt.Before(now) {
+				today = append(today, result)
+			}
+		}
+		if len(today) == 0 {
+			// Days without games are not worth a message
+			return
+		}
+		format := settings.channelTextFormat(string(channelID))
+		bot.deliverGuildMessage(ctx, channelID, settings, eventRecap, renderRecap(today, format), nil)
+	})
+}
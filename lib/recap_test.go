@@ -0,0 +1,44 @@
+package timatch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecapDue(t *testing.T) {
+	settings := &guildSettings{Recap: "23:00", Timezone: "Europe/Stockholm"}
+	// 21:30 UTC is 23:30 in Stockholm in the summer
+	now := time.Date(2019, 8, 20, 21, 30, 0, 0, time.UTC)
+	day := settings.recapDue(now)
+	if want := time.Date(2019, 8, 19, 22, 0, 0, 0, time.UTC); !day.Equal(want) {
+		t.Errorf("recapDue() = %v, want %v", day, want)
+	}
+	if day := settings.recapDue(now.Add(-time.Hour)); !day.IsZero() {
+		t.Errorf("recapDue() before the recap time = %v, want zero", day)
+	}
+	if day := (&guildSettings{}).recapDue(now); !day.IsZero() {
+		t.Errorf("recapDue() without recap = %v, want zero", day)
+	}
+}
+
+func TestRenderRecap(t *testing.T) {
+	game := func(matchID, seriesID int64, gameNumber int, winner, loser string) matchResult {
+		return matchResult{Duration: 2100, matchesFinishedDataItem: matchesFinishedDataItem{
+			MatchID: matchID, SeriesID: seriesID, GameNumber: gameNumber,
+			WinnerName: winner, LoserName: loser, WinnerScore: 30, LoserScore: 20}}
+	}
+	// Most recently finished first, like loadResults
+	results := []matchResult{
+		game(3, 0, 1, "Secret", "PSG.LGD"),
+		game(2, 7, 2, "OG", "Team Liquid"),
+		game(1, 7, 1, "Team Liquid", "OG"),
+	}
+	want := "**Recap of today's games**\n" +
+		"OG vs. Team Liquid:\n" +
+		"• Team Liquid defeated OG (30 - 20, Game 1) in 35:00\n" +
+		"• OG defeated Team Liquid (30 - 20, Game 2) in 35:00\n" +
+		"Secret defeated PSG.LGD (30 - 20, Game 1) in 35:00"
+	if got := renderRecap(results, defaultTextFormat); got != want {
+		t.Errorf("renderRecap() = %q, want %q", got, want)
+	}
+}
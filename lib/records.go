@@ -0,0 +1,146 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// goldDeficits are the largest net worth deficits of the teams of a
+// game, by team id
+type goldDeficits map[int]int
+
+// recordGame is a game holding a tournament record, and the value of
+// the record
+type recordGame struct {
+	MatchID int64  `json:"match_id"`
+	Winner  string `json:"winner"`
+	Loser   string `json:"loser"`
+	Value   int    `json:"value"`
+}
+
+// tournamentRecords are the records of a league. A nil record has not
+// been set yet.
+type tournamentRecords struct {
+	Longest   *recordGame `json:"longest,omitempty"`
+	Shortest  *recordGame `json:"shortest,omitempty"`
+	MostKills *recordGame `json:"most_kills,omitempty"`
+	// Comeback is the game won from the largest net worth deficit, only
+	// known for games seen live
+	Comeback *recordGame `json:"comeback,omitempty"`
+}
+
+func recordsKey(leagueID int) string {
+	return "records/" + strconv.Itoa(leagueID)
+}
+
+// trackDeficits updates the largest net worth deficits of the teams of a
+// live game, from the net worth of the players on the scoreboard
+func (bot *bot) trackDeficits(game dota.LiveLeagueGame) {
+	if game.RadiantTeam.TeamID == 0 || game.DireTeam.TeamID == 0 {
+		return
+	}
+	radiant, dire := 0, 0
+	for _, player := range game.Scoreboard.Radiant.Players {
+		radiant += player.NetWorth
+	}
+	for _, player := range game.Scoreboard.Dire.Players {
+		dire += player.NetWorth
+	}
+	deficits, ok := bot.deficits[game.MatchID]
+	if !ok {
+		deficits = make(goldDeficits)
+		bot.deficits[game.MatchID] = deficits
+	}
+	if dire-radiant > deficits[game.RadiantTeam.TeamID] {
+		deficits[game.RadiantTeam.TeamID] = dire - radiant
+	}
+	if radiant-dire > deficits[game.DireTeam.TeamID] {
+		deficits[game.DireTeam.TeamID] = radiant - dire
+	}
+}
+
+// updateRecords updates the records with a finished game, returning the
+// announcements of the records broken. comeback is the largest net
+// worth deficit the winner overcame, 0 if not known. Records are set
+// without an announcement by the first game of the league.
+func updateRecords(records *tournamentRecords, result matchResult, comeback int, leagueName string, format textFormat) []string {
+	game := func(value int) *recordGame {
+		return &recordGame{MatchID: result.MatchID, Winner: result.WinnerName, Loser: result.LoserName, Value: value}
+	}
+	var broken []string
+	kills := result.WinnerScore + result.LoserScore
+	if result.Duration > 0 {
+		if records.Longest == nil || result.Duration > records.Longest.Value {
+			if records.Longest != nil {
+				broken = append(broken, fmt.Sprintf("New longest game of %s: %s vs. %s lasted %d minutes!",
+					leagueName, result.WinnerName, result.LoserName, result.Duration/60))
+			}
+			records.Longest = game(result.Duration)
+		}
+		if records.Shortest == nil || result.Duration < records.Shortest.Value {
+			if records.Shortest != nil {
+				broken = append(broken, fmt.Sprintf("New shortest game of %s: %s defeated %s in %d minutes!",
+					leagueName, result.WinnerName, result.LoserName, result.Duration/60))
+			}
+			records.Shortest = game(result.Duration)
+		}
+	}
+	if kills > 0 && (records.MostKills == nil || kills > records.MostKills.Value) {
+		if records.MostKills != nil {
+			broken = append(broken, fmt.Sprintf("New most kills in a game of %s: %s in %s vs. %s!",
+				leagueName, format.Integer(int64(kills)), result.WinnerName, result.LoserName))
+		}
+		records.MostKills = game(kills)
+	}
+	if comeback > 0 && (records.Comeback == nil || comeback > records.Comeback.Value) {
+		if records.Comeback != nil {
+			broken = append(broken, fmt.Sprintf("New biggest comeback of %s: %s beat %s from %s gold behind!",
+				leagueName, result.WinnerName, result.LoserName, format.Integer(int64(comeback))))
+		}
+		records.Comeback = game(comeback)
+	}
+	return broken
+}
+
+// checkRecords updates the records of the league with a finished game,
+// announcing the records it broke
+func (bot *bot) checkRecords(ctx context.Context, result matchResult) {
+	comeback := bot.deficits[result.MatchID][result.WinnerTeamID]
+	delete(bot.deficits, result.MatchID)
+	bot.leagueMu.RLock()
+	leagueID, leagueName := bot.leagueID, bot.leagueName
+	bot.leagueMu.RUnlock()
+	if leagueName == "" {
+		leagueName = fmt.Sprintf("League %d", leagueID)
+	}
+	logger := bot.logger.WithField(logFieldLeagueID, leagueID)
+	var records tournamentRecords
+	if _, err := bot.store.Get(ctx, recordsKey(leagueID), &records); err != nil {
+		logger.WithError(err).Error("Error getting records")
+		return
+	}
+	// updateRecords replaces the records broken rather than changing
+	// them, so previous keeps the records before the game
+	previous := records
+	broken := updateRecords(&records, result, comeback, leagueName, defaultTextFormat)
+	if records == previous {
+		return
+	}
+	if err := bot.store.Set(ctx, recordsKey(leagueID), records, resultTTL); err != nil {
+		logger.WithError(err).Error("Error storing records")
+		return
+	}
+	if len(broken) == 0 {
+		return
+	}
+	bot.sendGuildMessage(ctx, eventRecords, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+		// Rendered again in the format of each channel
+		records := previous
+		broken := updateRecords(&records, result, comeback, leagueName, settings.channelTextFormat(string(channelID)))
+		return strings.Join(broken, "\n")
+	})
+}
@@ -0,0 +1,49 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestTrackDeficits(t *testing.T) {
+	bot := &bot{deficits: make(map[int64]goldDeficits)}
+	game := dota.LiveLeagueGame{MatchID: 1,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: 10}, DireTeam: dota.LiveLeagueGamesTeam{TeamID: 20}}
+	game.Scoreboard.Radiant.Players = []dota.LiveLeagueGameScoreboardPlayer{{NetWorth: 5000}}
+	game.Scoreboard.Dire.Players = []dota.LiveLeagueGameScoreboardPlayer{{NetWorth: 20000}}
+	bot.trackDeficits(game)
+	game.Scoreboard.Radiant.Players[0].NetWorth = 30000
+	bot.trackDeficits(game)
+	if want := (goldDeficits{10: 15000, 20: 10000}); !reflect.DeepEqual(bot.deficits[1], want) {
+		t.Errorf("Deficits = %v, want %v", bot.deficits[1], want)
+	}
+}
+
+func TestUpdateRecords(t *testing.T) {
+	game := func(matchID int64, duration, winnerScore, loserScore int) matchResult {
+		return matchResult{Duration: duration, matchesFinishedDataItem: matchesFinishedDataItem{MatchID: matchID,
+			WinnerName: "OG", LoserName: "Team Liquid", WinnerScore: winnerScore, LoserScore: loserScore}}
+	}
+	var records tournamentRecords
+	if broken := updateRecords(&records, game(1, 2400, 30, 20), 5000, "TI", defaultTextFormat); len(broken) != 0 {
+		t.Errorf("updateRecords() of the first game = %v, want no announcements", broken)
+	}
+	broken := updateRecords(&records, game(2, 4260, 40, 20), 8000, "TI", defaultTextFormat)
+	want := []string{
+		"New longest game of TI: OG vs. Team Liquid lasted 71 minutes!",
+		"New most kills in a game of TI: 60 in OG vs. Team Liquid!",
+		"New biggest comeback of TI: OG beat Team Liquid from 8,000 gold behind!",
+	}
+	if !reflect.DeepEqual(broken, want) {
+		t.Errorf("updateRecords() = %q, want %q", broken, want)
+	}
+	broken = updateRecords(&records, game(3, 900, 10, 2), 0, "TI", defaultTextFormat)
+	if want := []string{"New shortest game of TI: OG defeated Team Liquid in 15 minutes!"}; !reflect.DeepEqual(broken, want) {
+		t.Errorf("updateRecords() = %q, want %q", broken, want)
+	}
+	if records.Longest.MatchID != 2 || records.Shortest.MatchID != 3 || records.Comeback.Value != 8000 {
+		t.Errorf("Records = %+v, want longest 2, shortest 3 and a comeback of 8000", records)
+	}
+}
@@ -0,0 +1,40 @@
+package timatch
+
+import (
+	"github.com/verath/timatch/lib/dota"
+)
+
+// Thresholds for games to count as remade, e.g. after a disconnect in the
+// first minutes or a game setting being wrong
+const (
+	// remakeMaxDuration and remakeMaxKills are the longest duration, in
+	// seconds, and the most kills of a game ended early without a fight
+	remakeMaxDuration = 6 * 60
+	remakeMaxKills    = 2
+	// restartMaxDuration is the longest duration of a game followed by
+	// a new game of the same teams, which restarted the game
+	restartMaxDuration = 15 * 60
+)
+
+// isRemake tests if a finished game was remade rather than played out:
+// either it ended within minutes with next to no kills, or it ended early
+// and the same teams are already playing a new game. live are the live
+// games.
+func isRemake(matchID int64, details *dota.MatchDetails, live []dota.LiveLeagueGame) bool {
+	if details.Duration <= remakeMaxDuration && details.RadiantScore+details.DireScore <= remakeMaxKills {
+		return true
+	}
+	if details.Duration > restartMaxDuration || details.RadiantTeamID == 0 || details.DireTeamID == 0 {
+		return false
+	}
+	for _, game := range live {
+		if game.MatchID == matchID {
+			continue
+		}
+		teams := [2]int{game.RadiantTeam.TeamID, game.DireTeam.TeamID}
+		if teams == [2]int{details.RadiantTeamID, details.DireTeamID} || teams == [2]int{details.DireTeamID, details.RadiantTeamID} {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestIsRemake(t *testing.T) {
+	live := []dota.LiveLeagueGame{{
+		MatchID:     2,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: 20},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamID: 10},
+	}}
+	tests := []struct {
+		details dota.MatchDetails
+		want    bool
+	}{
+		{dota.MatchDetails{Duration: 3 * 60, RadiantScore: 1}, true},
+		{dota.MatchDetails{Duration: 3 * 60, RadiantScore: 2, DireScore: 3}, false},
+		// Restarted, the same teams are playing again
+		{dota.MatchDetails{Duration: 12 * 60, RadiantScore: 4, DireScore: 3, RadiantTeamID: 10, DireTeamID: 20}, true},
+		{dota.MatchDetails{Duration: 12 * 60, RadiantScore: 4, DireScore: 3, RadiantTeamID: 10, DireTeamID: 30}, false},
+		{dota.MatchDetails{Duration: 30 * 60, RadiantScore: 4, DireScore: 3, RadiantTeamID: 10, DireTeamID: 20}, false},
+	}
+	for _, test := range tests {
+		if got := isRemake(1, &test.details, live); got != test.want {
+			t.Errorf("isRemake(%+v) = %v, want %v", test.details, got, test.want)
+		}
+	}
+}
+
+func TestRenderRemake(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 1, WinnerName: "OG", LoserName: "Liquid", Remake: true}}
+	got, err := renderTemplate(tmplMatchesFinished, defaultTextFormat, items)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	if strings.Contains(got, "defeated") || !strings.Contains(got, "Game Remade:") {
+		t.Errorf("renderTemplate() of remake = %q, want no winner", got)
+	}
+}
@@ -0,0 +1,62 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxReminderMinutes is the longest a reminder can be set before the
+// start of a series
+const maxReminderMinutes = 24 * 60
+
+// reminderLead returns how long before the scheduled start of a series it
+// is announced to the channel of a subscription: the reminder of the
+// channel if set, else upNextLead
+func reminderLead(sub *channelSubscription) time.Duration {
+	if sub != nil && sub.ReminderMinutes > 0 {
+		return time.Duration(sub.ReminderMinutes) * time.Minute
+	}
+	return upNextLead
+}
+
+// renderReminder renders the reminder of a series starting in
+// untilStart, e.g. "⏰ OG vs. Team Liquid starts in 30 minutes, at
+// 18:00 CEST"
+func (bot *bot) renderReminder(series scheduledSeries, untilStart time.Duration, format textFormat) string {
+	minutes := int((untilStart + time.Minute/2) / time.Minute)
+	return fmt.Sprintf("⏰ %s starts in %d minutes, at %s", bot.renderScheduledSeries(series), minutes, format.Time(series.ScheduledTime))
+}
+
+// handleRemindCommand shows or changes how long before scheduled series
+// start the channel the command is used in is reminded of them
+func (bot *bot) handleRemindCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	minutes := in.intOption("minutes", -1)
+	if minutes < 0 {
+		sub, err := bot.interactionSubscription(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		if sub == nil {
+			return textResponse("This channel is not subscribed to announcements."), nil
+		}
+		if sub.ReminderMinutes == 0 {
+			return textResponse(fmt.Sprintf("This channel gets series announced %d minutes before they start.", int(upNextLead/time.Minute))), nil
+		}
+		return textResponse(fmt.Sprintf("This channel is reminded of series %d minutes before they start.", sub.ReminderMinutes)), nil
+	}
+	if minutes > maxReminderMinutes {
+		return textResponse(fmt.Sprintf("Give at most %d minutes.", maxReminderMinutes)), nil
+	}
+	return bot.changeSubscription(ctx, in, func(settings *guildSettings) string {
+		sub := settings.subscription(in.ChannelID)
+		if sub == nil {
+			return "This channel is not subscribed to announcements."
+		}
+		sub.ReminderMinutes = minutes
+		if minutes == 0 {
+			return fmt.Sprintf("This channel will get series announced %d minutes before they start.", int(upNextLead/time.Minute))
+		}
+		return fmt.Sprintf("This channel will be reminded of series %d minutes before they start.", minutes)
+	})
+}
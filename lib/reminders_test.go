@@ -0,0 +1,48 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestHandleRemindCommand(t *testing.T) {
+	ctx := context.Background()
+	bot := &bot{store: storage.NewMemoryStore()}
+	in := &interaction{ChannelID: "10", User: &discordgo.User{ID: "1"}}
+	bot.changeDMSubscription(ctx, in, func(settings *guildSettings) string {
+		settings.Subscriptions = append(settings.Subscriptions, channelSubscription{ChannelID: in.ChannelID})
+		return "ok"
+	})
+	in.Data.Options = []interactionDataOption{{Name: "minutes", Value: json.RawMessage("60")}}
+	if _, err := bot.handleRemindCommand(ctx, in); err != nil {
+		t.Fatalf("handleRemindCommand() error: %v", err)
+	}
+	sub, err := bot.interactionSubscription(ctx, in)
+	if err != nil {
+		t.Fatalf("interactionSubscription() error: %v", err)
+	}
+	if sub.ReminderMinutes != 60 {
+		t.Errorf("ReminderMinutes = %d, want 60", sub.ReminderMinutes)
+	}
+	if lead := reminderLead(sub); lead != time.Hour {
+		t.Errorf("reminderLead() = %v, want 1h", lead)
+	}
+	if lead := reminderLead(nil); lead != upNextLead {
+		t.Errorf("reminderLead() without subscription = %v, want %v", lead, upNextLead)
+	}
+}
+
+func TestRenderReminder(t *testing.T) {
+	bot := &bot{teamNames: map[int]string{1: "OG", 2: "Team Liquid"}}
+	series := scheduledSeries{ScheduledTime: time.Date(2019, 8, 20, 18, 0, 0, 0, time.UTC), TeamID1: 1, TeamID2: 2}
+	got := bot.renderReminder(series, 30*time.Minute-10*time.Second, textFormat{})
+	want := "⏰ OG vs. Team Liquid starts in 30 minutes, at 18:00 UTC"
+	if got != want {
+		t.Errorf("renderReminder() = %q, want %q", got, want)
+	}
+}
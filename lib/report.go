@@ -0,0 +1,527 @@
+package timatch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// reportTopHeroes is the number of heroes listed as most picked and most
+// banned in the tournament report
+const reportTopHeroes = 5
+
+// reportEmbedColor is the color of the tournament report embeds
+const reportEmbedColor = 0xC23C2A
+
+// reportPostedKey returns the store key recording that the report of a
+// league has been posted
+func reportPostedKey(leagueID int) string {
+	return "report/" + strconv.Itoa(leagueID) + "/posted"
+}
+
+// tournamentReport is the "tournament in numbers" report of a league,
+// built from the stored match results and the playoff bracket
+type tournamentReport struct {
+	LeagueName string
+	Games      int
+	// Duration is the total game time of all games
+	Duration   time.Duration
+	MostPicked []heroCount
+	MostBanned []heroCount
+	// LongestGame is nil if no games were played
+	LongestGame *matchResult
+	// BiggestStomp is the game won by the most kills, nil if no games
+	// were played
+	BiggestStomp *matchResult
+	// Upset is nil if no game was won by the lower placed team
+	Upset *reportUpset
+	// Champion is empty if the bracket has not been decided
+	Champion     string
+	ChampionID   int
+	ChampionPath []reportSeries
+	// ChampionLogo is the url of the champion's logo, "" if not known
+	ChampionLogo string
+}
+
+type heroCount struct {
+	Name  string
+	Count int
+}
+
+// reportUpset is a game won by the team placing lower in the final
+// standings
+type reportUpset struct {
+	Result          matchResult
+	WinnerStanding  int
+	LoserStanding   int
+	standingsGained int
+}
+
+// reportSeries is a playoff series played by the champion
+type reportSeries struct {
+	Round    string
+	Opponent string
+	Wins     int
+	Losses   int
+}
+
+// Hours returns the total game time, in hours
+func (report *tournamentReport) Hours() string {
+	return defaultTextFormat.Decimal(report.Duration.Hours(), 1)
+}
+
+// AverageDuration returns the average game duration as m:ss, or "-" if
+// no games were played
+func (report *tournamentReport) AverageDuration() string {
+	if report.Games == 0 {
+		return "-"
+	}
+	return formatDuration(float32(report.Duration.Seconds()) / float32(report.Games))
+}
+
+// buildReport builds the report of a league from its results and playoff
+// bracket node groups. heroNames maps hero ids to names, teamName returns
+// the name of a team by id.
+func buildReport(leagueName string, results []matchResult, groups []dota.LeagueNodeGroup,
+	heroNames map[int]string, teamName func(teamID int) string) *tournamentReport {
+	report := &tournamentReport{LeagueName: leagueName, Games: len(results)}
+	picks := make(map[int]int)
+	bans := make(map[int]int)
+	for i := range results {
+		result := &results[i]
+		report.Duration += time.Duration(result.Duration) * time.Second
+		if report.LongestGame == nil || result.Duration > report.LongestGame.Duration {
+			report.LongestGame = result
+		}
+		if report.BiggestStomp == nil || result.WinnerScore-result.LoserScore >
+			report.BiggestStomp.WinnerScore-report.BiggestStomp.LoserScore {
+			report.BiggestStomp = result
+		}
+		for _, heroID := range result.Picks {
+			picks[heroID]++
+		}
+		for _, heroID := range result.Bans {
+			bans[heroID]++
+		}
+	}
+	report.MostPicked = topHeroes(picks, heroNames)
+	report.MostBanned = topHeroes(bans, heroNames)
+	report.Upset = biggestUpset(results, groups)
+	report.ChampionID, report.ChampionPath = championPath(groups, teamName)
+	if report.ChampionID != 0 {
+		report.Champion = teamName(report.ChampionID)
+	}
+	return report
+}
+
+// topHeroes returns the reportTopHeroes heroes with the highest counts.
+// Ties are ordered by name.
+func topHeroes(counts map[int]int, heroNames map[int]string) []heroCount {
+	heroes := make([]heroCount, 0, len(counts))
+	for heroID, count := range counts {
+		heroes = append(heroes, heroCount{Name: heroName(heroNames, heroID), Count: count})
+	}
+	sort.Slice(heroes, func(i, j int) bool {
+		if heroes[i].Count != heroes[j].Count {
+			return heroes[i].Count > heroes[j].Count
+		}
+		return heroes[i].Name < heroes[j].Name
+	})
+	if len(heroes) > reportTopHeroes {
+		heroes = heroes[:reportTopHeroes]
+	}
+	return heroes
+}
+
+// biggestUpset returns the game won by the team placing the most places
+// lower than the losing team in the final standings of the bracket
+func biggestUpset(results []matchResult, groups []dota.LeagueNodeGroup) *reportUpset {
+	standings := make(map[int]int)
+	for _, group := range groups {
+		for _, standing := range group.TeamStandings {
+			if standing.Standing == 0 {
+				continue
+			}
+			if best, ok := standings[standing.TeamID]; !ok || standing.Standing < best {
+				standings[standing.TeamID] = standing.Standing
+			}
+		}
+	}
+	var upset *reportUpset
+	for _, result := range results {
+		winner, okWinner := standings[result.WinnerTeamID]
+		loser, okLoser := standings[result.LoserTeamID]
+		if !okWinner || !okLoser || winner <= loser {
+			continue
+		}
+		if upset == nil || winner-loser > upset.standingsGained {
+			upset = &reportUpset{
+				Result:          result,
+				WinnerStanding:  winner,
+				LoserStanding:   loser,
+				standingsGained: winner - loser,
+			}
+		}
+	}
+	return upset
+}
+
+// championPath returns the team id of the winner of the final of the
+// first bracket group, and the series the champion played through all
+// bracket groups. Returns 0 if the final has not been completed.
+func championPath(groups []dota.LeagueNodeGroup, teamName func(teamID int) string) (int, []reportSeries) {
+	if len(groups) == 0 {
+		return 0, nil
+	}
+	rounds := bracketRounds(groups[0])
+	if len(rounds) == 0 || len(rounds[len(rounds)-1]) != 1 {
+		return 0, nil
+	}
+	final := rounds[len(rounds)-1][0]
+	if !final.IsCompleted {
+		return 0, nil
+	}
+	champion := final.TeamID1
+	if final.Team2Wins > final.Team1Wins {
+		champion = final.TeamID2
+	}
+	var path []reportSeries
+	for _, group := range groups {
+		for i, round := range bracketRounds(group) {
+			for _, node := range round {
+				if !node.IsCompleted || (node.TeamID1 != champion && node.TeamID2 != champion) {
+					continue
+				}
+				series := reportSeries{Round: node.Name}
+				if series.Round == "" {
+					series.Round = fmt.Sprintf("%s round %d", group.Name, i+1)
+				}
+				if node.TeamID1 == champion {
+					series.Opponent = teamName(node.TeamID2)
+					series.Wins, series.Losses = node.Team1Wins, node.Team2Wins
+				} else {
+					series.Opponent = teamName(node.TeamID1)
+					series.Wins, series.Losses = node.Team2Wins, node.Team1Wins
+				}
+				path = append(path, series)
+			}
+		}
+	}
+	return champion, path
+}
+
+// bracketCompleted tests if all playoff bracket groups are completed,
+// i.e. the tournament is over
+func bracketCompleted(groups []dota.LeagueNodeGroup) bool {
+	if len(groups) == 0 {
+		return false
+	}
+	for _, group := range groups {
+		if !group.IsCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// generateReport builds the report of the league being watched
+func (bot *bot) generateReport(ctx context.Context) (*tournamentReport, error) {
+	bot.leagueMu.RLock()
+	leagueID, leagueName := bot.leagueID, bot.leagueName
+	bot.leagueMu.RUnlock()
+	if leagueID == 0 {
+		return nil, errors.New("Not watching any league")
+	}
+	if leagueName == "" {
+		leagueName = fmt.Sprintf("League %d", leagueID)
+	}
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	// The report is the same for all guilds, so is in English
+	heroNames, err := bot.heroNames(ctx, defaultTextFormat.language.code)
+	if err != nil {
+		// Heroes are shown by id rather than not reporting at all
+		bot.logger.WithError(err).Warn("Error getting hero names")
+	}
+	bot.bracket.mu.Lock()
+	groups := bot.bracket.groups
+	bot.bracket.mu.Unlock()
+	report := buildReport(leagueName, results, groups, heroNames, bot.teamName)
+	if report.ChampionID != 0 {
+		if report.ChampionLogo, err = bot.teamLogoURL(ctx, report.ChampionID); err != nil {
+			// The report is still complete without the logo
+			bot.logger.WithError(err).Warnf("Error getting logo of team %d", report.ChampionID)
+		}
+	}
+	return report, nil
+}
+
+// postReport posts the tournament report to all channels, once per
+// league. Called when the playoff bracket is completed.
+func (bot *bot) postReport(ctx context.Context) {
+	posted, err := bot.store.SetNX(ctx, reportPostedKey(bot.leagueID), time.Now(), resultTTL)
+	if err != nil {
+		bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error recording report as posted")
+		return
+	}
+	if !posted {
+		return
+	}
+	report, err := bot.generateReport(ctx)
+	if err != nil {
+		bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error generating report")
+		// Released so that the report is posted by a later call
+		if err := bot.store.Delete(ctx, reportPostedKey(bot.leagueID)); err != nil {
+			bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error releasing report as posted")
+		}
+		return
+	}
+	bot.sendEmbeds(ctx, func(settings *guildSettings) []*discordgo.MessageEmbed {
+		return renderReportEmbeds(report, settings.textFormat())
+	})
+}
+
+// postIdleReport posts the tournament report once the league has had no
+// live games for summaryAfter, for leagues that end without a completed
+// playoff bracket. The report is still only posted once per league.
+func (bot *bot) postIdleReport(ctx context.Context) {
+	bot.stateMu.Lock()
+	lastLiveAt := bot.lastLiveAt
+	bot.stateMu.Unlock()
+	if bot.summaryAfter <= 0 || time.Since(lastLiveAt) < bot.summaryAfter {
+		return
+	}
+	results, err := bot.loadResults(ctx, bot.leagueID)
+	if err != nil {
+		bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error loading results")
+		return
+	}
+	// A league that has not started yet has nothing to sum up
+	if len(results) == 0 {
+		return
+	}
+	bot.postReport(ctx)
+}
+
+// handleSummaryCommand responds with the tournament report of the league
+// so far
+func (bot *bot) handleSummaryCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if bot.currentLeagueID() == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	report, err := bot.generateReport(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error generating report")
+	}
+	return &interactionResponseData{Embeds: renderReportEmbeds(report, bot.interactionTextFormat(ctx, in))}, nil
+}
+
+// renderReportEmbeds renders the report as Discord embeds: the numbers,
+// the heroes and the champion's path.
+func renderReportEmbeds(report *tournamentReport, format textFormat) []*discordgo.MessageEmbed {
+	numbers := &discordgo.MessageEmbed{
+		Title: report.LeagueName + " in numbers",
+		Color: reportEmbedColor,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Games", Value: format.Integer(int64(report.Games)), Inline: true},
+			{Name: "Hours of Dota", Value: format.Decimal(report.Duration.Hours(), 1), Inline: true},
+			{Name: "Average game", Value: report.AverageDuration(), Inline: true},
+		},
+	}
+	if report.LongestGame != nil {
+		numbers.Fields = append(numbers.Fields, &discordgo.MessageEmbedField{
+			Name:  "Longest game",
+			Value: renderReportGame(report.LongestGame),
+		})
+	}
+	if report.BiggestStomp != nil {
+		numbers.Fields = append(numbers.Fields, &discordgo.MessageEmbedField{
+			Name:  "Biggest stomp",
+			Value: renderReportStomp(report.BiggestStomp, format),
+		})
+	}
+	if report.Upset != nil {
+		numbers.Fields = append(numbers.Fields, &discordgo.MessageEmbedField{
+			Name: "Biggest upset",
+			Value: fmt.Sprintf("%s (%s) defeated %s (%s)",
+				report.Upset.Result.WinnerName, ordinal(report.Upset.WinnerStanding),
+				report.Upset.Result.LoserName, ordinal(report.Upset.LoserStanding)),
+		})
+	}
+	embeds := []*discordgo.MessageEmbed{numbers}
+	if len(report.MostPicked) > 0 || len(report.MostBanned) > 0 {
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			Title: "Heroes",
+			Color: reportEmbedColor,
+			Fields: []*discordgo.MessageEmbedField{
+				{Name: "Most picked", Value: renderHeroCounts(report.MostPicked), Inline: true},
+				{Name: "Most banned", Value: renderHeroCounts(report.MostBanned), Inline: true},
+			},
+		})
+	}
+	if report.Champion != "" {
+		lines := make([]string, 0, len(report.ChampionPath))
+		for _, series := range report.ChampionPath {
+			lines = append(lines, fmt.Sprintf("%s: %s vs. %s", series.Round, format.Score(series.Wins, series.Losses), series.Opponent))
+		}
+		champion := &discordgo.MessageEmbed{
+			Title:       "Champions: " + report.Champion,
+			Color:       reportEmbedColor,
+			Description: strings.Join(lines, "\n"),
+		}
+		if report.ChampionLogo != "" {
+			champion.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: report.ChampionLogo}
+		}
+		embeds = append(embeds, champion)
+	}
+	return embeds
+}
+
+func renderReportGame(result *matchResult) string {
+	return fmt.Sprintf("%s vs. %s, %s (match %d)", result.WinnerName, result.LoserName,
+		formatDuration(float32(result.Duration)), result.MatchID)
+}
+
+func renderReportStomp(result *matchResult, format textFormat) string {
+	return fmt.Sprintf("%s defeated %s %s (match %d)", result.WinnerName, result.LoserName,
+		format.Score(result.WinnerScore, result.LoserScore), result.MatchID)
+}
+
+func renderHeroCounts(heroes []heroCount) string {
+	if len(heroes) == 0 {
+		return "-"
+	}
+	lines := make([]string, 0, len(heroes))
+	for _, hero := range heroes {
+		lines = append(lines, fmt.Sprintf("%s (%d)", hero.Name, hero.Count))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var reportTemplateFuncs = map[string]interface{}{
+	"ordinal": ordinal,
+	"game":    renderReportGame,
+	"score":   defaultTextFormat.Score,
+	"stomp": func(result *matchResult) string {
+		return renderReportStomp(result, defaultTextFormat)
+	},
+}
+
+const reportMarkdown = `# {{.LeagueName}} in numbers
+
+* Games: {{.Games}}
+* Hours of Dota: {{.Hours}}
+* Average game: {{.AverageDuration}}
+{{- with .LongestGame}}
+* Longest game: {{game .}}
+{{- end}}
+{{- with .BiggestStomp}}
+* Biggest stomp: {{stomp .}}
+{{- end}}
+{{- with .Upset}}
+* Biggest upset: {{.Result.WinnerName}} ({{ordinal .WinnerStanding}}) defeated {{.Result.LoserName}} ({{ordinal .LoserStanding}})
+{{- end}}
+
+## Most picked heroes
+{{range .MostPicked}}
+1. {{.Name}} ({{.Count}})
+{{- end}}
+
+## Most banned heroes
+{{range .MostBanned}}
+1. {{.Name}} ({{.Count}})
+{{- end}}
+{{with .Champion}}
+## Champions: {{.}}
+{{range $.ChampionPath}}
+* {{.Round}}: {{score .Wins .Losses}} vs. {{.Opponent}}
+{{- end}}
+{{end}}`
+
+const reportHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.LeagueName}} in numbers</title></head>
+<body>
+<h1>{{.LeagueName}} in numbers</h1>
+<ul>
+<li>Games: {{.Games}}</li>
+<li>Hours of Dota: {{.Hours}}</li>
+<li>Average game: {{.AverageDuration}}</li>
+{{- with .LongestGame}}
+<li>Longest game: {{game .}}</li>
+{{- end}}
+{{- with .BiggestStomp}}
+<li>Biggest stomp: {{stomp .}}</li>
+{{- end}}
+{{- with .Upset}}
+<li>Biggest upset: {{.Result.WinnerName}} ({{ordinal .WinnerStanding}}) defeated {{.Result.LoserName}} ({{ordinal .LoserStanding}})</li>
+{{- end}}
+</ul>
+<h2>Most picked heroes</h2>
+<ol>
+{{- range .MostPicked}}
+<li>{{.Name}} ({{.Count}})</li>
+{{- end}}
+</ol>
+<h2>Most banned heroes</h2>
+<ol>
+{{- range .MostBanned}}
+<li>{{.Name}} ({{.Count}})</li>
+{{- end}}
+</ol>
+{{- with .Champion}}
+<h2>Champions: {{.}}</h2>
+<ul>
+{{- range $.ChampionPath}}
+<li>{{.Round}}: {{score .Wins .Losses}} vs. {{.Opponent}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`
+
+var (
+	tmplReportMarkdown = template.Must(template.New("ReportMarkdown").Funcs(reportTemplateFuncs).Parse(reportMarkdown))
+	tmplReportHTML     = htmltemplate.Must(htmltemplate.New("ReportHTML").Funcs(reportTemplateFuncs).Parse(reportHTML))
+)
+
+// handleReport serves the tournament report of the league being watched,
+// as HTML if the path ends in .html, otherwise as Markdown
+func (bot *bot) handleReport(w http.ResponseWriter, r *http.Request) {
+	report, err := bot.generateReport(r.Context())
+	if err != nil {
+		bot.logger.WithError(err).Error("Error generating report")
+		http.Error(w, "Error generating report", http.StatusInternalServerError)
+		return
+	}
+	var buf bytes.Buffer
+	if strings.HasSuffix(r.URL.Path, ".html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = tmplReportHTML.Execute(&buf, report)
+	} else {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		err = tmplReportMarkdown.Execute(&buf, report)
+	}
+	if err != nil {
+		bot.logger.WithError(err).Error("Error rendering report")
+		http.Error(w, "Error rendering report", http.StatusInternalServerError)
+		return
+	}
+	if _, err := buf.WriteTo(w); err != nil {
+		bot.logger.WithError(err).Error("Error writing report response")
+	}
+}
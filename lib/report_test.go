@@ -0,0 +1,133 @@
+package timatch
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func testReportResult(matchID int64, winner, loser int, duration int, picks, bans []int) matchResult {
+	names := map[int]string{1: "OG", 2: "Liquid", 3: "PSG.LGD", 4: "Secret"}
+	return matchResult{
+		Duration: duration,
+		Picks:    picks,
+		Bans:     bans,
+		matchesFinishedDataItem: matchesFinishedDataItem{
+			MatchID:      matchID,
+			WinnerName:   names[winner],
+			LoserName:    names[loser],
+			WinnerTeamID: winner,
+			LoserTeamID:  loser,
+		},
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	teamNames := map[int]string{1: "OG", 2: "Liquid", 3: "PSG.LGD", 4: "Secret"}
+	heroNames := map[int]string{1: "Anti-Mage", 2: "Axe", 3: "Bane"}
+	group := dota.LeagueNodeGroup{
+		Name:          "Playoffs",
+		NodeGroupType: dota.NodeGroupTypeBracketSingle,
+		IsCompleted:   true,
+		TeamStandings: []dota.LeagueTeamStanding{
+			{TeamID: 1, Standing: 1}, {TeamID: 3, Standing: 2}, {TeamID: 2, Standing: 3}, {TeamID: 4, Standing: 4},
+		},
+		Nodes: []dota.LeagueNode{
+			{NodeID: 1, Name: "Semifinal", TeamID1: 1, TeamID2: 2, Team1Wins: 2, Team2Wins: 1, IsCompleted: true},
+			{NodeID: 2, Name: "Semifinal", TeamID1: 3, TeamID2: 4, Team1Wins: 2, Team2Wins: 0, IsCompleted: true},
+			{NodeID: 3, TeamID1: 3, TeamID2: 1, Team1Wins: 1, Team2Wins: 3, IsCompleted: true, IncomingNodeID1: 1, IncomingNodeID2: 2},
+		},
+	}
+	results := []matchResult{
+		testReportResult(1, 1, 3, 1800, []int{1, 2}, []int{3}),
+		testReportResult(2, 4, 3, 3600, []int{1, 3}, []int{3}),
+		testReportResult(3, 2, 1, 2400, []int{1}, []int{2}),
+	}
+	results[0].WinnerScore, results[0].LoserScore = 40, 10
+	results[1].WinnerScore, results[1].LoserScore = 30, 25
+	report := buildReport("The International", results, []dota.LeagueNodeGroup{group}, heroNames,
+		func(teamID int) string { return teamNames[teamID] })
+
+	if report.Games != 3 {
+		t.Errorf("Games = %d, want 3", report.Games)
+	}
+	if want := 7800 * time.Second; report.Duration != want {
+		t.Errorf("Duration = %v, want %v", report.Duration, want)
+	}
+	if report.LongestGame == nil || report.LongestGame.MatchID != 2 {
+		t.Errorf("LongestGame = %+v, want match 2", report.LongestGame)
+	}
+	if report.BiggestStomp == nil || report.BiggestStomp.MatchID != 1 {
+		t.Errorf("BiggestStomp = %+v, want match 1", report.BiggestStomp)
+	}
+	if got, want := report.AverageDuration(), "43:20"; got != want {
+		t.Errorf("AverageDuration() = %q, want %q", got, want)
+	}
+	wantPicked := []heroCount{{"Anti-Mage", 3}, {"Axe", 1}, {"Bane", 1}}
+	if !reflect.DeepEqual(report.MostPicked, wantPicked) {
+		t.Errorf("MostPicked = %v, want %v", report.MostPicked, wantPicked)
+	}
+	wantBanned := []heroCount{{"Bane", 2}, {"Axe", 1}}
+	if !reflect.DeepEqual(report.MostBanned, wantBanned) {
+		t.Errorf("MostBanned = %v, want %v", report.MostBanned, wantBanned)
+	}
+	// Secret (4th) beating PSG.LGD (2nd) is a bigger upset than Liquid
+	// (3rd) beating OG (1st)
+	if report.Upset == nil || report.Upset.Result.MatchID != 2 {
+		t.Errorf("Upset = %+v, want match 2", report.Upset)
+	}
+	if report.Champion != "OG" || report.ChampionID != 1 {
+		t.Errorf("Champion = %q (%d), want OG (1)", report.Champion, report.ChampionID)
+	}
+	wantPath := []reportSeries{
+		{Round: "Semifinal", Opponent: "Liquid", Wins: 2, Losses: 1},
+		{Round: "Playoffs round 2", Opponent: "PSG.LGD", Wins: 3, Losses: 1},
+	}
+	if !reflect.DeepEqual(report.ChampionPath, wantPath) {
+		t.Errorf("ChampionPath = %v, want %v", report.ChampionPath, wantPath)
+	}
+	if !bracketCompleted([]dota.LeagueNodeGroup{group}) {
+		t.Error("bracketCompleted() = false, want true")
+	}
+
+	var md bytes.Buffer
+	if err := tmplReportMarkdown.Execute(&md, report); err != nil {
+		t.Fatalf("Error rendering markdown: %+v", err)
+	}
+	for _, want := range []string{"# The International in numbers", "* Hours of Dota: 2.2",
+		"* Biggest stomp: OG defeated PSG.LGD 40 - 10 (match 1)", "1. Anti-Mage (3)", "## Champions: OG"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown report does not contain %q:\n%s", want, md.String())
+		}
+	}
+	var html bytes.Buffer
+	if err := tmplReportHTML.Execute(&html, report); err != nil {
+		t.Fatalf("Error rendering HTML: %+v", err)
+	}
+	if !strings.Contains(html.String(), "<h2>Champions: OG</h2>") {
+		t.Errorf("HTML report does not contain the champion:\n%s", html.String())
+	}
+	if embeds := renderReportEmbeds(report, defaultTextFormat); len(embeds) != 3 {
+		t.Errorf("renderReportEmbeds() returned %d embeds, want 3", len(embeds))
+	} else if embeds[2].Thumbnail != nil {
+		t.Errorf("Champion embed thumbnail = %+v, want none without a logo", embeds[2].Thumbnail)
+	}
+	report.ChampionLogo = "https://example.com/og.png"
+	if embeds := renderReportEmbeds(report, defaultTextFormat); embeds[2].Thumbnail == nil || embeds[2].Thumbnail.URL != report.ChampionLogo {
+		t.Errorf("Champion embed thumbnail = %+v, want the logo", embeds[2].Thumbnail)
+	}
+}
+
+func TestBuildReportEmpty(t *testing.T) {
+	report := buildReport("League 1", nil, nil, nil, func(int) string { return "" })
+	if report.LongestGame != nil || report.Upset != nil || report.Champion != "" {
+		t.Errorf("buildReport() of no results = %+v, want an empty report", report)
+	}
+	if embeds := renderReportEmbeds(report, defaultTextFormat); len(embeds) != 1 {
+		t.Errorf("renderReportEmbeds() returned %d embeds, want 1", len(embeds))
+	}
+}
@@ -0,0 +1,69 @@
+package timatch
+
+import (
+	"strings"
+)
+
+// Result details, how much of the result of a game is announced
+const (
+	// resultDetailFull announces the winner and the kill score of games
+	resultDetailFull = "full"
+	// resultDetailGames announces the winner of games, but not the kill
+	// score
+	resultDetailGames = "games"
+	// resultDetailSeries announces only that games ended, leaving the
+	// winner to be learnt from the series announcement
+	resultDetailSeries = "series"
+)
+
+// resultDetails are the result details a guild can choose from. The first
+// one is the default.
+var resultDetails = []string{resultDetailFull, resultDetailGames, resultDetailSeries}
+
+func findResultDetail(name string) (string, bool) {
+	for _, detail := range resultDetails {
+		if strings.EqualFold(detail, name) {
+			return detail, true
+		}
+	}
+	return "", false
+}
+
+// resultDetail returns the result detail chosen by the guild
+func (settings *guildSettings) resultDetail() string {
+	if detail, ok := findResultDetail(settings.ResultDetail); ok {
+		return detail
+	}
+	return resultDetails[0]
+}
+
+// showsWinners tests if the winners of games are given in the format
+func (format textFormat) showsWinners() bool {
+	return format.resultDetail != resultDetailSeries
+}
+
+// showsKills tests if the kill scores of finished games are given in the
+// format
+func (format textFormat) showsKills() bool {
+	return format.resultDetail != resultDetailSeries && format.resultDetail != resultDetailGames
+}
+
+// FirstTeam and SecondTeam are the teams of a finished game in
+// alphabetical order, for naming the teams without giving the winner away
+func (item matchesFinishedDataItem) FirstTeam() string {
+	first, _ := item.teamsByName()
+	return first
+}
+
+func (item matchesFinishedDataItem) SecondTeam() string {
+	_, second := item.teamsByName()
+	return second
+}
+
+func (item matchesFinishedDataItem) teamsByName() (string, string) {
+	first, second := item.WinnerName, item.LoserName
+	if strings.ToLower(second) < strings.ToLower(first) {
+		first, second = second, first
+	}
+	return first, second
+}
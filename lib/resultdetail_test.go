@@ -0,0 +1,50 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResultDetail(t *testing.T) {
+	items := []matchesFinishedDataItem{{GameNumber: 2, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17}}
+	tests := []struct {
+		settings guildSettings
+		want     string
+	}{
+		{guildSettings{}, "Match Ended: OG defeated Liquid (32 - 17, Game 2)"},
+		{guildSettings{ResultDetail: "games"}, "Match Ended: OG defeated Liquid (Game 2)"},
+		{guildSettings{ResultDetail: "series"}, "Match Ended: Liquid vs. OG (Game 2)"},
+		{guildSettings{ResultDetail: "games", Language: "ru"}, "Матч окончен: победа OG над Liquid (игра 2)"},
+		{guildSettings{ResultDetail: "series", Accessible: true}, "Match ended, game 2. Liquid versus OG."},
+		{guildSettings{ResultDetail: "games", Layout: layoutCompact}, "✅ OG beat Liquid (G2)"},
+		{guildSettings{ResultDetail: "series", Layout: layoutCompact}, "🏁 Liquid vs OG (G2)"},
+	}
+	for _, test := range tests {
+		got, err := renderTemplate(tmplMatchesFinished, test.settings.textFormat(), items)
+		if err != nil {
+			t.Fatalf("renderTemplate() error: %v", err)
+		}
+		if strings.TrimSpace(got) != test.want {
+			t.Errorf("renderTemplate() with %+v = %q, want %q", test.settings, got, test.want)
+		}
+	}
+
+	result := matchResult{matchesFinishedDataItem: items[0]}
+	resultTests := []struct {
+		settings guildSettings
+		spoilers bool
+		want     string
+	}{
+		{guildSettings{}, true, "Liquid vs. OG (Game 2): ||OG won 32 - 17||"},
+		{guildSettings{ResultDetail: "games"}, false, "OG defeated Liquid (Game 2)"},
+		{guildSettings{ResultDetail: "games"}, true, "Liquid vs. OG (Game 2): ||OG won||"},
+		{guildSettings{ResultDetail: "series"}, false, "Liquid vs. OG (Game 2)"},
+		{guildSettings{ResultDetail: "series"}, true, "Liquid vs. OG (Game 2)"},
+		{guildSettings{ResultDetail: "games", Accessible: true}, false, "Game 2. Winner: OG. Loser: Liquid."},
+	}
+	for _, test := range resultTests {
+		if got := renderResult(result, test.spoilers, test.settings.textFormat()); got != test.want {
+			t.Errorf("renderResult() with %+v, spoilers %t = %q, want %q", test.settings, test.spoilers, got, test.want)
+		}
+	}
+}
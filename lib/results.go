@@ -0,0 +1,257 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// resultTTL is the time results of finished matches are kept in the
+// store. Long enough to cover an entire tournament.
+const resultTTL = 60 * 24 * time.Hour
+
+// maxResultsCount is the maximum number of results listed by /results
+const maxResultsCount = 25
+
+// componentRevealResults is the name of the button revealing the results
+// hidden by /results in accessibility mode
+const componentRevealResults = "reveal_results"
+
+// matchResult is the stored result of a finished match
+type matchResult struct {
+	LeagueID   int       `json:"league_id"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Duration is the length of the match, in seconds
+	Duration int `json:"duration"`
+	// Picks and Bans are the hero ids picked and banned in the match
+	Picks []int `json:"picks,omitempty"`
+	Bans  []int `json:"bans,omitempty"`
+	// Players are the heroes played in the match, and by whom
+	Players []resultPlayer `json:"players,omitempty"`
+	matchesFinishedDataItem
+}
+
+// resultPlayer is a player of a finished match
+type resultPlayer struct {
+	AccountID int64 `json:"account_id"`
+	HeroID    int   `json:"hero_id"`
+	Won       bool  `json:"won"`
+}
+
+// resultKeyPrefix is the prefix of the store keys of all results
+const resultKeyPrefix = "result/"
+
+// resultKey returns the store key of the result of a match
+func resultKey(matchID int64) string {
+	return resultKeyPrefix + strconv.FormatInt(matchID, 10)
+}
+
+// saveResult stores the result of a finished match, returning the result
+func (bot *bot) saveResult(ctx context.Context, details *dota.MatchDetails, item matchesFinishedDataItem) matchResult {
+	result := matchResult{
+		LeagueID:                bot.currentLeagueID(),
+		FinishedAt:              time.Now(),
+		Duration:                details.Duration,
+		matchesFinishedDataItem: item,
+	}
+	for _, player := range details.Players {
+		result.Players = append(result.Players, resultPlayer{
+			AccountID: player.AccountID,
+			HeroID:    player.HeroID,
+			Won:       player.IsRadiant() == details.RadiantWin,
+		})
+	}
+	for _, pickBan := range details.PicksBans {
+		if pickBan.IsPick {
+			result.Picks = append(result.Picks, pickBan.HeroID)
+		} else {
+			result.Bans = append(result.Bans, pickBan.HeroID)
+		}
+	}
+	if err := bot.store.Set(ctx, resultKey(item.MatchID), result, resultTTL); err != nil {
+		bot.logger.WithField(logFieldMatchID, item.MatchID).WithError(err).Errorf("Error storing result of %d", item.MatchID)
+	}
+	return result
+}
+
+// saveResults is the subscriber of the event bus rating and storing the
+// results of the finished games, and keeping the records broken. Remade
+// games are not results
+func (bot *bot) saveResults(ctx context.Context, events []WatcherEvent) {
+	for _, event := range finishedGames(events, false) {
+		if event.Remake {
+			bot.logger.WithField(logFieldMatchID, event.Game.MatchID).Infof("Match %d was remade", event.Game.MatchID)
+			continue
+		}
+		item := bot.finishedItem(event)
+		bot.rateResult(ctx, &item)
+		result := bot.saveResult(ctx, &event.Details, item)
+		if bot.records {
+			bot.checkRecords(ctx, result)
+		}
+	}
+}
+
+// loadResults returns the stored results of the given league, or of all
+// leagues if leagueID is 0, most recently finished first
+func (bot *bot) loadResults(ctx context.Context, leagueID int) ([]matchResult, error) {
+	keys, err := bot.store.Keys(ctx, resultKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error listing results")
+	}
+	results := make([]matchResult, 0, len(keys))
+	for _, key := range keys {
+		var result matchResult
+		found, err := bot.store.Get(ctx, key, &result)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting %s", key)
+		}
+		if found && (leagueID == 0 || result.LeagueID == leagueID) {
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].FinishedAt.After(results[j].FinishedAt)
+	})
+	return results, nil
+}
+
+// renderResult renders a result as a single line, with as much of the
+// result as the result detail of the format gives. With spoilers set, the
+// winner and the score are hidden behind Discord spoiler tags, and the
+// teams are listed in alphabetical order so that the order does not give
+// the winner away. In accessibility mode the result is left out rather
+// than hidden, to be revealed using a button.
+func renderResult(result matchResult, spoilers bool, format textFormat) string {
+	if !format.showsWinners() || spoilers {
+		var line string
+		if format.accessible {
+			line = fmt.Sprintf("%s versus %s, game %d.", result.FirstTeam(), result.SecondTeam(), result.GameNumber)
+		} else {
+			line = fmt.Sprintf("%s vs. %s (Game %d)", result.FirstTeam(), result.SecondTeam(), result.GameNumber)
+		}
+		if !format.showsWinners() {
+			// There is nothing to hide
+			return resultTime(result, format) + line
+		}
+		if format.accessible {
+			return resultTime(result, format) + line + " Result hidden."
+		}
+		outcome := result.WinnerName + " won"
+		if format.showsKills() {
+			outcome += " " + format.Score(result.WinnerScore, result.LoserScore)
+		}
+		return resultTime(result, format) + line + ": ||" + outcome + "||"
+	}
+	if format.accessible {
+		line := fmt.Sprintf("Game %d. Winner: %s. Loser: %s.", result.GameNumber, result.WinnerName, result.LoserName)
+		if format.showsKills() {
+			line += " Kills: " + format.Score(result.WinnerScore, result.LoserScore) + "."
+		}
+		return resultTime(result, format) + line
+	}
+	if !format.showsKills() {
+		return resultTime(result, format) + fmt.Sprintf("%s defeated %s (Game %d)",
+			result.WinnerName, result.LoserName, result.GameNumber)
+	}
+	return resultTime(result, format) + fmt.Sprintf("%s defeated %s (%s, Game %d)",
+		result.WinnerName, result.LoserName, format.Score(result.WinnerScore, result.LoserScore), result.GameNumber)
+}
+
+// resultTime returns the time a result finished as a prefix of its line,
+// or "" unless the guild has set a time zone
+func resultTime(result matchResult, format textFormat) string {
+	if format.location == nil {
+		return ""
+	}
+	return format.Time(result.FinishedAt) + ": "
+}
+
+// handleResultsCommand responds with the results of today's games, in the
+// time zone of the guild (UTC by default), or the last count games if
+// count is given
+func (bot *bot) handleResultsCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	count := in.intOption("count", 0)
+	spoilers := in.boolOption("spoilers", false)
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	title := "Results of today's games"
+	if count > 0 {
+		if count > maxResultsCount {
+			count = maxResultsCount
+		}
+		if len(results) > count {
+			results = results[:count]
+		}
+		title = fmt.Sprintf("Results of the last %d games", len(results))
+	} else {
+		today := format.StartOfDay(time.Now())
+		n := 0
+		for n < len(results) && !results[n].FinishedAt.Before(today) {
+			n++
+		}
+		results = results[:n]
+		if len(results) > maxResultsCount {
+			results = results[:maxResultsCount]
+		}
+	}
+	if len(results) == 0 {
+		return textResponse("No games have finished yet."), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", title)
+	// Listed oldest first, like the announcements were
+	for i := len(results) - 1; i >= 0; i-- {
+		b.WriteString(renderResult(results[i], spoilers, format))
+		b.WriteString("\n")
+	}
+	res := textResponse(b.String())
+	if spoilers && format.accessible && format.showsWinners() {
+		// Spoiler tags are not announced by screen readers, so the
+		// results are revealed by a button instead
+		customID := fmt.Sprintf("%s:%d:%d", componentRevealResults,
+			results[len(results)-1].FinishedAt.Unix(), results[0].FinishedAt.Unix())
+		res.Components = []messageComponent{buttonRow("Reveal results", customID)}
+	}
+	return res, nil
+}
+
+// handleRevealResults responds with the results hidden by /results in
+// accessibility mode, given the finish times of the first and last result
+// in the custom id of the button
+func (bot *bot) handleRevealResults(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	var from, to int64
+	if _, err := fmt.Sscanf(in.Data.CustomID, componentRevealResults+":%d:%d", &from, &to); err != nil {
+		return nil, errors.Wrapf(err, "Error parsing custom id %q", in.Data.CustomID)
+	}
+	results, err := bot.loadResults(ctx, bot.currentLeagueID())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	var b strings.Builder
+	for i := len(results) - 1; i >= 0; i-- {
+		if finished := results[i].FinishedAt.Unix(); finished < from || finished > to {
+			continue
+		}
+		b.WriteString(renderResult(results[i], false, format))
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		return textResponse("The results are no longer available."), nil
+	}
+	return textResponse(b.String()), nil
+}
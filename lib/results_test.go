@@ -0,0 +1,30 @@
+package timatch
+
+import "testing"
+
+func TestRenderResult(t *testing.T) {
+	result := func(winner, loser string) matchResult {
+		return matchResult{matchesFinishedDataItem: matchesFinishedDataItem{
+			GameNumber:  2,
+			WinnerName:  winner,
+			LoserName:   loser,
+			WinnerScore: 2,
+			LoserScore:  0,
+		}}
+	}
+	tests := []struct {
+		result   matchResult
+		spoilers bool
+		want     string
+	}{
+		{result("OG", "Liquid"), false, "OG defeated Liquid (2 - 0, Game 2)"},
+		{result("OG", "Liquid"), true, "Liquid vs. OG (Game 2): ||OG won 2 - 0||"},
+		{result("Liquid", "OG"), true, "Liquid vs. OG (Game 2): ||Liquid won 2 - 0||"},
+		{result("alliance", "Liquid"), true, "alliance vs. Liquid (Game 2): ||alliance won 2 - 0||"},
+	}
+	for _, tt := range tests {
+		if got := renderResult(tt.result, tt.spoilers, defaultTextFormat); got != tt.want {
+			t.Errorf("renderResult(%+v, %v) = %q, want %q", tt.result, tt.spoilers, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// componentRevealResult is the name of the buttons of finished
+// announcements revealing the results hidden by the result detail of the
+// guild. The custom id of a button is followed by the id of its match
+const componentRevealResult = "reveal_result"
+
+// Discord allows at most 5 buttons per row and 5 rows per message, and
+// labels of at most 80 characters
+const (
+	maxButtonsPerRow     = 5
+	maxButtonRows        = 5
+	maxButtonLabelLength = 80
+)
+
+// revealResultButtons returns the buttons revealing the results of the
+// finished games, a button per game
+func revealResultButtons(items []matchesFinishedDataItem) []messageComponent {
+	if len(items) > maxButtonsPerRow*maxButtonRows {
+		items = items[:maxButtonsPerRow*maxButtonRows]
+	}
+	rows := make([]messageComponent, 0, 1)
+	for i, item := range items {
+		label := "Reveal result"
+		if len(items) > 1 {
+			label = fmt.Sprintf("Reveal %s vs. %s (Game %d)", item.FirstTeam(), item.SecondTeam(), item.GameNumber)
+			if runes := []rune(label); len(runes) > maxButtonLabelLength {
+				label = string(runes[:maxButtonLabelLength])
+			}
+		}
+		if i%maxButtonsPerRow == 0 {
+			rows = append(rows, messageComponent{Type: componentTypeActionRow})
+		}
+		row := &rows[len(rows)-1]
+		row.Components = append(row.Components, messageComponent{
+			Type:     componentTypeButton,
+			Style:    buttonStyleSecondary,
+			Label:    label,
+			CustomID: componentRevealResult + ":" + strconv.FormatInt(item.MatchID, 10),
+		})
+	}
+	return rows
+}
+
+// handleRevealResult responds with the full result of the match of a
+// reveal button, only visible to the user clicking it
+func (bot *bot) handleRevealResult(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	matchID, err := strconv.ParseInt(strings.TrimPrefix(in.Data.CustomID, componentRevealResult+":"), 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error parsing custom id %q", in.Data.CustomID)
+	}
+	var result matchResult
+	found, err := bot.store.Get(ctx, resultKey(matchID), &result)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading result")
+	}
+	if !found {
+		return textResponse("The result is no longer available."), nil
+	}
+	format := bot.interactionTextFormat(ctx, in)
+	format.resultDetail = resultDetailFull
+	return textResponse(renderResult(result, false, format)), nil
+}
@@ -0,0 +1,52 @@
+package timatch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestRevealResultButtons(t *testing.T) {
+	item := matchesFinishedDataItem{MatchID: 1, GameNumber: 2, WinnerName: "Team Liquid", LoserName: "OG"}
+	rows := revealResultButtons([]matchesFinishedDataItem{item})
+	if len(rows) != 1 || len(rows[0].Components) != 1 || rows[0].Components[0].Label != "Reveal result" ||
+		rows[0].Components[0].CustomID != "reveal_result:1" {
+		t.Errorf("revealResultButtons() of a game = %+v, want a single reveal button", rows)
+	}
+	items := make([]matchesFinishedDataItem, 7)
+	for i := range items {
+		items[i] = item
+	}
+	rows = revealResultButtons(items)
+	if len(rows) != 2 || len(rows[0].Components) != 5 || len(rows[1].Components) != 2 {
+		t.Errorf("revealResultButtons() of 7 games = %d rows, want rows of 5 and 2 buttons", len(rows))
+	}
+	if got, want := rows[0].Components[0].Label, "Reveal OG vs. Team Liquid (Game 2)"; got != want {
+		t.Errorf("Button label = %q, want %q", got, want)
+	}
+}
+
+func TestHandleRevealResult(t *testing.T) {
+	bot := &bot{store: storage.NewMemoryStore()}
+	ctx := context.Background()
+	result := matchResult{matchesFinishedDataItem: matchesFinishedDataItem{MatchID: 1, GameNumber: 2,
+		WinnerName: "OG", LoserName: "Team Liquid", WinnerScore: 32, LoserScore: 17}}
+	if err := bot.store.Set(ctx, resultKey(1), result, 0); err != nil {
+		t.Fatal(err)
+	}
+	in := &interaction{ChannelID: "10", User: &discordgo.User{ID: "1"}}
+	in.Data.CustomID = "reveal_result:1"
+	res, err := bot.handleRevealResult(ctx, in)
+	if err != nil {
+		t.Fatalf("handleRevealResult() error: %v", err)
+	}
+	if want := "OG defeated Team Liquid (32 - 17, Game 2)"; res.Content != want {
+		t.Errorf("handleRevealResult() = %q, want %q", res.Content, want)
+	}
+	in.Data.CustomID = "reveal_result:2"
+	if res, err := bot.handleRevealResult(ctx, in); err != nil || res.Content != "The result is no longer available." {
+		t.Errorf("handleRevealResult() of unknown result = %+v, %v, want no longer available", res, err)
+	}
+}
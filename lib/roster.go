@@ -0,0 +1,279 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/opendota"
+)
+
+// proPlayersMaxAge is the time the pro player mapping is cached for
+const proPlayersMaxAge = 6 * time.Hour
+
+// proPlayersCache caches the OpenDota pro player list, used for mapping
+// account ids to player names, countries and roles
+type proPlayersCache struct {
+	mu        sync.Mutex
+	fetchedAt time.Time
+	byAccount map[int64]opendota.ProPlayer
+}
+
+// getProPlayers returns the pro players by account id, fetching them if
+// the cache is older than proPlayersMaxAge. A stale mapping is returned
+// if fetching fails.
+func (bot *bot) getProPlayers(ctx context.Context) (map[int64]opendota.ProPlayer, error) {
+	bot.proPlayers.mu.Lock()
+	defer bot.proPlayers.mu.Unlock()
+	if bot.proPlayers.byAccount != nil && time.Since(bot.proPlayers.fetchedAt) < proPlayersMaxAge {
+		return bot.proPlayers.byAccount, nil
+	}
+	players, err := bot.openDotaClient.GetProPlayers(ctx)
+	if err != nil {
+		if bot.proPlayers.byAccount != nil {
+			bot.logger.WithError(err).Warn("Error refreshing pro players, using stale data")
+			return bot.proPlayers.byAccount, nil
+		}
+		return nil, errors.Wrap(err, "Error getting pro players")
+	}
+	byAccount := make(map[int64]opendota.ProPlayer, len(players))
+	for _, player := range players {
+		byAccount[player.AccountID] = player
+		bot.learnTeamName(player.TeamID, player.TeamName)
+	}
+	bot.proPlayers.byAccount = byAccount
+	bot.proPlayers.fetchedAt = time.Now()
+	return byAccount, nil
+}
+
+// resolvePlayerNames replaces the in-game names of the players of games
+// with their OpenDota pro player names, where known. The games are
+// returned unchanged if the pro players cannot be fetched.
+func (bot *bot) resolvePlayerNames(ctx context.Context, games []dota.LiveLeagueGame) []dota.LiveLeagueGame {
+	proPlayers, err := bot.getProPlayers(ctx)
+	if err != nil {
+		bot.logger.WithError(err).Warn("Error resolving player names")
+		return games
+	}
+	resolved := make([]dota.LiveLeagueGame, len(games))
+	for i, game := range games {
+		players := make([]dota.LiveLeagueGamePlayer, len(game.Players))
+		for j, player := range game.Players {
+			if pro, ok := proPlayers[player.AccountID]; ok && pro.Name != "" {
+				player.Name = pro.Name
+			}
+			players[j] = player
+		}
+		game.Players = players
+		resolved[i] = game
+	}
+	return resolved
+}
+
+// findTeamID resolves a team id from query, which is either a team id or
+// the name or tag of a team
+func (bot *bot) findTeamID(ctx context.Context, query string) (int, error) {
+	query = strings.TrimSpace(query)
+	if teamID, err := strconv.Atoi(query); err == nil {
+		return teamID, nil
+	}
+	bot.teamNamesMu.RLock()
+	for teamID, name := range bot.teamNames {
+		if strings.EqualFold(name, query) {
+			bot.teamNamesMu.RUnlock()
+			return teamID, nil
+		}
+	}
+	bot.teamNamesMu.RUnlock()
+	players, err := bot.getProPlayers(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error getting pro players")
+	}
+	for _, player := range players {
+		if player.TeamID == 0 {
+			continue
+		}
+		if strings.EqualFold(player.TeamName, query) || strings.EqualFold(player.TeamTag, query) {
+			return player.TeamID, nil
+		}
+	}
+	return 0, nil
+}
+
+// countryFlag returns the flag emoji of an ISO 3166-1 alpha-2 country
+// code, or "" if the code is not valid
+func countryFlag(countryCode string) string {
+	if len(countryCode) != 2 {
+		return ""
+	}
+	var flag strings.Builder
+	for _, c := range strings.ToUpper(countryCode) {
+		if c < 'A' || c > 'Z' {
+			return ""
+		}
+		// Regional indicator symbols A-Z start at U+1F1E6
+		flag.WriteRune(0x1F1E6 + c - 'A')
+	}
+	return flag.String()
+}
+
+// rosterPositionMatches is the number of recent matches of a team used
+// for inferring the positions of its players
+const rosterPositionMatches = 3
+
+// teamPositionsCache caches the inferred player positions of teams
+type teamPositionsCache struct {
+	mu     sync.Mutex
+	byTeam map[int]teamPositions
+}
+
+type teamPositions struct {
+	fetchedAt time.Time
+	// positions maps account ids to positions, 1-5
+	positions map[int64]int
+}
+
+// matchPositions returns the positions (1-5) of the players of one side
+// of a match, by account id. Positions describe farm priority, so the
+// players are ranked by gold per minute.
+func matchPositions(players []dota.MatchPlayer, radiant bool) map[int64]int {
+	side := make([]dota.MatchPlayer, 0, 5)
+	for _, player := range players {
+		if player.IsRadiant() == radiant {
+			side = append(side, player)
+		}
+	}
+	sort.SliceStable(side, func(i, j int) bool { return side[i].GoldPerMin > side[j].GoldPerMin })
+	positions := make(map[int64]int, len(side))
+	for i, player := range side {
+		positions[player.AccountID] = i + 1
+	}
+	return positions
+}
+
+// votePositions combines the positions of several matches, most recent
+// first, giving each player the position played in most of the matches.
+// Ties are won by the position of the most recent match.
+func votePositions(matches []map[int64]int) map[int64]int {
+	votes := make(map[int64]map[int]int)
+	for _, positions := range matches {
+		for accountID, position := range positions {
+			if votes[accountID] == nil {
+				votes[accountID] = make(map[int]int)
+			}
+			votes[accountID][position]++
+		}
+	}
+	result := make(map[int64]int, len(votes))
+	for accountID, counts := range votes {
+		best := 0
+		for _, positions := range matches {
+			position, ok := positions[accountID]
+			if ok && counts[position] > counts[best] {
+				best = position
+			}
+		}
+		result[accountID] = best
+	}
+	return result
+}
+
+// teamPositions infers the positions of the players of a team from its
+// most recent matches. The result is cached for proPlayersMaxAge.
+func (bot *bot) teamPositions(ctx context.Context, teamID int) (map[int64]int, error) {
+	bot.positions.mu.Lock()
+	defer bot.positions.mu.Unlock()
+	if cached, ok := bot.positions.byTeam[teamID]; ok && time.Since(cached.fetchedAt) < proPlayersMaxAge {
+		return cached.positions, nil
+	}
+	teamMatches, err := bot.openDotaClient.GetTeamMatches(ctx, teamID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting team matches")
+	}
+	if len(teamMatches) > rosterPositionMatches {
+		teamMatches = teamMatches[:rosterPositionMatches]
+	}
+	matches := make([]map[int64]int, 0, len(teamMatches))
+	for _, match := range teamMatches {
+		details, err := bot.matchData.GetMatchDetails(ctx, match.MatchID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting match details for %d", match.MatchID)
+		}
+		matches = append(matches, matchPositions(details.Result.Players, match.Radiant))
+	}
+	positions := votePositions(matches)
+	if bot.positions.byTeam == nil {
+		bot.positions.byTeam = make(map[int]teamPositions)
+	}
+	bot.positions.byTeam[teamID] = teamPositions{fetchedAt: time.Now(), positions: positions}
+	return positions, nil
+}
+
+// handleRosterCommand responds with the players of a team, ordered by
+// their positions as inferred from the team's recent matches. Players
+// whose position is not known are listed last.
+func (bot *bot) handleRosterCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	query := in.stringOption("team")
+	teamID, err := bot.findTeamID(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error finding team")
+	}
+	if teamID == 0 {
+		return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	teamInfo, err := bot.dotaClient.GetTeamInfoByTeamID(ctx, teamID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting team info")
+	}
+	if len(teamInfo.Result.Teams) == 0 || teamInfo.Result.Teams[0].TeamID != teamID {
+		return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	team := teamInfo.Result.Teams[0]
+	bot.learnTeamName(team.TeamID, team.Name)
+	players, err := bot.getProPlayers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting pro players")
+	}
+	positions, err := bot.teamPositions(ctx, teamID)
+	if err != nil {
+		// The roster is still useful without positions
+		bot.logger.WithError(err).Warnf("Error inferring positions of team %d", teamID)
+	}
+	accountIDs := team.PlayerAccountIDs()
+	sort.SliceStable(accountIDs, func(i, j int) bool {
+		pi, pj := positions[accountIDs[i]], positions[accountIDs[j]]
+		return pi != 0 && (pj == 0 || pi < pj)
+	})
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** roster\n", team.Name)
+	if len(accountIDs) == 0 {
+		b.WriteString("No players registered.")
+	}
+	for _, accountID := range accountIDs {
+		position := "Pos ?"
+		if p := positions[accountID]; p != 0 {
+			position = fmt.Sprintf("Pos %d", p)
+		}
+		player, ok := players[accountID]
+		if !ok {
+			fmt.Fprintf(&b, "%s - Unknown player (%d)\n", position, accountID)
+			continue
+		}
+		name := player.Name
+		if name == "" {
+			name = player.PersonaName
+		}
+		flag := countryFlag(player.CountryCode)
+		if flag != "" {
+			flag += " "
+		}
+		fmt.Fprintf(&b, "%s - %s%s\n", position, flag, name)
+	}
+	return textResponse(b.String()), nil
+}
@@ -0,0 +1,64 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestCountryFlag(t *testing.T) {
+	tests := []struct {
+		countryCode string
+		want        string
+	}{
+		{"se", "🇸🇪"},
+		{"US", "🇺🇸"},
+		{"", ""},
+		{"s", ""},
+		{"swe", ""},
+		{"s1", ""},
+	}
+	for _, tt := range tests {
+		if got := countryFlag(tt.countryCode); got != tt.want {
+			t.Errorf("countryFlag(%q) = %q, want %q", tt.countryCode, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPositions(t *testing.T) {
+	players := []dota.MatchPlayer{
+		{AccountID: 1, PlayerSlot: 0, GoldPerMin: 300},
+		{AccountID: 2, PlayerSlot: 1, GoldPerMin: 700},
+		{AccountID: 3, PlayerSlot: 2, GoldPerMin: 250},
+		{AccountID: 4, PlayerSlot: 3, GoldPerMin: 550},
+		{AccountID: 5, PlayerSlot: 4, GoldPerMin: 600},
+		{AccountID: 6, PlayerSlot: 128, GoldPerMin: 900},
+		{AccountID: 7, PlayerSlot: 129, GoldPerMin: 100},
+	}
+	want := map[int64]int{2: 1, 5: 2, 4: 3, 1: 4, 3: 5}
+	if got := matchPositions(players, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("matchPositions(radiant) = %v, want %v", got, want)
+	}
+	want = map[int64]int{6: 1, 7: 2}
+	if got := matchPositions(players, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("matchPositions(dire) = %v, want %v", got, want)
+	}
+}
+
+func TestVotePositions(t *testing.T) {
+	matches := []map[int64]int{
+		{1: 2, 2: 1, 3: 3},
+		{1: 1, 2: 2, 3: 3},
+		{1: 1, 2: 2, 4: 4},
+	}
+	want := map[int64]int{1: 1, 2: 2, 3: 3, 4: 4}
+	if got := votePositions(matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("votePositions() = %v, want %v", got, want)
+	}
+	// A tie is won by the most recent match
+	matches = []map[int64]int{{1: 3}, {1: 4}}
+	if got := votePositions(matches); got[1] != 3 {
+		t.Errorf("votePositions() tie = %d, want 3", got[1])
+	}
+}
@@ -0,0 +1,166 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scheduleUpdateInterval is the time between fetches of the schedule of
+// the league
+const scheduleUpdateInterval = 15 * time.Minute
+
+// upNextLead is how long before its scheduled start a series is
+// announced as up next
+const upNextLead = 15 * time.Minute
+
+// dailyScheduleHour is the hour of the day, in the time zone of the
+// guild, from which the schedule of the day is posted
+const dailyScheduleHour = 9
+
+// announcementDailySchedule is the announcement of the schedule of a day,
+// used in the idempotency keys of announcements
+const announcementDailySchedule = "dailyschedule"
+
+// scheduledSeries is a series of the league yet to start
+type scheduledSeries struct {
+	NodeID        int
+	ScheduledTime time.Time
+	TeamID1       int
+	TeamID2       int
+	// Stage is the stage of the league of the series, see leagueStages
+	Stage string
+}
+
+// scheduleState is the schedule of the watched league, polled alongside
+// the live games
+type scheduleState struct {
+	lastUpdate time.Time
+	// series are the scheduled series as of the last update, by
+	// scheduled time
+	series []scheduledSeries
+}
+
+// updateSchedule fetches the schedule of the league and announces the
+// series about to start and the schedule of the day
+func (bot *bot) updateSchedule(ctx context.Context) {
+	if !bot.scheduleUpdates {
+		return
+	}
+	if time.Since(bot.schedule.lastUpdate) >= scheduleUpdateInterval {
+		bot.schedule.lastUpdate = time.Now()
+		leagueData, err := bot.dotaClient.GetLeagueData(ctx, bot.leagueID)
+		if err != nil {
+			bot.logger.WithField(logFieldLeagueID, bot.leagueID).WithError(err).Error("Error getting league schedule")
+		} else {
+			bot.schedule.series = upcomingSeries(leagueStages(leagueData.NodeGroups))
+		}
+	}
+	if len(bot.schedule.series) == 0 {
+		return
+	}
+	now := time.Now()
+	bot.sendGuildMessage(ctx, eventSchedule, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+		format := settings.channelTextFormat(string(channelID))
+		lead := reminderLead(sub)
+		var lines []string
+		for _, series := range bot.schedule.series {
+			untilStart := series.ScheduledTime.Sub(now)
+			if untilStart < 0 || untilStart > lead || !bot.isScheduleAnnounced(series, sub) {
+				continue
+			}
+			if !bot.claimAnnouncement(ctx, eventSchedule, int64(series.NodeID), channelID) {
+				continue
+			}
+			if sub != nil && sub.ReminderMinutes > 0 {
+				lines = append(lines, bot.renderReminder(series, untilStart, format))
+			} else {
+				lines = append(lines, bot.renderUpNext(series, format))
+			}
+		}
+		if daily := bot.dailySchedule(ctx, channelID, sub, format, now); daily != "" {
+			lines = append(lines, daily)
+		}
+		return strings.Join(lines, "\n")
+	})
+}
+
+// upcomingSeries returns the series of the stages that have a scheduled
+// time and have not started, by scheduled time
+func upcomingSeries(stages []seriesStage) []scheduledSeries {
+	var upcoming []scheduledSeries
+	for _, stage := range stages {
+		node := stage.node
+		if node.ScheduledTime == 0 || node.HasStarted || node.IsCompleted {
+			continue
+		}
+		upcoming = append(upcoming, scheduledSeries{
+			NodeID:        node.NodeID,
+			ScheduledTime: time.Unix(node.ScheduledTime, 0),
+			TeamID1:       node.TeamID1,
+			TeamID2:       node.TeamID2,
+			Stage:         stage.stage,
+		})
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].ScheduledTime.Before(upcoming[j].ScheduledTime) })
+	return upcoming
+}
+
+// isScheduleAnnounced tests if a series should be included in the
+// schedule announcements to a channel. Series without known teams are
+// only announced to channels not subscribed to teams.
+func (bot *bot) isScheduleAnnounced(series scheduledSeries, sub *channelSubscription) bool {
+	return bot.isAnnouncedTeam(series.TeamID1, series.TeamID2) && sub.includesTeams(series.TeamID1, series.TeamID2)
+}
+
+// dailySchedule returns the schedule of the day to post to a channel, or
+// "" if already posted, not yet dailyScheduleHour in the time zone of the
+// guild, or no series are left to play in the day
+func (bot *bot) dailySchedule(ctx context.Context, channelID channelID, sub *channelSubscription, format textFormat, now time.Time) string {
+	startOfDay := format.StartOfDay(now)
+	if now.Before(startOfDay.Add(dailyScheduleHour * time.Hour)) {
+		return ""
+	}
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+	var today []scheduledSeries
+	for _, series := range bot.schedule.series {
+		if !series.ScheduledTime.Before(now) && series.ScheduledTime.Before(endOfDay) && bot.isScheduleAnnounced(series, sub) {
+			today = append(today, series)
+		}
+	}
+	if len(today) == 0 {
+		return ""
+	}
+	// The day as e.g. 20190820, so that each day is posted once
+	day := int64(startOfDay.Year()*10000 + int(startOfDay.Month())*100 + startOfDay.Day())
+	if !bot.claimAnnouncement(ctx, announcementDailySchedule, day, channelID) {
+		return ""
+	}
+	return bot.renderDailySchedule(today, format)
+}
+
+// renderUpNext renders the announcement of a series about to start, e.g.
+// "⏰ Up next: OG vs. Team Liquid (Upper Bracket Final) at 18:00 CEST"
+func (bot *bot) renderUpNext(series scheduledSeries, format textFormat) string {
+	return fmt.Sprintf("⏰ Up next: %s at %s", bot.renderScheduledSeries(series), format.Time(series.ScheduledTime))
+}
+
+// renderDailySchedule renders the schedule of a day, one series per line
+func (bot *bot) renderDailySchedule(series []scheduledSeries, format textFormat) string {
+	var b strings.Builder
+	b.WriteString("📅 Today's schedule:")
+	for _, series := range series {
+		fmt.Fprintf(&b, "\n- %s: %s", format.Time(series.ScheduledTime), bot.renderScheduledSeries(series))
+	}
+	return b.String()
+}
+
+func (bot *bot) renderScheduledSeries(series scheduledSeries) string {
+	s := bot.teamName(series.TeamID1) + " vs. " + bot.teamName(series.TeamID2)
+	if series.Stage != "" {
+		s += " (" + series.Stage + ")"
+	}
+	return s
+}
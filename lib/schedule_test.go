@@ -0,0 +1,61 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestUpcomingSeries(t *testing.T) {
+	now := time.Now().Unix()
+	stages := []seriesStage{
+		{node: dota.LeagueNode{NodeID: 1, ScheduledTime: now + 7200}, stage: grandFinalStage},
+		{node: dota.LeagueNode{NodeID: 2, ScheduledTime: now + 3600}},
+		{node: dota.LeagueNode{NodeID: 3, ScheduledTime: now - 3600, HasStarted: true}},
+		{node: dota.LeagueNode{NodeID: 4}},
+	}
+	series := upcomingSeries(stages)
+	if len(series) != 2 || series[0].NodeID != 2 || series[1].NodeID != 1 {
+		t.Fatalf("upcomingSeries() = %v, want nodes 2 and 1", series)
+	}
+	if series[1].Stage != grandFinalStage {
+		t.Errorf("upcomingSeries() stage = %q, want %q", series[1].Stage, grandFinalStage)
+	}
+}
+
+func TestDailySchedule(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{
+		logger:    logger,
+		store:     storage.NewMemoryStore(),
+		teamNames: map[int]string{1: "OG", 2: "Team Liquid"},
+	}
+	format := textFormat{}
+	now := time.Date(2019, 8, 20, 10, 0, 0, 0, time.UTC)
+	bot.schedule.series = []scheduledSeries{
+		{NodeID: 1, ScheduledTime: now.Add(2 * time.Hour), TeamID1: 1, TeamID2: 2, Stage: "Upper Bracket Final"},
+		{NodeID: 2, ScheduledTime: now.Add(20 * time.Hour), TeamID1: 1, TeamID2: 2},
+	}
+	ctx := context.Background()
+	if got := bot.dailySchedule(ctx, "1", nil, format, now.Add(-2*time.Hour)); got != "" {
+		t.Errorf("dailySchedule() before %d:00 = %q, want none", dailyScheduleHour, got)
+	}
+	got := bot.dailySchedule(ctx, "1", nil, format, now)
+	want := "📅 Today's schedule:\n- 12:00 UTC: OG vs. Team Liquid (Upper Bracket Final)"
+	if got != want {
+		t.Errorf("dailySchedule() = %q, want %q", got, want)
+	}
+	if got := bot.dailySchedule(ctx, "1", nil, format, now); got != "" {
+		t.Errorf("dailySchedule() posted twice: %q", got)
+	}
+	if got := bot.renderUpNext(bot.schedule.series[0], format); !strings.HasPrefix(got, "⏰ Up next: OG vs. Team Liquid") {
+		t.Errorf("renderUpNext() = %q", got)
+	}
+}
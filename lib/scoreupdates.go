@@ -0,0 +1,60 @@
+package timatch
+
+import (
+	"github.com/verath/timatch/lib/dota"
+)
+
+// scoreUpdateInterval is the game time between score updates of games
+// played by notable teams
+const scoreUpdateInterval = 10 * 60
+
+// scoreUpdate is a score update of a live game
+type scoreUpdate struct {
+	Game         dota.LiveLeagueGame
+	RadiantScore int
+	DireScore    int
+	Duration     string
+	// Favored is the team favored to win and WinChance its win
+	// probability in percent, see favoredTeam. Empty for toss-ups
+	Favored   string
+	WinChance int
+	// RoshanAlive is true if Roshan is alive, else RoshanRespawn is the
+	// time until Roshan respawns. Neither is set if not known
+	RoshanAlive   bool
+	RoshanRespawn string
+}
+
+// checkScoreUpdate returns a score update of a live game if a notable
+// team is playing and another scoreUpdateInterval of game time has
+// passed since the last update. ok is false if no update is due.
+func (bot *bot) checkScoreUpdate(game dota.LiveLeagueGame) (update scoreUpdate, ok bool) {
+	if !isGameStarted(game) || !bot.isNotableTeam(game.RadiantTeam.TeamID, game.DireTeam.TeamID) {
+		return scoreUpdate{}, false
+	}
+	mark := int(game.Scoreboard.Duration) / scoreUpdateInterval
+	last, seen := bot.scoreUpdates[game.MatchID]
+	if !seen {
+		// Games first seen mid-game, e.g. after a restart, get their
+		// first update at the next mark
+		bot.scoreUpdates[game.MatchID] = mark
+		return scoreUpdate{}, false
+	}
+	if mark <= last {
+		return scoreUpdate{}, false
+	}
+	bot.scoreUpdates[game.MatchID] = mark
+	update = scoreUpdate{
+		Game:         game,
+		RadiantScore: game.Scoreboard.Radiant.Score,
+		DireScore:    game.Scoreboard.Dire.Score,
+		Duration:     formatDuration(game.Scoreboard.Duration),
+	}
+	update.Favored, update.WinChance = favoredTeam(game)
+	if timer := game.Scoreboard.RoshanRespawnTimer; timer != nil {
+		update.RoshanAlive = *timer == 0
+		if *timer > 0 {
+			update.RoshanRespawn = formatDuration(float32(*timer))
+		}
+	}
+	return update, true
+}
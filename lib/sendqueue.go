@@ -0,0 +1,197 @@
+package timatch
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+// sendMaxAttempts is the number of times sending a message is attempted
+// before it is dropped
+const sendMaxAttempts = 5
+
+// sendRetryBackoff is the wait before the first retry of a message,
+// doubled for every retry after that
+const sendRetryBackoff = 1 * time.Second
+
+// sendShutdownTimeout is the time given to the send queue to send the
+// queued messages when shutting down
+const sendShutdownTimeout = 5 * time.Second
+
+// outboundMessage is a message queued for sending
+type outboundMessage struct {
+	channelID channelID
+	content   string
+	tts       bool
+	// sent is called with the id of the message once sent, if not nil.
+	// Only set for messages not split by sendChannelMessage
+	sent func(messageID string)
+	// components are the buttons of the message, if any
+	components []messageComponent
+}
+
+// sendStats are the counts of messages handled by a send queue
+type sendStats struct {
+	Sent    int64
+	Retried int64
+	Dropped int64
+	// Queued is the number of messages waiting to be sent, not
+	// including those being sent
+	Queued int
+}
+
+// sendQueue sends messages in the background, so that a slow or failing
+// Discord API does not hold up polling. Each channel has its own queue
+// and worker, so that messages to a channel are sent in order without a
+// failing channel holding up the others. Messages failing with transient
+// errors are retried with backoff.
+type sendQueue struct {
+	logger  *logrus.Logger
+	deliver func(msg outboundMessage) error
+	backoff time.Duration
+
+	mu sync.Mutex
+	// pending are the queued messages by channel. A channel has a
+	// running worker as long as it has an entry
+	pending map[channelID][]outboundMessage
+	stats   sendStats
+	workers sync.WaitGroup
+}
+
+func newSendQueue(logger *logrus.Logger, deliver func(msg outboundMessage) error) *sendQueue {
+	return &sendQueue{
+		logger:  logger,
+		deliver: deliver,
+		backoff: sendRetryBackoff,
+		pending: make(map[channelID][]outboundMessage),
+	}
+}
+
+// enqueue queues a message, starting a worker for the channel if it does
+// not have one
+func (queue *sendQueue) enqueue(msg outboundMessage) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	_, running := queue.pending[msg.channelID]
+	queue.pending[msg.channelID] = append(queue.pending[msg.channelID], msg)
+	if !running {
+		queue.workers.Add(1)
+		go queue.work(msg.channelID)
+	}
+}
+
+// work sends the queued messages of a channel, until there are none
+func (queue *sendQueue) work(channelID channelID) {
+	defer queue.workers.Done()
+	for {
+		queue.mu.Lock()
+		msgs := queue.pending[channelID]
+		if len(msgs) == 0 {
+			delete(queue.pending, channelID)
+			queue.mu.Unlock()
+			return
+		}
+		msg := msgs[0]
+		queue.pending[channelID] = msgs[1:]
+		queue.mu.Unlock()
+		queue.send(msg)
+	}
+}
+
+// send delivers a message, retrying on transient errors
+func (queue *sendQueue) send(msg outboundMessage) {
+	logger := queue.logger.WithField(logFieldChannelID, msg.channelID)
+	backoff := queue.backoff
+	for attempt := 1; ; attempt++ {
+		err := queue.deliver(msg)
+		if err == nil {
+			queue.count(func(stats *sendStats) { stats.Sent++ })
+			return
+		}
+		if !isTransientDiscordError(err) || attempt == sendMaxAttempts {
+			logger.WithError(err).Errorf("Failed sending message to channel %s, dropping it", msg.channelID)
+			queue.count(func(stats *sendStats) { stats.Dropped++ })
+			return
+		}
+		logger.WithError(err).Warnf("Failed sending message to channel %s, retrying in %s", msg.channelID, backoff)
+		queue.count(func(stats *sendStats) { stats.Retried++ })
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (queue *sendQueue) count(fn func(stats *sendStats)) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	fn(&queue.stats)
+}
+
+// getStats returns the counts of messages handled so far, and the number
+// of messages queued
+func (queue *sendQueue) getStats() sendStats {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	stats := queue.stats
+	for _, msgs := range queue.pending {
+		stats.Queued += len(msgs)
+	}
+	return stats
+}
+
+// wait waits for the queued messages to be sent, for at most timeout.
+// Returns false on timeout.
+func (queue *sendQueue) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		queue.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// isTransientDiscordError tests if a failed request to Discord may
+// succeed if retried. Client errors, such as missing permissions in a
+// channel, are not transient.
+func isTransientDiscordError(err error) bool {
+	if restErr, ok := err.(*discordgo.RESTError); ok && restErr.Response != nil {
+		return restErr.Response.StatusCode >= 500 || restErr.Response.StatusCode == 429
+	}
+	// Network errors, and discordgo giving up retrying a 502
+	return true
+}
+
+// deliverMessage sends a message, as queued by the send queue
+func (bot *bot) deliverMessage(msg outboundMessage) error {
+	var (
+		m   *discordgo.Message
+		err error
+	)
+	if len(msg.components) > 0 {
+		// discordgo does not support sending components
+		data := struct {
+			Content    string             `json:"content"`
+			TTS        bool               `json:"tts"`
+			Components []messageComponent `json:"components"`
+		}{msg.content, msg.tts, msg.components}
+		var res []byte
+		if res, err = bot.discordSession.Request("POST", discordgo.EndpointChannelMessages(string(msg.channelID)), data); err == nil {
+			err = json.Unmarshal(res, &m)
+		}
+	} else if msg.tts {
+		m, err = bot.discordSession.ChannelMessageSendTTS(string(msg.channelID), msg.content)
+	} else {
+		m, err = bot.discordSession.ChannelMessageSend(string(msg.channelID), msg.content)
+	}
+	if err == nil && msg.sent != nil {
+		msg.sent(m.ID)
+	}
+	return err
+}
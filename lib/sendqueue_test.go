@@ -0,0 +1,66 @@
+package timatch
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSendQueue(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	var mu sync.Mutex
+	var sent []string
+	failures := map[string]int{"retried": 2}
+	queue := newSendQueue(logger, func(msg outboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case msg.content == "forbidden":
+			return &discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusForbidden}}
+		case failures[msg.content] > 0:
+			failures[msg.content]--
+			return errors.New("connection reset")
+		}
+		sent = append(sent, string(msg.channelID)+":"+msg.content)
+		return nil
+	})
+	queue.backoff = time.Millisecond
+	queue.enqueue(outboundMessage{channelID: "1", content: "retried"})
+	queue.enqueue(outboundMessage{channelID: "1", content: "second"})
+	queue.enqueue(outboundMessage{channelID: "2", content: "forbidden"})
+	if !queue.wait(time.Second) {
+		t.Fatal("wait() timed out")
+	}
+	// Messages to a channel are sent in order, even when retried
+	if len(sent) != 2 || sent[0] != "1:retried" || sent[1] != "1:second" {
+		t.Errorf("sent = %q, want 1:retried, 1:second", sent)
+	}
+	want := sendStats{Sent: 2, Retried: 2, Dropped: 1}
+	if stats := queue.getStats(); stats != want {
+		t.Errorf("getStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestIsTransientDiscordError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), true},
+		{&discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusInternalServerError}}, true},
+		{&discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusTooManyRequests}}, true},
+		{&discordgo.RESTError{Response: &http.Response{StatusCode: http.StatusNotFound}}, false},
+	}
+	for _, tt := range tests {
+		if got := isTransientDiscordError(tt.err); got != tt.want {
+			t.Errorf("isTransientDiscordError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
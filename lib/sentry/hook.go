@@ -0,0 +1,225 @@
+// Package sentry implements a logrus hook reporting errors to Sentry (or
+// any service accepting the Sentry store API).
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// sendTimeout is the timeout of sending a single event
+const sendTimeout = 5 * time.Second
+
+// maxPending is the maximum number of events being sent at the same
+// time. Events logged while at the limit are dropped, so that a burst
+// of errors can not block the bot.
+const maxPending = 10
+
+// Hook is a logrus hook sending error (and more severe) log entries as
+// events to Sentry. Structured log fields, e.g. match_id, are attached
+// to the events as tags. Errors added with WithError are attached as an
+// exception, including the stack trace if created by github.com/pkg/errors.
+type Hook struct {
+	storeURL   string
+	authHeader string
+	pending    chan struct{}
+}
+
+// NewHook creates a hook sending events to the project identified by dsn,
+// on the form "https://<key>@<host>/<project id>".
+func NewHook(dsn string) (*Hook, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing dsn")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("Missing key in dsn")
+	}
+	projectID := strings.Trim(u.Path, "/")
+	if projectID == "" {
+		return nil, errors.New("Missing project id in dsn")
+	}
+	storeURL := fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, projectID)
+	authHeader := fmt.Sprintf("Sentry sentry_version=7, sentry_client=timatch/1.0, sentry_key=%s", u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		authHeader += ", sentry_secret=" + secret
+	}
+	return &Hook{
+		storeURL:   storeURL,
+		authHeader: authHeader,
+		pending:    make(chan struct{}, maxPending),
+	}, nil
+}
+
+func (hook *Hook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel}
+}
+
+// Fire sends the log entry to Sentry. Fatal and panic entries are sent
+// synchronously, as the process is about to exit.
+func (hook *Hook) Fire(entry *logrus.Entry) error {
+	body, err := json.Marshal(newEvent(entry))
+	if err != nil {
+		return errors.Wrap(err, "Error encoding event")
+	}
+	if entry.Level <= logrus.FatalLevel {
+		return hook.send(body)
+	}
+	select {
+	case hook.pending <- struct{}{}:
+	default:
+		return errors.New("Too many pending events, dropping event")
+	}
+	go func() {
+		defer func() { <-hook.pending }()
+		// Errors can not be logged here, as it could cause a loop
+		_ = hook.send(body)
+	}()
+	return nil
+}
+
+func (hook *Hook) send(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", hook.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", hook.authHeader)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "Error sending event")
+	}
+	res.Body.Close()
+	if res.StatusCode != 200 {
+		return errors.Errorf("Bad HTTP response status code: %d", res.StatusCode)
+	}
+	return nil
+}
+
+type event struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Logger    string            `json:"logger"`
+	Platform  string            `json:"platform"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Exception *exception        `json:"exception,omitempty"`
+}
+
+type exception struct {
+	Values []exceptionValue `json:"values"`
+}
+
+type exceptionValue struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+// stackTracer is implemented by errors created by github.com/pkg/errors
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+func newEvent(entry *logrus.Entry) *event {
+	ev := &event{
+		EventID:   newEventID(),
+		Timestamp: entry.Time.UTC().Format("2006-01-02T15:04:05"),
+		Level:     sentryLevel(entry.Level),
+		Logger:    "timatch",
+		Platform:  "go",
+		Message:   entry.Message,
+		Tags:      make(map[string]string),
+	}
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok && key == logrus.ErrorKey {
+			ev.Exception = newException(err)
+			continue
+		}
+		ev.Tags[key] = fmt.Sprint(value)
+	}
+	return ev
+}
+
+// newException creates an exception from err, including the stack trace
+// of the innermost error that has one
+func newException(err error) *exception {
+	value := exceptionValue{
+		Type:  fmt.Sprintf("%T", errors.Cause(err)),
+		Value: err.Error(),
+	}
+	var tracer stackTracer
+	for e := err; e != nil; {
+		if t, ok := e.(stackTracer); ok {
+			tracer = t
+		}
+		causer, ok := e.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		e = causer.Cause()
+	}
+	if tracer != nil {
+		st := &stacktrace{}
+		trace := tracer.StackTrace()
+		// Sentry expects the frames ordered oldest call first
+		for i := len(trace) - 1; i >= 0; i-- {
+			line, _ := strconv.Atoi(fmt.Sprintf("%d", trace[i]))
+			st.Frames = append(st.Frames, frame{
+				Function: fmt.Sprintf("%n", trace[i]),
+				Filename: fmt.Sprintf("%s", trace[i]),
+				Lineno:   line,
+			})
+		}
+		value.Stacktrace = st
+	}
+	return &exception{Values: []exceptionValue{value}}
+}
+
+func sentryLevel(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return "fatal"
+	case logrus.ErrorLevel:
+		return "error"
+	case logrus.WarnLevel:
+		return "warning"
+	case logrus.InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+package sentry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewEventException(t *testing.T) {
+	err := errors.Wrap(errors.New("connection refused"), "Error getting live games")
+	entry := logrus.NewEntry(logrus.New()).WithError(err).WithField("match_id", int64(42))
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "Error polling"
+	ev := newEvent(entry)
+
+	if ev.Exception == nil || len(ev.Exception.Values) != 1 {
+		t.Fatalf("Exception = %+v, want one value", ev.Exception)
+	}
+	value := ev.Exception.Values[0]
+	if value.Value != "Error getting live games: connection refused" {
+		t.Errorf("Value = %q", value.Value)
+	}
+	if value.Stacktrace == nil || len(value.Stacktrace.Frames) == 0 {
+		t.Fatal("Exception has no stack frames")
+	}
+	// Frames are ordered oldest call first, so the last frame is where
+	// the error was created
+	last := value.Stacktrace.Frames[len(value.Stacktrace.Frames)-1]
+	if last.Function != "TestNewEventException" || last.Filename != "hook_test.go" || last.Lineno == 0 {
+		t.Errorf("Last frame = %+v, want this test function", last)
+	}
+	if ev.Tags["match_id"] != "42" {
+		t.Errorf("Tags = %v, want match_id 42", ev.Tags)
+	}
+	if _, ok := ev.Tags[logrus.ErrorKey]; ok {
+		t.Error("Error included as a tag")
+	}
+}
+
+func TestNewEventWithoutError(t *testing.T) {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "Something failed"
+	ev := newEvent(entry)
+	if ev.Exception != nil {
+		t.Errorf("Exception = %+v, want nil", ev.Exception)
+	}
+	if ev.Level != "error" || ev.Message != "Something failed" {
+		t.Errorf("Level, Message = %q, %q", ev.Level, ev.Message)
+	}
+}
+
+func TestHookSend(t *testing.T) {
+	received := make(chan *event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/7/store/" {
+			t.Errorf("Path = %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=key") {
+			t.Errorf("X-Sentry-Auth = %s", auth)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		ev := &event{}
+		if err := json.Unmarshal(body, ev); err != nil {
+			t.Errorf("Error decoding event: %v", err)
+		}
+		received <- ev
+	}))
+	defer srv.Close()
+	hook, err := NewHook(strings.Replace(srv.URL, "http://", "http://key@", 1) + "/7")
+	if err != nil {
+		t.Fatalf("NewHook: %v", err)
+	}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(hook)
+	logger.WithError(errors.New("boom")).Error("Error polling")
+	select {
+	case ev := <-received:
+		if ev.Exception == nil || ev.Exception.Values[0].Stacktrace == nil {
+			t.Error("Sent event has no stack trace")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event sent")
+	}
+}
@@ -0,0 +1,86 @@
+package timatch
+
+import (
+	"context"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// sequencePageSize is the number of matches requested per page of the
+// sequence of all recorded matches
+const sequencePageSize = 100
+
+// sequenceMaxPages is the most pages of the sequence requested per poll.
+// Thousands of matches are recorded every hour, so the pages of a poll
+// usually reach the newest match, and the next poll continues if not
+const sequenceMaxPages = 20
+
+// sequenceSource is the source of the sequence of all recorded matches,
+// implemented by *dota.Client
+type sequenceSource interface {
+	GetMatchHistoryPage(ctx context.Context, leagueID int, startAtMatchID int64, matchesRequested int) (*dota.MatchHistoryResponse, error)
+	GetMatchHistoryBySequenceNum(ctx context.Context, startAtMatchSeqNum int64, matchesRequested int) (*dota.MatchSequenceResponse, error)
+	GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error)
+}
+
+// seedMatchSeqNum sets the sequence number finished games are polled from
+// to that of the newest recorded public match. Called without holding mu,
+// see updateFinished
+func (watcher *Watcher) seedMatchSeqNum(ctx context.Context) {
+	matchSeqNum := int64(0)
+	defer func() {
+		watcher.mu.Lock()
+		watcher.matchSeqNum = matchSeqNum
+		watcher.mu.Unlock()
+	}()
+	historyRes, err := watcher.sequence.GetMatchHistoryPage(ctx, 0, 0, 1)
+	if err != nil || len(historyRes.Result.Matches) == 0 {
+		watcher.logger.WithError(err).Warn("Error getting the newest match, getting the full match history")
+		return
+	}
+	detailsRes, err := watcher.sequence.GetMatchDetails(ctx, historyRes.Result.Matches[0].MatchID)
+	if err != nil {
+		watcher.logger.WithError(err).Warn("Error getting the newest match, getting the full match history")
+		return
+	}
+	matchSeqNum = detailsRes.Result.MatchSeqNum
+}
+
+// updateFinishedBySequence finds the finished games of the league in the
+// matches recorded since the last poll, returning false if the sequence
+// could not be got, in which case the full match history should be got
+// instead. Called without holding mu, see updateFinished
+func (watcher *Watcher) updateFinishedBySequence(ctx context.Context) bool {
+	watcher.mu.Lock()
+	matchSeqNum := watcher.matchSeqNum
+	watcher.mu.Unlock()
+	for page := 0; page < sequenceMaxPages; page++ {
+		res, err := watcher.sequence.GetMatchHistoryBySequenceNum(ctx, matchSeqNum, sequencePageSize)
+		if err != nil {
+			watcher.logger.WithError(err).Warnf("Error getting matches from sequence number %d", matchSeqNum)
+			return page > 0
+		}
+		watcher.mu.Lock()
+		watcher.applyMatchSequence(ctx, res.Result.Matches)
+		matchSeqNum = watcher.matchSeqNum
+		watcher.mu.Unlock()
+		if len(res.Result.Matches) < sequencePageSize {
+			return true
+		}
+	}
+	watcher.logger.Debugf("Not caught up with the match sequence, continuing from %d next poll", matchSeqNum)
+	return true
+}
+
+// applyMatchSequence handles a page of the sequence of recorded matches,
+// advancing the sequence number past the matches of the page
+func (watcher *Watcher) applyMatchSequence(ctx context.Context, matches []dota.SequenceMatch) {
+	for _, match := range matches {
+		if match.MatchSeqNum >= watcher.matchSeqNum {
+			watcher.matchSeqNum = match.MatchSeqNum + 1
+		}
+		if _, ok := watcher.matchLeagues[match.MatchID]; ok || match.LeagueID == watcher.leagueID {
+			watcher.matchFinished(ctx, match.HistoryMatch())
+		}
+	}
+}
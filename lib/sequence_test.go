@@ -0,0 +1,34 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestApplyMatchSequence(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	watcher := newWatcher(logger, &stubDataSource{})
+	watcher.leagueID = 10749
+	watcher.matchSeqNum = 100
+	watcher.started = map[int64]struct{}{1: {}, 2: {}}
+	matches := []dota.SequenceMatch{
+		{MatchID: 1, LeagueID: 10749, MatchDetails: dota.MatchDetails{MatchSeqNum: 100}},
+		{MatchID: 2, LeagueID: 0, MatchDetails: dota.MatchDetails{MatchSeqNum: 101}},
+		{MatchID: 3, LeagueID: 10749, MatchDetails: dota.MatchDetails{MatchSeqNum: 102}},
+	}
+	watcher.applyMatchSequence(context.Background(), matches)
+	if watcher.matchSeqNum != 103 {
+		t.Errorf("expected the sequence number to be 103, got %d", watcher.matchSeqNum)
+	}
+	if len(watcher.finishedQueue) != 1 || watcher.finishedQueue[0].MatchID != 1 {
+		t.Errorf("expected only match 1 to be queued, got %v", watcher.finishedQueue)
+	}
+	if _, ok := watcher.finished[2]; ok {
+		t.Error("expected the match of another league not to be finished")
+	}
+}
@@ -0,0 +1,644 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+)
+
+// guildSettings are the settings of a guild, changed using the
+// /settings command
+type guildSettings struct {
+	// Subscriptions are the channels to send announcements to. Unless
+	// SubscriptionsSet, the first text channel of the guild is used
+	Subscriptions    []channelSubscription `json:"subscriptions,omitempty"`
+	SubscriptionsSet bool                  `json:"subscriptions_set,omitempty"`
+	// BroadcastLanguages are the languages of the broadcasts linked to
+	// in announcements. If empty, broadcasts in all languages are linked
+	BroadcastLanguages []string `json:"broadcast_languages,omitempty"`
+	// MinImportance is the minimum importance score of games to
+	// announce. Unless MinImportanceSet, the -minimportance flag is used
+	MinImportance    int  `json:"min_importance,omitempty"`
+	MinImportanceSet bool `json:"min_importance_set,omitempty"`
+	// ScoreStyle and NumberLocale are the names of the score style and
+	// number locale used in messages, see textFormat
+	ScoreStyle   string `json:"score_style,omitempty"`
+	NumberLocale string `json:"number_locale,omitempty"`
+	// TeamRoles are the roles pinged when games of teams start, changed
+	// using the /teamrole command
+	TeamRoles []teamRole `json:"team_roles,omitempty"`
+	// StagingChannelID is a channel getting all announcements, ignoring
+	// the filters of the guild, until StagingUntil
+	StagingChannelID string    `json:"staging_channel_id,omitempty"`
+	StagingUntil     time.Time `json:"staging_until,omitempty"`
+	// QuietHours is the daily period during which announcements are held
+	// back, or nil
+	QuietHours *quietHours `json:"quiet_hours,omitempty"`
+	// Timezone is the IANA time zone times are given in, see textFormat
+	Timezone string `json:"timezone,omitempty"`
+	// Language is the code of the language of announcements, and
+	// ChannelLanguages those of channels not using the guild's language
+	Language         string            `json:"language,omitempty"`
+	ChannelLanguages map[string]string `json:"channel_languages,omitempty"`
+	// Templates are the texts of the templates replaced using the
+	// /template command, by the names used by RenderPreview
+	Templates map[string]string `json:"templates,omitempty"`
+	// TTS is whether announcements are sent as TTS, by event, for the
+	// events not using their default, see ttsEvents
+	TTS map[string]bool `json:"tts,omitempty"`
+	// Accessible is true if the guild is in accessibility mode, changed
+	// using the /accessible command
+	Accessible bool `json:"accessible,omitempty"`
+	// Layout is the layout of announcements, see layouts, and
+	// ChannelLayouts those of channels not using the guild's layout
+	Layout         string            `json:"layout,omitempty"`
+	ChannelLayouts map[string]string `json:"channel_layouts,omitempty"`
+	// ResultDetail is how much of the results of games is announced, see
+	// resultDetails
+	ResultDetail string `json:"result_detail,omitempty"`
+	// Ticker is true if games are announced using a single message per
+	// game, edited with the live score, see liveTicker
+	Ticker bool `json:"ticker,omitempty"`
+	// SeriesThreads is true if the games of each series are announced in
+	// a thread of the series, see seriesThread
+	SeriesThreads bool `json:"series_threads,omitempty"`
+	// PinLive is true if started announcements are pinned while the
+	// games are live
+	PinLive bool `json:"pin_live,omitempty"`
+	// Predictions is true if drafting games are followed by a prompt
+	// to predict the winner, see renderPredictionPrompt
+	Predictions bool `json:"predictions,omitempty"`
+	// Betting is true if members can bet points on games with /bet
+	Betting bool `json:"betting,omitempty"`
+	// Recap is the time of day, as "HH:MM" in the time zone of the
+	// guild, to send a recap of the day's games at, or "" for no recap
+	Recap string `json:"recap,omitempty"`
+}
+
+// minImportance returns the minimum importance score of games to announce
+// to the guild, defaultMin unless changed with /settings
+func (settings *guildSettings) minImportance(defaultMin int) int {
+	if settings.MinImportanceSet {
+		return settings.MinImportance
+	}
+	return defaultMin
+}
+
+// guildSetting describes a setting that can be changed with /settings
+type guildSetting struct {
+	name        string
+	description string
+	get         func(bot *bot, settings *guildSettings) string
+	// set validates and sets the value of the setting. Returned
+	// errors are shown to the user, so should be human readable
+	set func(bot *bot, guildID string, settings *guildSettings, value string) error
+}
+
+var guildSettingsList = []guildSetting{
+	{
+		name:        "channel",
+		description: "Channel to send announcements to, replacing all subscribed channels",
+		get: func(bot *bot, settings *guildSettings) string {
+			if !settings.SubscriptionsSet {
+				return "(first text channel)"
+			}
+			if len(settings.Subscriptions) == 0 {
+				return "(none)"
+			}
+			channels := make([]string, 0, len(settings.Subscriptions))
+			for _, sub := range settings.Subscriptions {
+				channels = append(channels, "<#"+sub.ChannelID+">")
+			}
+			return strings.Join(channels, ", ")
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			id, err := bot.parseGuildChannel(guildID, value)
+			if err != nil {
+				return err
+			}
+			settings.Subscriptions = []channelSubscription{{ChannelID: id}}
+			settings.SubscriptionsSet = true
+			return nil
+		},
+	},
+	{
+		name:        "languages",
+		description: "Comma separated broadcast languages to link to, or \"all\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			if len(settings.BroadcastLanguages) == 0 {
+				return "all"
+			}
+			return strings.Join(settings.BroadcastLanguages, ", ")
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			if strings.EqualFold(strings.TrimSpace(value), "all") {
+				settings.BroadcastLanguages = nil
+				return nil
+			}
+			languages := make([]string, 0)
+			for _, language := range strings.Split(value, ",") {
+				language = strings.TrimSpace(language)
+				if language == "" {
+					continue
+				}
+				known := false
+				for _, channel := range bot.broadcastChannels {
+					if strings.EqualFold(channel.Language, language) {
+						known = true
+						language = channel.Language
+						break
+					}
+				}
+				if !known {
+					return errors.Errorf("No broadcasts in %s are configured", language)
+				}
+				languages = append(languages, language)
+			}
+			if len(languages) == 0 {
+				return errors.New("Give a comma separated list of languages, or \"all\"")
+			}
+			settings.BroadcastLanguages = languages
+			return nil
+		},
+	},
+	{
+		name:        "minimportance",
+		description: "Minimum importance score (0-100) of games to announce, or \"default\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			if !settings.MinImportanceSet {
+				return fmt.Sprintf("%d (default)", bot.minImportance)
+			}
+			return strconv.Itoa(settings.MinImportance)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			value = strings.TrimSpace(value)
+			if strings.EqualFold(value, "default") {
+				settings.MinImportance = 0
+				settings.MinImportanceSet = false
+				return nil
+			}
+			minImportance, err := strconv.Atoi(value)
+			if err != nil || minImportance < 0 || minImportance > maxImportance {
+				return errors.Errorf("Give a score from 0 to %d, or \"default\"", maxImportance)
+			}
+			settings.MinImportance = minImportance
+			settings.MinImportanceSet = true
+			return nil
+		},
+	},
+	{
+		name:        "scorestyle",
+		description: "How scores are written: " + scoreStyleExamples(),
+		get: func(bot *bot, settings *guildSettings) string {
+			return settings.textFormat().score.name
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			style, ok := findScoreStyle(strings.TrimSpace(value))
+			if !ok {
+				return errors.Errorf("Unknown score style, give one of %s", scoreStyleExamples())
+			}
+			settings.ScoreStyle = style.name
+			return nil
+		},
+	},
+	{
+		name:        "numbers",
+		description: "How numbers are written: " + numberLocaleExamples(),
+		get: func(bot *bot, settings *guildSettings) string {
+			return settings.textFormat().number.name
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			locale, ok := findNumberLocale(strings.TrimSpace(value))
+			if !ok {
+				return errors.Errorf("Unknown number format, give one of %s", numberLocaleExamples())
+			}
+			settings.NumberLocale = locale.name
+			return nil
+		},
+	},
+	{
+		name:        "staging",
+		description: "Channel getting all announcements, ignoring importance and team filters, for a trial period, e.g. \"#channel 3d\", or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			id := settings.stagingChannel(time.Now())
+			if id == "" {
+				return "off"
+			}
+			return "<#" + id + "> until " + settings.textFormat().DateTime(settings.StagingUntil)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			fields := strings.Fields(value)
+			if len(fields) == 1 && strings.EqualFold(fields[0], "off") {
+				settings.StagingChannelID = ""
+				settings.StagingUntil = time.Time{}
+				return nil
+			}
+			if len(fields) < 1 || len(fields) > 2 {
+				return errors.New("Give the channel as #channel, optionally followed by a period, e.g. 3d")
+			}
+			id, err := bot.parseGuildChannel(guildID, fields[0])
+			if err != nil {
+				return err
+			}
+			period := defaultStagingPeriod
+			if len(fields) == 2 {
+				if period, err = parseDuration(fields[1]); err != nil {
+					return errors.Errorf("Invalid period %q, give e.g. 12h or 3d", fields[1])
+				}
+			}
+			settings.StagingChannelID = id
+			settings.StagingUntil = time.Now().Add(period)
+			return nil
+		},
+	},
+	{
+		name:        "tts",
+		description: "Read out announcements using TTS: \"on\", \"off\" or \"default\", optionally after one of " + announcementEventNames(),
+		get: func(bot *bot, settings *guildSettings) string {
+			return settings.ttsString()
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return settings.setTTS(value)
+		},
+	},
+	{
+		name:        "results",
+		description: "How much of game results to give: \"full\" (winner and kills), \"games\" (winner only) or \"series\" (series winners only)",
+		get: func(bot *bot, settings *guildSettings) string {
+			return settings.resultDetail()
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			detail, ok := findResultDetail(strings.TrimSpace(value))
+			if !ok {
+				return errors.Errorf("Unknown result detail, give one of %s", strings.Join(resultDetails, ", "))
+			}
+			settings.ResultDetail = detail
+			return nil
+		},
+	},
+	{
+		name:        "ticker",
+		description: "Announce games using a single message per game, edited with the live score and the result: \"on\" or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			return onOffString(settings.Ticker)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return parseOnOff(value, &settings.Ticker)
+		},
+	},
+	{
+		name:        "threads",
+		description: "Announce the games of each series in a thread named after the matchup: \"on\" or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			return onOffString(settings.SeriesThreads)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return parseOnOff(value, &settings.SeriesThreads)
+		},
+	},
+	{
+		name:        "pin",
+		description: "Pin started announcements while the games are live: \"on\" or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			return onOffString(settings.PinLive)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return parseOnOff(value, &settings.PinLive)
+		},
+	},
+	{
+		name:        "predictions",
+		description: "Let members predict the winners of drafting games, see /leaderboard: \"on\" or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			return onOffString(settings.Predictions)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return parseOnOff(value, &settings.Predictions)
+		},
+	},
+	{
+		name:        "betting",
+		description: "Let members bet points on games with /bet: \"on\" or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			return onOffString(settings.Betting)
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			return parseOnOff(value, &settings.Betting)
+		},
+	},
+	{
+		name:        "recap",
+		description: "Time of day to send a recap of the day's games at, e.g. \"23:00\" in the time zone of the server, or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			if settings.Recap == "" {
+				return "off"
+			}
+			return settings.Recap
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			if strings.EqualFold(strings.TrimSpace(value), "off") {
+				settings.Recap = ""
+				return nil
+			}
+			recap, err := parseRecapTime(value)
+			if err != nil {
+				return err
+			}
+			settings.Recap = recap
+			return nil
+		},
+	},
+	{
+		name:        "quiethours",
+		description: "Daily hours during which announcements are held back and sent as a digest afterwards, e.g. \"01:00-09:00 Europe/Stockholm\", or \"off\"",
+		get: func(bot *bot, settings *guildSettings) string {
+			if settings.QuietHours == nil {
+				return "off"
+			}
+			return settings.QuietHours.String()
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			if strings.EqualFold(strings.TrimSpace(value), "off") {
+				settings.QuietHours = nil
+				return nil
+			}
+			quiet, err := parseQuietHours(value)
+			if err != nil {
+				return err
+			}
+			settings.QuietHours = quiet
+			return nil
+		},
+	},
+	{
+		name:        "timezone",
+		description: "Time zone of the times in messages, e.g. \"Europe/Stockholm\", or \"off\" to not show times",
+		get: func(bot *bot, settings *guildSettings) string {
+			if settings.Timezone == "" {
+				return "off"
+			}
+			return settings.Timezone
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			value = strings.TrimSpace(value)
+			if strings.EqualFold(value, "off") {
+				settings.Timezone = ""
+				return nil
+			}
+			loc, err := time.LoadLocation(value)
+			if err != nil || value == "" || value == "Local" {
+				return errors.Errorf("Unknown time zone %q, give e.g. Europe/Stockholm", value)
+			}
+			settings.Timezone = loc.String()
+			return nil
+		},
+	},
+	{
+		name:        "language",
+		description: "Language of announcements, e.g. " + messageLanguageExamples() + ". Follow with a #channel to only change the language of the channel, or give \"default #channel\" to reset it",
+		get: func(bot *bot, settings *guildSettings) string {
+			s := settings.language("").code
+			channels := make([]string, 0, len(settings.ChannelLanguages))
+			for channelID, code := range settings.ChannelLanguages {
+				channels = append(channels, "<#"+channelID+">: "+code)
+			}
+			sort.Strings(channels)
+			if len(channels) > 0 {
+				s += " (" + strings.Join(channels, ", ") + ")"
+			}
+			return s
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			fields := strings.Fields(value)
+			if len(fields) < 1 || len(fields) > 2 {
+				return errors.Errorf("Give one of %s, optionally followed by a #channel", messageLanguageExamples())
+			}
+			if len(fields) == 2 {
+				id, err := bot.parseGuildChannel(guildID, fields[1])
+				if err != nil {
+					return err
+				}
+				if strings.EqualFold(fields[0], "default") {
+					delete(settings.ChannelLanguages, id)
+					return nil
+				}
+				language, ok := findMessageLanguage(fields[0])
+				if !ok {
+					return errors.Errorf("Unknown language, give one of %s", messageLanguageExamples())
+				}
+				if settings.ChannelLanguages == nil {
+					settings.ChannelLanguages = make(map[string]string)
+				}
+				settings.ChannelLanguages[id] = language.code
+				return nil
+			}
+			language, ok := findMessageLanguage(fields[0])
+			if !ok {
+				return errors.Errorf("Unknown language, give one of %s", messageLanguageExamples())
+			}
+			settings.Language = language.code
+			return nil
+		},
+	},
+	{
+		name:        "layout",
+		description: "Layout of announcements, " + strings.Join(layouts, " or ") + ". Follow with a #channel to only change the layout of the channel, or give \"default #channel\" to reset it",
+		get: func(bot *bot, settings *guildSettings) string {
+			s := settings.layout("")
+			channels := make([]string, 0, len(settings.ChannelLayouts))
+			for channelID, layout := range settings.ChannelLayouts {
+				channels = append(channels, "<#"+channelID+">: "+layout)
+			}
+			sort.Strings(channels)
+			if len(channels) > 0 {
+				s += " (" + strings.Join(channels, ", ") + ")"
+			}
+			return s
+		},
+		set: func(bot *bot, guildID string, settings *guildSettings, value string) error {
+			fields := strings.Fields(value)
+			if len(fields) < 1 || len(fields) > 2 {
+				return errors.Errorf("Give %s, optionally followed by a #channel", strings.Join(layouts, " or "))
+			}
+			if len(fields) == 2 && strings.EqualFold(fields[0], "default") {
+				id, err := bot.parseGuildChannel(guildID, fields[1])
+				if err != nil {
+					return err
+				}
+				delete(settings.ChannelLayouts, id)
+				return nil
+			}
+			layout, ok := findLayout(fields[0])
+			if !ok {
+				return errors.Errorf("Unknown layout, give %s", strings.Join(layouts, " or "))
+			}
+			if len(fields) == 2 {
+				id, err := bot.parseGuildChannel(guildID, fields[1])
+				if err != nil {
+					return err
+				}
+				if settings.ChannelLayouts == nil {
+					settings.ChannelLayouts = make(map[string]string)
+				}
+				settings.ChannelLayouts[id] = layout
+				return nil
+			}
+			settings.Layout = layout
+			return nil
+		},
+	},
+}
+
+// parseOnOff parses an "on" or "off" setting into on
+func parseOnOff(value string, on *bool) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on":
+		*on = true
+	case "off":
+		*on = false
+	default:
+		return errors.New("Give \"on\" or \"off\"")
+	}
+	return nil
+}
+
+// parseGuildChannel parses a channel mention, returning the id of the
+// channel if it is a channel of the guild
+func (bot *bot) parseGuildChannel(guildID string, value string) (string, error) {
+	id := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "<#"), ">")
+	ch, err := bot.discordSession.State.Channel(id)
+	if err != nil || ch.GuildID != guildID {
+		return "", errors.New("Unknown channel, give the channel as #channel")
+	}
+	return id, nil
+}
+
+// interactionTextFormat returns the text format of the guild an
+// interaction is from, or the default format outside of guilds
+func (bot *bot) interactionTextFormat(ctx context.Context, in *interaction) textFormat {
+	if in.GuildID == "" {
+		sub, err := bot.interactionSubscription(ctx, in)
+		if err != nil || sub == nil {
+			return defaultTextFormat
+		}
+		return (&guildSettings{Accessible: sub.Accessible}).textFormat()
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		bot.logger.WithField(logFieldGuildID, in.GuildID).WithError(err).Error("Error getting settings, using defaults")
+		return defaultTextFormat
+	}
+	return settings.channelTextFormat(in.ChannelID)
+}
+
+// guildSettingChoices returns the setting names as command option choices
+func guildSettingChoices() []applicationCommandOptionChoice {
+	choices := make([]applicationCommandOptionChoice, 0, len(guildSettingsList))
+	for _, setting := range guildSettingsList {
+		choices = append(choices, applicationCommandOptionChoice{Name: setting.name, Value: setting.name})
+	}
+	return choices
+}
+
+func findGuildSetting(name string) *guildSetting {
+	for i := range guildSettingsList {
+		if guildSettingsList[i].name == name {
+			return &guildSettingsList[i]
+		}
+	}
+	return nil
+}
+
+// guildSettingsKey returns the store key of the settings of a guild
+func guildSettingsKey(guildID guildID) string {
+	return "guild/" + string(guildID) + "/settings"
+}
+
+// getGuildSettings returns the settings of a guild. Guilds that have not
+// changed any settings get the default settings.
+func (bot *bot) getGuildSettings(ctx context.Context, guildID guildID) (*guildSettings, error) {
+	settings := &guildSettings{}
+	if _, err := bot.store.Get(ctx, guildSettingsKey(guildID), settings); err != nil {
+		return nil, errors.Wrap(err, "Error getting guild settings")
+	}
+	return settings, nil
+}
+
+func (bot *bot) saveGuildSettings(ctx context.Context, guildID guildID, settings *guildSettings) error {
+	err := bot.store.Set(ctx, guildSettingsKey(guildID), settings, 0)
+	return errors.Wrap(err, "Error saving guild settings")
+}
+
+// handleSettingsCommand shows the settings of the guild, or changes a
+// setting if both a name and value is given. Changing settings requires
+// the Manage Server permission.
+func (bot *bot) handleSettingsCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Settings can only be changed in a server."), nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	name, value := in.stringOption("name"), in.stringOption("value")
+	if name == "" || value == "" {
+		var b strings.Builder
+		b.WriteString("**Settings**\n")
+		for _, setting := range guildSettingsList {
+			if name != "" && setting.name != name {
+				continue
+			}
+			fmt.Fprintf(&b, "`%s`: %s - %s\n", setting.name, setting.get(bot, settings), setting.description)
+		}
+		return textResponse(b.String()), nil
+	}
+	if !in.hasPermission(permissionManageGuild) {
+		return textResponse("Changing settings requires the Manage Server permission."), nil
+	}
+	setting := findGuildSetting(name)
+	if setting == nil {
+		return textResponse("Unknown setting " + name), nil
+	}
+	if err := setting.set(bot, in.GuildID, settings, value); err != nil {
+		return textResponse(err.Error()), nil
+	}
+	if err := bot.saveGuildSettings(ctx, guildID(in.GuildID), settings); err != nil {
+		return nil, err
+	}
+	bot.applyGuildSettings(guildID(in.GuildID), settings)
+	return textResponse(fmt.Sprintf("`%s` set to %s", setting.name, setting.get(bot, settings))), nil
+}
+
+// applyGuildSettings updates the bot state after the settings of a guild
+// have changed
+func (bot *bot) applyGuildSettings(guildID guildID, settings *guildSettings) {
+	var channels []*discordgo.Channel
+	if guild, err := bot.discordSession.State.Guild(string(guildID)); err == nil {
+		channels = guild.Channels
+	}
+	bot.setGuildChannels(guildID, settings.announceChannels(channels))
+}
+
+// filterBroadcastChannels returns the channels broadcasting in one of the
+// guild's broadcast languages
+func (settings *guildSettings) filterBroadcastChannels(channels []BroadcastChannel) []BroadcastChannel {
+	if len(settings.BroadcastLanguages) == 0 {
+		return channels
+	}
+	filtered := make([]BroadcastChannel, 0, len(channels))
+	for _, channel := range channels {
+		for _, language := range settings.BroadcastLanguages {
+			if strings.EqualFold(channel.Language, language) {
+				filtered = append(filtered, channel)
+				break
+			}
+		}
+	}
+	return filtered
+}
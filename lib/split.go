@@ -0,0 +1,46 @@
+package timatch
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMessageLength is the maximum length of a Discord message
+const maxMessageLength = 2000
+
+// splitContent splits the content of a message into messages short enough
+// for Discord, on line boundaries
+func splitContent(content string) []string {
+	if len(content) <= maxMessageLength {
+		return []string{content}
+	}
+	return splitMessage(strings.Split(content, "\n"), maxMessageLength)
+}
+
+// splitMessage joins lines into messages of at most max bytes. Lines
+// longer than max are cut, on a character boundary.
+func splitMessage(lines []string, max int) []string {
+	messages := make([]string, 0, 1)
+	var b strings.Builder
+	for _, line := range lines {
+		if len(line) > max {
+			cut := max
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			line = line[:cut]
+		}
+		if b.Len() > 0 && b.Len()+1+len(line) > max {
+			messages = append(messages, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(line)
+	}
+	if b.Len() > 0 {
+		messages = append(messages, b.String())
+	}
+	return messages
+}
@@ -0,0 +1,39 @@
+package timatch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	lines := []string{"aaaa", "bbbb", "cccc", strings.Repeat("d", 12)}
+	want := []string{"aaaa\nbbbb", "cccc", "dddddddddd"}
+	if got := splitMessage(lines, 10); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitMessage() = %q, want %q", got, want)
+	}
+	// Long lines are not cut within a character
+	if got, want := splitMessage([]string{"ааааа"}, 5), []string{"аа"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("splitMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestSplitContent(t *testing.T) {
+	if got := splitContent("short"); !reflect.DeepEqual(got, []string{"short"}) {
+		t.Errorf("splitContent() = %q, want a single message", got)
+	}
+	line := strings.Repeat("x", 150)
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = line
+	}
+	got := splitContent(strings.Join(lines, "\n"))
+	if len(got) != 2 {
+		t.Fatalf("splitContent() of %d characters = %d messages, want 2", 20*151-1, len(got))
+	}
+	for _, content := range got {
+		if len(content) > maxMessageLength || strings.HasPrefix(content, "\n") {
+			t.Errorf("splitContent() message of %d characters, want whole lines of at most %d", len(content), maxMessageLength)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package timatch
+
+import (
+	"fmt"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// grandFinalStage is the stage of the series of the last round of a
+// playoff bracket
+const grandFinalStage = "Grand Final"
+
+// seriesStage is a series of the league data with the name of its stage
+type seriesStage struct {
+	node  dota.LeagueNode
+	stage string
+}
+
+// leagueStages returns the series of the node groups of the league data,
+// including nested ones, with their stage: the name of the series if it
+// has one, e.g. "Upper Bracket Final", the grand final for the last round
+// of a playoff bracket, the round of other playoff series, or else the
+// name of the node group, e.g. "Group Stage"
+func leagueStages(groups []dota.LeagueNodeGroup) []seriesStage {
+	var stages []seriesStage
+	for _, group := range groups {
+		if group.IsBracket() {
+			rounds := bracketRounds(group)
+			for i, round := range rounds {
+				for _, node := range round {
+					stage := fmt.Sprintf("%s Round %d", group.Name, i+1)
+					if i == len(rounds)-1 {
+						stage = grandFinalStage
+					}
+					if node.Name != "" {
+						stage = node.Name
+					}
+					stages = append(stages, seriesStage{node: node, stage: stage})
+				}
+			}
+		} else if group.Name != "" {
+			for _, node := range group.Nodes {
+				stage := group.Name
+				if node.Name != "" {
+					stage = node.Name
+				}
+				stages = append(stages, seriesStage{node: node, stage: stage})
+			}
+		}
+		stages = append(stages, leagueStages(group.NodeGroups)...)
+	}
+	return stages
+}
+
+// gameStage returns the stage of the league a live game is played in, or
+// empty if its series is not in the league data
+func (bot *bot) gameStage(game dota.LiveLeagueGame) string {
+	for _, stage := range bot.bracket.stages {
+		if isSeriesNode(stage.node, game) {
+			return stage.stage
+		}
+	}
+	return ""
+}
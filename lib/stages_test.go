@@ -0,0 +1,62 @@
+package timatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestLeagueStages(t *testing.T) {
+	groups := []dota.LeagueNodeGroup{
+		{
+			Name: "Group Stage",
+			Nodes: []dota.LeagueNode{
+				{NodeID: 1, SeriesID: 11},
+			},
+		},
+		{
+			Name:          "Playoffs",
+			NodeGroupType: dota.NodeGroupTypeBracketSingle,
+			Nodes: []dota.LeagueNode{
+				{NodeID: 2, SeriesID: 12, Name: "Upper Bracket Final"},
+				{NodeID: 3, SeriesID: 13},
+				{NodeID: 4, SeriesID: 14, IncomingNodeID1: 2, IncomingNodeID2: 3},
+			},
+		},
+	}
+	bot := &bot{}
+	bot.bracket.stages = leagueStages(groups)
+	tests := []struct {
+		seriesID int64
+		want     string
+	}{
+		{11, "Group Stage"},
+		{12, "Upper Bracket Final"},
+		{13, "Playoffs Round 1"},
+		{14, grandFinalStage},
+		{15, ""},
+	}
+	for _, test := range tests {
+		if got := bot.gameStage(dota.LiveLeagueGame{SeriesID: test.seriesID}); got != test.want {
+			t.Errorf("gameStage() of series %d = %q, want %q", test.seriesID, got, test.want)
+		}
+	}
+}
+
+func TestRenderTemplateStage(t *testing.T) {
+	games := []dota.LiveLeagueGame{{
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+		GameNumber:  2,
+		Stage:       "Upper Bracket Final",
+	}}
+	got, err := renderTemplate(tmplMatchesDrafting, textFormat{score: scoreStyles[0]}, games)
+	if err != nil {
+		t.Fatalf("Error rendering template: %+v", err)
+	}
+	want := "In Drafting: OG vs. Liquid (Upper Bracket Final, Game 2)"
+	if got = strings.TrimSpace(got); got != want {
+		t.Errorf("renderTemplate() with stage = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package timatch
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultStagingPeriod is the trial period of a staging channel set
+// without a period
+const defaultStagingPeriod = 7 * 24 * time.Hour
+
+// stagingChannel returns the staging channel of the guild, or "" if the
+// guild has no staging channel or its trial period has ended at now
+func (settings *guildSettings) stagingChannel(now time.Time) string {
+	if settings.StagingChannelID == "" || !now.Before(settings.StagingUntil) {
+		return ""
+	}
+	return settings.StagingChannelID
+}
+
+// stagingSettings returns the settings used for the staging channel of
+// the guild: those of the guild, but announcing games of any importance
+// and without pinging team roles
+func (settings *guildSettings) stagingSettings() *guildSettings {
+	staging := *settings
+	staging.MinImportance = 0
+	staging.MinImportanceSet = true
+	staging.TeamRoles = nil
+	return &staging
+}
+
+// parseDuration parses a duration given by a user, e.g. "8h", "30m" or
+// "3d". Only positive durations are valid.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	var d time.Duration
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, errors.Errorf("Invalid duration %q", s)
+		}
+		d = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, errors.Errorf("Invalid duration %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, errors.Errorf("Invalid duration %q", s)
+	}
+	return d, nil
+}
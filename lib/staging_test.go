@@ -0,0 +1,73 @@
+package timatch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStagingChannel(t *testing.T) {
+	now := time.Date(2019, 8, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		settings guildSettings
+		want     string
+	}{
+		{guildSettings{}, ""},
+		{guildSettings{StagingChannelID: "1", StagingUntil: now.Add(time.Hour)}, "1"},
+		{guildSettings{StagingChannelID: "1", StagingUntil: now}, ""},
+		{guildSettings{StagingChannelID: "1", StagingUntil: now.Add(-time.Hour)}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.settings.stagingChannel(now); got != tt.want {
+			t.Errorf("stagingChannel() of %+v = %q, want %q", tt.settings, got, tt.want)
+		}
+	}
+}
+
+func TestStagingSettings(t *testing.T) {
+	settings := &guildSettings{
+		MinImportance:    50,
+		MinImportanceSet: true,
+		ScoreStyle:       "colon",
+		TeamRoles:        []teamRole{{TeamID: 1, RoleID: "10"}},
+	}
+	staging := settings.stagingSettings()
+	if got := staging.minImportance(20); got != 0 {
+		t.Errorf("minImportance() of staging settings = %d, want 0", got)
+	}
+	if got := staging.roleMentions(1); got != "" {
+		t.Errorf("roleMentions() of staging settings = %q, want none", got)
+	}
+	if staging.ScoreStyle != "colon" {
+		t.Errorf("ScoreStyle of staging settings = %q, want colon", staging.ScoreStyle)
+	}
+	if settings.MinImportance != 50 || len(settings.TeamRoles) != 1 {
+		t.Error("stagingSettings() changed the guild settings")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"8h", 8 * time.Hour, false},
+		{"30m", 30 * time.Minute, false},
+		{" 3d ", 72 * time.Hour, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"0h", 0, true},
+		{"-2h", 0, true},
+		{"3", 0, true},
+		{"d", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDuration(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseDuration(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
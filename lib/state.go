@@ -0,0 +1,147 @@
+package timatch
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// matchStateTTL is the time match state is kept in the store for
+// matches that have not (yet) been seen finished
+const matchStateTTL = 24 * time.Hour
+
+// finishedMatchTTL is the time match state is kept in the store after
+// a match has finished
+const finishedMatchTTL = 7 * 24 * time.Hour
+
+// Match states, used as part of the store keys
+const (
+	matchStateDrafting = "drafting"
+	matchStateStarted  = "started"
+	matchStateFinished = "finished"
+)
+
+// matchStatePrefix returns the prefix of the store keys of all matches
+// seen in the given state
+func matchStatePrefix(state string) string {
+	return "match/" + state + "/"
+}
+
+// matchStateKey returns the store key recording that a match has been
+// seen in the given state
+func matchStateKey(state string, matchID int64) string {
+	return matchStatePrefix(state) + strconv.FormatInt(matchID, 10)
+}
+
+// gameNumberKey returns the store key of the game number of a match
+func gameNumberKey(matchID int64) string {
+	return "match/gamenumber/" + strconv.FormatInt(matchID, 10)
+}
+
+// loadState restores the matches of the store to the watcher, so that
+// matches already seen by a previous run (or another bot instance sharing
+// the store) are not announced again.
+func (bot *bot) loadState(ctx context.Context) error {
+	now := time.Now()
+	loaded := make(map[string]int)
+	for _, state := range []string{matchStateDrafting, matchStateStarted, matchStateFinished} {
+		prefix := matchStatePrefix(state)
+		keys, err := bot.store.Keys(ctx, prefix)
+		if err != nil {
+			return errors.Wrapf(err, "Error listing %s matches", state)
+		}
+		for _, key := range keys {
+			matchID, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), 10, 64)
+			if err != nil {
+				bot.logger.Warnf("Ignoring malformed key %s", key)
+				continue
+			}
+			bot.watcher.restore(state, matchID, now)
+			loaded[state]++
+			var gameNumber int
+			found, err := bot.store.Get(ctx, gameNumberKey(matchID), &gameNumber)
+			if err != nil {
+				return errors.Wrapf(err, "Error getting game number of %d", matchID)
+			}
+			if found {
+				bot.gameNumbers[matchID] = gameNumber
+			}
+		}
+	}
+	bot.logger.Debugf("Loaded state: %d drafting, %d started, %d finished",
+		loaded[matchStateDrafting], loaded[matchStateStarted], loaded[matchStateFinished])
+	return nil
+}
+
+// claimMatchState records in the store that a match has been seen in the
+// given state. ok is false if the state was already recorded, in which
+// case the match should not be announced again.
+func (bot *bot) claimMatchState(ctx context.Context, state string, matchID int64) (ok bool) {
+	ttl := matchStateTTL
+	if state == matchStateFinished {
+		ttl = finishedMatchTTL
+	}
+	ok, err := bot.store.SetNX(ctx, matchStateKey(state, matchID), time.Now(), ttl)
+	if err != nil {
+		// Announcing twice is better than not announcing at all
+		bot.logger.WithField(logFieldMatchID, matchID).WithError(err).Errorf("Error recording match %d as %s", matchID, state)
+		return true
+	}
+	if !ok {
+		bot.logger.WithField(logFieldMatchID, matchID).Debugf("Match %d already recorded as %s", matchID, state)
+	}
+	return ok
+}
+
+// setGameNumber records the game number of a match
+func (bot *bot) setGameNumber(ctx context.Context, matchID int64, gameNumber int) {
+	if current, ok := bot.gameNumbers[matchID]; ok && current == gameNumber {
+		return
+	}
+	bot.gameNumbers[matchID] = gameNumber
+	if err := bot.store.Set(ctx, gameNumberKey(matchID), gameNumber, matchStateTTL); err != nil {
+		bot.logger.WithField(logFieldMatchID, matchID).WithError(err).Errorf("Error storing game number of %d", matchID)
+	}
+}
+
+// claimWatcherState claims the state of a match for the watcher, see
+// Watcher.claim, expiring the stored state of matches claimed finished
+func (bot *bot) claimWatcherState(ctx context.Context, state string, matchID int64) bool {
+	if !bot.claimMatchState(ctx, state, matchID) {
+		return false
+	}
+	if state == matchStateFinished {
+		bot.expireMatchState(ctx, matchID)
+	}
+	return true
+}
+
+// expireMatchState sets the lifetime of the stored states of a match to
+// finishedMatchTTL, now that the match has finished. The game number is
+// expired when the result is announced, see expireGameNumber
+func (bot *bot) expireMatchState(ctx context.Context, matchID int64) {
+	for _, state := range []string{matchStateDrafting, matchStateStarted} {
+		var seenAt time.Time
+		key := matchStateKey(state, matchID)
+		found, err := bot.store.Get(ctx, key, &seenAt)
+		if err == nil && found {
+			err = bot.store.Set(ctx, key, seenAt, finishedMatchTTL)
+		}
+		if err != nil {
+			bot.logger.WithField(logFieldMatchID, matchID).WithError(err).Errorf("Error updating %s", key)
+		}
+	}
+}
+
+// expireGameNumber sets the lifetime of the stored game number of a match
+// to finishedMatchTTL, now that the match has finished
+func (bot *bot) expireGameNumber(ctx context.Context, matchID int64) {
+	if gameNumber, ok := bot.gameNumbers[matchID]; ok {
+		if err := bot.store.Set(ctx, gameNumberKey(matchID), gameNumber, finishedMatchTTL); err != nil {
+			bot.logger.WithField(logFieldMatchID, matchID).WithError(err).Errorf("Error storing game number of %d", matchID)
+		}
+	}
+}
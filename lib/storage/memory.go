@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func (entry memoryEntry) expired() bool {
+	return !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt)
+}
+
+// MemoryStore is a Store keeping all values in memory. State stored in
+// a MemoryStore does not survive a restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+func (store *MemoryStore) Get(ctx context.Context, key string, v interface{}) (bool, error) {
+	store.mu.Lock()
+	entry, ok := store.entries[key]
+	if ok && entry.expired() {
+		delete(store.entries, key)
+		ok = false
+	}
+	store.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(entry.data, v); err != nil {
+		return false, errors.Wrapf(err, "Error decoding value for %s", key)
+	}
+	return true, nil
+}
+
+func (store *MemoryStore) Set(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	entry, err := newMemoryEntry(key, v, ttl)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[key] = entry
+	return nil
+}
+
+func (store *MemoryStore) SetNX(ctx context.Context, key string, v interface{}, ttl time.Duration) (bool, error) {
+	entry, err := newMemoryEntry(key, v, ttl)
+	if err != nil {
+		return false, err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if existing, ok := store.entries[key]; ok && !existing.expired() {
+		return false, nil
+	}
+	store.entries[key] = entry
+	return true, nil
+}
+
+func (store *MemoryStore) Delete(ctx context.Context, key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.entries, key)
+	return nil
+}
+
+func (store *MemoryStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	keys := make([]string, 0)
+	for key, entry := range store.entries {
+		if entry.expired() {
+			delete(store.entries, key)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (store *MemoryStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entry, ok := store.entries[key]
+	if !ok || entry.expired() || entry.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(entry.expiresAt), nil
+}
+
+func (store *MemoryStore) Close() error {
+	return nil
+}
+
+func newMemoryEntry(key string, v interface{}, ttl time.Duration) (memoryEntry, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return memoryEntry{}, errors.Wrapf(err, "Error encoding value for %s", key)
+	}
+	entry := memoryEntry{data: data}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	return entry, nil
+}
@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// redisDialTimeout is the timeout used when connecting to redis
+const redisDialTimeout = 10 * time.Second
+
+// redisKeyPrefix is prepended to all keys stored in redis, so that the
+// bot may share a redis database with other applications
+const redisKeyPrefix = "timatch:"
+
+// RedisStore is a Store backed by a redis server. Several bot instances
+// can share state by using the same redis database.
+//
+// RedisStore implements the small subset of the redis protocol (RESP)
+// required for the Store interface over a single connection, which is
+// re-established if a command fails.
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply sent by the redis server
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+func NewRedisStore(u *url.URL) (*RedisStore, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	password, _ := u.User.Password()
+	db := 0
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		var err error
+		db, err = strconv.Atoi(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error parsing redis database number")
+		}
+	}
+	store := &RedisStore{
+		addr:     addr,
+		password: password,
+		db:       db,
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := store.connect(context.Background()); err != nil {
+		return nil, errors.Wrap(err, "Error connecting to redis")
+	}
+	return store, nil
+}
+
+func (store *RedisStore) Get(ctx context.Context, key string, v interface{}) (bool, error) {
+	reply, err := store.do(ctx, "GET", redisKeyPrefix+key)
+	if err != nil {
+		return false, errors.Wrapf(err, "Error getting %s", key)
+	}
+	if reply == nil {
+		return false, nil
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return false, errors.Errorf("Unexpected reply type %T for GET", reply)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, errors.Wrapf(err, "Error decoding value for %s", key)
+	}
+	return true, nil
+}
+
+func (store *RedisStore) Set(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	_, err := store.set(ctx, key, v, ttl, false)
+	return err
+}
+
+func (store *RedisStore) SetNX(ctx context.Context, key string, v interface{}, ttl time.Duration) (bool, error) {
+	return store.set(ctx, key, v, ttl, true)
+}
+
+func (store *RedisStore) set(ctx context.Context, key string, v interface{}, ttl time.Duration, nx bool) (bool, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false, errors.Wrapf(err, "Error encoding value for %s", key)
+	}
+	args := []string{"SET", redisKeyPrefix + key, string(data)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+	if nx {
+		args = append(args, "NX")
+	}
+	reply, err := store.do(ctx, args...)
+	if err != nil {
+		return false, errors.Wrapf(err, "Error setting %s", key)
+	}
+	// SET replies with a nil bulk string if NX was given and the key
+	// already existed
+	return reply != nil, nil
+}
+
+func (store *RedisStore) Delete(ctx context.Context, key string) error {
+	if _, err := store.do(ctx, "DEL", redisKeyPrefix+key); err != nil {
+		return errors.Wrapf(err, "Error deleting %s", key)
+	}
+	return nil
+}
+
+func (store *RedisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	cursor := "0"
+	for {
+		reply, err := store.do(ctx, "SCAN", cursor, "MATCH", redisKeyPrefix+escapeRedisPattern(prefix)+"*", "COUNT", "500")
+		if err != nil {
+			return nil, errors.Wrap(err, "Error scanning keys")
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, errors.Errorf("Unexpected reply %v for SCAN", reply)
+		}
+		next, _ := parts[0].([]byte)
+		batch, _ := parts[1].([]interface{})
+		for _, k := range batch {
+			if key, ok := k.([]byte); ok {
+				keys = append(keys, strings.TrimPrefix(string(key), redisKeyPrefix))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+func (store *RedisStore) TTL(ctx context.Context, key string) (time.Duration, error) {
+	reply, err := store.do(ctx, "PTTL", redisKeyPrefix+key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error getting TTL of %s", key)
+	}
+	ms, ok := reply.(int64)
+	if !ok {
+		return 0, errors.Errorf("Unexpected reply type %T for PTTL", reply)
+	}
+	// PTTL replies with -1 for keys without expiry and -2 for missing keys
+	if ms < 0 {
+		return 0, nil
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (store *RedisStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.conn == nil {
+		return nil
+	}
+	err := store.conn.Close()
+	store.conn = nil
+	return err
+}
+
+// do sends a command to redis and returns the reply. The connection is
+// (re-)established if needed. Replies are returned as []byte for strings,
+// int64 for integers, []interface{} for arrays and nil for nil replies.
+func (store *RedisStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.conn == nil {
+		if err := store.connect(ctx); err != nil {
+			return nil, errors.Wrap(err, "Error reconnecting to redis")
+		}
+	}
+	reply, err := store.roundTrip(ctx, args)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// Only a top level error reply leaves the connection in a known
+		// state. For any other error, drop it so that the next command
+		// reconnects
+		store.conn.Close()
+		store.conn = nil
+	}
+	return reply, err
+}
+
+// connect dials redis and performs AUTH and SELECT as configured.
+// Must be called with mu held.
+func (store *RedisStore) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", store.addr)
+	if err != nil {
+		return errors.Wrap(err, "Error dialing redis")
+	}
+	store.conn = conn
+	store.reader = bufio.NewReader(conn)
+	if store.password != "" {
+		if _, err := store.roundTrip(ctx, []string{"AUTH", store.password}); err != nil {
+			store.conn.Close()
+			store.conn = nil
+			return errors.Wrap(err, "Error authenticating")
+		}
+	}
+	if store.db != 0 {
+		if _, err := store.roundTrip(ctx, []string{"SELECT", strconv.Itoa(store.db)}); err != nil {
+			store.conn.Close()
+			store.conn = nil
+			return errors.Wrap(err, "Error selecting database")
+		}
+	}
+	return nil
+}
+
+// roundTrip writes a command and reads its reply. Must be called with mu held.
+func (store *RedisStore) roundTrip(ctx context.Context, args []string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisDialTimeout)
+	}
+	if err := store.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	var cmd strings.Builder
+	cmd.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		cmd.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(store.conn, cmd.String()); err != nil {
+		return nil, errors.Wrap(err, "Error writing command")
+	}
+	return readRedisReply(store.reader)
+}
+
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading reply")
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("Empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "Error parsing bulk string length")
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, errors.Wrap(err, "Error reading bulk string")
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errors.Wrap(err, "Error parsing array length")
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, n)
+		for i := range arr {
+			if arr[i], err = readRedisReply(r); err != nil {
+				// The rest of the array is left unread, so even an
+				// error reply must not be passed on as a redisError,
+				// which would keep the connection
+				return nil, errors.Wrap(err, "Error reading array element")
+			}
+		}
+		return arr, nil
+	default:
+		return nil, errors.Errorf("Unknown reply type %q", line[0])
+	}
+}
+
+// escapeRedisPattern escapes the glob characters in s so that it can
+// be used as a literal in a SCAN MATCH pattern
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
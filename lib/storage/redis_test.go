@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRedisReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", []byte("OK"), false},
+		{"integer", ":42\r\n", int64(42), false},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), false},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, false},
+		{"bulk string with crlf", "$4\r\na\r\nb\r\n", []byte("a\r\nb"), false},
+		{"nil bulk string", "$-1\r\n", nil, false},
+		{"nil array", "*-1\r\n", nil, false},
+		{"array", "*2\r\n$1\r\na\r\n:1\r\n", []interface{}{[]byte("a"), int64(1)}, false},
+		{"nested array", "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nk\r\n",
+			[]interface{}{[]byte("0"), []interface{}{[]byte("k")}}, false},
+		{"error", "-ERR bad\r\n", nil, true},
+		{"bad integer", ":x\r\n", nil, true},
+		{"unknown type", "?\r\n", nil, true},
+		{"truncated bulk string", "$5\r\nhe", nil, true},
+		{"empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRedisReply(bufio.NewReader(strings.NewReader(tt.input)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadRedisReplyErrors(t *testing.T) {
+	_, err := readRedisReply(bufio.NewReader(strings.NewReader("-ERR top\r\n")))
+	if _, ok := err.(redisError); !ok {
+		t.Errorf("top level error reply: got %T, want redisError", err)
+	}
+	_, err = readRedisReply(bufio.NewReader(strings.NewReader("*2\r\n-ERR nested\r\n:1\r\n")))
+	if _, ok := err.(redisError); ok || err == nil {
+		t.Errorf("nested error reply: got %v, want a non-redisError error", err)
+	}
+}
+
+func TestEscapeRedisPattern(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"match/started/", "match/started/"},
+		{"a*b", `a\*b`},
+		{"a?b", `a\?b`},
+		{"[x]", `\[x\]`},
+		{`a\b`, `a\\b`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := escapeRedisPattern(tt.input); got != tt.want {
+			t.Errorf("escapeRedisPattern(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+// fakeRedis is a redis server replying to each command with the reply
+// returned by handle, for the lifetime of the test
+type fakeRedis struct {
+	listener net.Listener
+	handle   func(args []string) string
+	// conns is the number of connections accepted
+	conns chan struct{}
+}
+
+func newFakeRedis(t *testing.T, handle func(args []string) string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Can not listen on loopback: %v", err)
+	}
+	srv := &fakeRedis{listener: listener, handle: handle, conns: make(chan struct{}, 100)}
+	go srv.serve()
+	return srv
+}
+
+func (srv *fakeRedis) Close() error {
+	return srv.listener.Close()
+}
+
+func (srv *fakeRedis) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		srv.conns <- struct{}{}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				// Commands are sent as arrays of bulk strings, which
+				// is a reply as far as the parser is concerned
+				cmd, err := readRedisReply(r)
+				if err != nil {
+					return
+				}
+				parts := cmd.([]interface{})
+				args := make([]string, len(parts))
+				for i, part := range parts {
+					args[i] = string(part.([]byte))
+				}
+				fmt.Fprint(conn, srv.handle(args))
+			}
+		}()
+	}
+}
+
+func (srv *fakeRedis) store(t *testing.T) *RedisStore {
+	u, _ := url.Parse("redis://" + srv.listener.Addr().String())
+	store, err := NewRedisStore(u)
+	if err != nil {
+		t.Fatalf("NewRedisStore: %v", err)
+	}
+	return store
+}
+
+func TestRedisStoreKeys(t *testing.T) {
+	srv := newFakeRedis(t, func(args []string) string {
+		if args[0] != "SCAN" {
+			return "-ERR unexpected command\r\n"
+		}
+		if args[3] != `timatch:match/\*/*` {
+			return "-ERR unexpected pattern " + args[3] + "\r\n"
+		}
+		// Two pages, the second ending the scan with cursor 0
+		if args[1] == "0" {
+			return "*2\r\n$2\r\n17\r\n*2\r\n$13\r\ntimatch:match\r\n$16\r\ntimatch:match/*/\r\n"
+		}
+		return "*2\r\n$1\r\n0\r\n*1\r\n$17\r\ntimatch:match/*/1\r\n"
+	})
+	defer srv.Close()
+	store := srv.store(t)
+	defer store.Close()
+	keys, err := store.Keys(context.Background(), "match/*/")
+	if err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	want := []string{"match", "match/*/", "match/*/1"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys = %v, want %v", keys, want)
+	}
+}
+
+func TestRedisStoreGetSet(t *testing.T) {
+	values := make(map[string]string)
+	srv := newFakeRedis(t, func(args []string) string {
+		switch args[0] {
+		case "SET":
+			if _, ok := values[args[1]]; ok && args[len(args)-1] == "NX" {
+				return "$-1\r\n"
+			}
+			values[args[1]] = args[2]
+			return "+OK\r\n"
+		case "GET":
+			v, ok := values[args[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+		}
+		return "-ERR unexpected command\r\n"
+	})
+	defer srv.Close()
+	store := srv.store(t)
+	defer store.Close()
+	ctx := context.Background()
+	if err := store.Set(ctx, "a", 1, 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	ok, err := store.SetNX(ctx, "a", 2, 0)
+	if err != nil || ok {
+		t.Errorf("SetNX of existing key = %v, %v, want false, nil", ok, err)
+	}
+	var v int
+	found, err := store.Get(ctx, "a", &v)
+	if err != nil || !found || v != 1 {
+		t.Errorf("Get = %v, %v (v = %d), want true, nil (v = 1)", found, err, v)
+	}
+	found, err = store.Get(ctx, "missing", &v)
+	if err != nil || found {
+		t.Errorf("Get of missing key = %v, %v, want false, nil", found, err)
+	}
+}
+
+func TestRedisStoreReconnect(t *testing.T) {
+	srv := newFakeRedis(t, func(args []string) string {
+		switch args[1] {
+		case "timatch:top":
+			return "-ERR top level\r\n"
+		case "timatch:nested":
+			return "*2\r\n-ERR nested\r\n:1\r\n"
+		}
+		return "$-1\r\n"
+	})
+	defer srv.Close()
+	store := srv.store(t)
+	defer store.Close()
+	<-srv.conns
+	ctx := context.Background()
+	var v int
+	if _, err := store.Get(ctx, "top", &v); err == nil {
+		t.Fatal("Get with error reply succeeded")
+	}
+	if store.conn == nil {
+		t.Error("Connection dropped after a top level error reply")
+	}
+	if _, err := store.Get(ctx, "nested", &v); err == nil {
+		t.Fatal("Get with nested error reply succeeded")
+	}
+	if store.conn != nil {
+		t.Error("Connection kept after a nested error reply")
+	}
+	// The next command must work over a new connection
+	if _, err := store.Get(ctx, "other", &v); err != nil {
+		t.Fatalf("Get after reconnect: %v", err)
+	}
+	select {
+	case <-srv.conns:
+	default:
+		t.Error("Store did not reconnect")
+	}
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// snapshotVersion is the version of the snapshot format written by
+// Export. Import refuses snapshots of other versions.
+const snapshotVersion = 1
+
+// Snapshot is a portable copy of all values of a store, for moving the
+// state of the bot to another store or host
+type Snapshot struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry is a value in a Snapshot. ExpiresAt is zero for values
+// that do not expire.
+type SnapshotEntry struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"`
+}
+
+// Export copies all values of store into a snapshot, ordered by key.
+// Expiry is kept as an absolute time, so that values expire at the same
+// time regardless of when the snapshot is imported.
+func Export(ctx context.Context, store Store) (*Snapshot, error) {
+	keys, err := store.Keys(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	snapshot := &Snapshot{
+		Version:   snapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Entries:   make([]SnapshotEntry, 0, len(keys)),
+	}
+	for _, key := range keys {
+		var value json.RawMessage
+		found, err := store.Get(ctx, key, &value)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			// Expired since listing the keys
+			continue
+		}
+		ttl, err := store.TTL(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		entry := SnapshotEntry{Key: key, Value: value}
+		if ttl > 0 {
+			entry.ExpiresAt = snapshot.CreatedAt.Add(ttl)
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	return snapshot, nil
+}
+
+// Import stores the values of snapshot in store, replacing any values
+// stored under the same keys. Values that have expired since the
+// snapshot was exported are skipped. Returns the number of values stored.
+func Import(ctx context.Context, store Store, snapshot *Snapshot) (int, error) {
+	if snapshot.Version != snapshotVersion {
+		return 0, errors.Errorf("Unsupported snapshot version %d, expected %d", snapshot.Version, snapshotVersion)
+	}
+	imported := 0
+	for _, entry := range snapshot.Entries {
+		var ttl time.Duration
+		if !entry.ExpiresAt.IsZero() {
+			ttl = time.Until(entry.ExpiresAt)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		if err := store.Set(ctx, entry.Key, entry.Value, ttl); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// Verify checks that store holds the values of snapshot, returning an
+// error naming the first value that is missing or differs. Values that
+// have expired since the snapshot was exported are not checked.
+func Verify(ctx context.Context, store Store, snapshot *Snapshot) error {
+	for _, entry := range snapshot.Entries {
+		if !entry.ExpiresAt.IsZero() && time.Until(entry.ExpiresAt) <= 0 {
+			continue
+		}
+		var value json.RawMessage
+		found, err := store.Get(ctx, entry.Key, &value)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.Errorf("Missing value for %s", entry.Key)
+		}
+		if !bytes.Equal(value, entry.Value) {
+			return errors.Errorf("Value for %s differs", entry.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemoryStore()
+	src.Set(ctx, "guild/1/settings", map[string]string{"language": "ru"}, 0)
+	src.Set(ctx, "announced/started/2/3", true, time.Hour)
+	snapshot, err := Export(ctx, src)
+	if err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+	if len(snapshot.Entries) != 2 || snapshot.Entries[0].Key != "announced/started/2/3" {
+		t.Fatalf("Export() entries = %+v, want both keys in order", snapshot.Entries)
+	}
+	// The snapshot must survive being written to a file
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Error encoding snapshot: %v", err)
+	}
+	var decoded Snapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Error decoding snapshot: %v", err)
+	}
+	// An entry that has expired since the export is skipped
+	decoded.Entries = append(decoded.Entries, SnapshotEntry{
+		Key:       "mute/4",
+		Value:     json.RawMessage("true"),
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+
+	dst := NewMemoryStore()
+	n, err := Import(ctx, dst, &decoded)
+	if err != nil || n != 2 {
+		t.Fatalf("Import() = %d, %v, want 2 values", n, err)
+	}
+	var settings map[string]string
+	if found, _ := dst.Get(ctx, "guild/1/settings", &settings); !found || settings["language"] != "ru" {
+		t.Errorf("Imported settings = %v, want language ru", settings)
+	}
+	if ttl, _ := dst.TTL(ctx, "announced/started/2/3"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL of imported key = %v, want at most an hour", ttl)
+	}
+	if ttl, _ := dst.TTL(ctx, "guild/1/settings"); ttl != 0 {
+		t.Errorf("TTL of imported key without expiry = %v, want 0", ttl)
+	}
+	if found, _ := dst.Get(ctx, "mute/4", new(bool)); found {
+		t.Error("Expired entry was imported")
+	}
+
+	if err := Verify(ctx, dst, snapshot); err != nil {
+		t.Errorf("Verify() error: %v", err)
+	}
+	dst.Set(ctx, "guild/1/settings", map[string]string{"language": "en"}, 0)
+	if err := Verify(ctx, dst, snapshot); err == nil {
+		t.Error("Verify() of changed value = no error, want error")
+	}
+
+	decoded.Version = 2
+	if _, err := Import(ctx, dst, &decoded); err == nil {
+		t.Error("Import() of unknown version = no error, want error")
+	}
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Store is a key-value store used for persisting bot state. Values are
+// stored JSON encoded, so any value that can be marshaled to JSON may be
+// stored.
+type Store interface {
+	// Get decodes the value stored under key into v. If there is no
+	// value for the key, found is false and v is left untouched.
+	Get(ctx context.Context, key string, v interface{}) (found bool, err error)
+	// Set stores v under key. If ttl is > 0, the key expires after ttl.
+	Set(ctx context.Context, key string, v interface{}, ttl time.Duration) error
+	// SetNX stores v under key, but only if there is no value stored
+	// for the key already. ok is true if the value was stored.
+	SetNX(ctx context.Context, key string, v interface{}, ttl time.Duration) (ok bool, err error)
+	// Delete removes the value stored under key, if any.
+	Delete(ctx context.Context, key string) error
+	// Keys returns all keys starting with prefix, in no particular order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// TTL returns the time left until key expires, or 0 if the key does
+	// not expire or does not exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Open opens the store described by rawURL. Supported urls are
+// "memory://" for an in-memory store and "redis://[:password@]host[:port][/db]"
+// for a redis store.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing storage url")
+	}
+	switch u.Scheme {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStore(u)
+	default:
+		return nil, errors.Errorf("Unknown storage scheme: %s", u.Scheme)
+	}
+}
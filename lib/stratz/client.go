@@ -0,0 +1,200 @@
+// Package stratz is a client for the STRATZ GraphQL API
+// (https://stratz.com/api), used as an alternative source of the league
+// games otherwise fetched from the Steam Web API. Responses are converted
+// to the types of the dota package.
+package stratz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/apiclient"
+	"github.com/verath/timatch/lib/dota"
+)
+
+const apiBaseURL = "https://api.stratz.com"
+const pathGraphQL = "/graphql"
+
+// requestInterval is the minimum time between requests, as the default
+// STRATZ token allows 20 requests per second but 250 per minute
+const requestInterval = 250 * time.Millisecond
+
+// maxLeagueMatches is the number of the most recent matches of a league
+// fetched, matching the page size of the Steam GetMatchHistory
+const maxLeagueMatches = 100
+
+type Client struct {
+	token   string
+	baseURL *url.URL
+	api     *apiclient.Client
+}
+
+func NewClient(logger *logrus.Logger, token string) (*Client, error) {
+	baseURL, err := url.Parse(apiBaseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing apiBaseURL")
+	}
+	return &Client{
+		token:   token,
+		baseURL: baseURL,
+		api:     apiclient.NewClient(logger, requestInterval),
+	}, nil
+}
+
+// Stats returns the stats of the requests sent, per endpoint
+func (client *Client) Stats() []apiclient.EndpointStats {
+	return client.api.Stats()
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// query sends a GraphQL query, decoding its data into data
+func (client *Client) query(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	body, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return errors.Wrap(err, "Error encoding query")
+	}
+	req, err := http.NewRequest("POST", client.baseURL.String()+pathGraphQL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Error creating new request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+client.token)
+	// STRATZ rejects requests without this user agent
+	req.Header.Set("User-Agent", "STRATZ_API")
+	res := graphQLResponse{}
+	if err := client.api.GetJSON(ctx, req, &res, nil); err != nil {
+		return errors.Wrap(err, "Error sending request")
+	}
+	if len(res.Errors) > 0 {
+		messages := make([]string, len(res.Errors))
+		for i, e := range res.Errors {
+			messages[i] = e.Message
+		}
+		return errors.Errorf("Error in query: %s", strings.Join(messages, "; "))
+	}
+	if err := json.Unmarshal(res.Data, data); err != nil {
+		return errors.Wrap(err, "Error decoding data")
+	}
+	return nil
+}
+
+const queryLiveLeagueGames = `query LiveLeagueGames($leagueId: Int!) {
+  live {
+    matches(request: {leagueIds: [$leagueId], take: 100}) {
+      matchId
+      gameTime
+      spectators
+      radiantScore
+      direScore
+      radiantTeam { id name }
+      direTeam { id name }
+      players { steamAccountId heroId isRadiant networth steamAccount { name } }
+    }
+  }
+}`
+
+// GetLiveLeagueGames returns the live games of a league. STRATZ does not
+// give the series of live games, so the series fields are left zero.
+func (client *Client) GetLiveLeagueGames(ctx context.Context, leagueID int) (*dota.LiveLeagueGamesResponse, error) {
+	var data struct {
+		Live struct {
+			Matches []liveMatch `json:"matches"`
+		} `json:"live"`
+	}
+	if err := client.query(ctx, queryLiveLeagueGames, map[string]interface{}{"leagueId": leagueID}, &data); err != nil {
+		return nil, err
+	}
+	res := &dota.LiveLeagueGamesResponse{}
+	res.Result.Status = 200
+	res.Result.Games = make([]dota.LiveLeagueGame, len(data.Live.Matches))
+	for i, match := range data.Live.Matches {
+		res.Result.Games[i] = match.toLiveLeagueGame()
+	}
+	return res, nil
+}
+
+const queryLeagueMatches = `query LeagueMatches($leagueId: Int!, $take: Int!) {
+  league(id: $leagueId) {
+    matches(request: {take: $take, skip: 0}) { id startDateTime radiantTeamId direTeamId }
+  }
+}`
+
+// GetMatchHistory returns the most recent matches of a league
+func (client *Client) GetMatchHistory(ctx context.Context, leagueID int) (*dota.MatchHistoryResponse, error) {
+	var data struct {
+		League struct {
+			Matches []struct {
+				ID            int64 `json:"id"`
+				StartDateTime int64 `json:"startDateTime"`
+				RadiantTeamID int   `json:"radiantTeamId"`
+				DireTeamID    int   `json:"direTeamId"`
+			} `json:"matches"`
+		} `json:"league"`
+	}
+	variables := map[string]interface{}{"leagueId": leagueID, "take": maxLeagueMatches}
+	if err := client.query(ctx, queryLeagueMatches, variables, &data); err != nil {
+		return nil, err
+	}
+	res := &dota.MatchHistoryResponse{}
+	res.Result.Status = 1
+	res.Result.Matches = make([]dota.MatchHistoryMatch, len(data.League.Matches))
+	for i, match := range data.League.Matches {
+		res.Result.Matches[i] = dota.MatchHistoryMatch{
+			MatchID:       match.ID,
+			StartTime:     match.StartDateTime,
+			RadiantTeamID: match.RadiantTeamID,
+			DireTeamID:    match.DireTeamID,
+		}
+	}
+	return res, nil
+}
+
+const queryMatchDetails = `query MatchDetails($matchId: Long!) {
+  match(id: $matchId) {
+    id
+    didRadiantWin
+    durationSeconds
+    radiantTeam { id name }
+    direTeam { id name }
+    players {
+      steamAccountId isRadiant heroId kills deaths assists
+      goldPerMinute experiencePerMinute
+    }
+    pickBans { isPick heroId isRadiant order }
+  }
+}`
+
+// GetMatchDetails returns the details of a finished match
+func (client *Client) GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error) {
+	var data struct {
+		Match *match `json:"match"`
+	}
+	if err := client.query(ctx, queryMatchDetails, map[string]interface{}{"matchId": matchID}, &data); err != nil {
+		return nil, err
+	}
+	if data.Match == nil {
+		return nil, errors.Errorf("Match %d not found", matchID)
+	}
+	res := &dota.MatchDetailsResponse{}
+	res.Result.MatchDetails = data.Match.toMatchDetails()
+	return res, nil
+}
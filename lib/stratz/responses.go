@@ -0,0 +1,152 @@
+package stratz
+
+import "github.com/verath/timatch/lib/dota"
+
+type team struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type liveMatch struct {
+	MatchID      int64        `json:"matchId"`
+	GameTime     int          `json:"gameTime"`
+	Spectators   int          `json:"spectators"`
+	RadiantScore int          `json:"radiantScore"`
+	DireScore    int          `json:"direScore"`
+	RadiantTeam  *team        `json:"radiantTeam"`
+	DireTeam     *team        `json:"direTeam"`
+	Players      []livePlayer `json:"players"`
+}
+
+type livePlayer struct {
+	SteamAccountID int64 `json:"steamAccountId"`
+	HeroID         int   `json:"heroId"`
+	IsRadiant      bool  `json:"isRadiant"`
+	NetWorth       int   `json:"networth"`
+	SteamAccount   *struct {
+		Name string `json:"name"`
+	} `json:"steamAccount"`
+}
+
+func (t *team) toLiveLeagueGamesTeam() dota.LiveLeagueGamesTeam {
+	if t == nil {
+		return dota.LiveLeagueGamesTeam{}
+	}
+	return dota.LiveLeagueGamesTeam{TeamID: t.ID, TeamName: t.Name}
+}
+
+func (match liveMatch) toLiveLeagueGame() dota.LiveLeagueGame {
+	game := dota.LiveLeagueGame{
+		MatchID:     match.MatchID,
+		RadiantTeam: match.RadiantTeam.toLiveLeagueGamesTeam(),
+		DireTeam:    match.DireTeam.toLiveLeagueGamesTeam(),
+		Spectators:  match.Spectators,
+	}
+	game.Scoreboard.Duration = float32(match.GameTime)
+	game.Scoreboard.Radiant.Score = match.RadiantScore
+	game.Scoreboard.Dire.Score = match.DireScore
+	for _, player := range match.Players {
+		gamePlayer := dota.LiveLeagueGamePlayer{
+			AccountID: player.SteamAccountID,
+			HeroID:    player.HeroID,
+			Team:      dota.PlayerTeamDire,
+		}
+		if player.SteamAccount != nil {
+			gamePlayer.Name = player.SteamAccount.Name
+		}
+		scoreboardTeam := &game.Scoreboard.Dire
+		if player.IsRadiant {
+			gamePlayer.Team = dota.PlayerTeamRadiant
+			scoreboardTeam = &game.Scoreboard.Radiant
+		}
+		game.Players = append(game.Players, gamePlayer)
+		if player.HeroID == 0 {
+			// Not picked yet
+			continue
+		}
+		scoreboardTeam.Picks = append(scoreboardTeam.Picks, struct {
+			HeroID int `json:"hero_id"`
+		}{player.HeroID})
+		scoreboardTeam.Players = append(scoreboardTeam.Players, dota.LiveLeagueGameScoreboardPlayer{
+			HeroID:   player.HeroID,
+			NetWorth: player.NetWorth,
+		})
+	}
+	return game
+}
+
+type match struct {
+	ID              int64         `json:"id"`
+	DidRadiantWin   bool          `json:"didRadiantWin"`
+	DurationSeconds int           `json:"durationSeconds"`
+	RadiantTeam     *team         `json:"radiantTeam"`
+	DireTeam        *team         `json:"direTeam"`
+	Players         []matchPlayer `json:"players"`
+	PickBans        []struct {
+		IsPick    bool `json:"isPick"`
+		HeroID    int  `json:"heroId"`
+		IsRadiant bool `json:"isRadiant"`
+		Order     int  `json:"order"`
+	} `json:"pickBans"`
+}
+
+type matchPlayer struct {
+	SteamAccountID      int64 `json:"steamAccountId"`
+	IsRadiant           bool  `json:"isRadiant"`
+	HeroID              int   `json:"heroId"`
+	Kills               int   `json:"kills"`
+	Deaths              int   `json:"deaths"`
+	Assists             int   `json:"assists"`
+	GoldPerMinute       int   `json:"goldPerMinute"`
+	ExperiencePerMinute int   `json:"experiencePerMinute"`
+}
+
+// toMatchDetails converts the match to the Steam match details. The kill
+// scores of the teams are the sums of the kills of their players.
+func (m *match) toMatchDetails() *dota.MatchDetails {
+	radiantTeam := m.RadiantTeam.toLiveLeagueGamesTeam()
+	direTeam := m.DireTeam.toLiveLeagueGamesTeam()
+	details := &dota.MatchDetails{
+		RadiantWin:    m.DidRadiantWin,
+		RadiantName:   radiantTeam.TeamName,
+		DireName:      direTeam.TeamName,
+		RadiantTeamID: radiantTeam.TeamID,
+		DireTeamID:    direTeam.TeamID,
+		Duration:      m.DurationSeconds,
+	}
+	radiantSlot, direSlot := 0, 128
+	for _, player := range m.Players {
+		matchPlayer := dota.MatchPlayer{
+			AccountID:  player.SteamAccountID,
+			HeroID:     player.HeroID,
+			Kills:      player.Kills,
+			Deaths:     player.Deaths,
+			Assists:    player.Assists,
+			GoldPerMin: player.GoldPerMinute,
+			XPPerMin:   player.ExperiencePerMinute,
+		}
+		if player.IsRadiant {
+			matchPlayer.PlayerSlot = radiantSlot
+			radiantSlot++
+			details.RadiantScore += player.Kills
+		} else {
+			matchPlayer.PlayerSlot = direSlot
+			direSlot++
+			details.DireScore += player.Kills
+		}
+		details.Players = append(details.Players, matchPlayer)
+	}
+	for _, pickBan := range m.PickBans {
+		team := 1
+		if pickBan.IsRadiant {
+			team = 0
+		}
+		details.PicksBans = append(details.PicksBans, dota.PickBan{
+			IsPick: pickBan.IsPick,
+			HeroID: pickBan.HeroID,
+			Team:   team,
+			Order:  pickBan.Order,
+		})
+	}
+	return details
+}
@@ -0,0 +1,103 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// streamsCheckInterval is the minimum time between checking which
+// broadcast channels are live
+const streamsCheckInterval = 2 * time.Minute
+
+// BroadcastChannel is a Twitch channel broadcasting the league in a
+// language
+type BroadcastChannel struct {
+	// Language is the name of the broadcast language, e.g. "English"
+	Language string
+	// Login is the Twitch user login of the channel, e.g. "dota2ti"
+	Login string
+}
+
+// ParseBroadcastChannels parses a comma separated list of broadcast
+// channels on the form language=login, e.g.
+// "English=dota2ti,Russian=dota2ti_ru".
+func ParseBroadcastChannels(s string) ([]BroadcastChannel, error) {
+	channels := make([]BroadcastChannel, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			return nil, errors.Errorf("Missing '=' in broadcast channel %q", part)
+		}
+		language, login := strings.TrimSpace(part[:eq]), strings.TrimSpace(part[eq+1:])
+		if language == "" || login == "" {
+			return nil, errors.Errorf("Invalid broadcast channel %q", part)
+		}
+		channels = append(channels, BroadcastChannel{Language: language, Login: strings.ToLower(login)})
+	}
+	return channels, nil
+}
+
+// streamsState tracks which of the broadcast channels are live
+type streamsState struct {
+	mu         sync.Mutex
+	lastCheck  time.Time
+	liveLogins map[string]struct{}
+}
+
+// liveBroadcastChannels returns the broadcast channels that are live,
+// checking with Twitch if not checked in the last streamsCheckInterval.
+// The result of the last check is used if checking fails.
+func (bot *bot) liveBroadcastChannels(ctx context.Context) []BroadcastChannel {
+	if bot.twitchClient == nil || len(bot.broadcastChannels) == 0 {
+		return nil
+	}
+	bot.streams.mu.Lock()
+	defer bot.streams.mu.Unlock()
+	if time.Since(bot.streams.lastCheck) >= streamsCheckInterval {
+		bot.streams.lastCheck = time.Now()
+		logins := make([]string, 0, len(bot.broadcastChannels))
+		for _, channel := range bot.broadcastChannels {
+			logins = append(logins, channel.Login)
+		}
+		streams, err := bot.twitchClient.GetLiveStreams(ctx, logins)
+		if err != nil {
+			bot.logger.WithError(err).Error("Error getting live streams")
+		} else {
+			bot.streams.liveLogins = make(map[string]struct{}, len(streams))
+			for _, stream := range streams {
+				bot.streams.liveLogins[strings.ToLower(stream.UserLogin)] = struct{}{}
+			}
+		}
+	}
+	live := make([]BroadcastChannel, 0)
+	for _, channel := range bot.broadcastChannels {
+		if _, ok := bot.streams.liveLogins[channel.Login]; ok {
+			live = append(live, channel)
+		}
+	}
+	return live
+}
+
+// renderStreamLinks renders a line of links to the given channels, e.g.
+// "Watch in English: <https://twitch.tv/dota2ti> / Russian:
+// <https://twitch.tv/dota2ti_ru>".
+// Returns "" if there are no channels.
+func renderStreamLinks(channels []BroadcastChannel) string {
+	if len(channels) == 0 {
+		return ""
+	}
+	links := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		links = append(links, fmt.Sprintf("%s: <https://twitch.tv/%s>", channel.Language, channel.Login))
+	}
+	return "Watch in " + strings.Join(links, " / ")
+}
@@ -0,0 +1,55 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBroadcastChannels(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []BroadcastChannel
+		wantErr bool
+	}{
+		{"English=dota2ti", []BroadcastChannel{{"English", "dota2ti"}}, false},
+		{" English = Dota2TI , Russian=dota2ti_ru,", []BroadcastChannel{{"English", "dota2ti"}, {"Russian", "dota2ti_ru"}}, false},
+		{"", []BroadcastChannel{}, false},
+		{"dota2ti", nil, true},
+		{"=dota2ti", nil, true},
+		{"English=", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBroadcastChannels(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBroadcastChannels(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBroadcastChannels(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRenderStreamLinks(t *testing.T) {
+	if got := renderStreamLinks(nil); got != "" {
+		t.Errorf("renderStreamLinks(nil) = %q, want \"\"", got)
+	}
+	channels := []BroadcastChannel{{"English", "dota2ti"}, {"Russian", "dota2ti_ru"}}
+	want := "Watch in English: <https://twitch.tv/dota2ti> / Russian: <https://twitch.tv/dota2ti_ru>"
+	if got := renderStreamLinks(channels); got != want {
+		t.Errorf("renderStreamLinks() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterBroadcastChannels(t *testing.T) {
+	channels := []BroadcastChannel{{"English", "dota2ti"}, {"Russian", "dota2ti_ru"}}
+	settings := &guildSettings{}
+	if got := settings.filterBroadcastChannels(channels); !reflect.DeepEqual(got, channels) {
+		t.Errorf("filterBroadcastChannels() without languages = %v, want %v", got, channels)
+	}
+	settings.BroadcastLanguages = []string{"russian"}
+	want := []BroadcastChannel{{"Russian", "dota2ti_ru"}}
+	if got := settings.filterBroadcastChannels(channels); !reflect.DeepEqual(got, want) {
+		t.Errorf("filterBroadcastChannels() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,256 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// channelSubscription is a channel of a guild subscribed to announcements
+type channelSubscription struct {
+	ChannelID string `json:"channel_id"`
+	// Teams are the ids of the teams whose games are announced to the
+	// channel. If empty, all games are announced
+	Teams []int `json:"teams,omitempty"`
+	// ExcludedEvents are the kinds of announcements not sent to the
+	// channel, see announcementEvents
+	ExcludedEvents []string `json:"excluded_events,omitempty"`
+	// Accessible is true if direct messages to the user are sent in
+	// accessibility mode. Only used for direct message subscriptions
+	Accessible bool `json:"accessible,omitempty"`
+	// ReminderMinutes is how long before scheduled series start the
+	// channel is reminded of them, or 0 to announce them upNextLead
+	// before, see handleRemindCommand
+	ReminderMinutes int `json:"reminder_minutes,omitempty"`
+}
+
+// includesTeams tests if games between the given teams are announced to
+// the channel of the subscription. All games are announced to channels
+// without a subscription.
+func (sub *channelSubscription) includesTeams(teamIDs ...int) bool {
+	if sub == nil || len(sub.Teams) == 0 {
+		return true
+	}
+	for _, teamID := range teamIDs {
+		for _, subTeamID := range sub.Teams {
+			if teamID == subTeamID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// announcedGames returns the games to announce to a channel: those at
+// least as important as the guild's minimum importance, involving one of
+// the announced teams and the teams of the channel's subscription
+func (bot *bot) announcedGames(games []dota.LiveLeagueGame, settings *guildSettings, sub *channelSubscription) []dota.LiveLeagueGame {
+	announced := make([]dota.LiveLeagueGame, 0, len(games))
+	for _, game := range bot.filterImportantGames(games, settings.minImportance(bot.minImportance)) {
+		teamIDs := []int{game.RadiantTeam.TeamID, game.DireTeam.TeamID}
+		if bot.isAnnouncedTeam(teamIDs...) && sub.includesTeams(teamIDs...) {
+			announced = append(announced, game)
+		}
+	}
+	return announced
+}
+
+// announcedFinished is the announcedGames equivalent for finished games
+func (bot *bot) announcedFinished(items []matchesFinishedDataItem, settings *guildSettings, sub *channelSubscription) []matchesFinishedDataItem {
+	announced := make([]matchesFinishedDataItem, 0, len(items))
+	for _, item := range filterImportantFinished(items, settings.minImportance(bot.minImportance)) {
+		if bot.isAnnouncedTeam(item.WinnerTeamID, item.LoserTeamID) && sub.includesTeams(item.WinnerTeamID, item.LoserTeamID) {
+			announced = append(announced, item)
+		}
+	}
+	return announced
+}
+
+// announcedScoreUpdates returns the score updates of games involving one
+// of the announced teams and the teams of the channel's subscription
+func (bot *bot) announcedScoreUpdates(updates []scoreUpdate, sub *channelSubscription) []scoreUpdate {
+	announced := make([]scoreUpdate, 0, len(updates))
+	for _, update := range updates {
+		teamIDs := []int{update.Game.RadiantTeam.TeamID, update.Game.DireTeam.TeamID}
+		if bot.isAnnouncedTeam(teamIDs...) && sub.includesTeams(teamIDs...) {
+			announced = append(announced, update)
+		}
+	}
+	return announced
+}
+
+// subscription returns the subscription of a channel, or nil if the
+// channel is not subscribed
+func (settings *guildSettings) subscription(channelID string) *channelSubscription {
+	for i := range settings.Subscriptions {
+		if settings.Subscriptions[i].ChannelID == channelID {
+			return &settings.Subscriptions[i]
+		}
+	}
+	return nil
+}
+
+// announceChannels returns the channels of a guild to send announcements
+// to: the subscribed channels if the guild has changed its subscriptions,
+// or else the text channel with the first (lowest) position.
+func (settings *guildSettings) announceChannels(channels []*discordgo.Channel) []channelID {
+	if settings.SubscriptionsSet {
+		ids := make([]channelID, 0, len(settings.Subscriptions))
+		for _, sub := range settings.Subscriptions {
+			ids = append(ids, channelID(sub.ChannelID))
+		}
+		return ids
+	}
+	var firstCh *discordgo.Channel
+	for _, ch := range channels {
+		if ch.Type != discordgo.ChannelTypeGuildText {
+			continue
+		}
+		if firstCh == nil || firstCh.Position > ch.Position {
+			firstCh = ch
+		}
+	}
+	if firstCh == nil {
+		return nil
+	}
+	return []channelID{channelID(firstCh.ID)}
+}
+
+// initSubscriptions makes the current announce channels of the guild
+// explicit subscriptions, before the subscriptions are changed
+func (bot *bot) initSubscriptions(guildID guildID, settings *guildSettings) {
+	if settings.SubscriptionsSet {
+		return
+	}
+	settings.SubscriptionsSet = true
+	bot.channelsMu.RLock()
+	defer bot.channelsMu.RUnlock()
+	for chID, gID := range bot.channels {
+		if gID == guildID {
+			settings.Subscriptions = append(settings.Subscriptions, channelSubscription{ChannelID: string(chID)})
+		}
+	}
+	sort.Slice(settings.Subscriptions, func(i, j int) bool {
+		return settings.Subscriptions[i].ChannelID < settings.Subscriptions[j].ChannelID
+	})
+}
+
+// changeSubscription applies change to the settings of the guild of the
+// interaction and saves them, or to the subscription of the user if used
+// in direct messages. The response describes the outcome.
+func (bot *bot) changeSubscription(ctx context.Context, in *interaction, change func(settings *guildSettings) string) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return bot.changeDMSubscription(ctx, in, change)
+	}
+	if !in.hasPermission(permissionManageGuild) {
+		return textResponse("Changing subscriptions requires the Manage Server permission."), nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	bot.initSubscriptions(guildID(in.GuildID), settings)
+	content := change(settings)
+	if err := bot.saveGuildSettings(ctx, guildID(in.GuildID), settings); err != nil {
+		return nil, err
+	}
+	bot.applyGuildSettings(guildID(in.GuildID), settings)
+	return textResponse(content), nil
+}
+
+// subscriptionTeam resolves the team option of the /subscribe and
+// /unsubscribe commands. Returns 0 and a response to send if the option
+// is given but not a known team.
+func (bot *bot) subscriptionTeam(ctx context.Context, in *interaction) (int, *interactionResponseData, error) {
+	query := in.stringOption("team")
+	if query == "" {
+		return 0, nil, nil
+	}
+	teamID, err := bot.findTeamID(ctx, query)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "Error finding team")
+	}
+	if teamID == 0 {
+		return 0, textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	return teamID, nil, nil
+}
+
+// handleSubscribeCommand subscribes the channel the command is used in
+// to announcements. If a team is given, the channel only gets
+// announcements of games of the team, and any other teams subscribed to.
+func (bot *bot) handleSubscribeCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	teamID, res, err := bot.subscriptionTeam(ctx, in)
+	if res != nil || err != nil {
+		return res, err
+	}
+	return bot.changeSubscription(ctx, in, func(settings *guildSettings) string {
+		sub := settings.subscription(in.ChannelID)
+		if teamID == 0 {
+			if sub == nil {
+				settings.Subscriptions = append(settings.Subscriptions, channelSubscription{ChannelID: in.ChannelID})
+				return "This channel is now subscribed to announcements."
+			}
+			if len(sub.Teams) == 0 {
+				return "This channel is already subscribed to announcements."
+			}
+			sub.Teams = nil
+			return "This channel will now get announcements of all games."
+		}
+		if sub == nil {
+			settings.Subscriptions = append(settings.Subscriptions, channelSubscription{ChannelID: in.ChannelID})
+			sub = &settings.Subscriptions[len(settings.Subscriptions)-1]
+		} else if len(sub.Teams) > 0 && sub.includesTeams(teamID) {
+			return fmt.Sprintf("This channel is already subscribed to %s.", bot.teamName(teamID))
+		}
+		// A channel getting announcements of all games is narrowed down
+		// to the team
+		sub.Teams = append(sub.Teams, teamID)
+		return fmt.Sprintf("This channel will now get announcements of games of %s.", bot.teamName(teamID))
+	})
+}
+
+// handleUnsubscribeCommand stops announcements to the channel the
+// command is used in. If a team is given, only the team is removed from
+// the teams of the channel, unsubscribing the channel if it was the last.
+func (bot *bot) handleUnsubscribeCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	teamID, res, err := bot.subscriptionTeam(ctx, in)
+	if res != nil || err != nil {
+		return res, err
+	}
+	return bot.changeSubscription(ctx, in, func(settings *guildSettings) string {
+		for i, sub := range settings.Subscriptions {
+			if sub.ChannelID != in.ChannelID {
+				continue
+			}
+			if teamID != 0 {
+				teams := removeTeam(sub.Teams, teamID)
+				if len(teams) == len(sub.Teams) {
+					return fmt.Sprintf("This channel is not subscribed to %s.", bot.teamName(teamID))
+				}
+				if len(teams) > 0 {
+					settings.Subscriptions[i].Teams = teams
+					return fmt.Sprintf("This channel will no longer get announcements of games of %s.", bot.teamName(teamID))
+				}
+			}
+			settings.Subscriptions = append(settings.Subscriptions[:i], settings.Subscriptions[i+1:]...)
+			return "This channel will no longer get announcements."
+		}
+		return "This channel is not subscribed to announcements."
+	})
+}
+
+// removeTeam returns teams without teamID
+func removeTeam(teams []int, teamID int) []int {
+	result := make([]int, 0, len(teams))
+	for _, t := range teams {
+		if t != teamID {
+			result = append(result, t)
+		}
+	}
+	return result
+}
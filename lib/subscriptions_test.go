@@ -0,0 +1,61 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestIncludesTeams(t *testing.T) {
+	var none *channelSubscription
+	if !none.includesTeams(1, 2) {
+		t.Error("includesTeams() without subscription = false, want true")
+	}
+	all := &channelSubscription{ChannelID: "1"}
+	if !all.includesTeams(1, 2) {
+		t.Error("includesTeams() without teams = false, want true")
+	}
+	teams := &channelSubscription{ChannelID: "1", Teams: []int{2, 3}}
+	if !teams.includesTeams(1, 2) {
+		t.Error("includesTeams() of a subscribed team = false, want true")
+	}
+	if teams.includesTeams(1, 4) {
+		t.Error("includesTeams() of other teams = true, want false")
+	}
+}
+
+func TestAnnouncedGames(t *testing.T) {
+	game := func(matchID int64, radiant, dire int) dota.LiveLeagueGame {
+		return dota.LiveLeagueGame{
+			MatchID:     matchID,
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamID: radiant},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamID: dire},
+		}
+	}
+	bot := &bot{
+		minImportance:   20,
+		matchImportance: map[int64]int{1: 10, 2: 50, 3: 50},
+	}
+	games := []dota.LiveLeagueGame{game(1, 1, 2), game(2, 1, 3), game(3, 4, 5)}
+	settings := &guildSettings{}
+	sub := &channelSubscription{Teams: []int{1}}
+	got := bot.announcedGames(games, settings, sub)
+	if want := games[1:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("announcedGames() = %v, want %v", got, want)
+	}
+	settings.MinImportance, settings.MinImportanceSet = 0, true
+	got = bot.announcedGames(games, settings, sub)
+	if want := games[:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("announcedGames() with guild min importance = %v, want %v", got, want)
+	}
+}
+
+func TestRemoveTeam(t *testing.T) {
+	if got, want := removeTeam([]int{1, 2, 3}, 2), []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removeTeam() = %v, want %v", got, want)
+	}
+	if got := removeTeam([]int{1}, 2); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("removeTeam() of missing team = %v, want [1]", got)
+	}
+}
@@ -0,0 +1,126 @@
+package timatch
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// runTask runs a polling task until ctx is done, waiting the interval
+// returned by the task between runs
+func runTask(ctx context.Context, task func(ctx context.Context) time.Duration) {
+	for {
+		interval := task(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pollLive polls the live games, returning the events of the poll: the
+// failure of the last poll of the match history, if it failed, the games
+// whose details were fetched since the last poll, the games first seen
+// drafting and started, and the games live. Nothing is polled while there
+// are no games to watch, see isWatching.
+func (watcher *Watcher) pollLive(ctx context.Context) []WatcherEvent {
+	watcher.mu.Lock()
+	leagueID, watching := watcher.leagueID, watcher.isWatching()
+	watcher.mu.Unlock()
+	var res *dota.LiveLeagueGamesResponse
+	var err error
+	if watching {
+		res, err = watcher.source.GetLiveLeagueGames(ctx, leagueID)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	now := time.Now()
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	var events []WatcherEvent
+	if watcher.historyErr != nil {
+		events = append(events, &PollFailed{Err: watcher.historyErr})
+		watcher.historyErr = nil
+	}
+	events = append(events, watcher.collectFinished(now)...)
+	// Queued once per poll, so that games whose details are not yet
+	// available are retried no more often than that
+	watcher.details.queue(watcher.finishedQueue, now)
+	watcher.prune(now)
+	switch {
+	case !watching:
+		if len(events) > 0 {
+			events = append(events, &LiveGames{})
+		}
+	case err != nil:
+		watcher.logger.WithField(logFieldLeagueID, leagueID).WithError(err).Error("Error getting live games")
+		events = append(events, &PollFailed{Err: err})
+	default:
+		events = append(events, watcher.updateLive(ctx, res.Result.Games, now)...)
+		games := make([]dota.LiveLeagueGame, len(watcher.lastLive))
+		copy(games, watcher.lastLive)
+		events = append(events, &LiveGames{Games: games})
+	}
+	return events
+}
+
+// pollHistory is the task polling the match history for finished games,
+// queuing them for their details to be fetched. Only holds mu between
+// requests
+func (watcher *Watcher) pollHistory(ctx context.Context) time.Duration {
+	if err := watcher.updateFinished(ctx); err != nil && ctx.Err() == nil {
+		watcher.mu.Lock()
+		watcher.historyErr = err
+		watcher.mu.Unlock()
+	}
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	watcher.details.queue(watcher.finishedQueue, time.Now())
+	return historyInterval
+}
+
+// updateFinished finds the finished games in the match history
+func (watcher *Watcher) updateFinished(ctx context.Context) error {
+	watcher.mu.Lock()
+	if !watcher.isWatching() || (!watcher.hasUnfinishedGames() && !watcher.backfillDue()) {
+		watcher.logger.Debug("Not fetching match history, all known games already finished")
+		// The sequence would fall behind while not polled
+		watcher.matchSeqNum = 0
+		watcher.mu.Unlock()
+		return nil
+	}
+	bySequence := watcher.sequence != nil && watcher.matchSeqNum != 0 && !watcher.backfillDue()
+	leagueIDs := watcher.historyLeagues()
+	watcher.mu.Unlock()
+	if bySequence && watcher.updateFinishedBySequence(ctx) {
+		return nil
+	}
+	if watcher.sequence != nil {
+		// Before getting the history, so that games finishing after it
+		// was got are in the sequence
+		watcher.seedMatchSeqNum(ctx)
+	}
+	for _, leagueID := range leagueIDs {
+		historyRes, err := watcher.source.GetMatchHistory(ctx, leagueID)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			watcher.logger.WithField(logFieldLeagueID, leagueID).WithError(err).Error("Error getting match history")
+			return errors.Wrap(err, "Error getting match history")
+		}
+		watcher.mu.Lock()
+		if watcher.backfillDue() {
+			watcher.backfillFinished(ctx, historyRes.Result.Matches)
+		}
+		for _, match := range historyRes.Result.Matches {
+			watcher.matchFinished(ctx, match)
+		}
+		watcher.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestRunTask(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runTask(ctx, func(ctx context.Context) time.Duration {
+			if runs++; runs == 3 {
+				cancel()
+			}
+			return time.Millisecond
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the task to stop once the context is done")
+	}
+	if runs != 3 {
+		t.Errorf("expected the task to run 3 times, ran %d times", runs)
+	}
+}
+
+func TestPollLiveNotWatching(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	watcher := newWatcher(logger, &stubDataSource{games: []dota.LiveLeagueGame{{MatchID: 1}}})
+	if events := watcher.pollLive(context.Background()); len(events) != 0 {
+		t.Errorf("expected no events without a league, got %v", events)
+	}
+	if watcher.livePolled {
+		t.Error("expected no live games to be polled without a league")
+	}
+}
+
+func TestPollLiveFailed(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	watcher := newWatcher(logger, &stubDataSource{err: errors.New("unavailable")})
+	watcher.setLeague(10749, false)
+	watcher.historyErr = errors.New("history unavailable")
+	events := watcher.pollLive(context.Background())
+	if len(events) != 2 {
+		t.Fatalf("expected the history and live failures, got %v", events)
+	}
+	for _, event := range events {
+		if _, ok := event.(*PollFailed); !ok {
+			t.Errorf("expected a PollFailed, got %T", event)
+		}
+	}
+	if watcher.historyErr != nil {
+		t.Error("expected the history failure to be sent once")
+	}
+}
@@ -0,0 +1,101 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// teamRole is a Discord role pinged when a game of a team starts
+type teamRole struct {
+	TeamID int    `json:"team_id"`
+	RoleID string `json:"role_id"`
+}
+
+// roleMentions returns the mentions of the roles of the given teams,
+// separated by spaces, or "" if none of the teams have a role
+func (settings *guildSettings) roleMentions(teamIDs ...int) string {
+	mentions := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, teamID := range teamIDs {
+		for _, role := range settings.TeamRoles {
+			if role.TeamID != teamID {
+				continue
+			}
+			if _, ok := seen[role.RoleID]; ok {
+				continue
+			}
+			seen[role.RoleID] = struct{}{}
+			mentions = append(mentions, "<@&"+role.RoleID+">")
+		}
+	}
+	return strings.Join(mentions, " ")
+}
+
+// setTeamRole maps a team to a role, replacing any previous role of the
+// team. An empty roleID removes the role of the team. Returns false if
+// removing a role of a team without one.
+func (settings *guildSettings) setTeamRole(teamID int, roleID string) bool {
+	for i, role := range settings.TeamRoles {
+		if role.TeamID != teamID {
+			continue
+		}
+		if roleID == "" {
+			settings.TeamRoles = append(settings.TeamRoles[:i], settings.TeamRoles[i+1:]...)
+		} else {
+			settings.TeamRoles[i].RoleID = roleID
+		}
+		return true
+	}
+	if roleID == "" {
+		return false
+	}
+	settings.TeamRoles = append(settings.TeamRoles, teamRole{TeamID: teamID, RoleID: roleID})
+	return true
+}
+
+// handleTeamRoleCommand lists the team roles of the guild, or maps a team
+// to a role if a team is given. Giving a team without a role removes the
+// role of the team. Changing roles requires the Manage Server permission.
+func (bot *bot) handleTeamRoleCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	if in.GuildID == "" {
+		return textResponse("Team roles can only be used in a server."), nil
+	}
+	settings, err := bot.getGuildSettings(ctx, guildID(in.GuildID))
+	if err != nil {
+		return nil, err
+	}
+	query := in.stringOption("team")
+	if query == "" {
+		if len(settings.TeamRoles) == 0 {
+			return textResponse("No team roles set."), nil
+		}
+		var b strings.Builder
+		b.WriteString("**Team roles**\n")
+		for _, role := range settings.TeamRoles {
+			fmt.Fprintf(&b, "%s: <@&%s>\n", bot.teamName(role.TeamID), role.RoleID)
+		}
+		return textResponse(b.String()), nil
+	}
+	if !in.hasPermission(permissionManageGuild) {
+		return textResponse("Changing team roles requires the Manage Server permission."), nil
+	}
+	teamID, err := bot.findTeamID(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if teamID == 0 {
+		return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	roleID := in.stringOption("role")
+	if !settings.setTeamRole(teamID, roleID) {
+		return textResponse(fmt.Sprintf("%s has no role.", bot.teamName(teamID))), nil
+	}
+	if err := bot.saveGuildSettings(ctx, guildID(in.GuildID), settings); err != nil {
+		return nil, err
+	}
+	if roleID == "" {
+		return textResponse(fmt.Sprintf("%s no longer has a role.", bot.teamName(teamID))), nil
+	}
+	return textResponse(fmt.Sprintf("<@&%s> will be pinged when a game of %s starts.", roleID, bot.teamName(teamID))), nil
+}
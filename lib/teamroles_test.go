@@ -0,0 +1,50 @@
+package timatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoleMentions(t *testing.T) {
+	settings := &guildSettings{TeamRoles: []teamRole{
+		{TeamID: 1, RoleID: "10"},
+		{TeamID: 2, RoleID: "20"},
+		{TeamID: 3, RoleID: "10"},
+	}}
+	tests := []struct {
+		teamIDs []int
+		want    string
+	}{
+		{nil, ""},
+		{[]int{4, 5}, ""},
+		{[]int{1, 4}, "<@&10>"},
+		{[]int{1, 2}, "<@&10> <@&20>"},
+		{[]int{1, 3}, "<@&10>"},
+	}
+	for _, tt := range tests {
+		if got := settings.roleMentions(tt.teamIDs...); got != tt.want {
+			t.Errorf("roleMentions(%v) = %q, want %q", tt.teamIDs, got, tt.want)
+		}
+	}
+}
+
+func TestSetTeamRole(t *testing.T) {
+	settings := &guildSettings{}
+	if settings.setTeamRole(1, "") {
+		t.Error("setTeamRole() removing a missing role = true, want false")
+	}
+	settings.setTeamRole(1, "10")
+	settings.setTeamRole(2, "20")
+	settings.setTeamRole(1, "11")
+	want := []teamRole{{TeamID: 1, RoleID: "11"}, {TeamID: 2, RoleID: "20"}}
+	if !reflect.DeepEqual(settings.TeamRoles, want) {
+		t.Errorf("TeamRoles = %v, want %v", settings.TeamRoles, want)
+	}
+	if !settings.setTeamRole(1, "") {
+		t.Error("setTeamRole() removing a role = false, want true")
+	}
+	want = []teamRole{{TeamID: 2, RoleID: "20"}}
+	if !reflect.DeepEqual(settings.TeamRoles, want) {
+		t.Errorf("TeamRoles = %v, want %v", settings.TeamRoles, want)
+	}
+}
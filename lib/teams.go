@@ -0,0 +1,31 @@
+package timatch
+
+// teamIDSet returns the set of the given team ids
+func teamIDSet(teamIDs []int) map[int]struct{} {
+	set := make(map[int]struct{}, len(teamIDs))
+	for _, teamID := range teamIDs {
+		set[teamID] = struct{}{}
+	}
+	return set
+}
+
+// isAnnouncedTeam tests if games between the given teams are announced
+// at all, as restricted by the -teams and -ignoreteams flags. Games of
+// an ignored team are never announced, and if announced teams are given
+// only games involving one of them are.
+func (bot *bot) isAnnouncedTeam(teamIDs ...int) bool {
+	for _, teamID := range teamIDs {
+		if _, ok := bot.ignoreTeams[teamID]; ok {
+			return false
+		}
+	}
+	if len(bot.teams) == 0 {
+		return true
+	}
+	for _, teamID := range teamIDs {
+		if _, ok := bot.teams[teamID]; ok {
+			return true
+		}
+	}
+	return false
+}
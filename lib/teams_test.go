@@ -0,0 +1,25 @@
+package timatch
+
+import "testing"
+
+func TestIsAnnouncedTeam(t *testing.T) {
+	tests := []struct {
+		teams, ignoreTeams []int
+		teamIDs            []int
+		want               bool
+	}{
+		{nil, nil, []int{1, 2}, true},
+		{[]int{1}, nil, []int{1, 2}, true},
+		{[]int{1}, nil, []int{2, 3}, false},
+		{nil, []int{2}, []int{1, 2}, false},
+		{nil, []int{2}, []int{1, 3}, true},
+		{[]int{1}, []int{2}, []int{1, 2}, false},
+	}
+	for _, tt := range tests {
+		bot := &bot{teams: teamIDSet(tt.teams), ignoreTeams: teamIDSet(tt.ignoreTeams)}
+		if got := bot.isAnnouncedTeam(tt.teamIDs...); got != tt.want {
+			t.Errorf("isAnnouncedTeam(%v) with teams %v, ignoreteams %v = %v, want %v",
+				tt.teamIDs, tt.teams, tt.ignoreTeams, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// teamStats are the tournament stats of a team
+type teamStats struct {
+	Wins   int
+	Losses int
+	// Duration is the total game time of the team's games
+	Duration time.Duration
+	// KillsFor and KillsAgainst are the total kills of the team and of
+	// its opponents
+	KillsFor     int
+	KillsAgainst int
+}
+
+// Games returns the number of games played by the team
+func (stats teamStats) Games() int {
+	return stats.Wins + stats.Losses
+}
+
+// computeTeamStats computes the stats of a team from the results of a
+// league
+func computeTeamStats(results []matchResult, teamID int) teamStats {
+	var stats teamStats
+	for _, result := range results {
+		switch teamID {
+		case result.WinnerTeamID:
+			stats.Wins++
+			stats.KillsFor += result.WinnerScore
+			stats.KillsAgainst += result.LoserScore
+		case result.LoserTeamID:
+			stats.Losses++
+			stats.KillsFor += result.LoserScore
+			stats.KillsAgainst += result.WinnerScore
+		default:
+			continue
+		}
+		stats.Duration += time.Duration(result.Duration) * time.Second
+	}
+	return stats
+}
+
+// renderTeamStats renders the stats of a team
+func renderTeamStats(name string, stats teamStats, format textFormat) string {
+	games := stats.Games()
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", name)
+	fmt.Fprintf(&b, "Record: %s (%s%% won)\n", format.Score(stats.Wins, stats.Losses),
+		format.Decimal(100*float64(stats.Wins)/float64(games), 0))
+	fmt.Fprintf(&b, "Average game: %s\n", formatDuration(float32(stats.Duration.Seconds())/float32(games)))
+	diff := float64(stats.KillsFor-stats.KillsAgainst) / float64(games)
+	sign := ""
+	if diff >= 0 {
+		sign = "+"
+	}
+	fmt.Fprintf(&b, "Kills: %s (%s%s per game)", format.Score(stats.KillsFor, stats.KillsAgainst),
+		sign, format.Decimal(diff, 1))
+	return b.String()
+}
+
+// handleTeamCommand responds with the tournament stats of a team
+func (bot *bot) handleTeamCommand(ctx context.Context, in *interaction) (*interactionResponseData, error) {
+	leagueID := bot.currentLeagueID()
+	if leagueID == 0 {
+		return textResponse("Not watching any league yet."), nil
+	}
+	query := in.stringOption("name")
+	teamID, err := bot.findTeamID(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error finding team")
+	}
+	if teamID == 0 {
+		return textResponse(fmt.Sprintf("Could not find a team named %q.", query)), nil
+	}
+	results, err := bot.loadResults(ctx, leagueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading results")
+	}
+	stats := computeTeamStats(results, teamID)
+	if stats.Games() == 0 {
+		return textResponse(fmt.Sprintf("%s has not played any games yet.", bot.teamName(teamID))), nil
+	}
+	return textResponse(renderTeamStats(bot.teamName(teamID), stats, bot.interactionTextFormat(ctx, in))), nil
+}
@@ -0,0 +1,29 @@
+package timatch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeTeamStats(t *testing.T) {
+	game := func(winner, loser, winnerScore, loserScore, duration int) matchResult {
+		return matchResult{Duration: duration, matchesFinishedDataItem: matchesFinishedDataItem{
+			WinnerTeamID: winner, LoserTeamID: loser, WinnerScore: winnerScore, LoserScore: loserScore}}
+	}
+	results := []matchResult{
+		game(1, 2, 30, 10, 1800),
+		game(2, 1, 25, 20, 2400),
+		game(3, 4, 40, 5, 1200),
+		game(1, 3, 20, 15, 3000),
+	}
+	stats := computeTeamStats(results, 1)
+	want := teamStats{Wins: 2, Losses: 1, Duration: 7200 * time.Second, KillsFor: 70, KillsAgainst: 50}
+	if stats != want {
+		t.Errorf("computeTeamStats() = %+v, want %+v", stats, want)
+	}
+	got := renderTeamStats("OG", stats, defaultTextFormat)
+	wantText := "**OG**\nRecord: 2 - 1 (67% won)\nAverage game: 40:00\nKills: 70 - 50 (+6.7 per game)"
+	if got != wantText {
+		t.Errorf("renderTeamStats() = %q, want %q", got, wantText)
+	}
+}
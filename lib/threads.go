@@ -0,0 +1,149 @@
+package timatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// threadTypePublic is the channel type of public threads
+const threadTypePublic = 11
+
+// threadAutoArchiveMinutes is the inactivity after which Discord archives
+// series threads. Archived threads are unarchived by new messages
+const threadAutoArchiveMinutes = 24 * 60
+
+// maxThreadNameLength is the longest name, in characters, Discord allows
+// for a thread
+const maxThreadNameLength = 100
+
+// seriesPart is the part of the data of an announcement concerning the
+// games of a series
+type seriesPart struct {
+	seriesID int64
+	// name is the matchup of the series, e.g. "OG vs. Team Liquid"
+	name string
+	// items is a slice of the type of the data of the announcement
+	items reflect.Value
+}
+
+// seriesOf returns the series of a game listed in the data of an
+// announcement. Games not part of a series have a series id of 0. ok is
+// false for data not listing games, such as the flood digest.
+func seriesOf(item interface{}) (seriesID int64, name string, ok bool) {
+	switch item := item.(type) {
+	case dota.LiveLeagueGame:
+		return item.SeriesID, item.RadiantTeam.TeamName + " vs. " + item.DireTeam.TeamName, true
+	case scoreUpdate:
+		return item.Game.SeriesID, item.Game.RadiantTeam.TeamName + " vs. " + item.Game.DireTeam.TeamName, true
+	case matchesFinishedDataItem:
+		// Named in alphabetical order, so that the name does not give
+		// the winner away
+		return item.SeriesID, item.FirstTeam() + " vs. " + item.SecondTeam(), true
+	}
+	return 0, "", false
+}
+
+// splitBySeries splits the data of an announcement listing games by the
+// series of the games, in the order the series are first listed. ok is
+// false for data not listing games.
+func splitBySeries(data interface{}) (parts []seriesPart, ok bool) {
+	list := reflect.ValueOf(data)
+	if list.Kind() != reflect.Slice {
+		return nil, false
+	}
+	index := make(map[int64]int)
+	for i := 0; i < list.Len(); i++ {
+		seriesID, name, ok := seriesOf(list.Index(i).Interface())
+		if !ok {
+			return nil, false
+		}
+		j, seen := index[seriesID]
+		if !seen {
+			j = len(parts)
+			index[seriesID] = j
+			parts = append(parts, seriesPart{seriesID: seriesID, name: name, items: reflect.MakeSlice(list.Type(), 0, 1)})
+		}
+		parts[j].items = reflect.Append(parts[j].items, list.Index(i))
+	}
+	return parts, true
+}
+
+func seriesThreadKey(seriesID int64, channelID channelID) string {
+	return fmt.Sprintf("thread/%d/%s", seriesID, channelID)
+}
+
+// seriesThread returns the id of the thread of a series in a channel,
+// creating the thread, named after the matchup, when the first game of
+// the series is announced
+func (bot *bot) seriesThread(ctx context.Context, parentID channelID, seriesID int64, name string) (channelID, error) {
+	var threadID channelID
+	found, err := bot.store.Get(ctx, seriesThreadKey(seriesID, parentID), &threadID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error getting series thread")
+	}
+	if found {
+		return threadID, nil
+	}
+	if runes := []rune(name); len(runes) > maxThreadNameLength {
+		name = string(runes[:maxThreadNameLength])
+	}
+	data := struct {
+		Name                string `json:"name"`
+		Type                int    `json:"type"`
+		AutoArchiveDuration int    `json:"auto_archive_duration"`
+	}{name, threadTypePublic, threadAutoArchiveMinutes}
+	res, err := bot.discordSession.Request("POST", discordgo.EndpointChannel(string(parentID))+"/threads", data)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating series thread")
+	}
+	var thread struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(res, &thread); err != nil {
+		return "", errors.Wrap(err, "Error decoding series thread")
+	}
+	threadID = channelID(thread.ID)
+	if err := bot.store.Set(ctx, seriesThreadKey(seriesID, parentID), threadID, finishedMatchTTL); err != nil {
+		return "", errors.Wrap(err, "Error storing series thread")
+	}
+	return threadID, nil
+}
+
+// sendSeriesThreads sends the parts of an announcement concerning series
+// to the threads of the series in the channel, rendering each part using
+// render. Returns the data not sent to a thread, to be announced in the
+// channel itself, or nil if all of it was sent.
+func (bot *bot) sendSeriesThreads(ctx context.Context, channelID channelID, settings *guildSettings, event string, data interface{}, render func(data interface{}) string) interface{} {
+	parts, ok := splitBySeries(data)
+	if !ok {
+		return data
+	}
+	var remaining reflect.Value
+	for _, part := range parts {
+		if part.seriesID != 0 {
+			threadID, err := bot.seriesThread(ctx, channelID, part.seriesID, part.name)
+			if err == nil {
+				if content := render(part.items.Interface()); content != "" {
+					bot.deliverGuildMessage(ctx, threadID, settings, event, content, part.items.Interface())
+				}
+				continue
+			}
+			bot.logger.WithField(logFieldChannelID, channelID).WithError(err).Errorf("Error getting thread of series %d, announcing in the channel", part.seriesID)
+		}
+		if !remaining.IsValid() {
+			remaining = part.items
+		} else {
+			remaining = reflect.AppendSlice(remaining, part.items)
+		}
+	}
+	if !remaining.IsValid() {
+		return nil
+	}
+	return remaining.Interface()
+}
@@ -0,0 +1,63 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/storage"
+)
+
+func TestSplitBySeries(t *testing.T) {
+	items := []matchesFinishedDataItem{
+		{MatchID: 1, SeriesID: 10, WinnerName: "Team Liquid", LoserName: "OG"},
+		{MatchID: 2},
+		{MatchID: 3, SeriesID: 10, WinnerName: "OG", LoserName: "Team Liquid"},
+	}
+	parts, ok := splitBySeries(items)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("splitBySeries() = %d parts, %v, want 2 parts", len(parts), ok)
+	}
+	if parts[0].seriesID != 10 || parts[0].name != "OG vs. Team Liquid" {
+		t.Errorf("First part is series %d named %q, want series 10 named after the teams alphabetically", parts[0].seriesID, parts[0].name)
+	}
+	if got := parts[0].items.Interface().([]matchesFinishedDataItem); len(got) != 2 || got[1].MatchID != 3 {
+		t.Errorf("First part = %+v, want matches 1 and 3", got)
+	}
+	if _, ok := splitBySeries(floodDigest{}); ok {
+		t.Error("splitBySeries(floodDigest) ok, want not split")
+	}
+}
+
+func TestSendSeriesThreads(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, store: storage.NewMemoryStore()}
+	var sent []string
+	bot.sends = newSendQueue(logger, func(msg outboundMessage) error {
+		sent = append(sent, string(msg.channelID)+":"+msg.content)
+		return nil
+	})
+	ctx := context.Background()
+	if err := bot.store.Set(ctx, seriesThreadKey(10, "1"), channelID("2"), 0); err != nil {
+		t.Fatal(err)
+	}
+	items := []matchesFinishedDataItem{{MatchID: 1, SeriesID: 10}, {MatchID: 2}}
+	render := func(data interface{}) string {
+		return fmt.Sprintf("finished %d", len(data.([]matchesFinishedDataItem)))
+	}
+	remaining := bot.sendSeriesThreads(ctx, "1", &guildSettings{SeriesThreads: true}, eventFinished, items, render)
+	if want := []matchesFinishedDataItem{{MatchID: 2}}; !reflect.DeepEqual(remaining, want) {
+		t.Errorf("sendSeriesThreads() = %+v, want %+v", remaining, want)
+	}
+	if !bot.sends.wait(time.Second) {
+		t.Fatal("wait() timed out")
+	}
+	if want := []string{"2:finished 1"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("Sent %v, want %v", sent, want)
+	}
+}
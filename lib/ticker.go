@@ -0,0 +1,157 @@
+package timatch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// liveTicker is the message of a game in a channel using the ticker,
+// edited with the live score of the game and finally its result
+type liveTicker struct {
+	channelID channelID
+	format    textFormat
+	// messageID is the id of the message, "" until it has been sent
+	messageID string
+	// content is the current content of the ticker, and sent the content
+	// last sent or scheduled as an edit
+	content string
+	sent    string
+}
+
+// liveTickers are the tickers of the live games, by match id
+type liveTickers struct {
+	mu      sync.Mutex
+	byMatch map[int64][]*liveTicker
+}
+
+// has tests if the game has a ticker in the channel
+func (tickers *liveTickers) has(matchID int64, channelID channelID) bool {
+	tickers.mu.Lock()
+	defer tickers.mu.Unlock()
+	for _, ticker := range tickers.byMatch[matchID] {
+		if ticker.channelID == channelID {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTicker tests if games should be announced to the channel using a
+// ticker. Games are announced as usual during quiet hours, as a ticker
+// held back until the game is over would not be live.
+func (settings *guildSettings) usesTicker() bool {
+	return settings.Ticker && settings.quietHoursEnd(time.Now()).IsZero()
+}
+
+// startTickers posts a ticker for each of the started games to a channel
+func (bot *bot) startTickers(channelID channelID, settings *guildSettings, games []dota.LiveLeagueGame) {
+	format := settings.channelTextFormat(string(channelID))
+	tts := settings.tts(eventStarted)
+	bot.tickers.mu.Lock()
+	defer bot.tickers.mu.Unlock()
+	if bot.tickers.byMatch == nil {
+		bot.tickers.byMatch = make(map[int64][]*liveTicker)
+	}
+	for _, game := range games {
+		content := renderTickerLive(game, format)
+		ticker := &liveTicker{channelID: channelID, format: format, content: content, sent: content}
+		bot.tickers.byMatch[game.MatchID] = append(bot.tickers.byMatch[game.MatchID], ticker)
+		bot.sends.enqueue(outboundMessage{
+			channelID: channelID,
+			content:   content,
+			tts:       tts,
+			sent: func(messageID string) {
+				bot.tickers.mu.Lock()
+				defer bot.tickers.mu.Unlock()
+				ticker.messageID = messageID
+				bot.flushTicker(ticker)
+			},
+		})
+	}
+}
+
+// updateTickers updates the tickers of the live games with their current
+// score
+func (bot *bot) updateTickers(games []dota.LiveLeagueGame) {
+	bot.tickers.mu.Lock()
+	defer bot.tickers.mu.Unlock()
+	for _, game := range games {
+		for _, ticker := range bot.tickers.byMatch[game.MatchID] {
+			ticker.content = renderTickerLive(game, ticker.format)
+			bot.flushTicker(ticker)
+		}
+	}
+}
+
+// finishTickers edits the tickers of the finished games in a channel to
+// show their result, returning the games without a ticker in the channel
+func (bot *bot) finishTickers(channelID channelID, items []matchesFinishedDataItem) []matchesFinishedDataItem {
+	bot.tickers.mu.Lock()
+	defer bot.tickers.mu.Unlock()
+	remaining := make([]matchesFinishedDataItem, 0, len(items))
+	for _, item := range items {
+		finished := false
+		for _, ticker := range bot.tickers.byMatch[item.MatchID] {
+			if ticker.channelID == channelID {
+				ticker.content = renderTickerFinished(item, ticker.format)
+				bot.flushTicker(ticker)
+				finished = true
+			}
+		}
+		if !finished {
+			remaining = append(remaining, item)
+		}
+	}
+	return remaining
+}
+
+// removeTickers forgets the tickers of the finished games, once their
+// results have been announced
+func (bot *bot) removeTickers(items []matchesFinishedDataItem) {
+	bot.tickers.mu.Lock()
+	defer bot.tickers.mu.Unlock()
+	for _, item := range items {
+		delete(bot.tickers.byMatch, item.MatchID)
+	}
+}
+
+// flushTicker schedules an edit of the ticker if its content changed,
+// once it has been sent. Must be called with the lock of the tickers held.
+func (bot *bot) flushTicker(ticker *liveTicker) {
+	if ticker.messageID == "" || ticker.content == ticker.sent {
+		return
+	}
+	ticker.sent = ticker.content
+	bot.editMessage(ticker.channelID, ticker.messageID, ticker.content)
+}
+
+// renderTickerLive renders the ticker of a live game. The kill score is
+// left out unless the guild announces the kill scores of finished games.
+func renderTickerLive(game dota.LiveLeagueGame, format textFormat) string {
+	radiant, dire := game.RadiantTeam.TeamName, game.DireTeam.TeamName
+	duration := formatDuration(game.Scoreboard.Duration)
+	if format.accessible {
+		if format.showsKills() {
+			return fmt.Sprintf("Live, game %d: %s versus %s, %s kills, after %s.", game.GameNumber, radiant, dire,
+				format.Score(game.Scoreboard.Radiant.Score, game.Scoreboard.Dire.Score), duration)
+		}
+		return fmt.Sprintf("Live, game %d: %s versus %s, after %s.", game.GameNumber, radiant, dire, duration)
+	}
+	if format.showsKills() {
+		return fmt.Sprintf("🔴 Live: %s %s %s (%s, Game %d)", radiant,
+			format.Score(game.Scoreboard.Radiant.Score, game.Scoreboard.Dire.Score), dire, duration, game.GameNumber)
+	}
+	return fmt.Sprintf("🔴 Live: %s vs. %s (%s, Game %d)", radiant, dire, duration, game.GameNumber)
+}
+
+// renderTickerFinished renders the final ticker of a game, with its result
+func renderTickerFinished(item matchesFinishedDataItem, format textFormat) string {
+	line := renderResult(matchResult{FinishedAt: time.Now(), matchesFinishedDataItem: item}, false, format)
+	if format.accessible {
+		return "Match ended. " + line
+	}
+	return "🏁 " + line
+}
@@ -0,0 +1,88 @@
+package timatch
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestTicker(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	bot := &bot{logger: logger, edits: newEditQueue()}
+	bot.sends = newSendQueue(logger, func(msg outboundMessage) error {
+		if msg.sent != nil {
+			msg.sent("100")
+		}
+		return nil
+	})
+	game := dota.LiveLeagueGame{
+		MatchID:     1,
+		GameNumber:  2,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+	}
+	settings := &guildSettings{Ticker: true}
+	bot.startTickers("10", settings, []dota.LiveLeagueGame{game})
+	// Updates before the ticker has been sent are edited in once sent
+	game.Scoreboard.Duration = 600
+	game.Scoreboard.Radiant.Score = 5
+	bot.updateTickers([]dota.LiveLeagueGame{game})
+	if !bot.sends.wait(time.Second) {
+		t.Fatal("wait() timed out")
+	}
+	edit, _, ok := bot.edits.next(time.Now())
+	want := messageEdit{channelID: "10", messageID: "100", content: "🔴 Live: OG 5 - 0 Liquid (10:00, Game 2)"}
+	if !ok || edit != want {
+		t.Fatalf("edit = %+v, %v, want %+v", edit, ok, want)
+	}
+	// Unchanged scores are not edited again
+	bot.updateTickers([]dota.LiveLeagueGame{game})
+	if _, wait, ok := bot.edits.next(time.Now().Add(editInterval)); ok || wait != 0 {
+		t.Errorf("Unchanged ticker edited")
+	}
+
+	items := []matchesFinishedDataItem{
+		{MatchID: 1, GameNumber: 2, WinnerName: "OG", LoserName: "Liquid", WinnerScore: 32, LoserScore: 17},
+		{MatchID: 2, GameNumber: 1, WinnerName: "Secret", LoserName: "EG", WinnerScore: 20, LoserScore: 10},
+	}
+	remaining := bot.finishTickers("10", items)
+	if len(remaining) != 1 || remaining[0].MatchID != 2 {
+		t.Errorf("finishTickers() = %+v, want only the game without a ticker", remaining)
+	}
+	edit, _, ok = bot.edits.next(time.Now().Add(editInterval))
+	want.content = "🏁 OG defeated Liquid (32 - 17, Game 2)"
+	if !ok || edit != want {
+		t.Errorf("edit = %+v, %v, want %+v", edit, ok, want)
+	}
+	bot.removeTickers(items)
+	if bot.tickers.has(1, "10") {
+		t.Error("Ticker of finished game not removed")
+	}
+}
+
+func TestRenderTickerLive(t *testing.T) {
+	game := dota.LiveLeagueGame{
+		GameNumber:  1,
+		RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+		DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+	}
+	game.Scoreboard.Duration = 125
+	game.Scoreboard.Dire.Score = 3
+	tests := []struct {
+		settings guildSettings
+		want     string
+	}{
+		{guildSettings{}, "🔴 Live: OG 0 - 3 Liquid (2:05, Game 1)"},
+		{guildSettings{ResultDetail: resultDetailGames}, "🔴 Live: OG vs. Liquid (2:05, Game 1)"},
+		{guildSettings{Accessible: true}, "Live, game 1: OG versus Liquid, 0 to 3 kills, after 2:05."},
+	}
+	for _, test := range tests {
+		if got := renderTickerLive(game, test.settings.textFormat()); got != test.want {
+			t.Errorf("renderTickerLive() with %+v = %q, want %q", test.settings, got, test.want)
+		}
+	}
+}
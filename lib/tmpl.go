@@ -5,28 +5,85 @@ import (
 	"text/template"
 )
 
-var tmplMatchesDrafting = template.Must(template.New("MatchesDrafting").Parse(strings.TrimSpace(`
+// newTemplate creates an announcement template. Templates are parsed with
+// the functions of an empty text format, which renderTemplate replaces
+// with those of the format of each guild.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(textFormat{}.templateFuncs())
+}
+
+var tmplMatchesDrafting = template.Must(newTemplate("MatchesDrafting").Parse(strings.TrimSpace(`
+{{ range . }}
+In Drafting: {{ .RadiantTeam.TeamName }} vs. {{ .DireTeam.TeamName }} ({{ with .Stage }}{{ . }}, {{ end }}Game {{ .GameNumber }})
+{{- end -}}
+`)))
+
+var tmplMatchesStarted = template.Must(newTemplate("MatchesStarted").Parse(strings.TrimSpace(`
 {{ range . }}
-In Drafting: {{ .RadiantTeam.TeamName }} vs. {{ .DireTeam.TeamName }} (Game {{ .GameNumber }})
+Match Started: {{ .RadiantTeam.TeamName }} vs. {{ .DireTeam.TeamName }} ({{ with .Stage }}{{ . }}, {{ end }}Game {{ .GameNumber }}{{ with clock }}, {{ . }}{{ end }})
+{{- with lineup .RadiantTeam .RadiantPlayers }}
+{{ . }}{{ end }}
+{{- with lineup .DireTeam .DirePlayers }}
+{{ . }}{{ end }}
 {{- end -}}
 `)))
 
-var tmplMatchesStarted = template.Must(template.New("MatchesStarted").Parse(strings.TrimSpace(`
+var tmplScoreUpdates = template.Must(newTemplate("ScoreUpdates").Parse(strings.TrimSpace(`
 {{ range . }}
-Match Started: {{ .RadiantTeam.TeamName }} vs. {{ .DireTeam.TeamName }} (Game {{ .GameNumber }})
+Score Update: {{ .Game.RadiantTeam.TeamName }} {{ score .RadiantScore .DireScore }} {{ .Game.DireTeam.TeamName }} ({{ .Duration }}, Game {{ .Game.GameNumber }}){{ if .Favored }}, {{ .Favored }} {{ .WinChance }}% favored{{ end }}
+{{- if .RoshanAlive }}, Roshan alive{{ else if .RoshanRespawn }}, Roshan up in {{ .RoshanRespawn }}{{ end }}
 {{- end -}}
 `)))
 
 type matchesFinishedDataItem struct {
+	MatchID     int64 `json:"match_id"`
 	GameNumber  int
 	WinnerName  string
 	LoserName   string
 	WinnerScore int
 	LoserScore  int
+
+	WinnerTeamID int
+	LoserTeamID  int
+	Importance   int
+	// SeriesID is the series of the match, or 0 if not known
+	SeriesID int64 `json:"series_id,omitempty"`
+	// Stage is the stage of the league the match was played in, or
+	// empty if not known, see dota.LiveLeagueGame.Stage
+	Stage string `json:"stage,omitempty"`
+	// UpsetWinnerSeed and UpsetLoserSeed are the seeds of the teams by
+	// Elo rating if the game was an upset, else 0, see eloRatings
+	UpsetWinnerSeed int `json:"upset_winner_seed,omitempty"`
+	UpsetLoserSeed  int `json:"upset_loser_seed,omitempty"`
+	// Remake is true if the game was remade rather than played out, see
+	// isRemake
+	Remake bool `json:"remake,omitempty"`
+	// Delayed is true if the result is announced late, as the details
+	// of the match were published long after it finished
+	Delayed bool `json:"delayed,omitempty"`
 }
 
-var tmplMatchesFinished = template.Must(template.New("MatchesFinished").Parse(strings.TrimSpace(`
+var tmplMatchesFinished = template.Must(newTemplate("MatchesFinished").Parse(strings.TrimSpace(`
 {{ range . }}
-Match Ended: {{ .WinnerName }} defeated {{ .LoserName }} ({{ .WinnerScore }} - {{ .LoserScore }}, Game {{ .GameNumber }})
+{{- if .Remake }}
+Game Remade: {{ .FirstTeam }} vs. {{ .SecondTeam }} (Game {{ .GameNumber }}) ended early and is replayed
+{{- else }}
+Match Ended{{ if .Delayed }} (delayed){{ end }}: {{ if winners }}{{ .WinnerName }} defeated {{ .LoserName }}{{ else }}{{ .FirstTeam }} vs. {{ .SecondTeam }}{{ end }} ({{ if kills }}{{ score .WinnerScore .LoserScore }}, {{ end }}{{ with .Stage }}{{ . }}, {{ end }}Game {{ .GameNumber }}{{ with clock }}, {{ . }}{{ end }})
+{{- if and winners .UpsetWinnerSeed }}
+Upset! #{{ .UpsetWinnerSeed }} seed defeats #{{ .UpsetLoserSeed }} seed{{ end }}
+{{- end }}
+{{- end -}}
+`)))
+
+var tmplFloodDigest = template.Must(newTemplate("FloodDigest").Parse(strings.TrimSpace(`
+{{ if .Started }}Other games started in the last hour:
+{{- range .Started }}
+- {{ .RadiantTeam.TeamName }} vs. {{ .DireTeam.TeamName }} (Game {{ .GameNumber }})
+{{- end }}
+{{ end }}
+{{- if .Finished }}Other games ended in the last hour:
+{{- range .Finished }}
+- {{ if winners }}{{ .WinnerName }} defeated {{ .LoserName }}{{ else }}{{ .FirstTeam }} vs. {{ .SecondTeam }}{{ end }} ({{ if kills }}{{ score .WinnerScore .LoserScore }}, {{ end }}Game {{ .GameNumber }})
+{{- end }}
 {{- end -}}
 `)))
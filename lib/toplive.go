@@ -0,0 +1,85 @@
+package timatch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// topLiveInterval is the time between polls of the top live games
+const topLiveInterval = 2 * time.Minute
+
+// matchStateTopLive is the state of a match announced as a top live game
+const matchStateTopLive = "toplive"
+
+// updateTopLiveGames announces the top live games of all of Dota that are
+// played by teams, or have an average MMR of at least topLiveMinMMR,
+// regardless of the league watched. Games of the watched league are left
+// to the regular announcements.
+func (bot *bot) updateTopLiveGames(ctx context.Context) {
+	if !bot.topLive || time.Since(bot.topLiveCheckedAt) < topLiveInterval {
+		return
+	}
+	bot.topLiveCheckedAt = time.Now()
+	res, err := bot.dotaClient.GetTopLiveGame(ctx, 0)
+	if err != nil {
+		bot.logger.WithError(err).Error("Error getting top live games")
+		return
+	}
+	var games []dota.TopLiveGame
+	for _, game := range res.GameList {
+		if !bot.isTopLiveAnnounced(game) {
+			continue
+		}
+		if _, ok := bot.topLiveSeen[game.MatchID]; ok {
+			continue
+		}
+		bot.topLiveSeen[game.MatchID] = time.Now()
+		if bot.claimMatchState(ctx, matchStateTopLive, game.MatchID) {
+			games = append(games, game)
+		}
+	}
+	if len(games) == 0 {
+		return
+	}
+	bot.sendGuildMessage(ctx, eventTopLive, func(channelID channelID, settings *guildSettings, sub *channelSubscription) string {
+		format := settings.channelTextFormat(string(channelID))
+		var lines []string
+		for _, game := range games {
+			if game.IsTeamGame() && (!bot.isAnnouncedTeam(game.RadiantTeamID, game.DireTeamID) || !sub.includesTeams(game.RadiantTeamID, game.DireTeamID)) {
+				continue
+			}
+			if !game.IsTeamGame() && sub != nil && len(sub.Teams) > 0 {
+				continue
+			}
+			if bot.claimAnnouncement(ctx, matchStateTopLive, game.MatchID, channelID) {
+				lines = append(lines, renderTopLiveGame(game, format))
+			}
+		}
+		return strings.Join(lines, "\n")
+	})
+}
+
+// isTopLiveAnnounced tests if a top live game should be announced
+func (bot *bot) isTopLiveAnnounced(game dota.TopLiveGame) bool {
+	if game.LeagueID != 0 && game.LeagueID == bot.leagueID {
+		return false
+	}
+	if game.IsTeamGame() {
+		return true
+	}
+	return bot.topLiveMinMMR > 0 && game.AverageMMR >= bot.topLiveMinMMR
+}
+
+// renderTopLiveGame renders the announcement of a top live game, e.g.
+// "📺 Live now: OG vs. Team Liquid (12,345 spectators)"
+func renderTopLiveGame(game dota.TopLiveGame, format textFormat) string {
+	spectators := format.Integer(int64(game.Spectators)) + " spectators"
+	if game.IsTeamGame() {
+		return fmt.Sprintf("📺 Live now: %s vs. %s (%s)", game.RadiantTeamName, game.DireTeamName, spectators)
+	}
+	return fmt.Sprintf("📺 Live now: a top game with an average MMR of %s (%s)", format.Integer(int64(game.AverageMMR)), spectators)
+}
@@ -0,0 +1,37 @@
+package timatch
+
+import (
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestIsTopLiveAnnounced(t *testing.T) {
+	bot := &bot{leagueID: 10749, topLiveMinMMR: 9000}
+	tests := []struct {
+		game dota.TopLiveGame
+		want bool
+	}{
+		{dota.TopLiveGame{RadiantTeamID: 1, DireTeamID: 2, LeagueID: 1}, true},
+		{dota.TopLiveGame{RadiantTeamID: 1, DireTeamID: 2, LeagueID: 10749}, false},
+		{dota.TopLiveGame{RadiantTeamID: 1, AverageMMR: 8000}, false},
+		{dota.TopLiveGame{AverageMMR: 9500}, true},
+	}
+	for _, test := range tests {
+		if got := bot.isTopLiveAnnounced(test.game); got != test.want {
+			t.Errorf("isTopLiveAnnounced(%+v) = %t, want %t", test.game, got, test.want)
+		}
+	}
+}
+
+func TestRenderTopLiveGame(t *testing.T) {
+	format := textFormat{number: numberLocales[0]}
+	game := dota.TopLiveGame{RadiantTeamID: 1, DireTeamID: 2, RadiantTeamName: "OG", DireTeamName: "Team Liquid", Spectators: 12345}
+	if got, want := renderTopLiveGame(game, format), "📺 Live now: OG vs. Team Liquid (12,345 spectators)"; got != want {
+		t.Errorf("renderTopLiveGame() = %q, want %q", got, want)
+	}
+	game = dota.TopLiveGame{AverageMMR: 9120, Spectators: 800}
+	if got, want := renderTopLiveGame(game, format), "📺 Live now: a top game with an average MMR of 9,120 (800 spectators)"; got != want {
+		t.Errorf("renderTopLiveGame() = %q, want %q", got, want)
+	}
+}
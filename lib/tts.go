@@ -0,0 +1,62 @@
+package timatch
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tts returns whether announcements of event are sent to the guild as TTS
+func (settings *guildSettings) tts(event string) bool {
+	if tts, ok := settings.TTS[event]; ok {
+		return tts
+	}
+	e, _ := findAnnouncementEvent(event)
+	return e.defaultTTS
+}
+
+// ttsString describes which announcements are sent to the guild as TTS
+func (settings *guildSettings) ttsString() string {
+	on := make([]string, 0, len(announcementEvents))
+	for _, event := range announcementEvents {
+		if settings.tts(event.name) {
+			on = append(on, event.name)
+		}
+	}
+	if len(on) == 0 {
+		return "off"
+	}
+	return strings.Join(on, ", ")
+}
+
+// setTTS parses and applies a tts setting, "on", "off" or "default" for
+// all announcements, or an event followed by one of those
+func (settings *guildSettings) setTTS(value string) error {
+	fields := strings.Fields(strings.ToLower(value))
+	events := announcementEvents
+	if len(fields) == 2 {
+		event, ok := findAnnouncementEvent(fields[0])
+		if !ok {
+			return errors.Errorf("Unknown announcement %q, give one of %s", fields[0], announcementEventNames())
+		}
+		events = []announcementEvent{event}
+		fields = fields[1:]
+	}
+	if len(fields) != 1 || (fields[0] != "on" && fields[0] != "off" && fields[0] != "default") {
+		return errors.Errorf("Give \"on\", \"off\" or \"default\", optionally after one of %s", announcementEventNames())
+	}
+	for _, event := range events {
+		if fields[0] == "default" {
+			delete(settings.TTS, event.name)
+			continue
+		}
+		if settings.TTS == nil {
+			settings.TTS = make(map[string]bool)
+		}
+		settings.TTS[event.name] = fields[0] == "on"
+	}
+	if len(settings.TTS) == 0 {
+		settings.TTS = nil
+	}
+	return nil
+}
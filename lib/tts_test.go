@@ -0,0 +1,30 @@
+package timatch
+
+import "testing"
+
+func TestGuildSettingsTTS(t *testing.T) {
+	settings := &guildSettings{}
+	if !settings.tts(eventStarted) || settings.tts(eventDrafting) || settings.tts(eventFollowUp) {
+		t.Errorf("tts() defaults wrong, got %s", settings.ttsString())
+	}
+	if err := settings.setTTS("finished off"); err != nil {
+		t.Fatalf("setTTS(finished off) error: %v", err)
+	}
+	if got := settings.ttsString(); got != "started" {
+		t.Errorf("ttsString() = %q, want started", got)
+	}
+	if err := settings.setTTS("off"); err != nil {
+		t.Fatalf("setTTS(off) error: %v", err)
+	}
+	if got := settings.ttsString(); got != "off" {
+		t.Errorf("ttsString() = %q, want off", got)
+	}
+	if err := settings.setTTS("Default"); err != nil || settings.TTS != nil {
+		t.Errorf("setTTS(default) = %v, TTS = %v, want defaults", err, settings.TTS)
+	}
+	for _, value := range []string{"", "maybe", "kills on", "started on now"} {
+		if err := settings.setTTS(value); err == nil {
+			t.Errorf("setTTS(%q) = no error, want error", value)
+		}
+	}
+}
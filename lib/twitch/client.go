@@ -0,0 +1,144 @@
+// Package twitch is a minimal client for the Twitch Helix API, used for
+// checking which broadcast channels are live.
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const apiBaseURL = "https://api.twitch.tv/helix"
+const tokenURL = "https://id.twitch.tv/oauth2/token"
+
+// maxLoginsPerRequest is the maximum number of user_login parameters
+// accepted by the streams endpoint
+const maxLoginsPerRequest = 100
+
+// Client authenticates using the client credentials flow, requesting a
+// new app access token when the current one is about to expire.
+type Client struct {
+	clientID     string
+	clientSecret string
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewClient(clientID, clientSecret string) *Client {
+	return &Client{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// accessToken returns a valid app access token
+func (client *Client) accessToken(ctx context.Context) (string, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.token != "" && time.Now().Before(client.tokenExpiry) {
+		return client.token, nil
+	}
+	query := url.Values{}
+	query.Set("client_id", client.clientID)
+	query.Set("client_secret", client.clientSecret)
+	query.Set("grant_type", "client_credentials")
+	req, err := http.NewRequest("POST", tokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating request")
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "Error sending request")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != 200 {
+		return "", errors.Errorf("Bad HTTP response status code: %d", res.StatusCode)
+	}
+	data := &tokenResponse{}
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		return "", errors.Wrap(err, "Error decoding result as JSON")
+	}
+	client.token = data.AccessToken
+	// Renew a minute early, so that a token is not used as it expires
+	client.tokenExpiry = time.Now().Add(time.Duration(data.ExpiresIn)*time.Second - time.Minute)
+	return client.token, nil
+}
+
+// Stream is a live stream
+type Stream struct {
+	UserLogin   string `json:"user_login"`
+	UserName    string `json:"user_name"`
+	Title       string `json:"title"`
+	Language    string `json:"language"`
+	ViewerCount int    `json:"viewer_count"`
+}
+
+type streamsResponse struct {
+	Data []Stream `json:"data"`
+}
+
+// GetLiveStreams returns the streams of the given user logins that are
+// currently live
+func (client *Client) GetLiveStreams(ctx context.Context, logins []string) ([]Stream, error) {
+	streams := make([]Stream, 0)
+	for start := 0; start < len(logins); start += maxLoginsPerRequest {
+		end := start + maxLoginsPerRequest
+		if end > len(logins) {
+			end = len(logins)
+		}
+		page, err := client.getStreams(ctx, logins[start:end])
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, page...)
+	}
+	return streams, nil
+}
+
+func (client *Client) getStreams(ctx context.Context, logins []string) ([]Stream, error) {
+	token, err := client.accessToken(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting access token")
+	}
+	query := url.Values{}
+	for _, login := range logins {
+		query.Add("user_login", login)
+	}
+	req, err := http.NewRequest("GET", apiBaseURL+"/streams?"+query.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating request")
+	}
+	req.Header.Set("Client-Id", client.clientID)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error sending request")
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized {
+		// Force a new token on the next request
+		client.mu.Lock()
+		client.token = ""
+		client.mu.Unlock()
+	}
+	if res.StatusCode != 200 {
+		return nil, errors.Errorf("Bad HTTP response status code: %d", res.StatusCode)
+	}
+	data := &streamsResponse{}
+	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+		return nil, errors.Wrap(err, "Error decoding result as JSON")
+	}
+	return data.Data, nil
+}
@@ -0,0 +1,527 @@
+package timatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// defaultWatcherInterval is the default of WatcherConfig.Interval
+const defaultWatcherInterval = updateInterval
+
+// historyInterval is the time between polls of the match history for
+// finished games, longer than the interval of the live games as finished
+// games are only announced once their details are available anyway
+const historyInterval = 2 * time.Minute
+
+// WatcherEvent is an event of the games of a league detected by a Watcher,
+// one of *DraftStarted, *GameStarted, *GameFinished, *SeriesFinished,
+// *GameDropped, *LiveGames and *PollFailed
+type WatcherEvent interface {
+	isWatcherEvent()
+}
+
+// DraftStarted is sent when a game of the league is first seen drafting
+type DraftStarted struct {
+	Game dota.LiveLeagueGame
+}
+
+// GameStarted is sent when a game of the league is past the drafting
+// phase
+type GameStarted struct {
+	Game dota.LiveLeagueGame
+}
+
+// GameFinished is sent when a started game is found in the match history
+// and its details are available. Game is the game as last seen live, of
+// which only the match id, and the game number if it could be inferred,
+// are known for games finished before the watcher started
+type GameFinished struct {
+	Game    dota.LiveLeagueGame
+	Details dota.MatchDetails
+	// Remake is true if the game was remade rather than played out, see
+	// isRemake
+	Remake bool
+	// Late is true if the details of the game were published long after
+	// it finished, see WatcherConfig.LateResults
+	Late bool
+}
+
+// SeriesFinished is sent after the GameFinished of the game deciding a
+// series, with the number of games won by each team
+type SeriesFinished struct {
+	SeriesID     int64
+	WinnerTeamID int
+	LoserTeamID  int
+	WinnerWins   int
+	LoserWins    int
+}
+
+// GameDropped is sent when a finished game is given up on, as its details
+// were not available within WatcherConfig.DetailsMaxAge and LateResults
+type GameDropped struct {
+	MatchID int64
+}
+
+// LiveGames is sent after the other events of each poll of the live games,
+// with the games live as of the poll
+type LiveGames struct {
+	Games []dota.LiveLeagueGame
+}
+
+// PollFailed is sent when polling the live games or the match history
+// failed. The events of a failed poll of the live games end with it
+// rather than a LiveGames
+type PollFailed struct {
+	Err error
+}
+
+func (*DraftStarted) isWatcherEvent()   {}
+func (*GameStarted) isWatcherEvent()    {}
+func (*GameFinished) isWatcherEvent()   {}
+func (*SeriesFinished) isWatcherEvent() {}
+func (*GameDropped) isWatcherEvent()    {}
+func (*LiveGames) isWatcherEvent()      {}
+func (*PollFailed) isWatcherEvent()     {}
+
+// WatcherConfig holds the configuration of a Watcher
+type WatcherConfig struct {
+	// SteamKey is the Steam Web API key used to poll the league
+	SteamKey string
+	// LeagueID is the id of the league to watch
+	LeagueID int
+	// Interval is the time between polls of the live games of the
+	// league. 0 for the default of 60 seconds
+	Interval time.Duration
+	// DetailsMaxAge is how long the details of a finished game are
+	// retried for before the game is dropped. 0 for the default of 10
+	// minutes
+	DetailsMaxAge time.Duration
+	// LateResults keeps checking for the details of a finished game for
+	// this long after DetailsMaxAge, every 15 minutes, sending the game
+	// as finished late if they are published. 0 to drop the game after
+	// DetailsMaxAge
+	LateResults time.Duration
+}
+
+// Watcher polls the live games and the match history of a league, sending
+// the games drafting, started and finished as events. It is the detection
+// of games of the Discord bot, for programs embedding it without the bot.
+//
+// The games of the league are polled every interval, and the match history
+// every historyInterval while there are games to find finished. The
+// details of the finished games are fetched in the background, see
+// detailFetcher, and sent as finished with the events of the next poll of
+// the live games, which is done as soon as they are fetched.
+type Watcher struct {
+	logger *logrus.Logger
+	source matchDataSource
+	// sequence is the source of the sequence of all recorded matches, or
+	// nil to get the full match history every poll, see
+	// updateFinishedBySequence
+	sequence      sequenceSource
+	detailsMaxAge time.Duration
+	lateResults   time.Duration
+	pruneAfter    time.Duration
+	// announceLive is true if the games already live on the first poll
+	// are sent as drafting and started, rather than only tracked
+	announceLive bool
+	// since is the time before the watcher started that games must have
+	// started for their results to be sent, or 0 for no limit, see
+	// isBeforeCutoff
+	since time.Duration
+	// backfill is the period the games finished before the watcher
+	// started are sent as finished for, see backfillFinished
+	backfill time.Duration
+	// follow tests if a live game is watched, or is nil to watch all
+	// games of the league. Games of any league are polled if following
+	// and leagueID is 0, see setLeague
+	follow func(game dota.LiveLeagueGame) bool
+	// claim records a match as seen in a state, e.g. matchStateStarted,
+	// returning false if it already was, such as by a previous run
+	// sharing the store of the bot. Matches are only sent as drafting,
+	// started or finished if claimed. nil to claim all matches
+	claim   func(ctx context.Context, state string, matchID int64) bool
+	events  chan WatcherEvent
+	details *detailFetcher
+
+	// mu guards the state below, shared by the tasks polling the live
+	// games and the match history. Not held during API requests
+	mu        sync.Mutex
+	leagueID  int
+	following bool
+	interval  time.Duration
+	startedAt time.Time
+	// livePolled is true once the live games have been polled
+	livePolled bool
+	drafting   map[int64]struct{}
+	started    map[int64]struct{}
+	finished   map[int64]struct{}
+	// live are the games seen live, as last seen, by match id
+	live map[int64]dota.LiveLeagueGame
+	// lastLive are the live games of the last poll
+	lastLive []dota.LiveLeagueGame
+	// finishedAt are the times the matches were found finished, and
+	// seenAt the times they were last seen live, by match id, see prune
+	finishedAt map[int64]time.Time
+	seenAt     map[int64]time.Time
+	prunedAt   time.Time
+	// startTimes are the starts of the live games seen, as unix
+	// timestamps by match id, see trackStartTime
+	startTimes map[int64]int64
+	// matchLeagues are the leagues of the games followed, by match id,
+	// see historyLeagues
+	matchLeagues map[int64]int
+	// backfilled are the inferred game numbers of the games queued by
+	// backfillFinished, by match id
+	backfilled map[int64]int
+	// finishedQueue are the finished games yet to have their details
+	// fetched
+	finishedQueue    []finishedQueueEntry
+	matchSeqNum      int64
+	backfilledLeague int
+	// historyErr is the error of the last poll of the match history, sent
+	// with the events of the next poll of the live games
+	historyErr error
+}
+
+// NewWatcher creates a Watcher of the league of config
+func NewWatcher(logger *logrus.Logger, config WatcherConfig) (*Watcher, error) {
+	if config.LeagueID == 0 {
+		return nil, errors.New("Error creating watcher: no league id")
+	}
+	client, err := dota.NewClient(logger, config.SteamKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating dota client")
+	}
+	watcher := newWatcher(logger, client)
+	watcher.sequence = client
+	watcher.leagueID = config.LeagueID
+	watcher.announceLive = true
+	watcher.lateResults = config.LateResults
+	if config.Interval != 0 {
+		watcher.interval = config.Interval
+	}
+	if config.DetailsMaxAge != 0 {
+		watcher.detailsMaxAge = config.DetailsMaxAge
+	}
+	return watcher, nil
+}
+
+func newWatcher(logger *logrus.Logger, source matchDataSource) *Watcher {
+	return &Watcher{
+		logger:        logger,
+		source:        source,
+		interval:      defaultWatcherInterval,
+		detailsMaxAge: defaultDetailsMaxAge,
+		pruneAfter:    defaultPruneAfter,
+		events:        make(chan WatcherEvent),
+		details:       newDetailFetcher(),
+		drafting:      make(map[int64]struct{}),
+		started:       make(map[int64]struct{}),
+		finished:      make(map[int64]struct{}),
+		live:          make(map[int64]dota.LiveLeagueGame),
+		finishedAt:    make(map[int64]time.Time),
+		seenAt:        make(map[int64]time.Time),
+		startTimes:    make(map[int64]int64),
+		matchLeagues:  make(map[int64]int),
+		backfilled:    make(map[int64]int),
+	}
+}
+
+// Events returns the channel the events are sent on, closed when Run
+// returns. The events must be received for the watcher to keep polling.
+// The events of a poll of the live games are sent together, ending with a
+// LiveGames, or a PollFailed if the poll failed.
+func (watcher *Watcher) Events() <-chan WatcherEvent {
+	return watcher.events
+}
+
+// Run polls the league until ctx is done
+func (watcher *Watcher) Run(ctx context.Context) error {
+	defer close(watcher.events)
+	var history sync.WaitGroup
+	defer history.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watcher.mu.Lock()
+	watcher.startedAt = time.Now()
+	watcher.mu.Unlock()
+	watcher.details.start(ctx, watcher.source.GetMatchDetails)
+	history.Add(1)
+	go func() {
+		defer history.Done()
+		runTask(ctx, watcher.pollHistory)
+	}()
+	for {
+		for _, event := range watcher.pollLive(ctx) {
+			select {
+			case watcher.events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		watcher.mu.Lock()
+		interval := watcher.interval
+		watcher.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		case <-watcher.details.wake:
+			// Polled right away, for the finished games to be sent
+			// as soon as their details are fetched
+		}
+	}
+}
+
+// setLeague sets the league watched. Games of any league are watched if
+// following, see follow
+func (watcher *Watcher) setLeague(leagueID int, following bool) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	watcher.leagueID = leagueID
+	watcher.following = following
+}
+
+// setInterval sets the time until the next poll of the live games, e.g.
+// shorter while a notable team is playing
+func (watcher *Watcher) setInterval(interval time.Duration) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	watcher.interval = interval
+}
+
+// isWatching tests if there are games to poll: of the watched league, or
+// followed in any league
+func (watcher *Watcher) isWatching() bool {
+	return watcher.leagueID != 0 || watcher.following
+}
+
+// queueLen returns the number of finished games yet to have their details
+// fetched
+func (watcher *Watcher) queueLen() int {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	return len(watcher.finishedQueue)
+}
+
+// isTracked tests if the watcher still keeps the state of a match, which
+// is forgotten some time after it finished, see prune
+func (watcher *Watcher) isTracked(matchID int64) bool {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	_, isDrafting := watcher.drafting[matchID]
+	_, isStarted := watcher.started[matchID]
+	_, isFinished := watcher.finished[matchID]
+	return isDrafting || isStarted || isFinished
+}
+
+// restore records a match as seen in a state by a previous run, so that
+// it is not sent again, see bot.loadState
+func (watcher *Watcher) restore(state string, matchID int64, now time.Time) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	switch state {
+	case matchStateDrafting:
+		watcher.drafting[matchID] = struct{}{}
+		watcher.seenAt[matchID] = now
+	case matchStateStarted:
+		watcher.started[matchID] = struct{}{}
+		watcher.seenAt[matchID] = now
+	case matchStateFinished:
+		watcher.finished[matchID] = struct{}{}
+		watcher.finishedAt[matchID] = now
+	}
+}
+
+func (watcher *Watcher) claimState(ctx context.Context, state string, matchID int64) bool {
+	return watcher.claim == nil || watcher.claim(ctx, state, matchID)
+}
+
+// updateLive updates the games seen live from a poll of the live games,
+// returning the events of the games first seen drafting and started
+func (watcher *Watcher) updateLive(ctx context.Context, games []dota.LiveLeagueGame, now time.Time) []WatcherEvent {
+	var drafting, started []WatcherEvent
+	// Games already live on the first poll are only tracked, so that
+	// restarts do not repeat their events
+	suppress := !watcher.livePolled && !watcher.announceLive
+	watcher.livePolled = true
+	watcher.lastLive = watcher.lastLive[:0]
+	for _, game := range games {
+		if watcher.follow != nil && !watcher.follow(game) {
+			continue
+		}
+		if watcher.following {
+			watcher.matchLeagues[game.MatchID] = game.LeagueID
+		}
+		if game.GameNumber == 0 {
+			game.GameNumber = game.RadiantSeriesWins + game.DireSeriesWins + 1
+		}
+		watcher.lastLive = append(watcher.lastLive, game)
+		watcher.live[game.MatchID] = game
+		watcher.seenAt[game.MatchID] = now
+		watcher.trackStartTime(game, now)
+		if !isGameStarted(game) {
+			if _, ok := watcher.drafting[game.MatchID]; !ok {
+				watcher.drafting[game.MatchID] = struct{}{}
+				if watcher.claimState(ctx, matchStateDrafting, game.MatchID) && !suppress {
+					drafting = append(drafting, &DraftStarted{Game: game})
+				}
+			}
+		} else {
+			if _, ok := watcher.started[game.MatchID]; !ok {
+				watcher.started[game.MatchID] = struct{}{}
+				if watcher.claimState(ctx, matchStateStarted, game.MatchID) && !suppress {
+					started = append(started, &GameStarted{Game: game})
+				}
+			}
+		}
+	}
+	return append(drafting, started...)
+}
+
+// hasUnfinishedGames tests if any game seen started is yet to be found
+// finished
+func (watcher *Watcher) hasUnfinishedGames() bool {
+	for matchID := range watcher.started {
+		if _, ok := watcher.finished[matchID]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFinished queues a match of the match history for its details to
+// be fetched, if seen started and not already finished
+func (watcher *Watcher) matchFinished(ctx context.Context, match dota.MatchHistoryMatch) {
+	_, isStarted := watcher.started[match.MatchID]
+	_, isFinished := watcher.finished[match.MatchID]
+	if !isStarted || isFinished {
+		return
+	}
+	logger := watcher.logger.WithField(logFieldMatchID, match.MatchID)
+	logger.Debugf("Match finished %d", match.MatchID)
+	watcher.markFinished(match.MatchID, time.Now())
+	beforeCutoff := watcher.isBeforeCutoff(match)
+	delete(watcher.startTimes, match.MatchID)
+	delete(watcher.matchLeagues, match.MatchID)
+	if beforeCutoff {
+		logger.Warnf("Ignoring match %d, started before the cutoff", match.MatchID)
+		return
+	}
+	if !watcher.claimState(ctx, matchStateFinished, match.MatchID) {
+		return
+	}
+	watcher.finishedQueue = append(watcher.finishedQueue, finishedQueueEntry{MatchID: match.MatchID, AddedAt: time.Now()})
+}
+
+// collectFinished returns the events of the games of the finished queue
+// whose details have been fetched in the background, see detailFetcher.
+// Games whose details could not be fetched are retried, backing off, until
+// they have been queued for longer than detailsMaxAge, then checked for a
+// late result until detailsMaxAge and lateResults.
+func (watcher *Watcher) collectFinished(now time.Time) []WatcherEvent {
+	var events []WatcherEvent
+	remainingQueue := make([]finishedQueueEntry, 0, len(watcher.finishedQueue))
+	fetched := watcher.details.collect()
+	for _, entry := range watcher.finishedQueue {
+		fetch, ok := fetched[entry.MatchID]
+		if !ok {
+			remainingQueue = append(remainingQueue, entry)
+			continue
+		}
+		if fetch.err != nil {
+			logger := watcher.logger.WithField(logFieldMatchID, entry.MatchID)
+			logger.WithError(fetch.err).Debugf("Error getting match details for %d", entry.MatchID)
+			age := now.Sub(entry.AddedAt)
+			if age <= watcher.detailsMaxAge {
+				entry.Attempts++
+				backoff := detailsBackoff(entry.Attempts)
+				entry.NextAttemptAt = now.Add(backoff)
+				logger.Debugf("Trying %d again in %s", entry.MatchID, backoff)
+				remainingQueue = append(remainingQueue, entry)
+			} else if age <= watcher.detailsMaxAge+watcher.lateResults {
+				if !entry.Late {
+					logger.Warnf("No match details for %d yet, checking for a late result every %s", entry.MatchID, lateResultsInterval)
+				}
+				entry.Late = true
+				entry.NextAttemptAt = now.Add(lateResultsInterval)
+				remainingQueue = append(remainingQueue, entry)
+			} else {
+				logger.Errorf("Giving up on fetching match details for %d", entry.MatchID)
+				events = append(events, &GameDropped{MatchID: entry.MatchID})
+				delete(watcher.live, entry.MatchID)
+				delete(watcher.backfilled, entry.MatchID)
+			}
+			continue
+		}
+		game, ok := watcher.live[entry.MatchID]
+		if !ok {
+			game = dota.LiveLeagueGame{MatchID: entry.MatchID, GameNumber: watcher.backfilled[entry.MatchID]}
+		}
+		finished := &GameFinished{
+			Game:    game,
+			Details: *fetch.details.Result.MatchDetails,
+			Remake:  isRemake(entry.MatchID, fetch.details.Result.MatchDetails, watcher.lastLive),
+			Late:    entry.Late,
+		}
+		events = append(events, finished)
+		if !finished.Remake && ok {
+			if series := seriesFinished(game, finished.Details.RadiantWin); series != nil {
+				events = append(events, series)
+			}
+		}
+		delete(watcher.live, entry.MatchID)
+		delete(watcher.backfilled, entry.MatchID)
+	}
+	watcher.finishedQueue = remainingQueue
+	return events
+}
+
+// seriesFinished returns the SeriesFinished of a finished game, by the
+// series wins of the teams as last seen live, or nil if the game did not
+// decide its series
+func seriesFinished(game dota.LiveLeagueGame, radiantWin bool) *SeriesFinished {
+	needed := 1
+	switch game.SeriesType {
+	case seriesTypeBestOf3:
+		needed = 2
+	case seriesTypeBestOf5:
+		needed = 3
+	}
+	series := &SeriesFinished{
+		SeriesID:     game.SeriesID,
+		WinnerTeamID: game.RadiantTeam.TeamID,
+		LoserTeamID:  game.DireTeam.TeamID,
+		WinnerWins:   game.RadiantSeriesWins + 1,
+		LoserWins:    game.DireSeriesWins,
+	}
+	if !radiantWin {
+		series.WinnerTeamID, series.LoserTeamID = series.LoserTeamID, series.WinnerTeamID
+		series.WinnerWins, series.LoserWins = game.DireSeriesWins+1, game.RadiantSeriesWins
+	}
+	if series.WinnerWins < needed {
+		return nil
+	}
+	return series
+}
+
+// isGameStarted tests if a game is past the drafting phase.
+func isGameStarted(game dota.LiveLeagueGame) bool {
+	if game.Scoreboard.Duration > 0 {
+		return true
+	}
+	// We check for 9 picks rather than 10 to err a bit
+	// on the safe side (we don't want to miss a game starting!)
+	direPicks := game.Scoreboard.Dire.Picks
+	radiantPicks := game.Scoreboard.Radiant.Picks
+	if len(direPicks)+len(radiantPicks) >= 9 {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package timatch
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/verath/timatch/lib/dota"
+)
+
+// scriptedDataSource gives the live games of each poll in turn, the
+// matches of history as the match history, and the details of the matches
+// in details
+type scriptedDataSource struct {
+	polls   [][]dota.LiveLeagueGame
+	history []dota.MatchHistoryMatch
+	details map[int64]*dota.MatchDetails
+}
+
+func (source *scriptedDataSource) GetLiveLeagueGames(ctx context.Context, leagueID int) (*dota.LiveLeagueGamesResponse, error) {
+	res := &dota.LiveLeagueGamesResponse{}
+	if len(source.polls) > 0 {
+		res.Result.Games = source.polls[0]
+		source.polls = source.polls[1:]
+	}
+	return res, nil
+}
+
+func (source *scriptedDataSource) GetMatchHistory(ctx context.Context, leagueID int) (*dota.MatchHistoryResponse, error) {
+	res := &dota.MatchHistoryResponse{}
+	res.Result.Matches = source.history
+	return res, nil
+}
+
+func (source *scriptedDataSource) GetMatchDetails(ctx context.Context, matchID int64) (*dota.MatchDetailsResponse, error) {
+	details, ok := source.details[matchID]
+	if !ok {
+		return nil, errors.New("no details")
+	}
+	res := &dota.MatchDetailsResponse{}
+	res.Result.MatchDetails = details
+	return res, nil
+}
+
+func TestWatcherPoll(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	drafting := dota.LiveLeagueGame{
+		MatchID:           1,
+		SeriesID:          7,
+		SeriesType:        seriesTypeBestOf3,
+		RadiantSeriesWins: 1,
+		RadiantTeam:       dota.LiveLeagueGamesTeam{TeamID: 10},
+		DireTeam:          dota.LiveLeagueGamesTeam{TeamID: 20},
+	}
+	started := drafting
+	started.Scoreboard.Duration = 60
+	source := &scriptedDataSource{
+		polls:   [][]dota.LiveLeagueGame{{drafting}, {started}, {}, {started}},
+		details: make(map[int64]*dota.MatchDetails),
+	}
+	watcher := newWatcher(logger, source)
+	watcher.setLeague(10749, false)
+	watcher.announceLive = true
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher.details.start(ctx, source.GetMatchDetails)
+	if events := watcher.pollLive(ctx); len(events) != 2 {
+		t.Fatalf("expected a DraftStarted, got %v", events)
+	} else if _, ok := events[0].(*DraftStarted); !ok {
+		t.Errorf("expected a DraftStarted, got %T", events[0])
+	}
+	if events := watcher.pollLive(ctx); len(events) != 2 {
+		t.Fatalf("expected a GameStarted, got %v", events)
+	} else if _, ok := events[0].(*GameStarted); !ok {
+		t.Errorf("expected a GameStarted, got %T", events[0])
+	}
+	source.history = []dota.MatchHistoryMatch{{MatchID: 1}}
+	source.details[1] = &dota.MatchDetails{RadiantWin: true, Duration: 2400, RadiantScore: 30}
+	watcher.pollHistory(ctx)
+	select {
+	case <-watcher.details.wake:
+	case <-time.After(time.Second):
+		t.Fatal("expected the details of match 1 to be fetched")
+	}
+	events := watcher.pollLive(ctx)
+	if len(events) != 3 {
+		t.Fatalf("expected a GameFinished, a SeriesFinished and the live games, got %v", events)
+	}
+	if finished, ok := events[0].(*GameFinished); !ok || finished.Game.MatchID != 1 {
+		t.Errorf("expected a GameFinished of match 1, got %v", events[0])
+	}
+	want := SeriesFinished{SeriesID: 7, WinnerTeamID: 10, LoserTeamID: 20, WinnerWins: 2, LoserWins: 0}
+	if series, ok := events[1].(*SeriesFinished); !ok || *series != want {
+		t.Errorf("expected %+v, got %+v", want, events[1])
+	}
+	// Still listed live after finishing, which must not start it again
+	if events := watcher.pollLive(ctx); len(events) != 1 {
+		t.Errorf("expected only the live games, got %v", events)
+	}
+}
+
+func TestSeriesFinished(t *testing.T) {
+	game := dota.LiveLeagueGame{
+		SeriesType:     seriesTypeBestOf5,
+		DireSeriesWins: 1,
+		RadiantTeam:    dota.LiveLeagueGamesTeam{TeamID: 10},
+		DireTeam:       dota.LiveLeagueGamesTeam{TeamID: 20},
+	}
+	if series := seriesFinished(game, false); series != nil {
+		t.Errorf("expected 2-0 not to finish a Bo5, got %+v", series)
+	}
+	game.DireSeriesWins = 2
+	if series := seriesFinished(game, false); series == nil || series.WinnerTeamID != 20 || series.WinnerWins != 3 {
+		t.Errorf("expected the dire team to win the Bo5 3-0, got %+v", series)
+	}
+}
@@ -0,0 +1,52 @@
+package timatch
+
+import (
+	"math"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+// minFavoredChance is the win probability, in percent, below which a live
+// game is a toss-up and no team is shown as favored
+const minFavoredChance = 55
+
+// winProbability estimates the probability of radiant winning a live game
+// from the net worth and kill leads. A net worth lead counts for less as
+// the game goes on, as the net worth of both teams grows: a 10k lead at
+// 25 minutes is about 80%, at 50 minutes about 68%. ok is false if the
+// net worth of the players is not known.
+func winProbability(game dota.LiveLeagueGame) (radiant float64, ok bool) {
+	scoreboard := game.Scoreboard
+	if len(scoreboard.Radiant.Players) == 0 || len(scoreboard.Dire.Players) == 0 {
+		return 0, false
+	}
+	netWorthLead := 0
+	for _, player := range scoreboard.Radiant.Players {
+		netWorthLead += player.NetWorth
+	}
+	for _, player := range scoreboard.Dire.Players {
+		netWorthLead -= player.NetWorth
+	}
+	minutes := float64(scoreboard.Duration) / 60
+	killLead := scoreboard.Radiant.Score - scoreboard.Dire.Score
+	x := float64(netWorthLead)/(1000+250*minutes) + 0.02*float64(killLead)
+	return 1 / (1 + math.Exp(-x)), true
+}
+
+// favoredTeam returns the name of the team favored to win a live game and
+// its win probability in percent. name is empty if the game is a toss-up
+// or the probability is not known.
+func favoredTeam(game dota.LiveLeagueGame) (name string, chance int) {
+	radiant, ok := winProbability(game)
+	if !ok {
+		return "", 0
+	}
+	name, chance = game.RadiantTeam.TeamName, int(math.Round(100*radiant))
+	if chance < 50 {
+		name, chance = game.DireTeam.TeamName, 100-chance
+	}
+	if chance < minFavoredChance {
+		return "", 0
+	}
+	return name, chance
+}
@@ -0,0 +1,37 @@
+package timatch
+
+import (
+	"testing"
+
+	"github.com/verath/timatch/lib/dota"
+)
+
+func TestFavoredTeam(t *testing.T) {
+	game := func(radiantNetWorth, direNetWorth int, duration float32) dota.LiveLeagueGame {
+		game := dota.LiveLeagueGame{
+			RadiantTeam: dota.LiveLeagueGamesTeam{TeamName: "OG"},
+			DireTeam:    dota.LiveLeagueGamesTeam{TeamName: "Liquid"},
+		}
+		game.Scoreboard.Duration = duration
+		game.Scoreboard.Radiant.Players = []dota.LiveLeagueGameScoreboardPlayer{{NetWorth: radiantNetWorth}}
+		game.Scoreboard.Dire.Players = []dota.LiveLeagueGameScoreboardPlayer{{NetWorth: direNetWorth}}
+		return game
+	}
+	tests := []struct {
+		game   dota.LiveLeagueGame
+		name   string
+		chance int
+	}{
+		{game(40000, 30000, 25*60), "OG", 80},
+		{game(30000, 40000, 25*60), "Liquid", 80},
+		{game(60000, 50000, 50*60), "OG", 68},
+		// Toss-ups favor no one
+		{game(30500, 30000, 25*60), "", 0},
+		{dota.LiveLeagueGame{}, "", 0},
+	}
+	for _, test := range tests {
+		if name, chance := favoredTeam(test.game); name != test.name || chance != test.chance {
+			t.Errorf("favoredTeam() = %q, %d, want %q, %d", name, chance, test.name, test.chance)
+		}
+	}
+}
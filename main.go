@@ -2,43 +2,317 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
-	"github.com/sirupsen/logrus"
+	"fmt"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/verath/timatch/lib"
+	"github.com/verath/timatch/lib/apiclient"
+	"github.com/verath/timatch/lib/sentry"
+	"github.com/verath/timatch/lib/storage"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "state" {
+		if err := runState(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "leagues" {
+		if err := runLeagues(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	var (
-		discordToken string
-		steamKey     string
-		leagueID     uint
-		debug        bool
+		discordToken  string
+		steamKey      string
+		leagueID      uint
+		autoLeague    bool
+		leagueName    string
+		storageURL    string
+		httpAddr      string
+		pprof         bool
+		floodControl  bool
+		notableTeams  string
+		teams         string
+		ignoreTeams   string
+		minImportance int
+		logFormat     string
+		sentryDSN     string
+		adminChannel  string
+		adminUser     string
+		prizeDist     string
+		prizeStep     int64
+		prizeRecord   int64
+		bracket       bool
+		draftReads    bool
+		summaryAfter  time.Duration
+		backfill      time.Duration
+		announceLive  bool
+		since         time.Duration
+		detailsMaxAge time.Duration
+		lateResults   time.Duration
+		pruneAfter    time.Duration
+		eventLog      string
+		historyScan   int
+		records       bool
+		matchStats    bool
+		schedule      bool
+		topLive       bool
+		followTeams   bool
+		matchIDs      string
+		topLiveMMR    int
+		hypeAlerts    string
+		twitchID      string
+		twitchSecret  string
+		stratzToken   string
+		dataSource    string
+		communityURL  string
+		streams       string
+		debug         bool
+		chaos         string
+		templateDir   string
+		languageDir   string
+		coalesce      time.Duration
 	)
 	flag.StringVar(&discordToken, "discordtoken", "", "Discord bot token")
 	flag.StringVar(&steamKey, "steamkey", "", "Steam API Key")
+	flag.StringVar(&stratzToken, "stratztoken", "", "STRATZ API token, for getting league games from STRATZ when the Steam API fails")
+	flag.StringVar(&dataSource, "datasource", "steam", "Primary source of league games, steam or stratz (requires stratztoken)")
 	flag.UintVar(&leagueID, "leagueid", 0, "Dota 2 league id of the league to watch")
+	flag.StringVar(&leagueName, "leaguename", "", "Name of the league to watch, resolved to its league id, e.g. \"The International 2024\"")
+	flag.BoolVar(&autoLeague, "autoleague", false, "Automatically detect and watch the current year's The International")
+	flag.StringVar(&storageURL, "storage", "memory://", "Storage for bot state, memory:// or redis://[:password@]host[:port][/db]")
+	flag.StringVar(&httpAddr, "http", "", "Address to serve admin HTTP endpoints (/healthz) on, e.g. :8080")
+	flag.BoolVar(&pprof, "pprof", false, "Serve net/http/pprof on the admin HTTP listener (requires -http)")
+	flag.BoolVar(&floodControl, "floodcontrol", false, "Only announce games of notable teams, with other games in an hourly digest")
+	flag.StringVar(&notableTeams, "notableteams", "", "Comma separated list of team ids of notable teams")
+	flag.StringVar(&teams, "teams", "", "Comma separated list of team ids, only games of these teams are announced")
+	flag.BoolVar(&followTeams, "follow", false, "Announce the official games of the teams in -teams in any league, instead of the games of a league")
+	flag.StringVar(&matchIDs, "matchids", "", "Comma separated list of match ids of games to announce in any league, e.g. showmatches, instead of the games of a league")
+	flag.StringVar(&ignoreTeams, "ignoreteams", "", "Comma separated list of team ids of teams whose games are not announced")
+	flag.IntVar(&minImportance, "minimportance", 0, "Minimum importance score (0-100) of games to announce")
+	flag.BoolVar(&bracket, "bracket", false, "Post the playoff bracket when a playoff series finishes")
+	flag.BoolVar(&draftReads, "draftreads", false, "Post a read of the drafts, e.g. \"heavy teamfight\", when games start")
+	flag.BoolVar(&records, "records", false, "Announce tournament records, e.g. the longest game, as they are broken")
+	flag.StringVar(&hypeAlerts, "hypealerts", "", "Comma separated list of in-game alerts to send: kills, tied, comeback, rapier, megacreeps and roshan")
+	flag.StringVar(&communityURL, "communitypredictions", "", "URL of aggregate community prediction data (JSON), compared against each server's predictions in /leaderboard")
+	flag.BoolVar(&matchStats, "matchstats", false, "Post the stats of finished games, e.g. the KDA of the players, once parsed by OpenDota")
+	flag.BoolVar(&schedule, "schedule", false, "Announce series about to start, and post the schedule of each day")
+	flag.BoolVar(&topLive, "toplive", false, "Announce live games of teams outside the league too, from the top live games of Dota")
+	flag.IntVar(&topLiveMMR, "topliveminmmr", 0, "With toplive, also announce top live games of at least this average MMR, e.g. 9000")
+	flag.DurationVar(&backfill, "backfill", 0, "Announce the results of games that finished up to this long ago without being announced, e.g. 24h")
+	flag.BoolVar(&announceLive, "announcelive", false, "Announce games that are already drafting or started when the bot starts")
+	flag.DurationVar(&since, "since", 0, "Ignore the results of games started more than this long before the bot started (default 3h)")
+	flag.DurationVar(&detailsMaxAge, "detailsmaxage", 0, "How long to retry getting the details of a finished game before giving up on its result (default 10m)")
+	flag.DurationVar(&lateResults, "lateresults", 0, "After detailsmaxage, keep checking every 15 minutes for this long for results published late, e.g. 6h")
+	flag.DurationVar(&pruneAfter, "pruneafter", 0, "How long to keep the state of finished games, and of games no longer live, in memory (default 24h)")
+	flag.StringVar(&eventLog, "eventlog", "", "File to append the games drafting, started and finished to, as JSON lines, e.g. events.jsonl")
+	flag.IntVar(&historyScan, "historyscan", 0, "Number of recent messages of each channel to scan for games already announced on startup, e.g. 50")
+	flag.DurationVar(&summaryAfter, "summaryafter", 0, "Post the tournament report once the league has had no live games for this long, e.g. 48h")
+	flag.StringVar(&adminChannel, "adminchannel", "", "Discord channel id to send operational alerts to")
+	flag.StringVar(&adminUser, "adminuser", "", "Discord user id to send operational alerts to as direct messages")
+	flag.StringVar(&prizeDist, "prizedistribution", timatch.DefaultPrizeDistribution, "Prize pool distribution, as a list of place:percent")
+	flag.Int64Var(&prizeStep, "prizemilestone", 0, "Announce the prize pool passing each multiple of this many dollars, e.g. 1000000")
+	flag.Int64Var(&prizeRecord, "prizerecord", 0, "Announce the prize pool passing this many dollars as the largest ever")
+	flag.StringVar(&twitchID, "twitchclientid", "", "Twitch application client id, for checking which -streams are live")
+	flag.StringVar(&twitchSecret, "twitchclientsecret", "", "Twitch application client secret")
+	flag.StringVar(&streams, "streams", "", "Comma separated list of broadcast channels as language=twitch login, e.g. English=dota2ti")
+	flag.DurationVar(&coalesce, "coalesce", 0, "Collect the messages to each channel for this long and send them as one, e.g. 30s")
+	flag.StringVar(&languageDir, "languagedir", "", "Directory of language bundles, a directory per language named by its code")
+	flag.StringVar(&templateDir, "templatedir", "", "Directory of template files replacing the announcement templates, e.g. finished.tmpl")
+	flag.StringVar(&sentryDSN, "sentrydsn", "", "Sentry DSN to report errors to")
+	flag.StringVar(&logFormat, "logformat", "text", "Log format, text or json")
 	flag.BoolVar(&debug, "debug", false, "True to log debug messages")
+	flag.StringVar(&chaos, "chaos", "", "Faults to inject into API requests, for testing, e.g. fail=0.1,delay=0.2,maxdelay=5s,malformed=0.05")
+	flag.Usage = usage
 	flag.Parse()
 
 	logger := logrus.New()
 	if debug {
 		logger.Level = logrus.DebugLevel
 	}
+	if sentryDSN != "" {
+		hook, err := sentry.NewHook(sentryDSN)
+		if err != nil {
+			logger.WithError(err).Fatal("Error creating sentry hook")
+		}
+		logger.AddHook(hook)
+	}
+	switch logFormat {
+	case "text":
+	case "json":
+		logger.Formatter = &logrus.JSONFormatter{}
+	default:
+		logger.Fatalf("Unknown logformat: %s", logFormat)
+	}
 	if discordToken == "" {
 		logger.Fatal("discordtoken is required")
 	}
 	if steamKey == "" {
 		logger.Fatal("steamkey is required")
 	}
-	if leagueID == 0 {
-		logger.Fatal("leagueid is required")
+	if leagueName != "" {
+		if leagueID != 0 {
+			logger.Fatal("leagueid and leaguename are mutually exclusive")
+		}
+		id, err := resolveLeagueName(logger, steamKey, leagueName)
+		if err != nil {
+			logger.WithError(err).Fatal("Error resolving leaguename")
+		}
+		logger.Infof("Resolved league %q to league id %d", leagueName, id)
+		leagueID = uint(id)
+	}
+	if followTeams {
+		if leagueID != 0 || autoLeague {
+			logger.Fatal("follow is mutually exclusive with leagueid, leaguename and autoleague")
+		}
+		if teams == "" {
+			logger.Fatal("follow requires teams to be set")
+		}
+		if dataSource != "steam" {
+			logger.Fatal("follow requires the steam datasource")
+		}
+	}
+	watchMatchIDs, err := parseMatchIDs(matchIDs)
+	if err != nil {
+		logger.WithError(err).Fatal("Error parsing matchids")
+	}
+	if len(watchMatchIDs) > 0 {
+		if leagueID != 0 || autoLeague || followTeams {
+			logger.Fatal("matchids is mutually exclusive with leagueid, leaguename, autoleague and follow")
+		}
+		if dataSource != "steam" {
+			logger.Fatal("matchids requires the steam datasource")
+		}
+	}
+	if leagueID == 0 && !autoLeague && !topLive && !followTeams && len(watchMatchIDs) == 0 {
+		logger.Fatal("leagueid is required unless leaguename, autoleague, toplive, follow or matchids is set")
+	}
+	if pprof && httpAddr == "" {
+		logger.Fatal("pprof requires http to be set")
+	}
+	if chaos != "" {
+		chaosConfig, err := apiclient.ParseChaos(chaos)
+		if err != nil {
+			logger.WithError(err).Fatal("Error parsing chaos")
+		}
+		logger.Warnf("Chaos mode enabled, injecting faults into API requests: %+v", *chaosConfig)
+		apiclient.SetChaos(chaosConfig)
 	}
-	bot, err := timatch.NewBot(logger, discordToken, steamKey, int(leagueID))
+	notableTeamIDs, err := parseTeamIDs(notableTeams)
 	if err != nil {
-		logger.Fatal("Error creating bot")
+		logger.WithError(err).Fatal("Error parsing notableteams")
+	}
+	teamIDs, err := parseTeamIDs(teams)
+	if err != nil {
+		logger.WithError(err).Fatal("Error parsing teams")
+	}
+	ignoreTeamIDs, err := parseTeamIDs(ignoreTeams)
+	if err != nil {
+		logger.WithError(err).Fatal("Error parsing ignoreteams")
+	}
+	prizeDistribution, err := timatch.ParsePrizeDistribution(prizeDist)
+	if err != nil {
+		logger.WithError(err).Fatal("Error parsing prizedistribution")
+	}
+	broadcastChannels, err := timatch.ParseBroadcastChannels(streams)
+	if err != nil {
+		logger.WithError(err).Fatal("Error parsing streams")
+	}
+	if len(broadcastChannels) > 0 && (twitchID == "" || twitchSecret == "") {
+		logger.Fatal("streams requires twitchclientid and twitchclientsecret to be set")
+	}
+	templates, err := readTemplates(templateDir)
+	if err != nil {
+		logger.WithError(err).Fatal("Error reading templatedir")
+	}
+	languages, err := readLanguageBundles(languageDir)
+	if err != nil {
+		logger.WithError(err).Fatal("Error reading languagedir")
+	}
+	store, err := storage.Open(storageURL)
+	if err != nil {
+		logger.WithError(err).Fatal("Error opening storage")
+	}
+	defer store.Close()
+	bot, err := timatch.NewBot(logger, timatch.Config{
+		DiscordToken:     discordToken,
+		SteamKey:         steamKey,
+		LeagueID:         int(leagueID),
+		AutoDetectLeague: autoLeague,
+		Store:            store,
+
+		HTTPAddr:           httpAddr,
+		PProf:              pprof,
+		FloodControl:       floodControl,
+		NotableTeams:       notableTeamIDs,
+		Teams:              teamIDs,
+		IgnoreTeams:        ignoreTeamIDs,
+		MinImportance:      minImportance,
+		BracketUpdates:     bracket,
+		DraftReads:         draftReads,
+		SummaryAfter:       summaryAfter,
+		Backfill:           backfill,
+		AnnounceLive:       announceLive,
+		Since:              since,
+		DetailsMaxAge:      detailsMaxAge,
+		LateResults:        lateResults,
+		PruneAfter:         pruneAfter,
+		EventLog:           eventLog,
+		HistoryScan:        historyScan,
+		Records:            records,
+		MatchStats:         matchStats,
+		Schedule:           schedule,
+		TopLive:            topLive,
+		FollowTeams:        followTeams,
+		MatchIDs:           watchMatchIDs,
+		TopLiveMinMMR:      topLiveMMR,
+		HypeAlerts:         strings.Split(hypeAlerts, ","),
+		AdminChannelID:     adminChannel,
+		AdminUserID:        adminUser,
+		PrizeDistribution:  prizeDistribution,
+		PrizeMilestoneStep: prizeStep,
+		PrizeRecord:        prizeRecord,
+		TwitchClientID:     twitchID,
+		StratzToken:        stratzToken,
+		DataSource:         dataSource,
+		TwitchClientSecret: twitchSecret,
+		BroadcastChannels:  broadcastChannels,
+		Templates:          templates,
+		Languages:          languages,
+		CoalesceWindow:     coalesce,
+
+		CommunityPredictionsURL: communityURL,
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("Error creating bot")
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -52,8 +326,125 @@ func main() {
 	logger.Info("Starting...")
 	err = bot.Run(ctx)
 	if errors.Cause(err) == context.Canceled {
-		logger.Debugf("Error caught in main: %+v", err)
+		logger.WithError(err).Debug("Error caught in main")
 	} else {
-		logger.Fatalf("Error caught in main: %+v", err)
+		logger.WithError(err).Fatal("Error caught in main")
+	}
+}
+
+// hiddenFlags are flags left out of the usage message, as they are only
+// meant for testing
+var hiddenFlags = map[string]bool{"chaos": true}
+
+// usage prints the usage message of the flags, except the hidden ones
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+	flag.VisitAll(func(f *flag.Flag) {
+		if hiddenFlags[f.Name] {
+			return
+		}
+		fmt.Fprintf(flag.CommandLine.Output(), "  -%s\n    \t%s", f.Name, f.Usage)
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			fmt.Fprintf(flag.CommandLine.Output(), " (default %q)", f.DefValue)
+		}
+		fmt.Fprintln(flag.CommandLine.Output())
+	})
+}
+
+// readTemplates reads the template files of dir, named after the template
+// they replace, e.g. finished.tmpl. Returns no templates if dir is empty.
+func readTemplates(dir string) (map[string]string, error) {
+	templates := make(map[string]string)
+	if dir == "" {
+		return templates, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		templates[strings.TrimSuffix(filepath.Base(path), ".tmpl")] = string(b)
+	}
+	return templates, nil
+}
+
+// languageBundleFile is the file of a language bundle describing the
+// language, next to its template files
+type languageBundleFile struct {
+	Name   string            `json:"name"`
+	Heroes map[int]string    `json:"heroes"`
+	Teams  map[string]string `json:"teams"`
+}
+
+// readLanguageBundles reads the language bundles in dir, each a
+// directory named by the language code holding a language.json and
+// template files as those of -templatedir
+func readLanguageBundles(dir string) ([]timatch.LanguageBundle, error) {
+	bundles := make([]timatch.LanguageBundle, 0)
+	if dir == "" {
+		return bundles, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "language.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var file languageBundleFile
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, errors.Wrapf(err, "Error parsing %s", path)
+		}
+		templates, err := readTemplates(filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, timatch.LanguageBundle{
+			Code:      filepath.Base(filepath.Dir(path)),
+			Name:      file.Name,
+			Templates: templates,
+			HeroNames: file.Heroes,
+			TeamNames: file.Teams,
+		})
+	}
+	return bundles, nil
+}
+
+// parseTeamIDs parses a comma separated list of team ids
+func parseTeamIDs(s string) ([]int, error) {
+	teamIDs := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		teamID, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid team id %q", part)
+		}
+		teamIDs = append(teamIDs, teamID)
+	}
+	return teamIDs, nil
+}
+
+func parseMatchIDs(s string) ([]int64, error) {
+	matchIDs := make([]int64, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		matchID, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid match id %q", part)
+		}
+		matchIDs = append(matchIDs, matchID)
 	}
+	return matchIDs, nil
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/storage"
+)
+
+// runMigrate implements the migrate subcommand, copying all bot state
+// from one storage to another and verifying the copy
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var fromURL, toURL string
+	flags.StringVar(&fromURL, "from", "", "Storage to copy from, as the -storage flag of the bot")
+	flags.StringVar(&toURL, "to", "", "Storage to copy to, as the -storage flag of the bot")
+	flags.Parse(args)
+	if fromURL == "" || toURL == "" {
+		return fmt.Errorf("from and to are required")
+	}
+	if fromURL == toURL {
+		return fmt.Errorf("from and to must be different storages")
+	}
+	from, err := storage.Open(fromURL)
+	if err != nil {
+		return errors.Wrap(err, "Error opening storage to copy from")
+	}
+	defer from.Close()
+	to, err := storage.Open(toURL)
+	if err != nil {
+		return errors.Wrap(err, "Error opening storage to copy to")
+	}
+	defer to.Close()
+
+	ctx := context.Background()
+	snapshot, err := storage.Export(ctx, from)
+	if err != nil {
+		return err
+	}
+	n, err := storage.Import(ctx, to, snapshot)
+	if err != nil {
+		return err
+	}
+	if err := storage.Verify(ctx, to, snapshot); err != nil {
+		return errors.Wrap(err, "Error verifying copied state")
+	}
+	fmt.Fprintf(os.Stderr, "Copied and verified %d values\n", n)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/verath/timatch/lib"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runRender implements the render subcommand, printing an announcement
+// template rendered against a fixture so that templates can be tried out
+// without running the bot
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	var (
+		templateArg  string
+		fixturePath  string
+		scoreStyle   string
+		numberLocale string
+		language     string
+		layout       string
+	)
+	flags.StringVar(&templateArg, "template", "", "Template to render, one of "+strings.Join(timatch.PreviewTemplateNames(), ", ")+
+		", or a template file named after one of them, e.g. finished.tmpl")
+	flags.StringVar(&fixturePath, "fixture", "", "JSON file with the data of the template, defaults to a bundled fixture")
+	flags.StringVar(&scoreStyle, "scorestyle", "", "Score style to render with, as the scorestyle setting")
+	flags.StringVar(&numberLocale, "numbers", "", "Number locale to render with, as the numbers setting")
+	flags.StringVar(&language, "language", "", "Language to render the bot's templates in, as the language setting")
+	flags.StringVar(&layout, "layout", "", "Layout to render the bot's templates in, as the layout setting")
+	flags.Parse(args)
+	if templateArg == "" {
+		return fmt.Errorf("template is required")
+	}
+	name, text := templateArg, ""
+	if _, err := os.Stat(templateArg); err == nil {
+		b, err := ioutil.ReadFile(templateArg)
+		if err != nil {
+			return err
+		}
+		base := filepath.Base(templateArg)
+		name, text = strings.TrimSuffix(base, filepath.Ext(base)), string(b)
+	}
+	var fixture []byte
+	if fixturePath != "" {
+		var err error
+		if fixture, err = ioutil.ReadFile(fixturePath); err != nil {
+			return err
+		}
+	}
+	msg, err := timatch.RenderPreview(name, text, fixture, timatch.PreviewFormat{
+		ScoreStyle:   scoreStyle,
+		NumberLocale: numberLocale,
+		Language:     language,
+		Layout:       layout,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(msg)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/verath/timatch/lib/storage"
+)
+
+// runState implements the state subcommand, exporting the bot state of a
+// store to a snapshot file or importing a snapshot into a store, so that
+// a bot can be moved to another host without losing state
+func runState(args []string) error {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		return fmt.Errorf("usage: timatch state export|import -storage url [-file snapshot.json]")
+	}
+	action := args[0]
+	flags := flag.NewFlagSet("state "+action, flag.ExitOnError)
+	var (
+		storageURL string
+		file       string
+	)
+	flags.StringVar(&storageURL, "storage", "", "Storage to "+action+", as the -storage flag of the bot")
+	flags.StringVar(&file, "file", "", "Snapshot file, defaults to stdout for export and stdin for import")
+	flags.Parse(args[1:])
+	if storageURL == "" {
+		return fmt.Errorf("storage is required")
+	}
+	store, err := storage.Open(storageURL)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	ctx := context.Background()
+	if action == "export" {
+		return exportState(ctx, store, file)
+	}
+	return importState(ctx, store, file)
+}
+
+func exportState(ctx context.Context, store storage.Store, file string) error {
+	snapshot, err := storage.Export(ctx, store)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snapshot); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d values\n", len(snapshot.Entries))
+	return nil
+}
+
+func importState(ctx context.Context, store storage.Store, file string) error {
+	var r io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var snapshot storage.Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return errors.Wrap(err, "Error decoding snapshot")
+	}
+	n, err := storage.Import(ctx, store, &snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d of %d values\n", n, len(snapshot.Entries))
+	return nil
+}